@@ -0,0 +1,35 @@
+// +build linux
+// +build !android
+
+package ui
+
+// backendView fills itself with red and reports when it has drawn and
+// where the pointer went down.
+type backendView struct {
+	View
+	drawn chan struct{}
+	down  chan Position
+}
+
+func (v *backendView) Draw(gfx *Graphics) {
+	r := v.Rect()
+	var p Path
+	p.MoveTo(r.Min)
+	p.LineTo(Position{X: r.Max.X, Y: r.Min.Y})
+	p.LineTo(r.Max)
+	p.LineTo(Position{X: r.Min.X, Y: r.Max.Y})
+	p.Close()
+	gfx.Fill(&p, Paint{Color: Color{1, 0, 0, 1}})
+	select {
+	case v.drawn <- struct{}{}:
+	default:
+	}
+}
+
+func (v *backendView) PointerDown(p Pointer) bool {
+	select {
+	case v.down <- p.Position:
+	default:
+	}
+	return true
+}
@@ -0,0 +1,616 @@
+package ui
+
+import (
+	"sort"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// bidiClass returns the bidirectional class of r.
+func bidiClass(r rune) bidi.Class {
+	p, _ := bidi.LookupRune(r)
+	return p.Class()
+}
+
+// baseLevel returns the embedding level of a paragraph from its first
+// strong character outside isolates (rules P2 and P3), or 0 if it has none.
+func baseLevel(classes []bidi.Class) uint8 {
+	level, _ := firstStrong(classes, false)
+	return level
+}
+
+// firstStrong returns the level, 0 or 1, of the first strong character of
+// classes that is not in an isolate, and whether there is one.  If pdi is
+// set, the search ends at a PDI that closes no isolate within classes, as
+// for the text of an FSI.
+func firstStrong(classes []bidi.Class, pdi bool) (uint8, bool) {
+	depth := 0
+	for _, c := range classes {
+		switch c {
+		case bidi.L:
+			if depth == 0 {
+				return 0, true
+			}
+		case bidi.R, bidi.AL:
+			if depth == 0 {
+				return 1, true
+			}
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			depth++
+		case bidi.PDI:
+			if depth > 0 {
+				depth--
+			} else if pdi {
+				return 0, false
+			}
+		case bidi.B:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// maxBidiDepth is the deepest explicit embedding level (BD2).
+const maxBidiDepth = 125
+
+// bidiLevels resolves the embedding levels of the characters of a paragraph
+// with the given classes, following the Unicode Bidirectional Algorithm
+// (UAX #9) from its explicit embeddings through its implicit levels.
+//
+// The characters that rule X9 removes take the level of the character
+// before them.  Rule L1 is left to resetWhitespaceLevels, as it applies to
+// each line.
+func bidiLevels(runes []rune, classes []bidi.Class, para uint8) []uint8 {
+	n := len(classes)
+	levels := make([]uint8, n)
+	t := make([]bidi.Class, n)
+	copy(t, classes)
+	match := matchingPDIs(classes)
+
+	// X1-X8: explicit levels and directions.
+	type status struct {
+		level    uint8
+		override bidi.Class
+		isolate  bool
+	}
+	stack := []status{{para, bidi.ON, false}}
+	overflowIsolates, overflowEmbeddings, validIsolates := 0, 0, 0
+	for i, c := range classes {
+		top := stack[len(stack)-1]
+		switch c {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO, bidi.RLI, bidi.LRI, bidi.FSI:
+			isolate := c == bidi.RLI || c == bidi.LRI || c == bidi.FSI
+			levels[i] = top.level
+			if isolate && top.override != bidi.ON {
+				t[i] = top.override
+			}
+			rtl := c == bidi.RLE || c == bidi.RLO || c == bidi.RLI
+			if c == bidi.FSI {
+				level, _ := firstStrong(classes[i+1:], true)
+				rtl = level == 1
+			}
+			level := (top.level + 2) &^ 1
+			if rtl {
+				level = (top.level + 1) | 1
+			}
+			if level <= maxBidiDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				override := bidi.ON
+				switch c {
+				case bidi.RLO:
+					override = bidi.R
+				case bidi.LRO:
+					override = bidi.L
+				}
+				if isolate {
+					validIsolates++
+				}
+				stack = append(stack, status{level, override, isolate})
+			} else if isolate {
+				overflowIsolates++
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+		case bidi.PDI:
+			if overflowIsolates > 0 {
+				overflowIsolates--
+			} else if validIsolates > 0 {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			levels[i] = top.level
+			if top.override != bidi.ON {
+				t[i] = top.override
+			}
+		case bidi.PDF:
+			levels[i] = top.level
+			switch {
+			case overflowIsolates > 0:
+			case overflowEmbeddings > 0:
+				overflowEmbeddings--
+			case !top.isolate && len(stack) > 1:
+				stack = stack[:len(stack)-1]
+			}
+		case bidi.B:
+			// X8: a paragraph separator ends everything.
+			levels[i] = para
+			stack = stack[:1]
+			overflowIsolates, overflowEmbeddings, validIsolates = 0, 0, 0
+		case bidi.BN:
+			levels[i] = top.level
+		default:
+			levels[i] = top.level
+			if top.override != bidi.ON {
+				t[i] = top.override
+			}
+		}
+	}
+
+	// X9: embeddings, overrides and boundary neutrals are removed.
+	var kept []int
+	pos := make([]int, n)
+	for i, c := range classes {
+		pos[i] = -1
+		if !removedByX9(c) {
+			pos[i] = len(kept)
+			kept = append(kept, i)
+		}
+	}
+
+	// X10: the remaining characters are resolved in isolating run
+	// sequences:  level runs joined across isolates.  Their ends are
+	// compared with the explicit levels around them, before any are resolved.
+	explicit := append([]uint8(nil), levels...)
+	var runStarts []int
+	runOf := make([]int, len(kept))
+	for k, i := range kept {
+		if k == 0 || levels[i] != levels[kept[k-1]] {
+			runStarts = append(runStarts, k)
+		}
+		runOf[k] = len(runStarts) - 1
+	}
+	runEnd := func(r int) int {
+		if r+1 < len(runStarts) {
+			return runStarts[r+1]
+		}
+		return len(kept)
+	}
+	for r, start := range runStarts {
+		if first := kept[start]; classes[first] == bidi.PDI && match[first] >= 0 {
+			// This run continues the sequence of the isolate's initiator.
+			continue
+		}
+		var seq []int
+		for cur := r; ; {
+			seq = append(seq, kept[runStarts[cur]:runEnd(cur)]...)
+			last := seq[len(seq)-1]
+			if !isIsolateInitiator(classes[last]) || match[last] == n {
+				break
+			}
+			next := runOf[pos[match[last]]]
+			if kept[runStarts[next]] != match[last] {
+				break
+			}
+			cur = next
+		}
+
+		level := explicit[seq[0]]
+		before, after := para, para
+		if k := pos[seq[0]]; k > 0 {
+			before = explicit[kept[k-1]]
+		}
+		if last := seq[len(seq)-1]; !isIsolateInitiator(classes[last]) {
+			if k := pos[last]; k+1 < len(kept) {
+				after = explicit[kept[k+1]]
+			}
+		}
+		resolveSequence(seq, runes, classes, t, levels, direction(max8(level, before)), direction(max8(level, after)))
+	}
+
+	// The removed characters take the level of the character before them.
+	for i, c := range classes {
+		if removedByX9(c) {
+			levels[i] = para
+			if i > 0 {
+				levels[i] = levels[i-1]
+			}
+		}
+	}
+	return levels
+}
+
+// resolveSequence resolves the types of an isolating run sequence of
+// characters, given by their indices, with rules W1 to N2, and then their
+// levels with rules I1 and I2.  sos and eos are the directions at its
+// start and end.
+func resolveSequence(seq []int, runes []rune, classes, t []bidi.Class, levels []uint8, sos, eos bidi.Class) {
+	e := direction(levels[seq[0]])
+
+	// W1: nonspacing marks take the type of what they mark.
+	for k, i := range seq {
+		if t[i] != bidi.NSM {
+			continue
+		}
+		switch {
+		case k == 0:
+			t[i] = sos
+		case isIsolateControl(t[seq[k-1]]):
+			t[i] = bidi.ON
+		default:
+			t[i] = t[seq[k-1]]
+		}
+	}
+
+	// W2, W3: European numbers after Arabic letters are Arabic numbers, and
+	// Arabic letters are right-to-left.
+	last := sos
+	for _, i := range seq {
+		switch t[i] {
+		case bidi.L, bidi.R:
+			last = t[i]
+		case bidi.AL:
+			last = bidi.AL
+			t[i] = bidi.R
+		case bidi.EN:
+			if last == bidi.AL {
+				t[i] = bidi.AN
+			}
+		}
+	}
+
+	// W4: a single separator between numbers of the same type joins them.
+	for k := 1; k+1 < len(seq); k++ {
+		a, b := t[seq[k-1]], t[seq[k+1]]
+		switch t[seq[k]] {
+		case bidi.ES:
+			if a == bidi.EN && b == bidi.EN {
+				t[seq[k]] = bidi.EN
+			}
+		case bidi.CS:
+			if a == b && (a == bidi.EN || a == bidi.AN) {
+				t[seq[k]] = a
+			}
+		}
+	}
+
+	// W5: terminators next to European numbers are part of them.
+	for k := 0; k < len(seq); {
+		if t[seq[k]] != bidi.ET {
+			k++
+			continue
+		}
+		j := k
+		for j < len(seq) && t[seq[j]] == bidi.ET {
+			j++
+		}
+		if k > 0 && t[seq[k-1]] == bidi.EN || j < len(seq) && t[seq[j]] == bidi.EN {
+			for ; k < j; k++ {
+				t[seq[k]] = bidi.EN
+			}
+		}
+		k = j
+	}
+
+	// W6: remaining separators and terminators are neutral.
+	for _, i := range seq {
+		switch t[i] {
+		case bidi.ES, bidi.ET, bidi.CS:
+			t[i] = bidi.ON
+		}
+	}
+
+	// W7: European numbers in left-to-right text are left-to-right.
+	last = sos
+	for _, i := range seq {
+		switch t[i] {
+		case bidi.L, bidi.R:
+			last = t[i]
+		case bidi.EN:
+			if last == bidi.L {
+				t[i] = bidi.L
+			}
+		}
+	}
+
+	// N0: paired brackets take the direction of what they enclose, or of
+	// what precedes them if that agrees.
+	for _, p := range bracketPairs(seq, runes, t) {
+		d := bidi.ON
+		for k := p[0] + 1; k < p[1]; k++ {
+			if s := strongDirection(t[seq[k]]); s == e {
+				d = e
+				break
+			} else if s != bidi.ON {
+				d = s
+			}
+		}
+		if d == bidi.ON {
+			continue
+		}
+		if d != e {
+			prior := sos
+			for k := p[0] - 1; k >= 0; k-- {
+				if s := strongDirection(t[seq[k]]); s != bidi.ON {
+					prior = s
+					break
+				}
+			}
+			if prior != d {
+				d = e
+			}
+		}
+		for _, k := range p {
+			t[seq[k]] = d
+			// Marks on the brackets follow them.
+			for k++; k < len(seq) && classes[seq[k]] == bidi.NSM; k++ {
+				t[seq[k]] = d
+			}
+		}
+	}
+
+	// N1, N2: other neutrals between text of one direction take it, and the
+	// rest take the embedding direction.  Numbers count as right-to-left.
+	for k := 0; k < len(seq); {
+		if !isNeutralOrIsolate(t[seq[k]]) {
+			k++
+			continue
+		}
+		j := k
+		for j < len(seq) && isNeutralOrIsolate(t[seq[j]]) {
+			j++
+		}
+		before, after := sos, eos
+		if k > 0 {
+			before = strongDirection(t[seq[k-1]])
+		}
+		if j < len(seq) {
+			after = strongDirection(t[seq[j]])
+		}
+		d := e
+		if before == after {
+			d = before
+		}
+		for ; k < j; k++ {
+			t[seq[k]] = d
+		}
+	}
+
+	// I1, I2: implicit levels.
+	for _, i := range seq {
+		if levels[i]%2 == 0 {
+			switch t[i] {
+			case bidi.R:
+				levels[i]++
+			case bidi.AN, bidi.EN:
+				levels[i] += 2
+			}
+		} else {
+			switch t[i] {
+			case bidi.L, bidi.EN, bidi.AN:
+				levels[i]++
+			}
+		}
+	}
+}
+
+// bracketPairs returns the positions in an isolating run sequence of its
+// paired brackets, in order of their opening brackets (rule BD16).
+func bracketPairs(seq []int, runes []rune, t []bidi.Class) [][2]int {
+	// maxDepth is the size of the stack of opening brackets, past which
+	// pairs are no longer sought.
+	const maxDepth = 63
+	type opener struct {
+		bracket rune
+		k       int
+	}
+	var stack []opener
+	var pairs [][2]int
+loop:
+	for k, i := range seq {
+		if t[i] != bidi.ON {
+			continue
+		}
+		b, open := pairedBracket(runes[i])
+		switch {
+		case b == 0:
+		case open:
+			if len(stack) == maxDepth {
+				break loop
+			}
+			stack = append(stack, opener{b, k})
+		default:
+			for j := len(stack) - 1; j >= 0; j-- {
+				if stack[j].bracket == b {
+					pairs = append(pairs, [2]int{stack[j].k, k})
+					stack = stack[:j]
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return pairs
+}
+
+// pairedBracket returns the opening bracket of the pair that r belongs to,
+// or 0 if it is not a paired bracket, and whether r opens the pair.
+// Canonically equivalent brackets are identified.
+func pairedBracket(r rune) (rune, bool) {
+	open := true
+	if _, ok := closingBrackets[r]; !ok {
+		o, ok := openingBrackets[r]
+		if !ok {
+			return 0, false
+		}
+		r, open = o, false
+	}
+	switch r {
+	case 0x2329:
+		r = 0x3008
+	}
+	return r, open
+}
+
+// closingBrackets maps the opening brackets of the Bidi_Paired_Bracket
+// property to their closing brackets.
+var closingBrackets = map[rune]rune{
+	0x0028: 0x0029, 0x005B: 0x005D, 0x007B: 0x007D, 0x0F3A: 0x0F3B, 0x0F3C: 0x0F3D,
+	0x169B: 0x169C, 0x2045: 0x2046, 0x207D: 0x207E, 0x208D: 0x208E, 0x2308: 0x2309,
+	0x230A: 0x230B, 0x2329: 0x232A, 0x2768: 0x2769, 0x276A: 0x276B, 0x276C: 0x276D,
+	0x276E: 0x276F, 0x2770: 0x2771, 0x2772: 0x2773, 0x2774: 0x2775, 0x27C5: 0x27C6,
+	0x27E6: 0x27E7, 0x27E8: 0x27E9, 0x27EA: 0x27EB, 0x27EC: 0x27ED, 0x27EE: 0x27EF,
+	0x2983: 0x2984, 0x2985: 0x2986, 0x2987: 0x2988, 0x2989: 0x298A, 0x298B: 0x298C,
+	0x298D: 0x2990, 0x298F: 0x298E, 0x2991: 0x2992, 0x2993: 0x2994, 0x2995: 0x2996,
+	0x2997: 0x2998, 0x29D8: 0x29D9, 0x29DA: 0x29DB, 0x29FC: 0x29FD, 0x2E22: 0x2E23,
+	0x2E24: 0x2E25, 0x2E26: 0x2E27, 0x2E28: 0x2E29, 0x2E55: 0x2E56, 0x2E57: 0x2E58,
+	0x2E59: 0x2E5A, 0x2E5B: 0x2E5C, 0x3008: 0x3009, 0x300A: 0x300B, 0x300C: 0x300D,
+	0x300E: 0x300F, 0x3010: 0x3011, 0x3014: 0x3015, 0x3016: 0x3017, 0x3018: 0x3019,
+	0x301A: 0x301B, 0xFE59: 0xFE5A, 0xFE5B: 0xFE5C, 0xFE5D: 0xFE5E, 0xFF08: 0xFF09,
+	0xFF3B: 0xFF3D, 0xFF5B: 0xFF5D, 0xFF5F: 0xFF60, 0xFF62: 0xFF63,
+}
+
+// openingBrackets is the inverse of closingBrackets.
+var openingBrackets = map[rune]rune{}
+
+func init() {
+	for o, c := range closingBrackets {
+		openingBrackets[c] = o
+	}
+}
+
+// matchingPDIs returns, for each isolate initiator, the index of its
+// matching PDI or len(classes) if it has none, and for each PDI, the index of
+// its initiator or -1 if it has none (rule BD9).
+func matchingPDIs(classes []bidi.Class) []int {
+	match := make([]int, len(classes))
+	var open []int
+	for i, c := range classes {
+		match[i] = -1
+		switch c {
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			match[i] = len(classes)
+			open = append(open, i)
+		case bidi.PDI:
+			if len(open) > 0 {
+				j := open[len(open)-1]
+				open = open[:len(open)-1]
+				match[i], match[j] = j, i
+			}
+		case bidi.B:
+			open = open[:0]
+		}
+	}
+	return match
+}
+
+// resetWhitespaceLevels sets the levels of the segment and paragraph
+// separators of a line, and of any white space and isolate formatting
+// characters before them or at the end of the line, to the paragraph level
+// (rule L1).
+func resetWhitespaceLevels(classes []bidi.Class, levels []uint8, para uint8) {
+	reset := true
+	for i := len(classes) - 1; i >= 0; i-- {
+		switch c := classes[i]; {
+		case c == bidi.S || c == bidi.B:
+			levels[i] = para
+			reset = true
+		case c == bidi.WS || isIsolateControl(c) || removedByX9(c):
+			if reset {
+				levels[i] = para
+			}
+		default:
+			reset = false
+		}
+	}
+}
+
+func direction(level uint8) bidi.Class {
+	if level%2 == 1 {
+		return bidi.R
+	}
+	return bidi.L
+}
+
+// strongDirection returns the direction that a resolved type counts as for
+// neutrals, or ON if it is neutral.
+func strongDirection(c bidi.Class) bidi.Class {
+	switch c {
+	case bidi.L:
+		return bidi.L
+	case bidi.R, bidi.AL, bidi.EN, bidi.AN:
+		return bidi.R
+	}
+	return bidi.ON
+}
+
+func max8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func removedByX9(c bidi.Class) bool {
+	switch c {
+	case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO, bidi.PDF, bidi.BN:
+		return true
+	}
+	return false
+}
+
+func isIsolateInitiator(c bidi.Class) bool {
+	return c == bidi.LRI || c == bidi.RLI || c == bidi.FSI
+}
+
+func isIsolateControl(c bidi.Class) bool {
+	return isIsolateInitiator(c) || c == bidi.PDI
+}
+
+func isNeutralOrIsolate(c bidi.Class) bool {
+	switch c {
+	case bidi.B, bidi.S, bidi.WS, bidi.ON:
+		return true
+	}
+	return isIsolateControl(c)
+}
+
+// visualOrder returns the indices of items with the given levels in the
+// order they are displayed from left to right (rule L2).
+func visualOrder(levels []uint8) []int {
+	n := len(levels)
+	order := make([]int, n)
+	lv := make([]uint8, n)
+	copy(lv, levels)
+	var max, minOdd uint8 = 0, 255
+	for i, l := range levels {
+		order[i] = i
+		if l > max {
+			max = l
+		}
+		if l%2 == 1 && l < minOdd {
+			minOdd = l
+		}
+	}
+	for l := max; l >= minOdd && l > 0; l-- {
+		for i := 0; i < n; {
+			if lv[i] < l {
+				i++
+				continue
+			}
+			j := i
+			for j < n && lv[j] >= l {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+				lv[a], lv[b] = lv[b], lv[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
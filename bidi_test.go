@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// TestBidiCharacterTest checks the levels and order of the samples of the
+// Unicode conformance test in testdata.
+func TestBidiCharacterTest(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "BidiCharacterTest.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) != 5 {
+			t.Fatalf("line %d: malformed", n)
+		}
+		var runes []rune
+		var classes []bidi.Class
+		for _, x := range strings.Fields(fields[0]) {
+			r, err := strconv.ParseUint(x, 16, 32)
+			if err != nil {
+				t.Fatalf("line %d: %v", n, err)
+			}
+			runes = append(runes, rune(r))
+			classes = append(classes, bidiClass(rune(r)))
+		}
+
+		var para uint8
+		switch fields[1] {
+		case "1":
+			para = 1
+		case "2":
+			para = baseLevel(classes)
+		}
+		if got := strconv.Itoa(int(para)); got != fields[2] {
+			t.Errorf("line %d: got paragraph level %s, want %s", n, got, fields[2])
+			continue
+		}
+
+		levels := bidiLevels(runes, classes, para)
+		resetWhitespaceLevels(classes, levels, para)
+		// Characters removed by rule X9 have no level and are not ordered.
+		var got []string
+		var kept []int
+		var keptLevels []uint8
+		for i, w := range strings.Fields(fields[3]) {
+			if w == "x" {
+				got = append(got, "x")
+				continue
+			}
+			got = append(got, strconv.Itoa(int(levels[i])))
+			kept = append(kept, i)
+			keptLevels = append(keptLevels, levels[i])
+		}
+		if g, w := strings.Join(got, " "), strings.Join(strings.Fields(fields[3]), " "); g != w {
+			t.Errorf("line %d: %s: got levels %s, want %s", n, fields[0], g, w)
+			continue
+		}
+		var order []string
+		for _, i := range visualOrder(keptLevels) {
+			order = append(order, strconv.Itoa(kept[i]))
+		}
+		if g, w := strings.Join(order, " "), strings.Join(strings.Fields(fields[4]), " "); g != w {
+			t.Errorf("line %d: %s: got order %s, want %s", n, fields[0], g, w)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
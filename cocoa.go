@@ -20,11 +20,14 @@ uintptr_t newWindow(double width, double height);
 void makeCurrentContext(uintptr_t ctx);
 void flushContext(uintptr_t ctx);
 NSPoint mapFromScreen(uintptr_t window, NSPoint pt);
+NSSize screenSize();
+void setTextInput(uintptr_t window, int enabled, int x, int y, int width, int height);
 */
 import "C"
 
 import (
 	"log"
+	"math"
 	"runtime"
 )
 
@@ -51,10 +54,13 @@ func applicationDidFinishLaunching() {
 	go appCallback()
 }
 
-func newWindowImpl(size Size) uintptr {
-	return uintptr(C.newWindow(C.double(size.Width), C.double(size.Height)))
+func newWindowImpl(size Size) (uintptr, error) {
+	return uintptr(C.newWindow(C.double(size.Width), C.double(size.Height))), nil
 }
 
+// mapWindowImpl does nothing, as Cocoa windows are shown by newWindow.
+func mapWindowImpl(w uintptr) {}
+
 //export preparedOpenGL
 func preparedOpenGL(window uintptr, ctx uintptr) {
 	go windowLoop(window, ctx)
@@ -68,6 +74,11 @@ func flushContext(ctx uintptr) {
 	C.flushContext(C.uintptr_t(ctx))
 }
 
+func beginFrame(ctx uintptr) bool { return true }
+
+// resizeContext does nothing; NSOpenGLView keeps its context in sync with the view.
+func resizeContext(ctx uintptr, s sizeEvent) {}
+
 // //export drawgl
 // func drawgl(id uintptr) {
 // 	theScreen.mu.Lock()
@@ -92,10 +103,10 @@ func resize(window uintptr, width, height, pxWidth, pxHeight float64) {
 	w := windows[window]
 	windowsMu.Unlock()
 
-	w.sizeEvents <- sizeEvent{
+	w.send(sizeEvent{
 		size: Size{width, height},
 		px:   Size{pxWidth, pxHeight},
-	}
+	})
 }
 
 // //export windowClosing
@@ -107,35 +118,104 @@ var mousePointer = activePointers.new(Pointer{
 	Type: PointerTypeMouse,
 })
 
+// mouseEvent receives the tool of the event and, for pens, its pressure,
+// tilt from -1 to 1 and counterclockwise rotation in degrees.
+//
 //export mouseEvent
-func mouseEvent(window uintptr, x, y float64, typ, button int32, flags uint32) {
+func mouseEvent(window uintptr, x, y float64, typ, button int32, flags uint32, tool int32, pressure, tangentialPressure, tiltX, tiltY, rotation float64) {
 	windowsMu.Lock()
 	defer windowsMu.Unlock()
 	w := windows[window]
 
-	var down, up bool
+	var down, up, leave bool
 
-	mousePointer.X = x
-	mousePointer.Y = y
+	p := mousePointer
+	if tool != toolMouse {
+		p = penPointer
+	}
+	p.X = x
+	p.Y = y
 
 	switch typ {
-	case C.NSMouseMoved, C.NSLeftMouseDragged, C.NSRightMouseDragged, C.NSOtherMouseDragged:
-		mousePointer.Button = PointerButtonNone
+	case C.NSMouseMoved, C.NSLeftMouseDragged, C.NSRightMouseDragged, C.NSOtherMouseDragged, C.NSMouseEntered:
+		p.Button = PointerButtonNone
+	case C.NSMouseExited:
+		leave = true
+		p.Button = PointerButtonNone
 	case C.NSLeftMouseDown, C.NSRightMouseDown, C.NSOtherMouseDown:
 		down = true
-		mousePointer.Button = cocoaMouseButton(button)
-		mousePointer.Buttons |= mousePointer.Button
+		p.Button = cocoaButton(tool, button)
+		p.Buttons |= p.Button
 	case C.NSLeftMouseUp, C.NSRightMouseUp, C.NSOtherMouseUp:
 		up = true
-		mousePointer.Button = cocoaMouseButton(button)
-		mousePointer.Buttons &^= mousePointer.Button
+		p.Button = cocoaButton(tool, button)
+		p.Buttons &^= p.Button
 	}
 
-	w.pointerEvents <- pointerEvent{
-		down: down,
-		up:   up,
-		p:    *mousePointer,
+	if tool == toolMouse {
+		p.setDefaultPressure()
+	} else {
+		p.Pressure = pressure
+		p.TangentialPressure = tangentialPressure
+		// Cocoa's tilt is y-up, like its coordinates.
+		p.TiltX = 90 * tiltX
+		p.TiltY = -90 * tiltY
+		p.Twist = math.Mod(360-rotation, 360)
+	}
+
+	w.send(pointerEvent{
+		down:  down,
+		up:    up,
+		leave: leave,
+		p:     *p,
+	})
+	if leave {
+		// The pen may have hovered too.
+		w.send(pointerEvent{leave: true, p: *penPointer})
+	}
+}
+
+// scrollEvent receives a position like mouseEvent and a delta in points if
+// precise and in lines otherwise.
+//
+//export scrollEvent
+func scrollEvent(window uintptr, x, y, dx, dy float64, precise bool, phase, momentumPhase uint32, flags uint32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	s := Scroll{
+		Position:  Position{x, y},
+		Delta:     Size{dx, dy},
+		Precise:   precise,
+		Phase:     cocoaScrollPhase(phase),
+		Modifiers: cocoaMods(flags),
+	}
+	if momentumPhase != C.NSEventPhaseNone {
+		s.Momentum = true
+		s.Phase = cocoaScrollPhase(momentumPhase)
+	}
+
+	w.send(s)
+}
+
+func cocoaScrollPhase(phase uint32) ScrollPhase {
+	switch {
+	case phase&(C.NSEventPhaseBegan|C.NSEventPhaseMayBegin) != 0:
+		return ScrollPhaseBegin
+	case phase&(C.NSEventPhaseChanged|C.NSEventPhaseStationary) != 0:
+		return ScrollPhaseChange
+	case phase&(C.NSEventPhaseEnded|C.NSEventPhaseCancelled) != 0:
+		return ScrollPhaseEnd
 	}
+	return ScrollPhaseNone
+}
+
+func cocoaButton(tool, button int32) PointerButtons {
+	if tool != toolMouse {
+		return penButton(tool, button == 0)
+	}
+	return cocoaMouseButton(button)
 }
 
 func cocoaMouseButton(button int32) PointerButtons {
@@ -156,6 +236,12 @@ func cocoaMouseButton(button int32) PointerButtons {
 	}
 }
 
+// screenSize returns the size of the main display in points.
+func screenSize() Size {
+	s := C.screenSize()
+	return Size{float64(s.width), float64(s.height)}
+}
+
 func (w *window) MapFromParent(p Position) Position {
 	pt := C.NSPoint{
 		x: C.double(p.X),
@@ -168,30 +254,110 @@ func (w *window) MapFromParent(p Position) Position {
 	}
 }
 
-// //export keyEvent
-// func keyEvent(id uintptr, runeVal rune, dir uint8, code uint16, flags uint32) {
-// 	sendWindowEvent(id, key.Event{
-// 		Rune:      cocoaRune(runeVal),
-// 		Direction: key.Direction(dir),
-// 		Code:      cocoaKeyCode(code),
-// 		Modifiers: cocoaMods(flags),
-// 	})
-// }
+//export keyEvent
+func keyEvent(window uintptr, runeVal rune, dir uint8, code uint16, flags uint32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
 
-// //export flagEvent
-// func flagEvent(id uintptr, flags uint32) {
-// 	for _, mod := range mods {
-// 		if flags&mod.flags == mod.flags && lastFlags&mod.flags != mod.flags {
-// 			keyEvent(id, -1, C.NSKeyDown, mod.code, flags)
-// 		}
-// 		if lastFlags&mod.flags == mod.flags && flags&mod.flags != mod.flags {
-// 			keyEvent(id, -1, C.NSKeyUp, mod.code, flags)
-// 		}
-// 	}
-// 	lastFlags = flags
-// }
+	k := Key{
+		Code:      cocoaKeyCode(code),
+		Rune:      cocoaRune(runeVal),
+		Modifiers: cocoaMods(flags),
+	}
+	switch dir {
+	case 0:
+		k.Direction = KeyPress
+		k.Repeat = true
+	case 1:
+		k.Direction = KeyPress
+	case 2:
+		k.Direction = KeyRelease
+	}
 
-// var lastFlags uint32
+	w.send(k)
+}
+
+//export flagEvent
+func flagEvent(window uintptr, flags uint32) {
+	for _, mod := range mods {
+		if flags&mod.flags == mod.flags && lastFlags&mod.flags != mod.flags {
+			keyEvent(window, -1, 1, mod.code, flags)
+		}
+		if lastFlags&mod.flags == mod.flags && flags&mod.flags != mod.flags {
+			keyEvent(window, -1, 2, mod.code, flags)
+		}
+	}
+	lastFlags = flags
+}
+
+var lastFlags uint32
+
+var mods = [...]struct {
+	flags uint32
+	code  uint16
+}{
+	// Left and right variants of modifier keys have their own masks,
+	// but they are not documented. These were determined empirically.
+	{1<<17 | 0x102, C.kVK_Shift},
+	{1<<17 | 0x104, C.kVK_RightShift},
+	{1<<18 | 0x101, C.kVK_Control},
+	{1<<18 | 0x2100, C.kVK_RightControl},
+	{1<<19 | 0x120, C.kVK_Option},
+	{1<<19 | 0x140, C.kVK_RightOption},
+	{1<<20 | 0x108, C.kVK_Command},
+	{1<<20 | 0x110, kVK_RightCommand},
+}
+
+// kVK_RightCommand is missing from older versions of HIToolbox/Events.h.
+const kVK_RightCommand = 0x36
+
+func cocoaMods(flags uint32) (m KeyModifiers) {
+	if flags&C.NSEventModifierFlagShift != 0 {
+		m |= KeyModifierShift
+	}
+	if flags&C.NSEventModifierFlagControl != 0 {
+		m |= KeyModifierControl
+	}
+	if flags&C.NSEventModifierFlagOption != 0 {
+		m |= KeyModifierAlt
+	}
+	if flags&C.NSEventModifierFlagCommand != 0 {
+		m |= KeyModifierMeta
+	}
+	return m
+}
+
+//export commitText
+func commitText(window uintptr, text *C.char) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	w.send(textEvent{c: Composition{Text: C.GoString(text)}})
+}
+
+// composeText receives the composition and its cursor position in bytes.
+//
+//export composeText
+func composeText(window uintptr, text *C.char, cursor int32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	w.send(textEvent{
+		compose: true,
+		c:       Composition{Text: C.GoString(text), Cursor: int(cursor)},
+	})
+}
+
+func setTextInput(window uintptr, t textInputState) {
+	enabled := C.int(0)
+	if t.enabled {
+		enabled = 1
+	}
+	C.setTextInput(C.uintptr_t(window), enabled, C.int(t.x), C.int(t.y), C.int(t.w), C.int(t.h))
+}
 
 // func sendLifecycle(id uintptr, setter func(*lifecycler.State, bool), val bool) {
 // 	theScreen.mu.Lock()
@@ -240,271 +406,272 @@ func (w *window) MapFromParent(p Position) Position {
 // 	sendLifecycle(id, (*lifecycler.State).SetFocused, val)
 // }
 
-// // cocoaRune marks the Carbon/Cocoa private-range unicode rune representing
-// // a non-unicode key event to -1, used for Rune in the key package.
-// //
-// // http://www.unicode.org/Public/MAPPINGS/VENDORS/APPLE/CORPCHAR.TXT
-// func cocoaRune(r rune) rune {
-// 	if '\uE000' <= r && r <= '\uF8FF' {
-// 		return -1
-// 	}
-// 	return r
-// }
+// cocoaRune marks the Carbon/Cocoa private-range unicode rune representing
+// a non-unicode key event to -1, used for Key.Rune.
+//
+// http://www.unicode.org/Public/MAPPINGS/VENDORS/APPLE/CORPCHAR.TXT
+func cocoaRune(r rune) rune {
+	if '\uE000' <= r && r <= '\uF8FF' {
+		return -1
+	}
+	return r
+}
 
-// // cocoaKeyCode converts a Carbon/Cocoa virtual key code number
-// // into the standard keycodes used by the key package.
-// //
-// // To get a sense of the key map, see the diagram on
-// //	http://boredzo.org/blog/archives/2007-05-22/virtual-key-codes
-// func cocoaKeyCode(vkcode uint16) key.Code {
-// 	switch vkcode {
-// 	case C.kVK_ANSI_A:
-// 		return key.CodeA
-// 	case C.kVK_ANSI_B:
-// 		return key.CodeB
-// 	case C.kVK_ANSI_C:
-// 		return key.CodeC
-// 	case C.kVK_ANSI_D:
-// 		return key.CodeD
-// 	case C.kVK_ANSI_E:
-// 		return key.CodeE
-// 	case C.kVK_ANSI_F:
-// 		return key.CodeF
-// 	case C.kVK_ANSI_G:
-// 		return key.CodeG
-// 	case C.kVK_ANSI_H:
-// 		return key.CodeH
-// 	case C.kVK_ANSI_I:
-// 		return key.CodeI
-// 	case C.kVK_ANSI_J:
-// 		return key.CodeJ
-// 	case C.kVK_ANSI_K:
-// 		return key.CodeK
-// 	case C.kVK_ANSI_L:
-// 		return key.CodeL
-// 	case C.kVK_ANSI_M:
-// 		return key.CodeM
-// 	case C.kVK_ANSI_N:
-// 		return key.CodeN
-// 	case C.kVK_ANSI_O:
-// 		return key.CodeO
-// 	case C.kVK_ANSI_P:
-// 		return key.CodeP
-// 	case C.kVK_ANSI_Q:
-// 		return key.CodeQ
-// 	case C.kVK_ANSI_R:
-// 		return key.CodeR
-// 	case C.kVK_ANSI_S:
-// 		return key.CodeS
-// 	case C.kVK_ANSI_T:
-// 		return key.CodeT
-// 	case C.kVK_ANSI_U:
-// 		return key.CodeU
-// 	case C.kVK_ANSI_V:
-// 		return key.CodeV
-// 	case C.kVK_ANSI_W:
-// 		return key.CodeW
-// 	case C.kVK_ANSI_X:
-// 		return key.CodeX
-// 	case C.kVK_ANSI_Y:
-// 		return key.CodeY
-// 	case C.kVK_ANSI_Z:
-// 		return key.CodeZ
-// 	case C.kVK_ANSI_1:
-// 		return key.Code1
-// 	case C.kVK_ANSI_2:
-// 		return key.Code2
-// 	case C.kVK_ANSI_3:
-// 		return key.Code3
-// 	case C.kVK_ANSI_4:
-// 		return key.Code4
-// 	case C.kVK_ANSI_5:
-// 		return key.Code5
-// 	case C.kVK_ANSI_6:
-// 		return key.Code6
-// 	case C.kVK_ANSI_7:
-// 		return key.Code7
-// 	case C.kVK_ANSI_8:
-// 		return key.Code8
-// 	case C.kVK_ANSI_9:
-// 		return key.Code9
-// 	case C.kVK_ANSI_0:
-// 		return key.Code0
-// 	// TODO: move the rest of these codes to constants in key.go
-// 	// if we are happy with them.
-// 	case C.kVK_Return:
-// 		return key.CodeReturnEnter
-// 	case C.kVK_Escape:
-// 		return key.CodeEscape
-// 	case C.kVK_Delete:
-// 		return key.CodeDeleteBackspace
-// 	case C.kVK_Tab:
-// 		return key.CodeTab
-// 	case C.kVK_Space:
-// 		return key.CodeSpacebar
-// 	case C.kVK_ANSI_Minus:
-// 		return key.CodeHyphenMinus
-// 	case C.kVK_ANSI_Equal:
-// 		return key.CodeEqualSign
-// 	case C.kVK_ANSI_LeftBracket:
-// 		return key.CodeLeftSquareBracket
-// 	case C.kVK_ANSI_RightBracket:
-// 		return key.CodeRightSquareBracket
-// 	case C.kVK_ANSI_Backslash:
-// 		return key.CodeBackslash
-// 	// 50: Keyboard Non-US "#" and ~
-// 	case C.kVK_ANSI_Semicolon:
-// 		return key.CodeSemicolon
-// 	case C.kVK_ANSI_Quote:
-// 		return key.CodeApostrophe
-// 	case C.kVK_ANSI_Grave:
-// 		return key.CodeGraveAccent
-// 	case C.kVK_ANSI_Comma:
-// 		return key.CodeComma
-// 	case C.kVK_ANSI_Period:
-// 		return key.CodeFullStop
-// 	case C.kVK_ANSI_Slash:
-// 		return key.CodeSlash
-// 	case C.kVK_CapsLock:
-// 		return key.CodeCapsLock
-// 	case C.kVK_F1:
-// 		return key.CodeF1
-// 	case C.kVK_F2:
-// 		return key.CodeF2
-// 	case C.kVK_F3:
-// 		return key.CodeF3
-// 	case C.kVK_F4:
-// 		return key.CodeF4
-// 	case C.kVK_F5:
-// 		return key.CodeF5
-// 	case C.kVK_F6:
-// 		return key.CodeF6
-// 	case C.kVK_F7:
-// 		return key.CodeF7
-// 	case C.kVK_F8:
-// 		return key.CodeF8
-// 	case C.kVK_F9:
-// 		return key.CodeF9
-// 	case C.kVK_F10:
-// 		return key.CodeF10
-// 	case C.kVK_F11:
-// 		return key.CodeF11
-// 	case C.kVK_F12:
-// 		return key.CodeF12
-// 	// 70: PrintScreen
-// 	// 71: Scroll Lock
-// 	// 72: Pause
-// 	// 73: Insert
-// 	case C.kVK_Home:
-// 		return key.CodeHome
-// 	case C.kVK_PageUp:
-// 		return key.CodePageUp
-// 	case C.kVK_ForwardDelete:
-// 		return key.CodeDeleteForward
-// 	case C.kVK_End:
-// 		return key.CodeEnd
-// 	case C.kVK_PageDown:
-// 		return key.CodePageDown
-// 	case C.kVK_RightArrow:
-// 		return key.CodeRightArrow
-// 	case C.kVK_LeftArrow:
-// 		return key.CodeLeftArrow
-// 	case C.kVK_DownArrow:
-// 		return key.CodeDownArrow
-// 	case C.kVK_UpArrow:
-// 		return key.CodeUpArrow
-// 	case C.kVK_ANSI_KeypadClear:
-// 		return key.CodeKeypadNumLock
-// 	case C.kVK_ANSI_KeypadDivide:
-// 		return key.CodeKeypadSlash
-// 	case C.kVK_ANSI_KeypadMultiply:
-// 		return key.CodeKeypadAsterisk
-// 	case C.kVK_ANSI_KeypadMinus:
-// 		return key.CodeKeypadHyphenMinus
-// 	case C.kVK_ANSI_KeypadPlus:
-// 		return key.CodeKeypadPlusSign
-// 	case C.kVK_ANSI_KeypadEnter:
-// 		return key.CodeKeypadEnter
-// 	case C.kVK_ANSI_Keypad1:
-// 		return key.CodeKeypad1
-// 	case C.kVK_ANSI_Keypad2:
-// 		return key.CodeKeypad2
-// 	case C.kVK_ANSI_Keypad3:
-// 		return key.CodeKeypad3
-// 	case C.kVK_ANSI_Keypad4:
-// 		return key.CodeKeypad4
-// 	case C.kVK_ANSI_Keypad5:
-// 		return key.CodeKeypad5
-// 	case C.kVK_ANSI_Keypad6:
-// 		return key.CodeKeypad6
-// 	case C.kVK_ANSI_Keypad7:
-// 		return key.CodeKeypad7
-// 	case C.kVK_ANSI_Keypad8:
-// 		return key.CodeKeypad8
-// 	case C.kVK_ANSI_Keypad9:
-// 		return key.CodeKeypad9
-// 	case C.kVK_ANSI_Keypad0:
-// 		return key.CodeKeypad0
-// 	case C.kVK_ANSI_KeypadDecimal:
-// 		return key.CodeKeypadFullStop
-// 	case C.kVK_ANSI_KeypadEquals:
-// 		return key.CodeKeypadEqualSign
-// 	case C.kVK_F13:
-// 		return key.CodeF13
-// 	case C.kVK_F14:
-// 		return key.CodeF14
-// 	case C.kVK_F15:
-// 		return key.CodeF15
-// 	case C.kVK_F16:
-// 		return key.CodeF16
-// 	case C.kVK_F17:
-// 		return key.CodeF17
-// 	case C.kVK_F18:
-// 		return key.CodeF18
-// 	case C.kVK_F19:
-// 		return key.CodeF19
-// 	case C.kVK_F20:
-// 		return key.CodeF20
-// 	// 116: Keyboard Execute
-// 	case C.kVK_Help:
-// 		return key.CodeHelp
-// 	// 118: Keyboard Menu
-// 	// 119: Keyboard Select
-// 	// 120: Keyboard Stop
-// 	// 121: Keyboard Again
-// 	// 122: Keyboard Undo
-// 	// 123: Keyboard Cut
-// 	// 124: Keyboard Copy
-// 	// 125: Keyboard Paste
-// 	// 126: Keyboard Find
-// 	case C.kVK_Mute:
-// 		return key.CodeMute
-// 	case C.kVK_VolumeUp:
-// 		return key.CodeVolumeUp
-// 	case C.kVK_VolumeDown:
-// 		return key.CodeVolumeDown
-// 	// 130: Keyboard Locking Caps Lock
-// 	// 131: Keyboard Locking Num Lock
-// 	// 132: Keyboard Locking Scroll Lock
-// 	// 133: Keyboard Comma
-// 	// 134: Keyboard Equal Sign
-// 	// ...: Bunch of stuff
-// 	case C.kVK_Control:
-// 		return key.CodeLeftControl
-// 	case C.kVK_Shift:
-// 		return key.CodeLeftShift
-// 	case C.kVK_Option:
-// 		return key.CodeLeftAlt
-// 	case C.kVK_Command:
-// 		return key.CodeLeftGUI
-// 	case C.kVK_RightControl:
-// 		return key.CodeRightControl
-// 	case C.kVK_RightShift:
-// 		return key.CodeRightShift
-// 	case C.kVK_RightOption:
-// 		return key.CodeRightAlt
-// 	// TODO key.CodeRightGUI
-// 	default:
-// 		return key.CodeUnknown
-// 	}
-// }
+// cocoaKeyCode converts a Carbon/Cocoa virtual key code number
+// into a KeyCode.
+//
+// To get a sense of the key map, see the diagram on
+//	http://boredzo.org/blog/archives/2007-05-22/virtual-key-codes
+func cocoaKeyCode(vkcode uint16) KeyCode {
+	switch vkcode {
+	case C.kVK_ANSI_A:
+		return KeyCodeA
+	case C.kVK_ANSI_B:
+		return KeyCodeB
+	case C.kVK_ANSI_C:
+		return KeyCodeC
+	case C.kVK_ANSI_D:
+		return KeyCodeD
+	case C.kVK_ANSI_E:
+		return KeyCodeE
+	case C.kVK_ANSI_F:
+		return KeyCodeF
+	case C.kVK_ANSI_G:
+		return KeyCodeG
+	case C.kVK_ANSI_H:
+		return KeyCodeH
+	case C.kVK_ANSI_I:
+		return KeyCodeI
+	case C.kVK_ANSI_J:
+		return KeyCodeJ
+	case C.kVK_ANSI_K:
+		return KeyCodeK
+	case C.kVK_ANSI_L:
+		return KeyCodeL
+	case C.kVK_ANSI_M:
+		return KeyCodeM
+	case C.kVK_ANSI_N:
+		return KeyCodeN
+	case C.kVK_ANSI_O:
+		return KeyCodeO
+	case C.kVK_ANSI_P:
+		return KeyCodeP
+	case C.kVK_ANSI_Q:
+		return KeyCodeQ
+	case C.kVK_ANSI_R:
+		return KeyCodeR
+	case C.kVK_ANSI_S:
+		return KeyCodeS
+	case C.kVK_ANSI_T:
+		return KeyCodeT
+	case C.kVK_ANSI_U:
+		return KeyCodeU
+	case C.kVK_ANSI_V:
+		return KeyCodeV
+	case C.kVK_ANSI_W:
+		return KeyCodeW
+	case C.kVK_ANSI_X:
+		return KeyCodeX
+	case C.kVK_ANSI_Y:
+		return KeyCodeY
+	case C.kVK_ANSI_Z:
+		return KeyCodeZ
+	case C.kVK_ANSI_1:
+		return KeyCode1
+	case C.kVK_ANSI_2:
+		return KeyCode2
+	case C.kVK_ANSI_3:
+		return KeyCode3
+	case C.kVK_ANSI_4:
+		return KeyCode4
+	case C.kVK_ANSI_5:
+		return KeyCode5
+	case C.kVK_ANSI_6:
+		return KeyCode6
+	case C.kVK_ANSI_7:
+		return KeyCode7
+	case C.kVK_ANSI_8:
+		return KeyCode8
+	case C.kVK_ANSI_9:
+		return KeyCode9
+	case C.kVK_ANSI_0:
+		return KeyCode0
+	case C.kVK_Return:
+		return KeyCodeReturnEnter
+	case C.kVK_Escape:
+		return KeyCodeEscape
+	case C.kVK_Delete:
+		return KeyCodeDeleteBackspace
+	case C.kVK_Tab:
+		return KeyCodeTab
+	case C.kVK_Space:
+		return KeyCodeSpacebar
+	case C.kVK_ANSI_Minus:
+		return KeyCodeHyphenMinus
+	case C.kVK_ANSI_Equal:
+		return KeyCodeEqualSign
+	case C.kVK_ANSI_LeftBracket:
+		return KeyCodeLeftSquareBracket
+	case C.kVK_ANSI_RightBracket:
+		return KeyCodeRightSquareBracket
+	case C.kVK_ANSI_Backslash:
+		return KeyCodeBackslash
+	// 50: Keyboard Non-US "#" and ~
+	case C.kVK_ANSI_Semicolon:
+		return KeyCodeSemicolon
+	case C.kVK_ANSI_Quote:
+		return KeyCodeApostrophe
+	case C.kVK_ANSI_Grave:
+		return KeyCodeGraveAccent
+	case C.kVK_ANSI_Comma:
+		return KeyCodeComma
+	case C.kVK_ANSI_Period:
+		return KeyCodeFullStop
+	case C.kVK_ANSI_Slash:
+		return KeyCodeSlash
+	case C.kVK_CapsLock:
+		return KeyCodeCapsLock
+	case C.kVK_F1:
+		return KeyCodeF1
+	case C.kVK_F2:
+		return KeyCodeF2
+	case C.kVK_F3:
+		return KeyCodeF3
+	case C.kVK_F4:
+		return KeyCodeF4
+	case C.kVK_F5:
+		return KeyCodeF5
+	case C.kVK_F6:
+		return KeyCodeF6
+	case C.kVK_F7:
+		return KeyCodeF7
+	case C.kVK_F8:
+		return KeyCodeF8
+	case C.kVK_F9:
+		return KeyCodeF9
+	case C.kVK_F10:
+		return KeyCodeF10
+	case C.kVK_F11:
+		return KeyCodeF11
+	case C.kVK_F12:
+		return KeyCodeF12
+	// 70: PrintScreen
+	// 71: Scroll Lock
+	// 72: Pause
+	// 73: Insert
+	case C.kVK_Home:
+		return KeyCodeHome
+	case C.kVK_PageUp:
+		return KeyCodePageUp
+	case C.kVK_ForwardDelete:
+		return KeyCodeDeleteForward
+	case C.kVK_End:
+		return KeyCodeEnd
+	case C.kVK_PageDown:
+		return KeyCodePageDown
+	case C.kVK_RightArrow:
+		return KeyCodeRightArrow
+	case C.kVK_LeftArrow:
+		return KeyCodeLeftArrow
+	case C.kVK_DownArrow:
+		return KeyCodeDownArrow
+	case C.kVK_UpArrow:
+		return KeyCodeUpArrow
+	case C.kVK_ANSI_KeypadClear:
+		return KeyCodeKeypadNumLock
+	case C.kVK_ANSI_KeypadDivide:
+		return KeyCodeKeypadSlash
+	case C.kVK_ANSI_KeypadMultiply:
+		return KeyCodeKeypadAsterisk
+	case C.kVK_ANSI_KeypadMinus:
+		return KeyCodeKeypadHyphenMinus
+	case C.kVK_ANSI_KeypadPlus:
+		return KeyCodeKeypadPlusSign
+	case C.kVK_ANSI_KeypadEnter:
+		return KeyCodeKeypadEnter
+	case C.kVK_ANSI_Keypad1:
+		return KeyCodeKeypad1
+	case C.kVK_ANSI_Keypad2:
+		return KeyCodeKeypad2
+	case C.kVK_ANSI_Keypad3:
+		return KeyCodeKeypad3
+	case C.kVK_ANSI_Keypad4:
+		return KeyCodeKeypad4
+	case C.kVK_ANSI_Keypad5:
+		return KeyCodeKeypad5
+	case C.kVK_ANSI_Keypad6:
+		return KeyCodeKeypad6
+	case C.kVK_ANSI_Keypad7:
+		return KeyCodeKeypad7
+	case C.kVK_ANSI_Keypad8:
+		return KeyCodeKeypad8
+	case C.kVK_ANSI_Keypad9:
+		return KeyCodeKeypad9
+	case C.kVK_ANSI_Keypad0:
+		return KeyCodeKeypad0
+	case C.kVK_ANSI_KeypadDecimal:
+		return KeyCodeKeypadFullStop
+	case C.kVK_ISO_Section:
+		return KeyCodeNonUSBackslash
+	case C.kVK_ANSI_KeypadEquals:
+		return KeyCodeKeypadEqualSign
+	case C.kVK_F13:
+		return KeyCodeF13
+	case C.kVK_F14:
+		return KeyCodeF14
+	case C.kVK_F15:
+		return KeyCodeF15
+	case C.kVK_F16:
+		return KeyCodeF16
+	case C.kVK_F17:
+		return KeyCodeF17
+	case C.kVK_F18:
+		return KeyCodeF18
+	case C.kVK_F19:
+		return KeyCodeF19
+	case C.kVK_F20:
+		return KeyCodeF20
+	// 116: Keyboard Execute
+	case C.kVK_Help:
+		return KeyCodeHelp
+	// 118: Keyboard Menu
+	// 119: Keyboard Select
+	// 120: Keyboard Stop
+	// 121: Keyboard Again
+	// 122: Keyboard Undo
+	// 123: Keyboard Cut
+	// 124: Keyboard Copy
+	// 125: Keyboard Paste
+	// 126: Keyboard Find
+	case C.kVK_Mute:
+		return KeyCodeMute
+	case C.kVK_VolumeUp:
+		return KeyCodeVolumeUp
+	case C.kVK_VolumeDown:
+		return KeyCodeVolumeDown
+	// 130: Keyboard Locking Caps Lock
+	// 131: Keyboard Locking Num Lock
+	// 132: Keyboard Locking Scroll Lock
+	// 133: Keyboard Comma
+	// 134: Keyboard Equal Sign
+	// ...: Bunch of stuff
+	case C.kVK_Control:
+		return KeyCodeLeftControl
+	case C.kVK_Shift:
+		return KeyCodeLeftShift
+	case C.kVK_Option:
+		return KeyCodeLeftAlt
+	case C.kVK_Command:
+		return KeyCodeLeftGUI
+	case C.kVK_RightControl:
+		return KeyCodeRightControl
+	case C.kVK_RightShift:
+		return KeyCodeRightShift
+	case C.kVK_RightOption:
+		return KeyCodeRightAlt
+	case kVK_RightCommand:
+		return KeyCodeRightGUI
+	default:
+		return KeyCodeUnknown
+	}
+}
@@ -19,8 +19,17 @@ void flushContext(uintptr_t context) {
 	[ctx flushBuffer];
 }
 
-@interface ScreenGLView : NSOpenGLView<NSWindowDelegate>
+@interface ScreenGLView : NSOpenGLView<NSWindowDelegate, NSTextInputClient>
 {
+	// textInput is set while a TextInput has the focus, and textInputRect is
+	// its composition rectangle in points from the top-left corner.
+	BOOL textInput;
+	NSRect textInputRect;
+	NSString *markedText;
+	// keyText collects the text inserted while interpreting a key press.
+	NSMutableString *keyText;
+	// eraser is set while the eraser end of a pen is near the tablet.
+	BOOL eraser;
 }
 @end
 
@@ -69,7 +78,27 @@ void flushContext(uintptr_t context) {
 
 - (void)mouseEventNS:(NSEvent *)theEvent {
 	NSPoint p = theEvent.locationInWindow;
-	mouseEvent((GoUintptr)self, p.x, p.y, theEvent.type, theEvent.buttonNumber, theEvent.modifierFlags);
+	int tool = 0;
+	double pressure = 0, tangentialPressure = 0, rotation = 0;
+	NSPoint tilt = NSZeroPoint;
+	// Tracking events have no subtype.
+	if (theEvent.type != NSEventTypeMouseEntered && theEvent.type != NSEventTypeMouseExited) {
+		if (theEvent.subtype == NSEventSubtypeTabletProximity) {
+			[self tabletProximity:theEvent];
+		} else if (theEvent.subtype == NSEventSubtypeTabletPoint) {
+			tool = eraser ? 2 : 1;
+			pressure = theEvent.pressure;
+			tangentialPressure = theEvent.tangentialPressure;
+			tilt = theEvent.tilt;
+			rotation = theEvent.rotation;
+		}
+	}
+	mouseEvent((GoUintptr)self, p.x, p.y, theEvent.type, theEvent.buttonNumber, theEvent.modifierFlags,
+		tool, pressure, tangentialPressure, tilt.x, tilt.y, rotation);
+}
+
+- (void)tabletProximity:(NSEvent *)theEvent {
+	eraser = theEvent.isEnteringProximity && theEvent.pointingDeviceType == NSPointingDeviceTypeEraser;
 }
 
 - (void)mouseMoved:(NSEvent *)theEvent        { [self mouseEventNS:theEvent]; }
@@ -82,50 +111,171 @@ void flushContext(uintptr_t context) {
 - (void)otherMouseDown:(NSEvent *)theEvent    { [self mouseEventNS:theEvent]; }
 - (void)otherMouseDragged:(NSEvent *)theEvent { [self mouseEventNS:theEvent]; }
 - (void)otherMouseUp:(NSEvent *)theEvent      { [self mouseEventNS:theEvent]; }
+- (void)mouseEntered:(NSEvent *)theEvent      { [self mouseEventNS:theEvent]; }
+- (void)mouseExited:(NSEvent *)theEvent       { [self mouseEventNS:theEvent]; }
 
-// - (void)scrollWheel:(NSEvent *)theEvent       { [self mouseEventNS:theEvent]; }
+- (void)scrollWheel:(NSEvent *)theEvent {
+	NSPoint p = theEvent.locationInWindow;
+	scrollEvent((GoUintptr)self, p.x, p.y, theEvent.scrollingDeltaX, theEvent.scrollingDeltaY,
+		theEvent.hasPreciseScrollingDeltas, theEvent.phase, theEvent.momentumPhase, theEvent.modifierFlags);
+}
 
-// // raw modifier key presses
-// - (void)flagsChanged:(NSEvent *)theEvent {
-// 	flagEvent((GoUintptr)self, theEvent.modifierFlags);
-// }
+// raw modifier key presses
+- (void)flagsChanged:(NSEvent *)theEvent {
+	flagEvent((GoUintptr)self, theEvent.modifierFlags);
+}
 
-// // overrides special handling of escape and tab
-// - (BOOL)performKeyEquivalent:(NSEvent *)theEvent {
-// 	[self key:theEvent];
-// 	return YES;
-// }
+// overrides special handling of escape and tab, leaving Command shortcuts to
+// the menu and keys that edit a composition to the input method
+- (BOOL)performKeyEquivalent:(NSEvent *)theEvent {
+	if (theEvent.modifierFlags & NSEventModifierFlagCommand) {
+		return [super performKeyEquivalent:theEvent];
+	}
+	if ([self hasMarkedText]) {
+		return NO;
+	}
+	[self key:theEvent];
+	return YES;
+}
 
-// - (void)keyDown:(NSEvent *)theEvent { [self key:theEvent]; }
-// - (void)keyUp:(NSEvent *)theEvent   { [self key:theEvent]; }
-
-// - (void)key:(NSEvent *)theEvent {
-// 	NSRange range = [theEvent.characters rangeOfComposedCharacterSequenceAtIndex:0];
-
-// 	uint8_t buf[4] = {0, 0, 0, 0};
-// 	if (![theEvent.characters getBytes:buf
-// 			maxLength:4
-// 			usedLength:nil
-// 			encoding:NSUTF32LittleEndianStringEncoding
-// 			options:NSStringEncodingConversionAllowLossy
-// 			range:range
-// 			remainingRange:nil]) {
-// 		NSLog(@"failed to read key event %@", theEvent);
-// 		return;
-// 	}
+// While a TextInput has the focus, key presses go through the input method
+// first.  Those that compose text are not reported as key events.
+- (void)keyDown:(NSEvent *)theEvent {
+	if (!textInput) {
+		[self key:theEvent];
+		return;
+	}
+	BOOL wasComposing = [self hasMarkedText];
+	keyText = [NSMutableString string];
+	[self interpretKeyEvents:@[theEvent]];
+	NSString *text = keyText;
+	keyText = nil;
+	if (!wasComposing && ![self hasMarkedText]) {
+		[self key:theEvent];
+	}
+	if (text.length > 0) {
+		commitText((GoUintptr)self, (char*)text.UTF8String);
+	}
+}
 
-// 	uint32_t rune = (uint32_t)buf[0]<<0 | (uint32_t)buf[1]<<8 | (uint32_t)buf[2]<<16 | (uint32_t)buf[3]<<24;
+- (void)keyUp:(NSEvent *)theEvent { [self key:theEvent]; }
+
+- (void)key:(NSEvent *)theEvent {
+	// Dead keys produce no characters.
+	uint8_t buf[4] = {0xff, 0xff, 0xff, 0xff};
+	if (theEvent.characters.length > 0 && ![theEvent.characters getBytes:buf
+			maxLength:4
+			usedLength:nil
+			encoding:NSUTF32LittleEndianStringEncoding
+			options:NSStringEncodingConversionAllowLossy
+			range:[theEvent.characters rangeOfComposedCharacterSequenceAtIndex:0]
+			remainingRange:nil]) {
+		NSLog(@"failed to read key event %@", theEvent);
+		return;
+	}
 
-// 	uint8_t direction;
-// 	if ([theEvent isARepeat]) {
-// 		direction = 0;
-// 	} else if (theEvent.type == NSEventTypeKeyDown) {
-// 		direction = 1;
-// 	} else {
-// 		direction = 2;
-// 	}
-// 	keyEvent((GoUintptr)self, (int32_t)rune, direction, theEvent.keyCode, theEvent.modifierFlags);
-// }
+	uint32_t rune = (uint32_t)buf[0]<<0 | (uint32_t)buf[1]<<8 | (uint32_t)buf[2]<<16 | (uint32_t)buf[3]<<24;
+
+	// 0 is a repeated press, 1 a press and 2 a release.
+	uint8_t direction;
+	if ([theEvent isARepeat]) {
+		direction = 0;
+	} else if (theEvent.type == NSEventTypeKeyDown) {
+		direction = 1;
+	} else {
+		direction = 2;
+	}
+	keyEvent((GoUintptr)self, (int32_t)rune, direction, theEvent.keyCode, theEvent.modifierFlags);
+}
+
+- (void)setMarkedTextString:(NSString *)text {
+	[markedText release];
+	markedText = text.length > 0 ? [text copy] : nil;
+}
+
+- (void)setTextInput:(BOOL)enabled rect:(NSRect)r {
+	if (!enabled && [self hasMarkedText]) {
+		[self setMarkedTextString:nil];
+		[self.inputContext discardMarkedText];
+	}
+	textInput = enabled;
+	textInputRect = r;
+	[self.inputContext invalidateCharacterCoordinates];
+}
+
+static NSString *plainString(id string) {
+	if ([string isKindOfClass:[NSAttributedString class]]) {
+		return [string string];
+	}
+	return string;
+}
+
+- (void)insertText:(id)string replacementRange:(NSRange)replacementRange {
+	NSString *text = plainString(string);
+	[self setMarkedTextString:nil];
+	if (keyText) {
+		[keyText appendString:text];
+	} else if (text.length > 0) {
+		commitText((GoUintptr)self, (char*)text.UTF8String);
+	}
+}
+
+- (void)setMarkedText:(id)string selectedRange:(NSRange)selectedRange replacementRange:(NSRange)replacementRange {
+	NSString *text = plainString(string);
+	[self setMarkedTextString:text];
+	// Go wants the cursor as a byte offset rather than in UTF-16 code units.
+	NSUInteger loc = MIN(selectedRange.location, text.length);
+	NSUInteger cursor = [[text substringToIndex:loc] lengthOfBytesUsingEncoding:NSUTF8StringEncoding];
+	composeText((GoUintptr)self, (char*)text.UTF8String, (int32_t)cursor);
+}
+
+- (void)unmarkText {
+	NSString *text = [markedText retain];
+	[self setMarkedTextString:nil];
+	[self.inputContext discardMarkedText];
+	if (text.length > 0) {
+		[self insertText:text replacementRange:NSMakeRange(NSNotFound, 0)];
+	}
+	[text release];
+}
+
+- (BOOL)hasMarkedText {
+	return markedText.length > 0;
+}
+
+- (NSRange)markedRange {
+	if ([self hasMarkedText]) {
+		return NSMakeRange(0, markedText.length);
+	}
+	return NSMakeRange(NSNotFound, 0);
+}
+
+// The text around the cursor belongs to the TextInput, so none is reported.
+- (NSRange)selectedRange {
+	return NSMakeRange(NSNotFound, 0);
+}
+
+- (NSArray<NSAttributedStringKey> *)validAttributesForMarkedText {
+	return @[];
+}
+
+- (NSAttributedString *)attributedSubstringForProposedRange:(NSRange)range actualRange:(NSRangePointer)actualRange {
+	return nil;
+}
+
+- (NSUInteger)characterIndexForPoint:(NSPoint)point {
+	return NSNotFound;
+}
+
+- (NSRect)firstRectForCharacterRange:(NSRange)range actualRange:(NSRangePointer)actualRange {
+	NSRect r = textInputRect;
+	r.origin.y = self.bounds.size.height - r.origin.y - r.size.height;
+	r = [self convertRect:r toView:nil];
+	return [self.window convertRectToScreen:r];
+}
+
+// Commands such as moving the cursor are left to key events.
+- (void)doCommandBySelector:(SEL)selector {}
 
 - (void)windowDidChangeScreenProfile:(NSNotification *)notification {
 	[self callResize];
@@ -210,6 +360,13 @@ uintptr_t newWindow(double width, double height) {
 		};
 		id pixFormat = [[NSOpenGLPixelFormat alloc] initWithAttributes:attr];
 		view = [[ScreenGLView alloc] initWithFrame:rect pixelFormat:pixFormat];
+		// Report the mouse entering and leaving the view, which follows its bounds.
+		NSTrackingArea *tracking = [[NSTrackingArea alloc] initWithRect:NSZeroRect
+				options:NSTrackingMouseEnteredAndExited | NSTrackingActiveInKeyWindow | NSTrackingInVisibleRect
+				owner:view
+				userInfo:nil];
+		[view addTrackingArea:tracking];
+		[tracking release];
 		[window setContentView:view];
 		[window setDelegate:view];
 		[window makeFirstResponder:view];
@@ -251,12 +408,23 @@ void runApp() {
 	[NSApp run];
 }
 
+void setTextInput(uintptr_t window, int enabled, int x, int y, int width, int height) {
+	ScreenGLView *v = (ScreenGLView*)window;
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[v setTextInput:enabled != 0 rect:NSMakeRect(x, y, width, height)];
+	});
+}
+
 NSPoint mapFromScreen(uintptr_t window, NSPoint pt) {
 	ScreenGLView *v = (ScreenGLView*)window;
 	pt.y = v.window.screen.frame.size.height - pt.y; // TODO: This adjustment doesn't belong here.
 	return [v.window convertPointFromScreen: pt];
 }
 
+NSSize screenSize() {
+	return CGDisplayBounds(CGMainDisplayID()).size;
+}
+
 uint64 threadID() {
 	uint64 id;
 	if (pthread_threadid_np(pthread_self(), &id)) {
@@ -0,0 +1,97 @@
+// +build !android,!ios
+
+package ui
+
+import (
+	"github.com/gordonklaus/ui/internal/digitizer"
+)
+
+type contactKey struct {
+	device int
+	pen    bool
+	id     uint32
+}
+
+// contacts holds the pointers of the digitizer contacts in range.
+var contacts = map[contactKey]*Pointer{}
+
+// contactEvent delivers a change to a contact on a digitizer that covers the
+// screen.  Calls must be serialized.
+func contactEvent(c digitizer.Contact) {
+	k := contactKey{c.Device, c.Pen, c.ID}
+	p := contacts[k]
+	if p == nil {
+		if !c.InRange {
+			return
+		}
+		typ := PointerTypeTouch
+		pen := uint32(0)
+		if c.Pen {
+			typ = PointerTypePen
+			pen = 1
+		}
+		p = activePointers.new(Pointer{
+			externalID: (uint32(c.Device)<<16 | c.ID&0x7fff<<1 | pen) << 2,
+			Type:       typ,
+		})
+		contacts[k] = p
+	}
+
+	// Digitizers report positions relative to the screen they cover.
+	s := screenSize()
+	p.Position = Position{s.Width * c.X, s.Height * c.Y}
+	p.ContactSize = Size{s.Width * c.Width, s.Height * c.Height}
+	p.TangentialPressure = c.BarrelPressure
+	p.TiltX, p.TiltY, p.Twist = c.TiltX, c.TiltY, c.Twist
+
+	send := func(e pointerEvent) {
+		if c.Pressure >= 0 {
+			p.Pressure = c.Pressure
+		} else {
+			p.setDefaultPressure()
+		}
+		for _, w := range openWindows() {
+			e := e
+			e.p = *p
+			// TODO: only send to the active window, otherwise activate the window, if in rect
+			e.p.Position = w.MapFromParent(p.Position)
+			w.send(e)
+		}
+	}
+
+	var buttons PointerButtons
+	switch {
+	case !c.Tip:
+	case !c.Pen:
+		buttons = PointerButtonTouchContact
+	case c.Eraser:
+		buttons = PointerButtonPenEraser
+	default:
+		buttons = PointerButtonPenContact
+	}
+	if c.Pen && c.Barrel {
+		buttons |= PointerButtonPenBarrel
+	}
+
+	changed := buttons ^ p.Buttons
+	for b := PointerButtons(1); b != 0; b <<= 1 {
+		if changed&b != 0 {
+			p.Button = b
+			p.Buttons ^= b
+			send(pointerEvent{down: buttons&b != 0, up: buttons&b == 0})
+		}
+	}
+	if changed == 0 && c.InRange {
+		p.Button = PointerButtonNone
+		send(pointerEvent{})
+	}
+
+	if !c.InRange {
+		if c.Pen {
+			p.Button = PointerButtonNone
+			send(pointerEvent{leave: true})
+		}
+		activePointers.delete(*p)
+		delete(contacts, k)
+	}
+}
@@ -0,0 +1,15 @@
+// +build linux,evdev
+
+package ui
+
+import (
+	"github.com/gordonklaus/ui/internal/evdev"
+)
+
+// With the evdev build tag, touchscreens and pens are read from the Linux
+// input devices instead of from hidraw, which needs fewer permissions and
+// covers devices that aren't USB.  The user must be able to read
+// /dev/input/event*.
+func init() {
+	go evdev.Run(contactEvent)
+}
@@ -0,0 +1,374 @@
+package ui
+
+import "math"
+
+// Flex is a container that lays its children out in a row or column, like a
+// CSS flexbox.  Each child starts at its preferred size and then grows or
+// shrinks according to its flex factors to fill the main axis, within the
+// bounds of its SizePolicy.
+type Flex struct {
+	View
+
+	direction FlexDirection
+	wrap      bool
+	justify   Justify
+	align     Align
+	gap       float64
+
+	factors map[View]flexFactors
+
+	sizePolicySet bool
+}
+
+type FlexDirection uint8
+
+const (
+	FlexRow FlexDirection = iota
+	FlexColumn
+)
+
+// Justify distributes free space along a container's main axis.
+type Justify uint8
+
+const (
+	JustifyStart Justify = iota
+	JustifyCenter
+	JustifyEnd
+	JustifySpaceBetween
+	JustifySpaceAround
+	JustifySpaceEvenly
+)
+
+// Align positions a child across a container's axis.
+type Align uint8
+
+const (
+	AlignStretch Align = iota
+	AlignStart
+	AlignCenter
+	AlignEnd
+)
+
+type flexFactors struct {
+	grow, shrink float64
+}
+
+var defaultFlexFactors = flexFactors{grow: 0, shrink: 1}
+
+func NewFlex(parent View) *Flex {
+	f := &Flex{factors: map[View]flexFactors{}}
+	f.View = NewView(f, parent)
+	return f
+}
+
+// NewHBox returns a Flex that lays its children out left to right.
+func NewHBox(parent View) *Flex {
+	return NewFlex(parent)
+}
+
+// NewVBox returns a Flex that lays its children out top to bottom.
+func NewVBox(parent View) *Flex {
+	f := NewFlex(parent)
+	f.direction = FlexColumn
+	return f
+}
+
+func (f *Flex) Direction() FlexDirection { return f.direction }
+func (f *Flex) SetDirection(d FlexDirection) {
+	f.direction = d
+	f.InvalidateLayout()
+}
+
+// Wrap reports whether children that don't fit on one line are moved to new lines.
+func (f *Flex) Wrap() bool { return f.wrap }
+func (f *Flex) SetWrap(wrap bool) {
+	f.wrap = wrap
+	f.InvalidateLayout()
+}
+
+func (f *Flex) Justify() Justify { return f.justify }
+func (f *Flex) SetJustify(j Justify) {
+	f.justify = j
+	f.InvalidateLayout()
+}
+
+func (f *Flex) Align() Align { return f.align }
+func (f *Flex) SetAlign(a Align) {
+	f.align = a
+	f.InvalidateLayout()
+}
+
+// Gap is the space between adjacent children and between lines.
+func (f *Flex) Gap() float64 { return f.gap }
+func (f *Flex) SetGap(gap float64) {
+	f.gap = gap
+	f.InvalidateLayout()
+}
+
+// FlexFactors returns the grow and shrink factors of child.  By default a
+// child does not grow and shrinks in proportion to its preferred size.
+func (f *Flex) FlexFactors(child View) (grow, shrink float64) {
+	ff := f.childFactors(child)
+	return ff.grow, ff.shrink
+}
+
+// SetFlexFactors sets the factors in proportion to which child takes up free
+// space on the main axis or gives up space when there is too little.
+func (f *Flex) SetFlexFactors(child View, grow, shrink float64) {
+	f.factors[child] = flexFactors{grow, shrink}
+	f.InvalidateLayout()
+}
+
+func (f *Flex) childFactors(child View) flexFactors {
+	if ff, ok := f.factors[child]; ok {
+		return ff
+	}
+	return defaultFlexFactors
+}
+
+// SizePolicy returns the policy set with SetSizePolicy or, if there is none,
+// a policy computed from the children's.
+func (f *Flex) SizePolicy() SizePolicy {
+	if f.sizePolicySet {
+		return f.View.SizePolicy()
+	}
+
+	children := f.view().children
+	var p SizePolicy
+	var mainPref, mainMin, mainMax, crossPref, crossMin float64
+	mainMaxBounded := len(children) > 0
+	for i, c := range children {
+		cp := c.SizePolicy()
+		pref := cp.Clamp(cp.Preferred)
+		gap := 0.0
+		if i > 0 {
+			gap = f.gap
+		}
+		mainPref += gap + f.direction.main(pref)
+		if f.wrap {
+			mainMin = math.Max(mainMin, f.direction.main(cp.Min))
+		} else {
+			mainMin += gap + f.direction.main(cp.Min)
+		}
+		if max := f.direction.main(cp.Max); max > 0 {
+			mainMax += gap + max
+		} else {
+			mainMaxBounded = false
+		}
+		crossPref = math.Max(crossPref, f.direction.cross(pref))
+		crossMin = math.Max(crossMin, f.direction.cross(cp.Min))
+	}
+	if !mainMaxBounded {
+		mainMax = 0
+	}
+	p.Preferred = f.direction.size(mainPref, crossPref)
+	p.Min = f.direction.size(mainMin, crossMin)
+	p.Max = f.direction.size(mainMax, 0)
+	return p
+}
+
+func (f *Flex) SetSizePolicy(p SizePolicy) {
+	f.sizePolicySet = true
+	f.View.SetSizePolicy(p)
+}
+
+type flexItem struct {
+	view              View
+	policy            SizePolicy
+	factors           flexFactors
+	main, cross       float64
+	mainPos, crossPos float64
+}
+
+func (f *Flex) Layout() {
+	children := f.view().children
+	isChild := map[View]bool{}
+	for _, c := range children {
+		isChild[c] = true
+	}
+	for c := range f.factors {
+		if !isChild[c] {
+			delete(f.factors, c)
+		}
+	}
+
+	r := f.Rect()
+	d := f.direction
+	mainSize, crossSize := d.main(r.Size()), d.cross(r.Size())
+
+	items := make([]flexItem, len(children))
+	for i, c := range children {
+		p := c.SizePolicy()
+		pref := p.Clamp(p.Preferred)
+		items[i] = flexItem{
+			view:    c,
+			policy:  p,
+			factors: f.childFactors(c),
+			main:    d.main(pref),
+			cross:   d.cross(pref),
+		}
+	}
+
+	// Break the children into lines.
+	var lines [][]flexItem
+	for start := 0; start < len(items); {
+		end := start + 1
+		used := items[start].main
+		for ; end < len(items); end++ {
+			if f.wrap && used+f.gap+items[end].main > mainSize {
+				break
+			}
+			used += f.gap + items[end].main
+		}
+		lines = append(lines, items[start:end])
+		start = end
+	}
+
+	var crossPos float64
+	for _, line := range lines {
+		free := f.resolveFlexibleLengths(line, mainSize)
+
+		lineCross := crossSize
+		if len(lines) > 1 {
+			lineCross = 0
+			for _, it := range line {
+				lineCross = math.Max(lineCross, it.cross)
+			}
+		}
+
+		pos, spacing := f.justify.distribute(free, len(line))
+		for i := range line {
+			it := &line[i]
+			it.mainPos = pos
+			pos += it.main + f.gap + spacing
+
+			min, max := d.cross(it.policy.Min), d.cross(it.policy.Max)
+			if f.align == AlignStretch {
+				it.cross = clampDimension(lineCross, min, max)
+			}
+			it.crossPos = crossPos + f.align.offset(lineCross-it.cross)
+
+			it.view.Move(r.Min.Add(d.size(it.mainPos, it.crossPos)))
+			it.view.Resize(d.size(it.main, it.cross))
+		}
+		crossPos += lineCross + f.gap
+	}
+}
+
+// resolveFlexibleLengths grows or shrinks the items of a line to fill
+// mainSize and returns the space left over.
+func (f *Flex) resolveFlexibleLengths(line []flexItem, mainSize float64) float64 {
+	d := f.direction
+	frozen := make([]bool, len(line))
+	for {
+		free := mainSize - f.gap*float64(len(line)-1)
+		for _, it := range line {
+			free -= it.main
+		}
+		if math.Abs(free) < 1e-9 {
+			return 0
+		}
+
+		weight := func(it flexItem) float64 {
+			if free > 0 {
+				return it.factors.grow
+			}
+			return it.factors.shrink * it.main
+		}
+		var total float64
+		for i, it := range line {
+			if !frozen[i] {
+				total += weight(it)
+			}
+		}
+		if total == 0 {
+			return free
+		}
+
+		changed := false
+		for i := range line {
+			it := &line[i]
+			if frozen[i] {
+				continue
+			}
+			w := weight(*it)
+			if w == 0 {
+				frozen[i] = true
+				continue
+			}
+			target := it.main + free*w/total
+			it.main = clampDimension(target, d.main(it.policy.Min), d.main(it.policy.Max))
+			if it.main != target {
+				frozen[i] = true
+				changed = true
+			}
+		}
+		if !changed {
+			return 0
+		}
+	}
+}
+
+// distribute returns the offset of the first of n items and the extra space
+// between adjacent items, given free space on the main axis.
+func (j Justify) distribute(free float64, n int) (start, spacing float64) {
+	if free <= 0 {
+		if j == JustifyCenter {
+			return free / 2, 0
+		}
+		if j == JustifyEnd {
+			return free, 0
+		}
+		return 0, 0
+	}
+	switch j {
+	default:
+		return 0, 0
+	case JustifyCenter:
+		return free / 2, 0
+	case JustifyEnd:
+		return free, 0
+	case JustifySpaceBetween:
+		if n < 2 {
+			return 0, 0
+		}
+		return 0, free / float64(n-1)
+	case JustifySpaceAround:
+		return free / float64(2*n), free / float64(n)
+	case JustifySpaceEvenly:
+		return free / float64(n+1), free / float64(n+1)
+	}
+}
+
+// offset returns the position of an item within free extra space.
+func (a Align) offset(free float64) float64 {
+	switch a {
+	default:
+		return 0
+	case AlignCenter:
+		return free / 2
+	case AlignEnd:
+		return free
+	}
+}
+
+func (d FlexDirection) main(s Size) float64 {
+	if d == FlexColumn {
+		return s.Height
+	}
+	return s.Width
+}
+
+func (d FlexDirection) cross(s Size) float64 {
+	if d == FlexColumn {
+		return s.Width
+	}
+	return s.Height
+}
+
+func (d FlexDirection) size(main, cross float64) Size {
+	if d == FlexColumn {
+		return Size{cross, main}
+	}
+	return Size{main, cross}
+}
@@ -0,0 +1,232 @@
+package ui_test
+
+import (
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// A leaf is a view with a fixed size policy.
+type leaf struct {
+	ui.View
+}
+
+func newLeaf(parent ui.View, p ui.SizePolicy) *leaf {
+	l := &leaf{}
+	l.View = ui.NewView(l, parent)
+	l.SetSizePolicy(p)
+	return l
+}
+
+func pref(width, height float64) ui.SizePolicy {
+	return ui.SizePolicy{Preferred: ui.Size{Width: width, Height: height}}
+}
+
+func rect(x0, y0, x1, y1 float64) ui.Rectangle {
+	return ui.Rectangle{Min: ui.Position{X: x0, Y: y0}, Max: ui.Position{X: x1, Y: y1}}
+}
+
+// childRects returns the rectangles of views in their parent's coordinates.
+func childRects(children []ui.View) []ui.Rectangle {
+	var rs []ui.Rectangle
+	for _, c := range children {
+		p, s := c.Position(), c.Size()
+		rs = append(rs, rect(p.X, p.Y, p.X+s.Width, p.Y+s.Height))
+	}
+	return rs
+}
+
+func equalRects(a, b []ui.Rectangle) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type flexChild struct {
+	policy       ui.SizePolicy
+	grow, shrink float64
+}
+
+func TestFlexLayout(t *testing.T) {
+	withMin := func(p ui.SizePolicy, width, height float64) ui.SizePolicy {
+		p.Min = ui.Size{Width: width, Height: height}
+		return p
+	}
+	withMax := func(p ui.SizePolicy, width, height float64) ui.SizePolicy {
+		p.Max = ui.Size{Width: width, Height: height}
+		return p
+	}
+	for _, test := range []struct {
+		name      string
+		direction ui.FlexDirection
+		size      ui.Size
+		wrap      bool
+		justify   ui.Justify
+		align     ui.Align
+		gap       float64
+		children  []flexChild
+		want      []ui.Rectangle
+	}{
+		{
+			name: "grow in proportion",
+			size: ui.Size{Width: 100, Height: 20},
+			children: []flexChild{
+				{pref(20, 10), 1, 1},
+				{pref(30, 10), 3, 1},
+				{pref(10, 10), 0, 1},
+			},
+			want: []ui.Rectangle{rect(0, 0, 30, 20), rect(30, 0, 90, 20), rect(90, 0, 100, 20)},
+		},
+		{
+			name: "shrink in proportion to factor and size",
+			size: ui.Size{Width: 100, Height: 20},
+			children: []flexChild{
+				{pref(60, 10), 1, 1},
+				{pref(60, 10), 1, 3},
+			},
+			want: []ui.Rectangle{rect(0, 0, 55, 20), rect(55, 0, 100, 20)},
+		},
+		{
+			name: "max freezes a growing child",
+			size: ui.Size{Width: 100, Height: 20},
+			children: []flexChild{
+				{withMax(pref(20, 10), 30, 0), 1, 1},
+				{pref(20, 10), 1, 1},
+			},
+			want: []ui.Rectangle{rect(0, 0, 30, 20), rect(30, 0, 100, 20)},
+		},
+		{
+			name: "min freezes a shrinking child",
+			size: ui.Size{Width: 60, Height: 20},
+			children: []flexChild{
+				{withMin(pref(50, 10), 40, 0), 0, 1},
+				{pref(50, 10), 0, 1},
+			},
+			want: []ui.Rectangle{rect(0, 0, 40, 20), rect(40, 0, 60, 20)},
+		},
+		{
+			name:      "column",
+			direction: ui.FlexColumn,
+			size:      ui.Size{Width: 20, Height: 100},
+			children: []flexChild{
+				{pref(10, 30), 1, 1},
+				{pref(10, 30), 1, 1},
+			},
+			want: []ui.Rectangle{rect(0, 0, 20, 50), rect(0, 50, 20, 100)},
+		},
+		{
+			name: "wrap with gap",
+			size: ui.Size{Width: 100, Height: 100},
+			wrap: true,
+			gap:  10,
+			children: []flexChild{
+				{pref(40, 10), 0, 1},
+				{pref(40, 20), 0, 1},
+				{pref(40, 10), 0, 1},
+			},
+			// Lines are as high as their highest child, which the others
+			// stretch to.
+			want: []ui.Rectangle{rect(0, 0, 40, 20), rect(50, 0, 90, 20), rect(0, 30, 40, 40)},
+		},
+		{
+			name:     "justify start",
+			size:     ui.Size{Width: 100, Height: 20},
+			justify:  ui.JustifyStart,
+			align:    ui.AlignStart,
+			children: []flexChild{{pref(20, 10), 0, 1}, {pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(0, 0, 20, 10), rect(20, 0, 40, 10)},
+		},
+		{
+			name:     "justify center",
+			size:     ui.Size{Width: 100, Height: 20},
+			justify:  ui.JustifyCenter,
+			align:    ui.AlignStart,
+			children: []flexChild{{pref(20, 10), 0, 1}, {pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(30, 0, 50, 10), rect(50, 0, 70, 10)},
+		},
+		{
+			name:     "justify end",
+			size:     ui.Size{Width: 100, Height: 20},
+			justify:  ui.JustifyEnd,
+			align:    ui.AlignStart,
+			children: []flexChild{{pref(20, 10), 0, 1}, {pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(60, 0, 80, 10), rect(80, 0, 100, 10)},
+		},
+		{
+			name:     "justify space between",
+			size:     ui.Size{Width: 100, Height: 20},
+			justify:  ui.JustifySpaceBetween,
+			align:    ui.AlignStart,
+			children: []flexChild{{pref(20, 10), 0, 1}, {pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(0, 0, 20, 10), rect(80, 0, 100, 10)},
+		},
+		{
+			name:     "justify space around",
+			size:     ui.Size{Width: 100, Height: 20},
+			justify:  ui.JustifySpaceAround,
+			align:    ui.AlignStart,
+			children: []flexChild{{pref(20, 10), 0, 1}, {pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(15, 0, 35, 10), rect(65, 0, 85, 10)},
+		},
+		{
+			name:     "justify space evenly",
+			size:     ui.Size{Width: 100, Height: 20},
+			justify:  ui.JustifySpaceEvenly,
+			align:    ui.AlignStart,
+			children: []flexChild{{pref(20, 10), 0, 1}, {pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(20, 0, 40, 10), rect(60, 0, 80, 10)},
+		},
+		{
+			name:     "align stretch within max",
+			size:     ui.Size{Width: 100, Height: 20},
+			align:    ui.AlignStretch,
+			children: []flexChild{{pref(20, 10), 0, 1}, {withMax(pref(20, 10), 0, 15), 0, 1}},
+			want:     []ui.Rectangle{rect(0, 0, 20, 20), rect(20, 0, 40, 15)},
+		},
+		{
+			name:     "align start",
+			size:     ui.Size{Width: 100, Height: 20},
+			align:    ui.AlignStart,
+			children: []flexChild{{pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(0, 0, 20, 10)},
+		},
+		{
+			name:     "align center",
+			size:     ui.Size{Width: 100, Height: 20},
+			align:    ui.AlignCenter,
+			children: []flexChild{{pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(0, 5, 20, 15)},
+		},
+		{
+			name:     "align end",
+			size:     ui.Size{Width: 100, Height: 20},
+			align:    ui.AlignEnd,
+			children: []flexChild{{pref(20, 10), 0, 1}},
+			want:     []ui.Rectangle{rect(0, 10, 20, 20)},
+		},
+	} {
+		f := ui.NewFlex(nil)
+		f.SetDirection(test.direction)
+		f.SetWrap(test.wrap)
+		f.SetJustify(test.justify)
+		f.SetAlign(test.align)
+		f.SetGap(test.gap)
+		var children []ui.View
+		for _, c := range test.children {
+			l := newLeaf(f, c.policy)
+			f.SetFlexFactors(l, c.grow, c.shrink)
+			children = append(children, l)
+		}
+		f.Resize(test.size)
+		f.Layout()
+		if got := childRects(children); !equalRects(got, test.want) {
+			t.Errorf("%s: got child rects %v, want %v", test.name, got, test.want)
+		}
+	}
+}
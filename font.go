@@ -0,0 +1,230 @@
+package ui
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// A Font is a TrueType or OpenType font.  Sizes are the height of the em
+// square, in the same units as the coordinates they are drawn in.
+type Font struct {
+	f  *sfnt.Font
+	ot *otTables
+
+	// mu guards buf, which sfnt needs for every lookup.
+	mu  sync.Mutex
+	buf sfnt.Buffer
+}
+
+// ParseFont parses a TrueType or OpenType font.
+func ParseFont(data []byte) (*Font, error) {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Font{f: f, ot: parseOTTables(data)}, nil
+}
+
+// FontMetrics describe a font at a size.
+type FontMetrics struct {
+	// Ascent is how far the font reaches above the baseline, and Descent how
+	// far below it.  Both are positive.
+	Ascent, Descent float64
+	// LineHeight is the distance between the baselines of lines.
+	LineHeight float64
+}
+
+// unitsPPEM returns the ppem at which sfnt measures in font units, times 64.
+func (f *Font) unitsPPEM() fixed.Int26_6 {
+	return fixed.Int26_6(f.f.UnitsPerEm()) << 6
+}
+
+// scale returns the factor from font units times 64 to size.
+func (f *Font) scale(size float64) float64 {
+	return size / float64(f.unitsPPEM())
+}
+
+// Metrics returns the metrics of f at size.
+func (f *Font) Metrics(size float64) FontMetrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := f.f.Metrics(&f.buf, f.unitsPPEM(), font.HintingNone)
+	if err != nil {
+		return FontMetrics{}
+	}
+	s := f.scale(size)
+	return FontMetrics{
+		Ascent:     float64(m.Ascent) * s,
+		Descent:    float64(m.Descent) * s,
+		LineHeight: float64(m.Height) * s,
+	}
+}
+
+// Advance returns the width of text at size, including kerning.
+func (f *Font) Advance(text string, size float64) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.layout(text, size, nil)
+}
+
+// Bounds returns the smallest rectangle holding the outlines of text at size,
+// relative to the start of its baseline.
+func (f *Font) Bounds(text string, size float64) Rectangle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.scale(size)
+	var r Rectangle
+	empty := true
+	f.layout(text, size, func(g sfnt.GlyphIndex, x float64) {
+		segments, err := f.f.LoadGlyph(&f.buf, g, f.unitsPPEM(), nil)
+		if err != nil {
+			return
+		}
+		for _, seg := range segments {
+			for _, a := range seg.Args[:segmentPoints(seg.Op)] {
+				p := Position{x + float64(a.X)*s, float64(a.Y) * s}
+				if empty {
+					r = Rectangle{p, p}
+					empty = false
+				}
+				r.Min.X = math.Min(r.Min.X, p.X)
+				r.Min.Y = math.Min(r.Min.Y, p.Y)
+				r.Max.X = math.Max(r.Max.X, p.X)
+				r.Max.Y = math.Max(r.Max.Y, p.Y)
+			}
+		}
+	})
+	return r
+}
+
+func segmentPoints(op sfnt.SegmentOp) int {
+	switch op {
+	case sfnt.SegmentOpQuadTo:
+		return 2
+	case sfnt.SegmentOpCubeTo:
+		return 3
+	}
+	return 1
+}
+
+// layout calls glyph, if not nil, with each glyph of text and its offset
+// along the baseline, and returns the advance of the whole.  f.mu must be
+// held.
+func (f *Font) layout(text string, size float64, glyph func(g sfnt.GlyphIndex, x float64)) float64 {
+	x := 0.
+	prev := sfnt.GlyphIndex(0)
+	for i, r := range text {
+		g := f.glyph(r)
+		if i > 0 {
+			x += f.kern(prev, g, size)
+		}
+		if glyph != nil {
+			glyph(g, x)
+		}
+		x += f.advance(g, size)
+		prev = g
+	}
+	return x
+}
+
+// glyph returns the glyph for r, or 0 if f has none.  f.mu must be held.
+func (f *Font) glyph(r rune) sfnt.GlyphIndex {
+	g, err := f.f.GlyphIndex(&f.buf, r)
+	if err != nil {
+		return 0
+	}
+	return g
+}
+
+// advance returns the advance of a glyph at size.  f.mu must be held.
+func (f *Font) advance(g sfnt.GlyphIndex, size float64) float64 {
+	a, err := f.f.GlyphAdvance(&f.buf, g, f.unitsPPEM(), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float64(a) * f.scale(size)
+}
+
+// kern returns the adjustment to the space between two glyphs at size, in
+// visual order.  f.mu must be held.
+func (f *Font) kern(g0, g1 sfnt.GlyphIndex, size float64) float64 {
+	k, err := f.f.Kern(&f.buf, g0, g1, f.unitsPPEM(), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float64(k) * f.scale(size)
+}
+
+// rasterize returns the coverage of a glyph at ppemX and ppemY pixels per em
+// horizontally and vertically in the alpha of a white image, and the offset
+// of the image from the glyph's origin.  The image is nil if the glyph has no
+// outline.
+func (f *Font) rasterize(g sfnt.GlyphIndex, ppemX, ppemY fixed.Int26_6) (*image.NRGBA, image.Point) {
+	f.mu.Lock()
+	segments, err := f.f.LoadGlyph(&f.buf, g, ppemY, nil)
+	f.mu.Unlock()
+	if err != nil || len(segments) == 0 {
+		return nil, image.Point{}
+	}
+	if ppemX != ppemY {
+		for i := range segments {
+			for j := range segments[i].Args {
+				a := &segments[i].Args[j]
+				a.X = fixed.Int26_6(int64(a.X) * int64(ppemX) / int64(ppemY))
+			}
+		}
+	}
+
+	var b fixed.Rectangle26_6
+	for i, seg := range segments {
+		for j, a := range seg.Args[:segmentPoints(seg.Op)] {
+			if i == 0 && j == 0 {
+				b = fixed.Rectangle26_6{Min: a, Max: a}
+			}
+			b = b.Union(fixed.Rectangle26_6{Min: a, Max: a.Add(fixed.Point26_6{X: 1, Y: 1})})
+		}
+	}
+	// A pixel of margin keeps linear filtering from blending in neighbors.
+	min := image.Pt(b.Min.X.Floor()-1, b.Min.Y.Floor()-1)
+	max := image.Pt(b.Max.X.Ceil()+1, b.Max.Y.Ceil()+1)
+	size := max.Sub(min)
+
+	z := vector.NewRasterizer(size.X, size.Y)
+	pt := func(a fixed.Point26_6) (float32, float32) {
+		return float32(a.X)/64 - float32(min.X), float32(a.Y)/64 - float32(min.Y)
+	}
+	for _, seg := range segments {
+		x0, y0 := pt(seg.Args[0])
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			z.ClosePath()
+			z.MoveTo(x0, y0)
+		case sfnt.SegmentOpLineTo:
+			z.LineTo(x0, y0)
+		case sfnt.SegmentOpQuadTo:
+			x1, y1 := pt(seg.Args[1])
+			z.QuadTo(x0, y0, x1, y1)
+		case sfnt.SegmentOpCubeTo:
+			x1, y1 := pt(seg.Args[1])
+			x2, y2 := pt(seg.Args[2])
+			z.CubeTo(x0, y0, x1, y1, x2, y2)
+		}
+	}
+	z.ClosePath()
+	mask := image.NewAlpha(image.Rect(0, 0, size.X, size.Y))
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+
+	img := image.NewNRGBA(mask.Bounds())
+	draw.Draw(img, img.Rect, image.White, image.Point{}, draw.Src)
+	for i, a := range mask.Pix {
+		img.Pix[4*i+3] = a
+	}
+	return img, min
+}
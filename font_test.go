@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func goRegular(t *testing.T) *Font {
+	f, err := ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParseFont(t *testing.T) {
+	goRegular(t)
+	if _, err := ParseFont([]byte("not a font")); err == nil {
+		t.Error("parsed a font from garbage")
+	}
+}
+
+func TestFontMetrics(t *testing.T) {
+	f := goRegular(t)
+	m := f.Metrics(10)
+	if m.Ascent <= 0 || m.Descent <= 0 || m.LineHeight < m.Ascent+m.Descent-1e-9 {
+		t.Errorf("got metrics %+v, want a positive ascent and descent within the line height", m)
+	}
+	m2 := f.Metrics(20)
+	if math.Abs(m2.Ascent-2*m.Ascent) > 1e-9 || math.Abs(m2.Descent-2*m.Descent) > 1e-9 || math.Abs(m2.LineHeight-2*m.LineHeight) > 1e-9 {
+		t.Errorf("metrics at twice the size are %+v, want twice %+v", m2, m)
+	}
+}
+
+func TestFontAdvance(t *testing.T) {
+	f := goRegular(t)
+	if a := f.Advance("", 10); a != 0 {
+		t.Errorf("empty text has advance %g, want 0", a)
+	}
+	text := "AVATAR"
+	f.mu.Lock()
+	want := 0.
+	for i, r := range text {
+		g := f.glyph(r)
+		if i > 0 {
+			want += f.kern(f.glyph(rune(text[i-1])), g, 10)
+		}
+		want += f.advance(g, 10)
+	}
+	f.mu.Unlock()
+	a := f.Advance(text, 10)
+	if math.Abs(a-want) > 1e-9 {
+		t.Errorf("got advance %g, want the sum of advances and kerning %g", a, want)
+	}
+	if a2 := f.Advance(text, 20); math.Abs(a2-2*a) > 1e-9 {
+		t.Errorf("advance at twice the size is %g, want %g", a2, 2*a)
+	}
+}
+
+func TestFontBounds(t *testing.T) {
+	f := goRegular(t)
+	if b := f.Bounds("", 10); b != (Rectangle{}) {
+		t.Errorf("empty text has bounds %v, want an empty rectangle", b)
+	}
+	// An x sits on the baseline, which is y = 0, and is no wider than its
+	// advance.
+	b := f.Bounds("x", 10)
+	if math.Abs(b.Max.Y) > .1 || b.Min.Y >= 0 || b.Min.X < 0 || b.Max.X > f.Advance("x", 10) {
+		t.Errorf("x has bounds %v and advance %g", b, f.Advance("x", 10))
+	}
+	// A p descends below it.
+	if b := f.Bounds("p", 10); b.Max.Y <= 0 {
+		t.Errorf("p has bounds %v, want it to reach below the baseline", b)
+	}
+	if b2 := f.Bounds("x", 20); math.Abs(b2.Width()-2*b.Width()) > 1e-9 {
+		t.Errorf("x at twice the size is %g wide, want %g", b2.Width(), 2*b.Width())
+	}
+}
+
+// inked returns the bounds of the pixels of img that are not transparent.
+func inked(img *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.RGBAAt(x, y).A > 0 {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestDrawText(t *testing.T) {
+	f := goRegular(t)
+	draw := func(width, height int) image.Rectangle {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		g := NewSoftwareGraphics(img, Size{100, 100})
+		defer g.release()
+		g.DrawText("Hello", f, 20, Color{1, 1, 1, 1}, Position{10, 50})
+		return inked(img)
+	}
+
+	r := draw(100, 100)
+	b := f.Bounds("Hello", 20)
+	want := image.Rect(int(10+b.Min.X), int(50+b.Min.Y), int(10+b.Max.X), int(50+b.Max.Y))
+	if d := 2; r.Min.X < want.Min.X-d || r.Min.Y < want.Min.Y-d || r.Max.X > want.Max.X+d || r.Max.Y > want.Max.Y+d || r.Dx() < want.Dx()-d || r.Dy() < want.Dy()-d {
+		t.Errorf("text inked %v, want about %v", r, want)
+	}
+
+	// Glyphs are rasterized at the scale of each axis.
+	r2 := draw(200, 100)
+	if math.Abs(float64(r2.Dx()-2*r.Dx())) > 2 || math.Abs(float64(r2.Dy()-r.Dy())) > 1 {
+		t.Errorf("text at twice the horizontal scale inked %v, want twice as wide as %v", r2, r)
+	}
+}
+
+func TestGlyphAtlas(t *testing.T) {
+	a := newGlyphAtlas(&softwareRenderer{})
+	defer a.release()
+
+	// Glyphs are placed in rows across a page.
+	p0, pos0 := a.place(image.Pt(600, 100))
+	p1, pos1 := a.place(image.Pt(600, 50))
+	p2, pos2 := a.place(image.Pt(300, 50))
+	if p0 != p1 || p1 != p2 || pos0 != (image.Point{}) || pos1 != image.Pt(0, 100) || pos2 != image.Pt(600, 100) {
+		t.Errorf("got positions %v, %v and %v on pages %p, %p and %p, want (0,0), (0,100) and (600,100) on one page", pos0, pos1, pos2, p0, p1, p2)
+	}
+
+	// Glyphs too big for a page get one of their own.
+	big, pos := a.place(image.Pt(atlasPageSize+10, 20))
+	if big.size != atlasPageSize+10 || pos != (image.Point{}) || len(a.pages) != 2 {
+		t.Errorf("a big glyph got a page of size %d at %v, with %d pages, want its own page", big.size, pos, len(a.pages))
+	}
+
+	// When the pages are full, the atlas is cleared and its pages retired.
+	for len(a.pages) < atlasMaxPages {
+		a.place(image.Pt(atlasPageSize, atlasPageSize))
+	}
+	full := append([]*atlasPage(nil), a.pages...)
+	a.glyphs[glyphKey{}] = atlasGlyph{}
+	p, pos := a.place(image.Pt(atlasPageSize, atlasPageSize))
+	if len(a.pages) != 1 || a.pages[0] != p || pos != (image.Point{}) {
+		t.Errorf("after the atlas filled, got %d pages, want 1 holding the new glyph", len(a.pages))
+	}
+	if len(a.glyphs) != 0 {
+		t.Error("clearing the atlas kept its glyphs")
+	}
+	if len(a.retired) != len(full) {
+		t.Errorf("clearing the atlas retired %d pages, want %d", len(a.retired), len(full))
+	}
+	a.releaseRetired()
+	if len(a.retired) != 0 {
+		t.Errorf("%d pages remain retired after their release", len(a.retired))
+	}
+}
+
+func TestGlyphAtlasCache(t *testing.T) {
+	f := goRegular(t)
+	a := newGlyphAtlas(&softwareRenderer{})
+	defer a.release()
+	f.mu.Lock()
+	gi := f.glyph('H')
+	f.mu.Unlock()
+
+	k := glyphKey{f, gi, 20 << 6, 20 << 6}
+	g := a.glyph(k)
+	if g.page == nil || g.rect.Empty() {
+		t.Fatalf("H rasterized to %+v, want pixels", g)
+	}
+	if a.glyph(k) != g {
+		t.Error("a glyph was rasterized again")
+	}
+	wide := a.glyph(glyphKey{f, gi, 40 << 6, 20 << 6})
+	if d := wide.rect.Dx() - 2*(g.rect.Dx()-2); d < -2 || d > 4 || wide.rect.Dy() != g.rect.Dy() {
+		t.Errorf("H at twice the horizontal ppem is %v, want twice as wide as %v", wide.rect.Size(), g.rect.Size())
+	}
+}
@@ -0,0 +1,475 @@
+package ui
+
+import (
+	"math"
+	"time"
+)
+
+// A GestureRecognizer interprets the pointer events of a view and its
+// descendants as a gesture.  Recognizers embed a *Gesture, which holds their
+// state, and implement the pointer methods to drive it with SetState.
+//
+// Every recognizer attached to the view under a pointer, or to one of its
+// ancestors, receives the pointer's events, in the view's coordinates,
+// before the views do.  When a recognizer begins or recognizes its gesture,
+// it takes the pointers it is tracking from the views, which receive
+// PointerCancel, and the other recognizers fail unless they may recognize
+// simultaneously with it.
+type GestureRecognizer interface {
+	gesture() *Gesture
+
+	PointerDown(Pointer)
+	PointerMove(Pointer)
+	PointerUp(Pointer)
+	PointerCancel(Pointer)
+
+	// Reset prepares the recognizer for a new gesture, after it ends,
+	// fails or is cancelled.
+	Reset()
+}
+
+type GestureState uint8
+
+const (
+	// GesturePossible is the state of a recognizer that has not yet
+	// decided whether its gesture is happening.
+	GesturePossible GestureState = iota
+	// GestureBegan, GestureChanged and GestureEnded are the states of a
+	// continuous gesture, such as a pan.
+	GestureBegan
+	GestureChanged
+	GestureEnded
+	GestureCancelled
+	GestureFailed
+
+	// GestureRecognized is the state of a discrete gesture, such as a tap,
+	// once it has happened.
+	GestureRecognized = GestureEnded
+)
+
+// Gesture holds the state shared by all recognizers.
+type Gesture struct {
+	self     GestureRecognizer
+	view     View
+	handler  func()
+	state    GestureState
+	pointers map[PointerID]Pointer
+
+	requireFailure []GestureRecognizer
+	simultaneous   []GestureRecognizer
+
+	arena *gestureArena
+	// pending is the state the recognizer reached while waiting for the
+	// recognizers it requires to fail.
+	pending GestureState
+	// generation counts resets, so that stale timers can be ignored.
+	generation int
+	// timeouts counts the calls to timeout, so that only the latest fires.
+	timeouts int
+}
+
+// NewGesture returns the Gesture for self, a recognizer that embeds it.
+// The handler is called on every change of state after the recognizer
+// begins or recognizes its gesture.
+func NewGesture(self GestureRecognizer, handler func()) *Gesture {
+	return &Gesture{
+		self:     self,
+		handler:  handler,
+		pointers: map[PointerID]Pointer{},
+	}
+}
+
+func (g *Gesture) gesture() *Gesture { return g }
+
+// View returns the view the recognizer is attached to.
+func (g *Gesture) View() View { return g.view }
+
+func (g *Gesture) State() GestureState { return g.state }
+
+// SetState moves the recognizer to a new state.  Recognizers call it from
+// their pointer methods or timers.
+func (g *Gesture) SetState(s GestureState) {
+	if g.arena == nil {
+		g.state = s
+		return
+	}
+	g.arena.setState(g.self, s)
+}
+
+// Pointers returns the pointers that are down, in the view's coordinates.
+func (g *Gesture) Pointers() []Pointer {
+	ps := make([]Pointer, 0, len(g.pointers))
+	for _, p := range g.pointers {
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// Centroid returns the average position of the pointers that are down.
+func (g *Gesture) Centroid() Position {
+	var c Position
+	for _, p := range g.pointers {
+		c.X += p.X
+		c.Y += p.Y
+	}
+	if n := float64(len(g.pointers)); n > 0 {
+		c.X /= n
+		c.Y /= n
+	}
+	return c
+}
+
+// RequireFailureOf keeps the recognizer from beginning or recognizing its
+// gesture until other fails, as a single tap waits for a double tap.
+func (g *Gesture) RequireFailureOf(other GestureRecognizer) {
+	g.requireFailure = append(g.requireFailure, other)
+}
+
+// RecognizeSimultaneouslyWith lets the recognizer and other recognize their
+// gestures at the same time, as a pinch and a rotation.
+func (g *Gesture) RecognizeSimultaneouslyWith(other GestureRecognizer) {
+	g.simultaneous = append(g.simultaneous, other)
+	o := other.gesture()
+	o.simultaneous = append(o.simultaneous, g.self)
+}
+
+func (g *Gesture) Reset() {}
+
+// PointerCancel cancels the gesture if it began and otherwise fails it.
+func (g *Gesture) PointerCancel(p Pointer) {
+	if g.inProgress() {
+		g.SetState(GestureCancelled)
+	} else {
+		g.SetState(GestureFailed)
+	}
+}
+
+// After calls f on the window's goroutine after d, unless the recognizer has
+// been reset in the meantime.
+func (g *Gesture) After(d time.Duration, f func()) *time.Timer {
+	gen := g.generation
+	v := g.view
+	return time.AfterFunc(d, func() {
+		v.Do(func() {
+			if g.generation == gen {
+				f()
+			}
+		})
+	})
+}
+
+// timeout is like After but replaces any earlier timeout.
+func (g *Gesture) timeout(d time.Duration, f func()) {
+	g.timeouts++
+	n := g.timeouts
+	g.After(d, func() {
+		if g.timeouts == n {
+			f()
+		}
+	})
+}
+
+func (g *Gesture) cancelTimeout() { g.timeouts++ }
+
+func (g *Gesture) inProgress() bool {
+	return g.state == GestureBegan || g.state == GestureChanged
+}
+
+func (g *Gesture) finished() bool {
+	return g.state == GestureEnded || g.state == GestureCancelled || g.state == GestureFailed
+}
+
+func (g *Gesture) simultaneousWith(r GestureRecognizer) bool {
+	for _, s := range g.simultaneous {
+		if s == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *view) AddGestureRecognizer(r GestureRecognizer) {
+	r.gesture().view = v.self
+	v.gestures = append(v.gestures, r)
+}
+
+func (v *view) RemoveGestureRecognizer(r GestureRecognizer) {
+	for i, g := range v.gestures {
+		if g == r {
+			v.gestures = append(v.gestures[:i], v.gestures[i+1:]...)
+			break
+		}
+	}
+	if a := r.gesture().arena; a != nil {
+		a.setState(r, GestureCancelled)
+		a.remove(r)
+	}
+}
+
+func (v *view) GestureRecognizers() []GestureRecognizer {
+	return append([]GestureRecognizer(nil), v.gestures...)
+}
+
+// gestureArena feeds pointer events to a window's recognizers and settles
+// which of them recognize their gestures.
+type gestureArena struct {
+	w *windowBase
+	// active holds the recognizers tracking pointers or waiting for more,
+	// and byPointer those tracking each pointer, innermost view first.
+	active    []GestureRecognizer
+	byPointer map[PointerID][]GestureRecognizer
+	// claimed holds the pointers taken from the views by a recognizer.
+	claimed map[PointerID]bool
+	// settling is set while a recognizer that began or recognized its
+	// gesture fails the others, so that it isn't reset before its handler
+	// is called.
+	settling bool
+}
+
+func newGestureArena(w *windowBase) *gestureArena {
+	return &gestureArena{
+		w:         w,
+		byPointer: map[PointerID][]GestureRecognizer{},
+		claimed:   map[PointerID]bool{},
+	}
+}
+
+func (a *gestureArena) pointerDown(p Pointer, target View) {
+	var rs []GestureRecognizer
+	for v := target; v != nil && v.view() != a.w.view(); v = v.Parent() {
+		rs = append(rs, v.view().gestures...)
+	}
+	a.byPointer[p.ID] = rs
+	for _, r := range rs {
+		g := r.gesture()
+		if g.arena == nil {
+			g.arena = a
+			a.active = append(a.active, r)
+		}
+		if g.finished() {
+			continue
+		}
+		q := a.mapPointer(g, p)
+		g.pointers[p.ID] = q
+		r.PointerDown(q)
+	}
+	a.cleanUp()
+}
+
+func (a *gestureArena) pointerMove(p Pointer) {
+	for _, r := range a.byPointer[p.ID] {
+		g := r.gesture()
+		if _, ok := g.pointers[p.ID]; !ok || g.finished() {
+			continue
+		}
+		q := a.mapPointer(g, p)
+		g.pointers[p.ID] = q
+		r.PointerMove(q)
+	}
+	a.cleanUp()
+}
+
+func (a *gestureArena) pointerUp(p Pointer) {
+	for _, r := range a.byPointer[p.ID] {
+		g := r.gesture()
+		if _, ok := g.pointers[p.ID]; !ok {
+			continue
+		}
+		delete(g.pointers, p.ID)
+		if !g.finished() {
+			r.PointerUp(a.mapPointer(g, p))
+		}
+	}
+}
+
+func (a *gestureArena) pointerCancel(p Pointer) {
+	for _, r := range a.byPointer[p.ID] {
+		g := r.gesture()
+		if _, ok := g.pointers[p.ID]; !ok {
+			continue
+		}
+		delete(g.pointers, p.ID)
+		if !g.finished() {
+			r.PointerCancel(a.mapPointer(g, p))
+		}
+	}
+}
+
+// release forgets a pointer once it is up or cancelled.
+func (a *gestureArena) release(id PointerID) {
+	delete(a.byPointer, id)
+	delete(a.claimed, id)
+	a.cleanUp()
+}
+
+func (a *gestureArena) mapPointer(g *Gesture, p Pointer) Pointer {
+	p.Position = g.view.view().mapFromWindow(p.Position)
+	return p
+}
+
+// setState applies a recognizer's change of state, subject to the others.
+func (a *gestureArena) setState(r GestureRecognizer, s GestureState) {
+	g := r.gesture()
+	old := g.state
+	if s == old && s != GestureChanged || g.finished() {
+		return
+	}
+
+	if old == GesturePossible && (s == GestureBegan || s == GestureRecognized) {
+		if a.waitsForFailure(g) {
+			g.pending = s
+			return
+		}
+		for _, o := range a.active {
+			if a.conflict(r, o) && o.gesture().inProgress() {
+				a.setState(r, GestureFailed)
+				return
+			}
+		}
+		g.state = s
+		a.claim(r)
+		settling := a.settling
+		a.settling = true
+		for _, o := range append([]GestureRecognizer(nil), a.active...) {
+			if a.conflict(r, o) {
+				if o.gesture().inProgress() {
+					a.setState(o, GestureCancelled)
+				} else {
+					a.setState(o, GestureFailed)
+				}
+			}
+		}
+		a.settling = settling
+		g.handler()
+		a.cleanUp()
+		return
+	}
+
+	g.state = s
+	g.pending = GesturePossible
+	if old != GesturePossible {
+		// Only gestures that began report their progress.
+		g.handler()
+	}
+	if s == GestureFailed || s == GestureCancelled || s == GestureEnded {
+		a.resolvePending()
+	}
+	a.cleanUp()
+}
+
+// conflict reports whether r and o track the same pointer and may not
+// recognize their gestures simultaneously.
+func (a *gestureArena) conflict(r, o GestureRecognizer) bool {
+	if r == o || r.gesture().simultaneousWith(o) {
+		return false
+	}
+	for _, rs := range a.byPointer {
+		if containsGesture(rs, r) && containsGesture(rs, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitsForFailure reports whether any recognizer that g requires to fail
+// might still recognize its gesture.
+func (a *gestureArena) waitsForFailure(g *Gesture) bool {
+	for _, o := range g.requireFailure {
+		og := o.gesture()
+		if og.arena == a && !og.finished() {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePending lets recognizers that were waiting for others to fail go
+// ahead, or fail in turn if one of those recognized its gesture.
+func (a *gestureArena) resolvePending() {
+	for _, r := range append([]GestureRecognizer(nil), a.active...) {
+		g := r.gesture()
+		if g.pending == GesturePossible || g.state != GesturePossible {
+			continue
+		}
+		recognized := false
+		for _, o := range g.requireFailure {
+			if s := o.gesture().state; s == GestureBegan || s == GestureChanged || s == GestureEnded {
+				recognized = true
+			}
+		}
+		if recognized {
+			a.setState(r, GestureFailed)
+		} else if !a.waitsForFailure(g) {
+			s := g.pending
+			g.pending = GesturePossible
+			a.setState(r, s)
+		}
+	}
+}
+
+// claim takes the pointers that r is tracking from the views.
+func (a *gestureArena) claim(r GestureRecognizer) {
+	for id, rs := range a.byPointer {
+		if a.claimed[id] || !containsGesture(rs, r) {
+			continue
+		}
+		a.claimed[id] = true
+		if v := a.w.pointerViews[id]; v != nil {
+			p := a.w.pointers[id]
+			p.Position = v.view().mapFromWindow(p.Position)
+			v.PointerCancel(p)
+		}
+		delete(a.w.pointerViews, id)
+	}
+}
+
+// cleanUp resets the recognizers that have finished and have no pointers down.
+func (a *gestureArena) cleanUp() {
+	if a.settling {
+		return
+	}
+	for _, r := range append([]GestureRecognizer(nil), a.active...) {
+		if g := r.gesture(); g.finished() && len(g.pointers) == 0 {
+			a.remove(r)
+		}
+	}
+}
+
+func (a *gestureArena) remove(r GestureRecognizer) {
+	for i, o := range a.active {
+		if o == r {
+			a.active = append(a.active[:i], a.active[i+1:]...)
+			break
+		}
+	}
+	for id, rs := range a.byPointer {
+		for i, o := range rs {
+			if o == r {
+				a.byPointer[id] = append(rs[:i:i], rs[i+1:]...)
+				break
+			}
+		}
+	}
+	g := r.gesture()
+	g.arena = nil
+	g.state = GesturePossible
+	g.pending = GesturePossible
+	g.pointers = map[PointerID]Pointer{}
+	g.generation++
+	r.Reset()
+	// A recognizer that waited for r to fail may now go ahead.
+	a.resolvePending()
+}
+
+func containsGesture(rs []GestureRecognizer, r GestureRecognizer) bool {
+	for _, x := range rs {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+func distance(p, q Position) float64 {
+	return math.Hypot(p.X-q.X, p.Y-q.Y)
+}
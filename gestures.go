@@ -0,0 +1,526 @@
+package ui
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// TapGesture recognizes one or more taps of one or more pointers.
+type TapGesture struct {
+	*Gesture
+
+	// Taps is the number of taps to recognize, and PointerCount the number
+	// of pointers in each.
+	Taps, PointerCount int
+	// MaxDistance is the farthest a pointer may move during the gesture.
+	MaxDistance float64
+	// MaxInterval is the longest a tap may last and the longest between
+	// taps.
+	MaxInterval time.Duration
+
+	// Position is where the last tap happened.
+	Position Position
+
+	start       map[PointerID]Position
+	origin      Position
+	taps        int
+	tapPointers int
+}
+
+// NewTapGesture returns a recognizer for a single tap of one pointer.
+func NewTapGesture(handler func(*TapGesture)) *TapGesture {
+	t := &TapGesture{
+		Taps:         1,
+		PointerCount: 1,
+		MaxDistance:  5,
+		MaxInterval:  300 * time.Millisecond,
+	}
+	t.Gesture = NewGesture(t, func() { handler(t) })
+	t.Reset()
+	return t
+}
+
+// NewDoubleTapGesture returns a recognizer for two taps of one pointer.
+func NewDoubleTapGesture(handler func(*TapGesture)) *TapGesture {
+	t := NewTapGesture(handler)
+	t.Taps = 2
+	return t
+}
+
+func (t *TapGesture) PointerDown(p Pointer) {
+	if t.pending != GesturePossible {
+		// The gesture is over and waiting for others to fail.
+		return
+	}
+	if t.taps == 0 && t.tapPointers == 0 {
+		t.origin = p.Position
+	}
+	t.tapPointers++
+	if t.tapPointers > t.PointerCount || distance(p.Position, t.origin) > t.MaxDistance {
+		t.SetState(GestureFailed)
+		return
+	}
+	t.start[p.ID] = p.Position
+	t.timeout(t.MaxInterval, func() { t.SetState(GestureFailed) })
+}
+
+func (t *TapGesture) PointerMove(p Pointer) {
+	if s, ok := t.start[p.ID]; ok && distance(p.Position, s) > t.MaxDistance {
+		t.SetState(GestureFailed)
+	}
+}
+
+func (t *TapGesture) PointerUp(p Pointer) {
+	if _, ok := t.start[p.ID]; !ok {
+		return
+	}
+	t.Position = p.Position
+	if len(t.pointers) > 0 {
+		return
+	}
+	if t.tapPointers != t.PointerCount {
+		t.SetState(GestureFailed)
+		return
+	}
+	t.taps++
+	t.tapPointers = 0
+	t.start = map[PointerID]Position{}
+	if t.taps == t.Taps {
+		t.cancelTimeout()
+		t.SetState(GestureRecognized)
+		return
+	}
+	t.timeout(t.MaxInterval, func() { t.SetState(GestureFailed) })
+}
+
+func (t *TapGesture) Reset() {
+	t.start = map[PointerID]Position{}
+	t.taps = 0
+	t.tapPointers = 0
+}
+
+// LongPressGesture recognizes pointers held still.  It begins once they have
+// been held for Duration and changes as they move afterward.
+type LongPressGesture struct {
+	*Gesture
+
+	PointerCount int
+	Duration     time.Duration
+	// MaxDistance is the farthest a pointer may move before the gesture
+	// begins.
+	MaxDistance float64
+
+	// Position is the centroid of the pointers.
+	Position Position
+
+	start map[PointerID]Position
+}
+
+// NewLongPressGesture returns a recognizer for one pointer held for half a
+// second.
+func NewLongPressGesture(handler func(*LongPressGesture)) *LongPressGesture {
+	l := &LongPressGesture{
+		PointerCount: 1,
+		Duration:     500 * time.Millisecond,
+		MaxDistance:  5,
+	}
+	l.Gesture = NewGesture(l, func() { handler(l) })
+	l.Reset()
+	return l
+}
+
+func (l *LongPressGesture) PointerDown(p Pointer) {
+	if l.inProgress() {
+		return
+	}
+	if len(l.pointers) > l.PointerCount {
+		l.SetState(GestureFailed)
+		return
+	}
+	l.start[p.ID] = p.Position
+	if len(l.pointers) == l.PointerCount {
+		l.timeout(l.Duration, func() {
+			l.Position = l.Centroid()
+			l.SetState(GestureBegan)
+		})
+	}
+}
+
+func (l *LongPressGesture) PointerMove(p Pointer) {
+	if l.inProgress() {
+		l.Position = l.Centroid()
+		l.SetState(GestureChanged)
+	} else if distance(p.Position, l.start[p.ID]) > l.MaxDistance {
+		l.SetState(GestureFailed)
+	}
+}
+
+func (l *LongPressGesture) PointerUp(p Pointer) {
+	if l.inProgress() {
+		l.SetState(GestureEnded)
+	} else {
+		l.SetState(GestureFailed)
+	}
+}
+
+func (l *LongPressGesture) Reset() {
+	l.start = map[PointerID]Position{}
+}
+
+// PanGesture recognizes pointers dragged together.  It begins once they
+// have moved MinDistance.
+type PanGesture struct {
+	*Gesture
+
+	// MinPointers and MaxPointers bound the number of pointers.  A zero
+	// MaxPointers is unbounded.
+	MinPointers, MaxPointers int
+	MinDistance              float64
+
+	// Translation is how far the centroid of the pointers has moved since
+	// the gesture started, and Velocity how fast, per second, it is moving.
+	Translation, Velocity Size
+
+	last     Position
+	lastTime time.Time
+}
+
+// NewPanGesture returns a recognizer for a drag of any number of pointers.
+func NewPanGesture(handler func(*PanGesture)) *PanGesture {
+	g := &PanGesture{
+		MinPointers: 1,
+		MinDistance: 3,
+	}
+	g.Gesture = NewGesture(g, func() { handler(g) })
+	return g
+}
+
+func (g *PanGesture) PointerDown(p Pointer) {
+	if g.MaxPointers > 0 && len(g.pointers) > g.MaxPointers {
+		if g.inProgress() {
+			g.SetState(GestureEnded)
+		} else {
+			g.SetState(GestureFailed)
+		}
+		return
+	}
+	g.last = g.Centroid()
+	g.lastTime = time.Now()
+}
+
+func (g *PanGesture) PointerMove(p Pointer) {
+	c := g.Centroid()
+	d := Size{c.X - g.last.X, c.Y - g.last.Y}
+	now := time.Now()
+	if dt := now.Sub(g.lastTime).Seconds(); dt > 0 {
+		g.Velocity = Size{d.Width / dt, d.Height / dt}
+	}
+	g.last, g.lastTime = c, now
+	g.Translation.Width += d.Width
+	g.Translation.Height += d.Height
+
+	switch {
+	case g.inProgress():
+		g.SetState(GestureChanged)
+	case len(g.pointers) >= g.MinPointers && math.Hypot(g.Translation.Width, g.Translation.Height) >= g.MinDistance:
+		g.SetState(GestureBegan)
+	}
+}
+
+func (g *PanGesture) PointerUp(p Pointer) {
+	if len(g.pointers) >= g.MinPointers && len(g.pointers) > 0 {
+		g.last = g.Centroid()
+		return
+	}
+	if g.inProgress() {
+		// Pointers that stopped before lifting aren't flung.
+		if time.Since(g.lastTime) > 100*time.Millisecond {
+			g.Velocity = Size{}
+		}
+		g.SetState(GestureEnded)
+	} else {
+		g.SetState(GestureFailed)
+	}
+}
+
+func (g *PanGesture) Reset() {
+	g.Translation = Size{}
+	g.Velocity = Size{}
+}
+
+// SwipeDirection is a set of directions.
+type SwipeDirection uint8
+
+const (
+	SwipeRight SwipeDirection = 1 << iota
+	SwipeLeft
+	SwipeUp
+	SwipeDown
+
+	SwipeAny = SwipeRight | SwipeLeft | SwipeUp | SwipeDown
+)
+
+// SwipeGesture recognizes a quick, mostly straight stroke.
+type SwipeGesture struct {
+	*Gesture
+
+	PointerCount int
+	// Directions are those in which the gesture is recognized.
+	Directions SwipeDirection
+	// MinDistance and MinVelocity are how far and how fast, per second, the
+	// pointers must move.
+	MinDistance, MinVelocity float64
+	// MaxDuration is the longest the stroke may last.
+	MaxDuration time.Duration
+
+	// Direction is that of the recognized swipe.
+	Direction SwipeDirection
+
+	start     Position
+	startTime time.Time
+	end       Position
+	count     int
+}
+
+// NewSwipeGesture returns a recognizer for a swipe of one pointer in any
+// direction.
+func NewSwipeGesture(handler func(*SwipeGesture)) *SwipeGesture {
+	s := &SwipeGesture{
+		PointerCount: 1,
+		Directions:   SwipeAny,
+		MinDistance:  10,
+		MinVelocity:  50,
+		MaxDuration:  500 * time.Millisecond,
+	}
+	s.Gesture = NewGesture(s, func() { handler(s) })
+	return s
+}
+
+func (s *SwipeGesture) PointerDown(p Pointer) {
+	s.count++
+	if s.count > s.PointerCount {
+		s.SetState(GestureFailed)
+		return
+	}
+	if s.count == s.PointerCount {
+		s.start = s.Centroid()
+		s.end = s.start
+		s.startTime = time.Now()
+		s.timeout(s.MaxDuration, func() { s.SetState(GestureFailed) })
+	}
+}
+
+func (s *SwipeGesture) PointerMove(p Pointer) {
+	if s.count == s.PointerCount {
+		s.end = s.Centroid()
+	}
+}
+
+func (s *SwipeGesture) PointerUp(p Pointer) {
+	if s.count != s.PointerCount {
+		s.SetState(GestureFailed)
+		return
+	}
+	if len(s.pointers) > 0 {
+		return
+	}
+	s.cancelTimeout()
+	dx, dy := s.end.X-s.start.X, s.end.Y-s.start.Y
+	switch {
+	case math.Abs(dx) >= math.Abs(dy) && dx > 0:
+		s.Direction = SwipeRight
+	case math.Abs(dx) >= math.Abs(dy):
+		s.Direction = SwipeLeft
+	case dy < 0:
+		s.Direction = SwipeUp
+	default:
+		s.Direction = SwipeDown
+	}
+	d := math.Hypot(dx, dy)
+	if s.Directions&s.Direction == 0 || d < s.MinDistance || d/time.Since(s.startTime).Seconds() < s.MinVelocity {
+		s.SetState(GestureFailed)
+		return
+	}
+	s.SetState(GestureRecognized)
+}
+
+func (s *SwipeGesture) Reset() {
+	s.count = 0
+}
+
+// PinchGesture recognizes two or more pointers moving toward or away from
+// each other.  It begins once their spread has changed by MinDistance.
+type PinchGesture struct {
+	*Gesture
+
+	MinDistance float64
+
+	// Scale is the pointers' spread relative to when the gesture started,
+	// and Velocity how fast, per second, it is changing.  Center is their
+	// centroid.
+	Scale, Velocity float64
+	Center          Position
+
+	base     float64
+	last     float64
+	lastTime time.Time
+}
+
+func NewPinchGesture(handler func(*PinchGesture)) *PinchGesture {
+	g := &PinchGesture{MinDistance: 3}
+	g.Gesture = NewGesture(g, func() { handler(g) })
+	g.Reset()
+	return g
+}
+
+// spread returns the mean distance of the pointers from their centroid.
+func (g *PinchGesture) spread() float64 {
+	c := g.Centroid()
+	d := 0.0
+	for _, p := range g.pointers {
+		d += distance(p.Position, c)
+	}
+	return d / float64(len(g.pointers))
+}
+
+// minPinchSpread is the smallest spread that a scale is measured against.
+// Pointers closer than this, as when they coincide, give no scale.
+const minPinchSpread = 1e-3
+
+// rebase keeps Scale steady when pointers are added or removed.
+func (g *PinchGesture) rebase() {
+	if len(g.pointers) >= 2 {
+		g.last = g.spread()
+		// A base of 0 is measured at the next move.
+		g.base = 0
+		if g.last >= minPinchSpread {
+			g.base = g.last / g.Scale
+		}
+		g.lastTime = time.Now()
+	}
+}
+
+func (g *PinchGesture) PointerDown(p Pointer) { g.rebase() }
+
+func (g *PinchGesture) PointerMove(p Pointer) {
+	if len(g.pointers) < 2 {
+		return
+	}
+	s := g.spread()
+	now := time.Now()
+	if g.base == 0 || s < minPinchSpread {
+		// Keep Scale until the pointers are far enough apart to measure
+		// against.
+		if g.base == 0 && s >= minPinchSpread {
+			g.base = s / g.Scale
+		}
+		g.last, g.lastTime = s, now
+		return
+	}
+	if dt := now.Sub(g.lastTime).Seconds(); dt > 0 {
+		g.Velocity = (s - g.last) / g.base / dt
+	}
+	g.last, g.lastTime = s, now
+	g.Scale = s / g.base
+	g.Center = g.Centroid()
+
+	if g.inProgress() {
+		g.SetState(GestureChanged)
+	} else if math.Abs(s-g.base) >= g.MinDistance {
+		g.SetState(GestureBegan)
+	}
+}
+
+func (g *PinchGesture) PointerUp(p Pointer) {
+	switch {
+	case len(g.pointers) >= 2:
+		g.rebase()
+	case g.inProgress():
+		g.SetState(GestureEnded)
+	case len(g.pointers) == 0:
+		g.SetState(GestureFailed)
+	}
+}
+
+func (g *PinchGesture) Reset() {
+	g.Scale = 1
+	g.Velocity = 0
+}
+
+// RotateGesture recognizes two pointers turning about each other.  It
+// begins once they have turned by MinRotation radians.
+type RotateGesture struct {
+	*Gesture
+
+	MinRotation float64
+
+	// Rotation is the clockwise angle, in radians, that the pointers have
+	// turned since the gesture started, and Velocity how fast, per second,
+	// they are turning.  Center is their centroid.
+	Rotation, Velocity float64
+	Center             Position
+
+	last     float64
+	lastTime time.Time
+}
+
+func NewRotateGesture(handler func(*RotateGesture)) *RotateGesture {
+	g := &RotateGesture{MinRotation: 0.1}
+	g.Gesture = NewGesture(g, func() { handler(g) })
+	return g
+}
+
+// angle returns the direction from the first pointer to go down to the
+// second.
+func (g *RotateGesture) angle() float64 {
+	ps := g.Pointers()
+	sort.Slice(ps, func(i, j int) bool { return ps[i].ID < ps[j].ID })
+	return math.Atan2(ps[1].Y-ps[0].Y, ps[1].X-ps[0].X)
+}
+
+func (g *RotateGesture) rebase() {
+	if len(g.pointers) >= 2 {
+		g.last = g.angle()
+		g.lastTime = time.Now()
+	}
+}
+
+func (g *RotateGesture) PointerDown(p Pointer) { g.rebase() }
+
+func (g *RotateGesture) PointerMove(p Pointer) {
+	if len(g.pointers) < 2 {
+		return
+	}
+	a := g.angle()
+	d := math.Remainder(a-g.last, 2*math.Pi)
+	now := time.Now()
+	if dt := now.Sub(g.lastTime).Seconds(); dt > 0 {
+		g.Velocity = d / dt
+	}
+	g.last, g.lastTime = a, now
+	g.Rotation += d
+	g.Center = g.Centroid()
+
+	if g.inProgress() {
+		g.SetState(GestureChanged)
+	} else if math.Abs(g.Rotation) >= g.MinRotation {
+		g.SetState(GestureBegan)
+	}
+}
+
+func (g *RotateGesture) PointerUp(p Pointer) {
+	switch {
+	case len(g.pointers) >= 2:
+		g.rebase()
+	case g.inProgress():
+		g.SetState(GestureEnded)
+	case len(g.pointers) == 0:
+		g.SetState(GestureFailed)
+	}
+}
+
+func (g *RotateGesture) Reset() {
+	g.Rotation = 0
+	g.Velocity = 0
+}
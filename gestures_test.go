@@ -0,0 +1,353 @@
+// +build !android,!ios
+
+package ui_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gordonklaus/ui"
+)
+
+func TestPinchCoincidentPointers(t *testing.T) {
+	root := newBox(ui.Color{}, nil)
+	var scales []float64
+	root.AddGestureRecognizer(ui.NewPinchGesture(func(g *ui.PinchGesture) {
+		if math.IsNaN(g.Scale) || math.IsInf(g.Scale, 0) || math.IsNaN(g.Velocity) || math.IsInf(g.Velocity, 0) {
+			t.Errorf("scale %v and velocity %v are not finite", g.Scale, g.Velocity)
+		}
+		scales = append(scales, g.Scale)
+	}))
+	w := newHeadless(t, ui.Size{Width: 40, Height: 10}, root)
+	defer w.Close()
+
+	touch := func(id ui.PointerID, x float64) ui.Pointer {
+		p := ui.Pointer{ID: id, Type: ui.PointerTypeTouch, Button: ui.PointerButtonTouchContact, Buttons: ui.PointerButtonTouchContact}
+		p.Position = ui.Position{X: x, Y: 5}
+		return p
+	}
+	// The pointers go down together, so there is no spread to scale.
+	w.InjectPointerDown(touch(1, 5))
+	w.InjectPointerDown(touch(2, 5))
+	// The scale is measured from here.
+	w.InjectPointerMove(touch(2, 7))
+	w.InjectPointerMove(touch(2, 15))
+	// Coinciding again keeps the scale.
+	w.InjectPointerMove(touch(2, 5))
+	w.InjectPointerMove(touch(2, 25))
+
+	var got []float64
+	w.Do(func() { got = scales })
+	want := []float64{5, 10}
+	if len(got) != len(want) {
+		t.Fatalf("got scales %v, want %v", got, want)
+	}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("got scales %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func touchAt(id ui.PointerID, x, y float64) ui.Pointer {
+	p := ui.Pointer{ID: id, Type: ui.PointerTypeTouch, Button: ui.PointerButtonTouchContact, Buttons: ui.PointerButtonTouchContact}
+	p.Position = ui.Position{X: x, Y: y}
+	return p
+}
+
+// tap injects a touch going down and up at a position.
+func tap(w *ui.HeadlessWindow, id ui.PointerID, x, y float64) {
+	w.InjectPointerDown(touchAt(id, x, y))
+	w.InjectPointerUp(touchAt(id, x, y))
+}
+
+var stateNames = map[ui.GestureState]string{
+	ui.GesturePossible:  "possible",
+	ui.GestureBegan:     "began",
+	ui.GestureChanged:   "changed",
+	ui.GestureEnded:     "ended",
+	ui.GestureCancelled: "cancelled",
+	ui.GestureFailed:    "failed",
+}
+
+// A stateLog records the states a recognizer's handler sees.
+type stateLog struct {
+	w      *ui.HeadlessWindow
+	states []string
+}
+
+func (l *stateLog) record(s ui.GestureState) {
+	l.states = append(l.states, stateNames[s])
+}
+
+func (l *stateLog) get() (s []string) {
+	l.w.Do(func() { s = append(s, l.states...) })
+	return
+}
+
+// waitFor waits for the log to hold n states, for recognizers that act on
+// timers.
+func (l *stateLog) waitFor(t *testing.T, n int) []string {
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if s := l.get(); len(s) >= n {
+			return s
+		}
+	}
+	t.Fatalf("timed out waiting for %d states; got %v", n, l.get())
+	return nil
+}
+
+func TestTapGesture(t *testing.T) {
+	root := newCanceler(nil)
+	var log stateLog
+	var at ui.Position
+	tapGesture := ui.NewTapGesture(func(g *ui.TapGesture) {
+		log.record(g.State())
+		at = g.Position
+	})
+	root.AddGestureRecognizer(tapGesture)
+	w := newHeadless(t, ui.Size{Width: 40, Height: 40}, root)
+	defer w.Close()
+	log.w = w
+
+	tap(w, 1, 10, 12)
+	if s := log.get(); !equalStrings(s, []string{"ended"}) {
+		t.Errorf("a tap gave states %v, want [ended]", s)
+	}
+	var events []string
+	w.Do(func() { events, root.events = root.events, nil })
+	if at != (ui.Position{X: 10, Y: 12}) {
+		t.Errorf("tap at %v, want (10, 12)", at)
+	}
+	// Recognizing the tap takes the pointer from the view.
+	if !equalStrings(events, []string{"down", "cancel"}) {
+		t.Errorf("view got %v, want [down cancel]", events)
+	}
+
+	// A pointer that moves too far is not a tap.
+	w.InjectPointerDown(touchAt(2, 10, 10))
+	w.InjectPointerMove(touchAt(2, 20, 10))
+	w.InjectPointerUp(touchAt(2, 20, 10))
+	if s := log.get(); len(s) != 1 {
+		t.Errorf("got states %v after a drag, want only the tap's", s)
+	}
+	w.Do(func() { events = root.events })
+	if !equalStrings(events, []string{"down", "move", "up"}) {
+		t.Errorf("view got %v after a drag, want [down move up]", events)
+	}
+}
+
+func TestTapRequireFailure(t *testing.T) {
+	root := newCanceler(nil)
+	var single, double stateLog
+	singleTap := ui.NewTapGesture(func(g *ui.TapGesture) { single.record(g.State()) })
+	doubleTap := ui.NewDoubleTapGesture(func(g *ui.TapGesture) { double.record(g.State()) })
+	doubleTap.MaxInterval = 100 * time.Millisecond
+	singleTap.RequireFailureOf(doubleTap)
+	root.AddGestureRecognizer(singleTap)
+	root.AddGestureRecognizer(doubleTap)
+	w := newHeadless(t, ui.Size{Width: 40, Height: 40}, root)
+	defer w.Close()
+	single.w, double.w = w, w
+
+	// The single tap waits for the double tap, which recognizes.
+	tap(w, 1, 10, 10)
+	if s := single.get(); len(s) != 0 {
+		t.Errorf("single tap gave states %v before the double tap failed", s)
+	}
+	tap(w, 2, 10, 10)
+	if s := double.get(); !equalStrings(s, []string{"ended"}) {
+		t.Errorf("double tap gave states %v, want [ended]", s)
+	}
+	if s := single.get(); len(s) != 0 {
+		t.Errorf("single tap gave states %v after a double tap", s)
+	}
+
+	// The single tap recognizes once the double tap times out.
+	tap(w, 3, 10, 10)
+	if s := single.waitFor(t, 1); !equalStrings(s, []string{"ended"}) {
+		t.Errorf("single tap gave states %v, want [ended]", s)
+	}
+	if s := double.get(); len(s) != 1 {
+		t.Errorf("double tap gave states %v after a single tap, want only those of the double tap", s)
+	}
+}
+
+func TestLongPressGesture(t *testing.T) {
+	root := newCanceler(nil)
+	var log stateLog
+	var at []ui.Position
+	g := ui.NewLongPressGesture(func(g *ui.LongPressGesture) {
+		log.record(g.State())
+		at = append(at, g.Position)
+	})
+	g.Duration = 10 * time.Millisecond
+	root.AddGestureRecognizer(g)
+	w := newHeadless(t, ui.Size{Width: 40, Height: 40}, root)
+	defer w.Close()
+	log.w = w
+
+	w.InjectPointerDown(touchAt(1, 10, 10))
+	log.waitFor(t, 1)
+	w.InjectPointerMove(touchAt(1, 30, 10))
+	w.InjectPointerUp(touchAt(1, 30, 10))
+	if s := log.get(); !equalStrings(s, []string{"began", "changed", "ended"}) {
+		t.Errorf("got states %v, want [began changed ended]", s)
+	}
+	var positions []ui.Position
+	var events []string
+	w.Do(func() { positions, events = at, root.events })
+	if len(positions) != 3 || positions[0] != (ui.Position{X: 10, Y: 10}) || positions[1] != (ui.Position{X: 30, Y: 10}) {
+		t.Errorf("got positions %v, want (10, 10) and then (30, 10)", positions)
+	}
+	if !equalStrings(events, []string{"down", "cancel"}) {
+		t.Errorf("view got %v, want [down cancel]", events)
+	}
+
+	// Moving before the duration fails the gesture.
+	w.InjectPointerDown(touchAt(2, 10, 10))
+	w.InjectPointerMove(touchAt(2, 30, 10))
+	time.Sleep(2 * g.Duration)
+	w.InjectPointerUp(touchAt(2, 30, 10))
+	if s := log.get(); len(s) != 3 {
+		t.Errorf("got states %v after a drag, want only the long press's", s)
+	}
+}
+
+func TestPanGesture(t *testing.T) {
+	root := newBox(ui.Color{}, nil)
+	child := newCanceler(root)
+	var log stateLog
+	var translations []ui.Size
+	root.AddGestureRecognizer(ui.NewPanGesture(func(g *ui.PanGesture) {
+		log.record(g.State())
+		translations = append(translations, g.Translation)
+	}))
+	w := newHeadless(t, ui.Size{Width: 40, Height: 40}, root)
+	defer w.Close()
+	log.w = w
+	w.Do(func() { child.Resize(ui.Size{Width: 40, Height: 40}) })
+
+	w.InjectPointerDown(touchAt(1, 5, 5))
+	// Less than MinDistance.
+	w.InjectPointerMove(touchAt(1, 6, 5))
+	w.InjectPointerMove(touchAt(1, 10, 5))
+	w.InjectPointerMove(touchAt(1, 12, 7))
+	w.InjectPointerUp(touchAt(1, 12, 7))
+	if s := log.get(); !equalStrings(s, []string{"began", "changed", "ended"}) {
+		t.Errorf("got states %v, want [began changed ended]", s)
+	}
+	var got []ui.Size
+	var events []string
+	w.Do(func() { got, events = translations, child.events })
+	want := []ui.Size{{Width: 5}, {Width: 7, Height: 2}, {Width: 7, Height: 2}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("got translations %v, want %v", got, want)
+	}
+	// The child had the pointer until the pan began.
+	if !equalStrings(events, []string{"down", "move", "cancel"}) {
+		t.Errorf("child got %v, want [down move cancel]", events)
+	}
+}
+
+func TestSwipeGesture(t *testing.T) {
+	root := newBox(ui.Color{}, nil)
+	var log stateLog
+	var directions []ui.SwipeDirection
+	g := ui.NewSwipeGesture(func(g *ui.SwipeGesture) {
+		log.record(g.State())
+		directions = append(directions, g.Direction)
+	})
+	g.Directions = ui.SwipeRight | ui.SwipeUp
+	g.MaxDuration = time.Second
+	root.AddGestureRecognizer(g)
+	w := newHeadless(t, ui.Size{Width: 40, Height: 40}, root)
+	defer w.Close()
+	log.w = w
+
+	swipe := func(id ui.PointerID, x0, y0, x1, y1 float64) {
+		w.InjectPointerDown(touchAt(id, x0, y0))
+		w.InjectPointerMove(touchAt(id, x1, y1))
+		w.InjectPointerUp(touchAt(id, x1, y1))
+	}
+	swipe(1, 5, 20, 35, 22)
+	swipe(2, 20, 35, 21, 5)
+	// Not in a recognized direction.
+	swipe(3, 35, 20, 5, 20)
+	// Too short.
+	swipe(4, 5, 20, 10, 20)
+	if s := log.get(); !equalStrings(s, []string{"ended", "ended"}) {
+		t.Errorf("got states %v, want [ended ended]", s)
+	}
+	var got []ui.SwipeDirection
+	w.Do(func() { got = directions })
+	if len(got) != 2 || got[0] != ui.SwipeRight || got[1] != ui.SwipeUp {
+		t.Errorf("got directions %v, want right and up", got)
+	}
+}
+
+func TestRotateGesture(t *testing.T) {
+	root := newBox(ui.Color{}, nil)
+	var log stateLog
+	var rotations []float64
+	root.AddGestureRecognizer(ui.NewRotateGesture(func(g *ui.RotateGesture) {
+		log.record(g.State())
+		rotations = append(rotations, g.Rotation)
+	}))
+	w := newHeadless(t, ui.Size{Width: 40, Height: 40}, root)
+	defer w.Close()
+	log.w = w
+
+	w.InjectPointerDown(touchAt(1, 20, 20))
+	w.InjectPointerDown(touchAt(2, 30, 20))
+	// A quarter turn clockwise, as y grows downward.
+	w.InjectPointerMove(touchAt(2, 20, 30))
+	w.InjectPointerMove(touchAt(2, 10, 20))
+	w.InjectPointerUp(touchAt(2, 10, 20))
+	w.InjectPointerUp(touchAt(1, 20, 20))
+	if s := log.get(); !equalStrings(s, []string{"began", "changed", "ended"}) {
+		t.Errorf("got states %v, want [began changed ended]", s)
+	}
+	var got []float64
+	w.Do(func() { got = rotations })
+	if len(got) != 3 || math.Abs(got[0]-math.Pi/2) > 1e-9 || math.Abs(got[1]-math.Pi) > 1e-9 {
+		t.Errorf("got rotations %v, want a quarter and then a half turn", got)
+	}
+}
+
+func TestRecognizeSimultaneously(t *testing.T) {
+	for _, simultaneous := range []bool{false, true} {
+		root := newBox(ui.Color{}, nil)
+		var pinches, rotations stateLog
+		pinch := ui.NewPinchGesture(func(g *ui.PinchGesture) { pinches.record(g.State()) })
+		rotate := ui.NewRotateGesture(func(g *ui.RotateGesture) { rotations.record(g.State()) })
+		if simultaneous {
+			pinch.RecognizeSimultaneouslyWith(rotate)
+		}
+		root.AddGestureRecognizer(pinch)
+		root.AddGestureRecognizer(rotate)
+		w := newHeadless(t, ui.Size{Width: 40, Height: 40}, root)
+		pinches.w, rotations.w = w, w
+
+		// The pointers spread apart and turn at once.
+		w.InjectPointerDown(touchAt(1, 20, 20))
+		w.InjectPointerDown(touchAt(2, 30, 20))
+		w.InjectPointerMove(touchAt(2, 20, 40))
+		w.InjectPointerUp(touchAt(2, 20, 40))
+		w.InjectPointerUp(touchAt(1, 20, 20))
+
+		wantRotations := []string(nil)
+		if simultaneous {
+			wantRotations = []string{"began", "ended"}
+		}
+		if s := pinches.get(); !equalStrings(s, []string{"began", "ended"}) {
+			t.Errorf("simultaneous=%v: pinch got states %v, want [began ended]", simultaneous, s)
+		}
+		if s := rotations.get(); !equalStrings(s, wantRotations) {
+			t.Errorf("simultaneous=%v: rotation got states %v, want %v", simultaneous, s, wantRotations)
+		}
+		w.Close()
+	}
+}
@@ -6,5 +6,7 @@ require (
 	github.com/flynn/hid v0.0.0-20190502022136-f1b9b6cc019a
 	github.com/go-gl/gl v0.0.0-20190320180904-bf2b1f2f34d7
 	github.com/go-gl/mathgl v0.0.0-20190416160123-c4601bc793c7
+	golang.org/x/image v0.0.0-20190802002840-cff245a6509b
 	golang.org/x/mobile v0.0.0-20200222142934-3c8601c510d0
+	golang.org/x/text v0.3.0
 )
@@ -1,78 +1,87 @@
 package ui
 
 import (
-	"encoding/binary"
-	"log"
+	"errors"
+	"image"
+	"math"
 
 	"github.com/go-gl/mathgl/mgl32"
-	"golang.org/x/mobile/exp/f32"
-	"golang.org/x/mobile/exp/gl/glutil"
-	"golang.org/x/mobile/gl"
 )
 
 type Graphics struct {
-	glctx   gl.Context
-	program gl.Program
-	mvp     gl.Uniform
-	pos     gl.Attrib
-	color   gl.Attrib
+	renderer renderer
 
+	// bounds is the region of window coordinates covered by the framebuffer.
+	bounds     Rectangle
 	proj, view mgl32.Mat4
-}
-
-func newGraphics(glctx gl.Context) *Graphics {
-	glctx.Enable(gl.BLEND)
-	glctx.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
-
-	const vertexShader = `#version 100
-		uniform mat4 mvp;
-		attribute vec2 pos;
-		attribute vec4 color;
-		varying vec4 vColor;
-
-		void main() {
-			gl_Position = mvp * vec4(pos, 0, 1);
-			vColor = color;
-		}`
 
-	const fragmentShader = `#version 100
-		precision mediump float;
-		varying vec4 vColor;
+	// glyphs is created by the first text drawn.
+	glyphs *glyphAtlas
+}
 
-		void main() {
-			gl_FragColor = vColor;
-		}`
+// A renderer executes the drawing commands of a Graphics, either through GL
+// or on the CPU.
+type renderer interface {
+	release()
+	clear(c Color)
+	newBuffer(data []float32) rendererBuffer
+	drawTriangles(b rendererBuffer, mvp mgl32.Mat4)
+	newTexture(width, height int) rendererTexture
+	// drawTexturedTriangles draws triangles whose vertices have texture
+	// coordinates, multiplying their colors by the texture's.
+	drawTexturedTriangles(b rendererBuffer, t rendererTexture, mvp mgl32.Mat4)
+
+	// framebufferSize returns the size in pixels of the current render target.
+	framebufferSize() (width, height int)
+	// readPixels returns a copy of the current render target.
+	readPixels() (*image.RGBA, error)
+	// renderOffscreen calls draw with a new render target of the given size
+	// and returns its contents.
+	renderOffscreen(width, height int, draw func()) (*image.RGBA, error)
+}
 
-	program, err := glutil.CreateProgram(glctx, vertexShader, fragmentShader)
-	if err != nil {
-		log.Fatalf("error creating GL program: %v", err)
-	}
+type rendererBuffer interface {
+	release()
+}
 
-	mvp := glctx.GetUniformLocation(program, "mvp")
-	pos := glctx.GetAttribLocation(program, "pos")
-	color := glctx.GetAttribLocation(program, "color")
+type rendererTexture interface {
+	release()
+	// upload replaces the texels at (x, y) with img, which is not
+	// premultiplied so that colors keep their precision at low alpha.
+	upload(x, y int, img *image.NRGBA)
+	setFilter(f TextureFilter)
+	setWrap(w TextureWrap)
+}
 
+func newGraphicsWithRenderer(r renderer) *Graphics {
 	return &Graphics{
-		glctx:   glctx,
-		program: program,
-		mvp:     mvp,
-		pos:     pos,
-		color:   color,
+		renderer: r,
+		view:     mgl32.Ident4(),
 	}
 }
 
 func (g *Graphics) release() {
-	g.glctx.DeleteProgram(g.program)
+	if g.glyphs != nil {
+		g.glyphs.release()
+	}
+	g.renderer.release()
 }
 
 func (g *Graphics) Size(s Size) {
-	w := float32(s.Width)
-	h := float32(s.Height)
+	g.setBounds(Rectangle{Max: Position{s.Width, s.Height}})
+}
+
+func (g *Graphics) setBounds(r Rectangle) {
+	g.bounds = r
+	w := float32(r.Width())
+	h := float32(r.Height())
+	x := float32(r.Min.X)
+	y := float32(r.Min.Y)
 	g.proj = mgl32.Mat4{
 		2 / w, 0, 0, 0,
 		0, -2 / h, 0, 0,
 		0, 0, -1, 0,
-		-1, 1, -1, 1,
+		-1 - 2*x/w, 1 + 2*y/h, -1, 1,
 	}
 }
 
@@ -86,23 +95,41 @@ func (g *Graphics) setViewTransform(t transform) {
 }
 
 func (g *Graphics) clear() {
-	g.glctx.ClearColor(0, 0, 0, 1)
-	g.glctx.Clear(gl.COLOR_BUFFER_BIT)
+	g.renderer.clear(Color{0, 0, 0, 1})
 }
 
-func (g *Graphics) Draw(buffer *TriangleBuffer, model mgl32.Mat4) {
-	g.glctx.UseProgram(g.program)
+func (g *Graphics) readPixels() (*image.RGBA, error) {
+	return g.renderer.readPixels()
+}
 
-	mvp := g.proj.Mul4(g.view).Mul4(model)
-	g.glctx.UniformMatrix4fv(g.mvp, mvp[:])
+// capture calls draw with an offscreen render target covering the region r of
+// window coordinates, at the same resolution as the framebuffer, and returns
+// the result.  The target starts out transparent.
+func (g *Graphics) capture(r Rectangle, draw func()) (*image.RGBA, error) {
+	fbWidth, fbHeight := g.renderer.framebufferSize()
+	width := int(math.Round(r.Width() * float64(fbWidth) / g.bounds.Width()))
+	height := int(math.Round(r.Height() * float64(fbHeight) / g.bounds.Height()))
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("cannot capture an empty rectangle")
+	}
+
+	bounds := g.bounds
+	g.setBounds(r)
+	defer g.setBounds(bounds)
 
-	buffer.draw(g.pos, g.color)
+	return g.renderer.renderOffscreen(width, height, func() {
+		g.renderer.clear(Color{})
+		draw()
+	})
+}
+
+func (g *Graphics) Draw(buffer *TriangleBuffer, model mgl32.Mat4) {
+	mvp := g.proj.Mul4(g.view).Mul4(model)
+	g.renderer.drawTriangles(buffer.buffer, mvp)
 }
 
 type TriangleBuffer struct {
-	gfx    *Graphics
-	buffer gl.Buffer
-	length int
+	buffer rendererBuffer
 }
 
 type Triangle [3]Vertex
@@ -116,7 +143,11 @@ type Color struct {
 	R, G, B, A float64
 }
 
-const coordsPerVertex = 6
+const (
+	coordsPerVertex = 6
+	// Textured vertices have texture coordinates between position and color.
+	texturedCoordsPerVertex = 8
+)
 
 func NewTriangleBuffer(gfx *Graphics, ts []Triangle) *TriangleBuffer {
 	data := []float32{}
@@ -133,23 +164,9 @@ func NewTriangleBuffer(gfx *Graphics, ts []Triangle) *TriangleBuffer {
 		}
 	}
 
-	buffer := gfx.glctx.CreateBuffer()
-	gfx.glctx.BindBuffer(gl.ARRAY_BUFFER, buffer)
-	gfx.glctx.BufferData(gl.ARRAY_BUFFER, f32.Bytes(binary.LittleEndian, data...), gl.STATIC_DRAW)
-
-	return &TriangleBuffer{gfx, buffer, 3 * len(ts)}
+	return &TriangleBuffer{gfx.renderer.newBuffer(data)}
 }
 
 func (b *TriangleBuffer) Release() {
-	b.gfx.glctx.DeleteBuffer(b.buffer)
-}
-
-func (b *TriangleBuffer) draw(pos, color gl.Attrib) {
-	b.gfx.glctx.BindBuffer(gl.ARRAY_BUFFER, b.buffer)
-	b.gfx.glctx.VertexAttribPointer(pos, 2, gl.FLOAT, false, 4*coordsPerVertex, 0)
-	b.gfx.glctx.EnableVertexAttribArray(pos)
-	b.gfx.glctx.VertexAttribPointer(color, 4, gl.FLOAT, false, 4*coordsPerVertex, 4*2)
-	b.gfx.glctx.EnableVertexAttribArray(color)
-
-	b.gfx.glctx.DrawArrays(gl.TRIANGLES, 0, b.length)
+	b.buffer.release()
 }
@@ -0,0 +1,299 @@
+package ui
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/mobile/exp/f32"
+	"golang.org/x/mobile/exp/gl/glutil"
+	"golang.org/x/mobile/gl"
+)
+
+type glRenderer struct {
+	glctx   gl.Context
+	program gl.Program
+	mvp     gl.Uniform
+	pos     gl.Attrib
+	color   gl.Attrib
+
+	textured glTexturedProgram
+}
+
+type glTexturedProgram struct {
+	program  gl.Program
+	mvp      gl.Uniform
+	pos      gl.Attrib
+	texCoord gl.Attrib
+	color    gl.Attrib
+}
+
+func newGraphics(glctx gl.Context) *Graphics {
+	glctx.Enable(gl.BLEND)
+	// Blending alpha with ONE keeps the framebuffer's alpha meaningful, so
+	// its contents are valid premultiplied RGBA, like the software renderer's.
+	glctx.BlendFuncSeparate(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA, gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+
+	const vertexShader = `#version 100
+		uniform mat4 mvp;
+		attribute vec2 pos;
+		attribute vec4 color;
+		varying vec4 vColor;
+
+		void main() {
+			gl_Position = mvp * vec4(pos, 0, 1);
+			vColor = color;
+		}`
+
+	const fragmentShader = `#version 100
+		precision mediump float;
+		varying vec4 vColor;
+
+		void main() {
+			gl_FragColor = vColor;
+		}`
+
+	program, err := glutil.CreateProgram(glctx, vertexShader, fragmentShader)
+	if err != nil {
+		log.Fatalf("error creating GL program: %v", err)
+	}
+
+	mvp := glctx.GetUniformLocation(program, "mvp")
+	pos := glctx.GetAttribLocation(program, "pos")
+	color := glctx.GetAttribLocation(program, "color")
+
+	const texturedVertexShader = `#version 100
+		uniform mat4 mvp;
+		attribute vec2 pos;
+		attribute vec2 texCoord;
+		attribute vec4 color;
+		varying vec2 vTexCoord;
+		varying vec4 vColor;
+
+		void main() {
+			gl_Position = mvp * vec4(pos, 0, 1);
+			vTexCoord = texCoord;
+			vColor = color;
+		}`
+
+	const texturedFragmentShader = `#version 100
+		precision mediump float;
+		uniform sampler2D tex;
+		varying vec2 vTexCoord;
+		varying vec4 vColor;
+
+		void main() {
+			gl_FragColor = vColor * texture2D(tex, vTexCoord);
+		}`
+
+	texturedProgram, err := glutil.CreateProgram(glctx, texturedVertexShader, texturedFragmentShader)
+	if err != nil {
+		log.Fatalf("error creating GL program: %v", err)
+	}
+
+	return newGraphicsWithRenderer(&glRenderer{
+		glctx:   glctx,
+		program: program,
+		mvp:     mvp,
+		pos:     pos,
+		color:   color,
+		textured: glTexturedProgram{
+			program:  texturedProgram,
+			mvp:      glctx.GetUniformLocation(texturedProgram, "mvp"),
+			pos:      glctx.GetAttribLocation(texturedProgram, "pos"),
+			texCoord: glctx.GetAttribLocation(texturedProgram, "texCoord"),
+			color:    glctx.GetAttribLocation(texturedProgram, "color"),
+		},
+	})
+}
+
+func (r *glRenderer) release() {
+	r.glctx.DeleteProgram(r.program)
+	r.glctx.DeleteProgram(r.textured.program)
+}
+
+func (r *glRenderer) clear(c Color) {
+	r.glctx.ClearColor(float32(c.R), float32(c.G), float32(c.B), float32(c.A))
+	r.glctx.Clear(gl.COLOR_BUFFER_BIT)
+}
+
+func (r *glRenderer) viewport() [4]int32 {
+	var v [4]int32
+	r.glctx.GetIntegerv(v[:], gl.VIEWPORT)
+	return v
+}
+
+func (r *glRenderer) framebufferSize() (width, height int) {
+	v := r.viewport()
+	return int(v[2]), int(v[3])
+}
+
+func (r *glRenderer) readPixels() (*image.RGBA, error) {
+	v := r.viewport()
+	width, height := int(v[2]), int(v[3])
+	buf := make([]byte, 4*width*height)
+	r.glctx.ReadPixels(buf, int(v[0]), int(v[1]), width, height, gl.RGBA, gl.UNSIGNED_BYTE)
+	if err := r.glctx.GetError(); err != gl.NO_ERROR {
+		return nil, fmt.Errorf("error reading pixels: GL error 0x%x", err)
+	}
+
+	// GL rows run bottom to top.
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		copy(img.Pix[y*img.Stride:], buf[(height-1-y)*4*width:(height-y)*4*width])
+	}
+	return img, nil
+}
+
+func (r *glRenderer) renderOffscreen(width, height int, draw func()) (*image.RGBA, error) {
+	var prevFramebuffer [1]int32
+	r.glctx.GetIntegerv(prevFramebuffer[:], gl.FRAMEBUFFER_BINDING)
+	prevViewport := r.viewport()
+	defer func() {
+		r.glctx.BindFramebuffer(gl.FRAMEBUFFER, gl.Framebuffer{Value: uint32(prevFramebuffer[0])})
+		r.glctx.Viewport(int(prevViewport[0]), int(prevViewport[1]), int(prevViewport[2]), int(prevViewport[3]))
+	}()
+
+	// A texture attachment is color-renderable on every GL version we support,
+	// unlike an RGBA8 renderbuffer.
+	texture := r.glctx.CreateTexture()
+	defer r.glctx.DeleteTexture(texture)
+	r.glctx.BindTexture(gl.TEXTURE_2D, texture)
+	r.glctx.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	r.glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
+
+	framebuffer := r.glctx.CreateFramebuffer()
+	defer r.glctx.DeleteFramebuffer(framebuffer)
+	r.glctx.BindFramebuffer(gl.FRAMEBUFFER, framebuffer)
+	r.glctx.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, texture, 0)
+	if status := r.glctx.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		return nil, fmt.Errorf("offscreen framebuffer is incomplete: status 0x%x", status)
+	}
+	r.glctx.Viewport(0, 0, width, height)
+
+	draw()
+	return r.readPixels()
+}
+
+type glBuffer struct {
+	glctx  gl.Context
+	buffer gl.Buffer
+	// length is the number of floats in the buffer.
+	length int
+}
+
+func (r *glRenderer) newBuffer(data []float32) rendererBuffer {
+	buffer := r.glctx.CreateBuffer()
+	r.glctx.BindBuffer(gl.ARRAY_BUFFER, buffer)
+	r.glctx.BufferData(gl.ARRAY_BUFFER, f32.Bytes(binary.LittleEndian, data...), gl.STATIC_DRAW)
+
+	return &glBuffer{r.glctx, buffer, len(data)}
+}
+
+func (b *glBuffer) release() {
+	b.glctx.DeleteBuffer(b.buffer)
+}
+
+func (r *glRenderer) drawTriangles(buffer rendererBuffer, mvp mgl32.Mat4) {
+	b := buffer.(*glBuffer)
+
+	r.glctx.UseProgram(r.program)
+	r.glctx.UniformMatrix4fv(r.mvp, mvp[:])
+
+	r.glctx.BindBuffer(gl.ARRAY_BUFFER, b.buffer)
+	r.glctx.VertexAttribPointer(r.pos, 2, gl.FLOAT, false, 4*coordsPerVertex, 0)
+	r.glctx.EnableVertexAttribArray(r.pos)
+	r.glctx.VertexAttribPointer(r.color, 4, gl.FLOAT, false, 4*coordsPerVertex, 4*2)
+	r.glctx.EnableVertexAttribArray(r.color)
+
+	r.glctx.DrawArrays(gl.TRIANGLES, 0, b.length/coordsPerVertex)
+}
+
+type glTexture struct {
+	glctx   gl.Context
+	texture gl.Texture
+}
+
+func (r *glRenderer) newTexture(width, height int) rendererTexture {
+	texture := r.glctx.CreateTexture()
+	r.glctx.BindTexture(gl.TEXTURE_2D, texture)
+	r.glctx.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	r.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	r.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	r.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	r.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	r.glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
+	return &glTexture{r.glctx, texture}
+}
+
+func (t *glTexture) release() {
+	t.glctx.DeleteTexture(t.texture)
+}
+
+func (t *glTexture) upload(x, y int, img *image.NRGBA) {
+	b := img.Bounds()
+	if b.Empty() {
+		return
+	}
+	pix := img.Pix
+	if img.Stride != 4*b.Dx() {
+		pix = make([]byte, 0, 4*b.Dx()*b.Dy())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := img.PixOffset(b.Min.X, y)
+			pix = append(pix, img.Pix[i:i+4*b.Dx()]...)
+		}
+	} else {
+		pix = pix[img.PixOffset(b.Min.X, b.Min.Y):]
+	}
+	t.glctx.BindTexture(gl.TEXTURE_2D, t.texture)
+	t.glctx.TexSubImage2D(gl.TEXTURE_2D, 0, x, y, b.Dx(), b.Dy(), gl.RGBA, gl.UNSIGNED_BYTE, pix)
+	t.glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
+}
+
+func (t *glTexture) setFilter(f TextureFilter) {
+	filter := gl.LINEAR
+	if f == TextureFilterNearest {
+		filter = gl.NEAREST
+	}
+	t.glctx.BindTexture(gl.TEXTURE_2D, t.texture)
+	t.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+	t.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+	t.glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
+}
+
+func (t *glTexture) setWrap(w TextureWrap) {
+	wrap := gl.CLAMP_TO_EDGE
+	switch w {
+	case TextureWrapRepeat:
+		wrap = gl.REPEAT
+	case TextureWrapMirror:
+		wrap = gl.MIRRORED_REPEAT
+	}
+	t.glctx.BindTexture(gl.TEXTURE_2D, t.texture)
+	t.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, wrap)
+	t.glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, wrap)
+	t.glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
+}
+
+func (r *glRenderer) drawTexturedTriangles(buffer rendererBuffer, texture rendererTexture, mvp mgl32.Mat4) {
+	b := buffer.(*glBuffer)
+	p := &r.textured
+
+	r.glctx.UseProgram(p.program)
+	r.glctx.UniformMatrix4fv(p.mvp, mvp[:])
+	r.glctx.BindTexture(gl.TEXTURE_2D, texture.(*glTexture).texture)
+
+	const stride = 4 * texturedCoordsPerVertex
+	r.glctx.BindBuffer(gl.ARRAY_BUFFER, b.buffer)
+	r.glctx.VertexAttribPointer(p.pos, 2, gl.FLOAT, false, stride, 0)
+	r.glctx.EnableVertexAttribArray(p.pos)
+	r.glctx.VertexAttribPointer(p.texCoord, 2, gl.FLOAT, false, stride, 4*2)
+	r.glctx.EnableVertexAttribArray(p.texCoord)
+	r.glctx.VertexAttribPointer(p.color, 4, gl.FLOAT, false, stride, 4*4)
+	r.glctx.EnableVertexAttribArray(p.color)
+
+	r.glctx.DrawArrays(gl.TRIANGLES, 0, b.length/texturedCoordsPerVertex)
+	r.glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
+}
@@ -0,0 +1,306 @@
+package ui
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// NewSoftwareGraphics returns a Graphics that rasterizes on the CPU into dst,
+// whose bounds cover a drawing area of the given size.  It needs no GPU or GL
+// driver, and its output matches that of the GL renderer:  pixel centers are
+// sampled with the top-left fill rule, colors are interpolated across each
+// triangle, and alpha blending leaves dst in premultiplied form.
+func NewSoftwareGraphics(dst *image.RGBA, size Size) *Graphics {
+	g := newGraphicsWithRenderer(&softwareRenderer{dst: dst})
+	g.Size(size)
+	return g
+}
+
+type softwareRenderer struct {
+	dst *image.RGBA
+}
+
+func (r *softwareRenderer) release() {}
+
+func (r *softwareRenderer) clear(c Color) {
+	// Like glClearColor, c is stored as is rather than premultiplied.
+	v := [4]uint8{clearChannel(c.R), clearChannel(c.G), clearChannel(c.B), clearChannel(c.A)}
+	pix := r.dst.Pix
+	b := r.dst.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		row := pix[y*r.dst.Stride : y*r.dst.Stride+4*b.Dx()]
+		for i := 0; i < len(row); i += 4 {
+			copy(row[i:i+4], v[:])
+		}
+	}
+}
+
+func clearChannel(x float64) uint8 {
+	return uint8(math.Round(255 * math.Max(0, math.Min(1, x))))
+}
+
+func (r *softwareRenderer) framebufferSize() (width, height int) {
+	b := r.dst.Bounds()
+	return b.Dx(), b.Dy()
+}
+
+func (r *softwareRenderer) readPixels() (*image.RGBA, error) {
+	b := r.dst.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Rect, r.dst, b.Min, draw.Src)
+	return img, nil
+}
+
+func (r *softwareRenderer) renderOffscreen(width, height int, f func()) (*image.RGBA, error) {
+	dst := r.dst
+	r.dst = image.NewRGBA(image.Rect(0, 0, width, height))
+	defer func() { r.dst = dst }()
+	f()
+	return r.dst, nil
+}
+
+type softwareBuffer []float32
+
+func (r *softwareRenderer) newBuffer(data []float32) rendererBuffer {
+	return softwareBuffer(data)
+}
+
+func (b softwareBuffer) release() {}
+
+// softwareTexture holds texels that are not premultiplied, like a GL
+// texture uploaded from an image.NRGBA.
+type softwareTexture struct {
+	img    *image.NRGBA
+	filter TextureFilter
+	wrap   TextureWrap
+}
+
+func (r *softwareRenderer) newTexture(width, height int) rendererTexture {
+	return &softwareTexture{img: image.NewNRGBA(image.Rect(0, 0, width, height))}
+}
+
+func (t *softwareTexture) release() {}
+
+func (t *softwareTexture) setFilter(f TextureFilter) { t.filter = f }
+func (t *softwareTexture) setWrap(w TextureWrap)      { t.wrap = w }
+
+func (t *softwareTexture) upload(x, y int, img *image.NRGBA) {
+	b := img.Bounds()
+	draw.Draw(t.img, b.Sub(b.Min).Add(image.Pt(x, y)), img, b.Min, draw.Src)
+}
+
+// sample returns the texel color at the texture coordinates (u, v),
+// filtered and wrapped as GL does:  LINEAR interpolates between texel
+// centers and NEAREST takes the texel containing the point.
+func (t *softwareTexture) sample(u, v float64) [4]float64 {
+	b := t.img.Bounds()
+	x := u * float64(b.Dx())
+	y := v * float64(b.Dy())
+	texel := func(x, y int) [4]float64 {
+		x = t.wrapCoord(x, b.Dx())
+		y = t.wrapCoord(y, b.Dy())
+		i := t.img.PixOffset(b.Min.X+x, b.Min.Y+y)
+		p := t.img.Pix[i : i+4 : i+4]
+		return [4]float64{float64(p[0]) / 255, float64(p[1]) / 255, float64(p[2]) / 255, float64(p[3]) / 255}
+	}
+	if t.filter == TextureFilterNearest {
+		return texel(int(math.Floor(x)), int(math.Floor(y)))
+	}
+
+	x0, y0 := math.Floor(x-.5), math.Floor(y-.5)
+	fx, fy := x-.5-x0, y-.5-y0
+	ix, iy := int(x0), int(y0)
+	c00, c10 := texel(ix, iy), texel(ix+1, iy)
+	c01, c11 := texel(ix, iy+1), texel(ix+1, iy+1)
+	var c [4]float64
+	for k := range c {
+		c[k] = (c00[k]*(1-fx)+c10[k]*fx)*(1-fy) + (c01[k]*(1-fx)+c11[k]*fx)*fy
+	}
+	return c
+}
+
+// wrapCoord maps a texel coordinate into [0, n) by the wrap mode.
+func (t *softwareTexture) wrapCoord(x, n int) int {
+	switch t.wrap {
+	case TextureWrapRepeat:
+		x %= n
+		if x < 0 {
+			x += n
+		}
+		return x
+	case TextureWrapMirror:
+		x %= 2 * n
+		if x < 0 {
+			x += 2 * n
+		}
+		if x >= n {
+			x = 2*n - 1 - x
+		}
+		return x
+	}
+	return clampInt(x, 0, n-1)
+}
+
+func clampInt(x, min, max int) int {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
+
+// softwareVertex is a vertex in image coordinates.
+type softwareVertex struct {
+	x, y  float64
+	invW  float64
+	color [4]float64
+	// u and v are texture coordinates.
+	u, v float64
+}
+
+func (r *softwareRenderer) drawTriangles(buffer rendererBuffer, mvp mgl32.Mat4) {
+	r.draw(buffer.(softwareBuffer), coordsPerVertex, nil, mvp)
+}
+
+func (r *softwareRenderer) drawTexturedTriangles(buffer rendererBuffer, texture rendererTexture, mvp mgl32.Mat4) {
+	r.draw(buffer.(softwareBuffer), texturedCoordsPerVertex, texture.(*softwareTexture), mvp)
+}
+
+// draw draws the triangles in data, whose vertices are stride floats long
+// and have texture coordinates if tex is not nil.
+func (r *softwareRenderer) draw(data softwareBuffer, stride int, tex *softwareTexture, mvp mgl32.Mat4) {
+	b := r.dst.Bounds()
+	width, height := float64(b.Dx()), float64(b.Dy())
+
+	n := 3 * stride
+triangles:
+	for i := 0; i+n <= len(data); i += n {
+		var vs [3]softwareVertex
+		for j := range vs {
+			d := data[i+j*stride:]
+			clip := mvp.Mul4x1(mgl32.Vec4{d[0], d[1], 0, 1})
+			w := float64(clip[3])
+			if w <= 0 {
+				// Triangles crossing the camera plane would need clipping; they don't occur in 2D drawing.
+				continue triangles
+			}
+			vs[j] = softwareVertex{
+				x:    snapSubpixel((float64(clip[0])/w + 1) / 2 * width),
+				y:    snapSubpixel((1 - float64(clip[1])/w) / 2 * height),
+				invW: 1 / w,
+			}
+			if tex != nil {
+				vs[j].u, vs[j].v = float64(d[2]), float64(d[3])
+				d = d[2:]
+			}
+			vs[j].color = [4]float64{float64(d[2]), float64(d[3]), float64(d[4]), float64(d[5])}
+		}
+		r.rasterize(vs, tex)
+	}
+}
+
+// snapSubpixel rounds a coordinate to the subpixel grid, as GL
+// implementations do.  It also makes the edge functions exact, so that pixels
+// lying exactly on an edge are assigned consistently by the fill rule.
+func snapSubpixel(x float64) float64 {
+	const subpixels = 256
+	return math.Round(x*subpixels) / subpixels
+}
+
+func edgeFunction(a, b softwareVertex, x, y float64) float64 {
+	return (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+}
+
+// isTopLeft reports whether the edge from a to b is a top or left edge of a
+// triangle with positive area, in image coordinates (y pointing down).
+func isTopLeft(a, b softwareVertex) bool {
+	dx, dy := b.x-a.x, b.y-a.y
+	return dy < 0 || dy == 0 && dx > 0
+}
+
+func (r *softwareRenderer) rasterize(vs [3]softwareVertex, tex *softwareTexture) {
+	area := edgeFunction(vs[0], vs[1], vs[2].x, vs[2].y)
+	if area == 0 || math.IsNaN(area) {
+		return
+	}
+	if area < 0 {
+		vs[1], vs[2] = vs[2], vs[1]
+		area = -area
+	}
+
+	b := r.dst.Bounds()
+	minX := math.Min(vs[0].x, math.Min(vs[1].x, vs[2].x))
+	maxX := math.Max(vs[0].x, math.Max(vs[1].x, vs[2].x))
+	minY := math.Min(vs[0].y, math.Min(vs[1].y, vs[2].y))
+	maxY := math.Max(vs[0].y, math.Max(vs[1].y, vs[2].y))
+	x0 := int(math.Max(0, math.Floor(minX-.5)))
+	x1 := int(math.Min(float64(b.Dx()), math.Ceil(maxX+.5)))
+	y0 := int(math.Max(0, math.Floor(minY-.5)))
+	y1 := int(math.Min(float64(b.Dy()), math.Ceil(maxY+.5)))
+
+	// Pixels exactly on an edge belong to the triangle only if the edge is a top or left edge.
+	var topLeft [3]bool
+	for i := range topLeft {
+		topLeft[i] = isTopLeft(vs[(i+1)%3], vs[(i+2)%3])
+	}
+
+	for y := y0; y < y1; y++ {
+		py := float64(y) + .5
+		for x := x0; x < x1; x++ {
+			px := float64(x) + .5
+
+			// l[i] is the barycentric weight of vertex i, from the edge opposite it.
+			var l [3]float64
+			inside := true
+			for i := range l {
+				e := edgeFunction(vs[(i+1)%3], vs[(i+2)%3], px, py)
+				if e < 0 || e == 0 && !topLeft[i] {
+					inside = false
+					break
+				}
+				l[i] = e / area
+			}
+			if !inside {
+				continue
+			}
+
+			// Interpolate colors with perspective correction.
+			invW := l[0]*vs[0].invW + l[1]*vs[1].invW + l[2]*vs[2].invW
+			interpolate := func(a func(v softwareVertex) float64) float64 {
+				return (l[0]*a(vs[0])*vs[0].invW + l[1]*a(vs[1])*vs[1].invW + l[2]*a(vs[2])*vs[2].invW) / invW
+			}
+			var c [4]float64
+			for k := range c {
+				c[k] = interpolate(func(v softwareVertex) float64 { return v.color[k] })
+			}
+			if tex != nil {
+				t := tex.sample(interpolate(func(v softwareVertex) float64 { return v.u }), interpolate(func(v softwareVertex) float64 { return v.v }))
+				for k := range c {
+					c[k] *= t[k]
+				}
+			}
+			r.blend(b.Min.X+x, b.Min.Y+y, c)
+		}
+	}
+}
+
+// blend composites c over the pixel at (x, y) like the GL renderer's blend
+// function:  SRC_ALPHA, ONE_MINUS_SRC_ALPHA for color and ONE,
+// ONE_MINUS_SRC_ALPHA for alpha.
+func (r *softwareRenderer) blend(x, y int, c [4]float64) {
+	for k := range c {
+		c[k] = math.Max(0, math.Min(1, c[k]))
+	}
+	a := c[3]
+	i := r.dst.PixOffset(x, y)
+	p := r.dst.Pix[i : i+4 : i+4]
+	for k := 0; k < 3; k++ {
+		p[k] = uint8(math.Round(255 * (c[k]*a + float64(p[k])/255*(1-a))))
+	}
+	p[3] = uint8(math.Round(255 * (a + float64(p[3])/255*(1-a))))
+}
@@ -0,0 +1,201 @@
+// +build !android,!ios
+
+package ui_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/gordonklaus/ui"
+	"github.com/gordonklaus/ui/uitest"
+)
+
+// drawing is a view drawn by a function.
+type drawing struct {
+	ui.View
+	draw func(gfx *ui.Graphics)
+}
+
+func newDrawing(draw func(gfx *ui.Graphics)) *drawing {
+	d := &drawing{draw: draw}
+	d.View = ui.NewView(d, nil)
+	return d
+}
+
+func (d *drawing) Draw(gfx *ui.Graphics) { d.draw(gfx) }
+
+// render draws with the software renderer at 1 pixel per millimetre.
+func render(t *testing.T, size ui.Size, draw func(gfx *ui.Graphics)) *image.RGBA {
+	img, err := uitest.Render(newDrawing(draw), size, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func near(a, b color.RGBA, tolerance uint8) bool {
+	d := func(x, y uint8) bool {
+		if x < y {
+			x, y = y, x
+		}
+		return x-y <= tolerance
+	}
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+}
+
+// star returns a five-pointed star drawn in one stroke, whose center is
+// wound twice.
+func star(center ui.Position, radius float64) *ui.Path {
+	var p ui.Path
+	for i := 0; i < 5; i++ {
+		a := -math.Pi/2 + float64(i)*4*math.Pi/5
+		q := ui.Position{X: center.X + radius*math.Cos(a), Y: center.Y + radius*math.Sin(a)}
+		if i == 0 {
+			p.MoveTo(q)
+		} else {
+			p.LineTo(q)
+		}
+	}
+	p.Close()
+	return &p
+}
+
+func drawStars(gfx *ui.Graphics) {
+	white := ui.Color{R: 1, G: 1, B: 1, A: 1}
+	gfx.Fill(star(ui.Position{X: 20, Y: 20}, 18), ui.Paint{Color: white, FillRule: ui.FillRuleNonZero})
+	gfx.Fill(star(ui.Position{X: 60, Y: 20}, 18), ui.Paint{Color: white, FillRule: ui.FillRuleEvenOdd})
+}
+
+func TestSoftwareFillRule(t *testing.T) {
+	img := render(t, ui.Size{Width: 80, Height: 40}, drawStars)
+	white, black := color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}
+	for _, test := range []struct {
+		name string
+		x, y int
+		c    color.RGBA
+	}{
+		{"nonzero center", 20, 21, white},
+		{"nonzero point", 20, 5, white},
+		{"even-odd center", 60, 21, black},
+		{"even-odd point", 60, 5, white},
+		{"outside", 40, 20, black},
+	} {
+		if c := img.RGBAAt(test.x, test.y); c != test.c {
+			t.Errorf("%s: pixel (%d, %d) is %v, want %v", test.name, test.x, test.y, c, test.c)
+		}
+	}
+
+	uitest.Golden(t, "fill_rule", newDrawing(drawStars), ui.Size{Width: 80, Height: 40}, &uitest.Options{Resolution: 2})
+}
+
+func drawBlending(gfx *ui.Graphics) {
+	gfx.Fill(rectPath(ui.Rectangle{Max: ui.Position{X: 30, Y: 30}}), ui.Paint{Color: ui.Color{B: 1, A: 1}})
+	gfx.Fill(rectPath(ui.Rectangle{Min: ui.Position{X: 10, Y: 10}, Max: ui.Position{X: 40, Y: 40}}), ui.Paint{Color: ui.Color{R: 1, A: .5}})
+	// Abutting translucent shapes must not blend twice along their shared
+	// edge, which the top-left fill rule guarantees.
+	green := ui.Paint{Color: ui.Color{G: 1, A: .5}}
+	gfx.Fill(rectPath(ui.Rectangle{Min: ui.Position{X: 0, Y: 32}, Max: ui.Position{X: 5.5, Y: 40}}), green)
+	gfx.Fill(rectPath(ui.Rectangle{Min: ui.Position{X: 5.5, Y: 32}, Max: ui.Position{X: 10, Y: 40}}), green)
+}
+
+func TestSoftwareBlending(t *testing.T) {
+	img := render(t, ui.Size{Width: 40, Height: 40}, drawBlending)
+	for _, test := range []struct {
+		name string
+		x, y int
+		c    color.RGBA
+	}{
+		{"opaque", 5, 5, color.RGBA{0, 0, 255, 255}},
+		{"over opaque", 20, 20, color.RGBA{128, 0, 128, 255}},
+		{"over background", 35, 35, color.RGBA{128, 0, 0, 255}},
+		{"left of edge", 4, 35, color.RGBA{0, 128, 0, 255}},
+		{"on edge", 5, 35, color.RGBA{0, 128, 0, 255}},
+		{"right of edge", 6, 35, color.RGBA{0, 128, 0, 255}},
+	} {
+		if c := img.RGBAAt(test.x, test.y); !near(c, test.c, 1) {
+			t.Errorf("%s: pixel (%d, %d) is %v, want %v", test.name, test.x, test.y, c, test.c)
+		}
+	}
+
+	uitest.Golden(t, "blending", newDrawing(drawBlending), ui.Size{Width: 40, Height: 40}, &uitest.Options{Resolution: 2})
+}
+
+// checkerboard returns a 2x2 image of red, green, blue and transparent white
+// texels.
+func checkerboard() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{0, 255, 0, 255})
+	img.SetNRGBA(0, 1, color.NRGBA{0, 0, 255, 255})
+	img.SetNRGBA(1, 1, color.NRGBA{255, 255, 255, 0})
+	return img
+}
+
+// drawTextures draws the checkerboard with nearest and linear filtering,
+// repeated, mirrored and at half opacity.
+func drawTextures(gfx *ui.Graphics) {
+	tex := ui.NewTexture(gfx, checkerboard())
+	defer tex.Release()
+	full := image.Rect(0, 0, 2, 2)
+	tex.SetFilter(ui.TextureFilterNearest)
+	gfx.DrawImage(tex, ui.Rectangle{Max: ui.Position{X: 20, Y: 20}}, full, 1)
+	tex.SetFilter(ui.TextureFilterLinear)
+	gfx.DrawImage(tex, ui.Rectangle{Min: ui.Position{X: 20}, Max: ui.Position{X: 40, Y: 20}}, full, 1)
+	tex.SetFilter(ui.TextureFilterNearest)
+	tex.SetWrap(ui.TextureWrapRepeat)
+	gfx.DrawImage(tex, ui.Rectangle{Min: ui.Position{Y: 20}, Max: ui.Position{X: 20, Y: 40}}, image.Rect(0, 0, 4, 4), 1)
+	tex.SetWrap(ui.TextureWrapMirror)
+	gfx.DrawImage(tex, ui.Rectangle{Min: ui.Position{X: 20, Y: 20}, Max: ui.Position{X: 40, Y: 40}}, image.Rect(0, 0, 4, 4), .5)
+}
+
+func TestSoftwareTextureSampling(t *testing.T) {
+	img := render(t, ui.Size{Width: 40, Height: 40}, drawTextures)
+	red, green, blue, black := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}, color.RGBA{0, 0, 255, 255}, color.RGBA{0, 0, 0, 255}
+	for _, test := range []struct {
+		name string
+		x, y int
+		c    color.RGBA
+	}{
+		{"nearest", 9, 9, red},
+		{"nearest", 10, 9, green},
+		{"nearest", 9, 10, blue},
+		{"nearest transparent", 10, 10, black},
+		// Texel centers are at 25 and 35 horizontally.  Before the first,
+		// the edge is clamped.
+		{"linear clamped", 20, 0, red},
+		{"linear clamped", 24, 4, red},
+		{"linear", 29, 4, color.RGBA{140, 115, 0, 255}},
+		{"linear", 30, 4, color.RGBA{115, 140, 0, 255}},
+		{"repeat", 10, 20, red},
+		{"repeat", 15, 20, green},
+		{"repeat", 5, 35, black},
+		// Mirrored, the columns are red, green, green, red.
+		{"mirror", 24, 20, color.RGBA{128, 0, 0, 255}},
+		{"mirror", 25, 20, color.RGBA{0, 128, 0, 255}},
+		{"mirror", 34, 20, color.RGBA{0, 128, 0, 255}},
+		{"mirror", 35, 20, color.RGBA{128, 0, 0, 255}},
+	} {
+		if c := img.RGBAAt(test.x, test.y); !near(c, test.c, 1) {
+			t.Errorf("%s: pixel (%d, %d) is %v, want %v", test.name, test.x, test.y, c, test.c)
+		}
+	}
+
+	uitest.Golden(t, "texture_sampling", newDrawing(drawTextures), ui.Size{Width: 40, Height: 40}, &uitest.Options{Resolution: 2})
+}
+
+func TestSoftwareFineDashes(t *testing.T) {
+	// The pattern is finer than a pixel, so the line is drawn solid.
+	img := render(t, ui.Size{Width: 40, Height: 10}, func(gfx *ui.Graphics) {
+		var p ui.Path
+		p.MoveTo(ui.Position{X: 2, Y: 5})
+		p.LineTo(ui.Position{X: 38, Y: 5})
+		gfx.Stroke(&p, ui.StrokeStyle{Color: ui.Color{R: 1, G: 1, B: 1, A: 1}, Width: 4, Dashes: []float64{.01, .02}})
+	})
+	for x := 2; x < 38; x++ {
+		if c := img.RGBAAt(x, 5); c != (color.RGBA{255, 255, 255, 255}) {
+			t.Fatalf("pixel (%d, 5) is %v, want white", x, c)
+		}
+	}
+}
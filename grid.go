@@ -0,0 +1,395 @@
+package ui
+
+import "math"
+
+// Grid is a container that places its children in the cells of a grid of
+// rows and columns.  A child may span several rows and columns.  Children
+// that have not been placed fill the free cells in row-major order.
+type Grid struct {
+	View
+
+	columns, rows     []Track
+	columnGap, rowGap float64
+	cells             map[View]GridCell
+	sizePolicySet     bool
+}
+
+// A Track is the sizing rule of a grid row or column.  Rows and columns beyond
+// those set on a Grid are AutoTracks.
+type Track struct {
+	kind  trackKind
+	value float64
+}
+
+type trackKind uint8
+
+const (
+	trackAuto trackKind = iota
+	trackFixed
+	trackFraction
+)
+
+// FixedTrack returns a track of the given size.
+func FixedTrack(size float64) Track { return Track{trackFixed, size} }
+
+// FractionTrack returns a track that takes a share of the space left over by
+// the other tracks, in proportion to fraction.
+func FractionTrack(fraction float64) Track { return Track{trackFraction, fraction} }
+
+// AutoTrack returns a track sized to the preferred sizes of its children.
+func AutoTrack() Track { return Track{} }
+
+// A GridCell locates a child in a Grid and aligns it within its cell.
+type GridCell struct {
+	Row, Column         int
+	RowSpan, ColumnSpan int
+
+	// Horizontal and Vertical align the child within the cell.
+	Horizontal, Vertical Align
+}
+
+func NewGrid(parent View) *Grid {
+	g := &Grid{cells: map[View]GridCell{}}
+	g.View = NewView(g, parent)
+	return g
+}
+
+func (g *Grid) Columns() []Track { return g.columns }
+func (g *Grid) SetColumns(tracks ...Track) {
+	g.columns = tracks
+	g.InvalidateLayout()
+}
+
+func (g *Grid) Rows() []Track { return g.rows }
+func (g *Grid) SetRows(tracks ...Track) {
+	g.rows = tracks
+	g.InvalidateLayout()
+}
+
+// Gap returns the space between adjacent columns and between adjacent rows.
+func (g *Grid) Gap() (column, row float64) { return g.columnGap, g.rowGap }
+func (g *Grid) SetGap(column, row float64) {
+	g.columnGap = column
+	g.rowGap = row
+	g.InvalidateLayout()
+}
+
+// Cell returns the cell of child and whether it has been placed.
+func (g *Grid) Cell(child View) (GridCell, bool) {
+	c, ok := g.cells[child]
+	return c, ok
+}
+
+// SetCell places child in a cell.  Negative rows and columns are treated as 0
+// and spans less than 1 as 1.
+func (g *Grid) SetCell(child View, c GridCell) {
+	if c.Row < 0 {
+		c.Row = 0
+	}
+	if c.Column < 0 {
+		c.Column = 0
+	}
+	if c.RowSpan < 1 {
+		c.RowSpan = 1
+	}
+	if c.ColumnSpan < 1 {
+		c.ColumnSpan = 1
+	}
+	g.cells[child] = c
+	g.InvalidateLayout()
+}
+
+// Place places child at the given row and column, spanning the given number
+// of rows and columns and stretched to fill them.
+func (g *Grid) Place(child View, row, column, rowSpan, columnSpan int) {
+	g.SetCell(child, GridCell{
+		Row:        row,
+		Column:     column,
+		RowSpan:    rowSpan,
+		ColumnSpan: columnSpan,
+	})
+}
+
+type gridItem struct {
+	view   View
+	cell   GridCell
+	policy SizePolicy
+}
+
+// items returns the children with their cells, auto-placing unplaced ones.
+func (g *Grid) items() []gridItem {
+	children := g.view().children
+	isChild := map[View]bool{}
+	for _, c := range children {
+		isChild[c] = true
+	}
+	for c := range g.cells {
+		if !isChild[c] {
+			delete(g.cells, c)
+		}
+	}
+
+	columns := len(g.columns)
+	occupied := map[[2]int]bool{}
+	for _, c := range g.cells {
+		columns = maxInt(columns, c.Column+c.ColumnSpan)
+		for r := c.Row; r < c.Row+c.RowSpan; r++ {
+			for col := c.Column; col < c.Column+c.ColumnSpan; col++ {
+				occupied[[2]int{r, col}] = true
+			}
+		}
+	}
+	if columns == 0 {
+		columns = 1
+	}
+
+	items := make([]gridItem, len(children))
+	next := 0
+	for i, child := range children {
+		cell, ok := g.cells[child]
+		if !ok {
+			for occupied[[2]int{next / columns, next % columns}] {
+				next++
+			}
+			cell = GridCell{Row: next / columns, Column: next % columns, RowSpan: 1, ColumnSpan: 1}
+			next++
+		}
+		items[i] = gridItem{child, cell, child.SizePolicy()}
+	}
+	return items
+}
+
+// gridSpan is the extent of an item along one axis of a Grid.
+type gridSpan struct {
+	start, span int
+	min, pref   float64
+}
+
+func (g *Grid) spans(items []gridItem, horizontal bool) []gridSpan {
+	spans := make([]gridSpan, len(items))
+	for i, it := range items {
+		pref := it.policy.Clamp(it.policy.Preferred)
+		if horizontal {
+			spans[i] = gridSpan{it.cell.Column, it.cell.ColumnSpan, it.policy.Min.Width, pref.Width}
+		} else {
+			spans[i] = gridSpan{it.cell.Row, it.cell.RowSpan, it.policy.Min.Height, pref.Height}
+		}
+	}
+	return spans
+}
+
+// trackCount returns the number of tracks needed along an axis.
+func trackCount(tracks []Track, spans []gridSpan) int {
+	n := len(tracks)
+	for _, s := range spans {
+		n = maxInt(n, s.start+s.span)
+	}
+	return n
+}
+
+func trackAt(tracks []Track, i int) Track {
+	if i < len(tracks) {
+		return tracks[i]
+	}
+	return AutoTrack()
+}
+
+// contentSizes returns the size each non-fixed track needs for the min or
+// preferred sizes of its items, ignoring the space available.
+func contentSizes(tracks []Track, spans []gridSpan, n int, gap float64, preferred bool) []float64 {
+	sizes := make([]float64, n)
+	for i := range sizes {
+		if t := trackAt(tracks, i); t.kind == trackFixed {
+			sizes[i] = t.value
+		}
+	}
+	need := func(s gridSpan) float64 {
+		if preferred {
+			return s.pref
+		}
+		return s.min
+	}
+
+	for _, s := range spans {
+		if s.span == 1 && trackAt(tracks, s.start).kind != trackFixed {
+			sizes[s.start] = math.Max(sizes[s.start], need(s))
+		}
+	}
+
+	// Spanning items grow the non-fixed tracks they cover equally.
+	for _, s := range spans {
+		if s.span == 1 {
+			continue
+		}
+		have := gap * float64(s.span-1)
+		var flexible []int
+		for i := s.start; i < s.start+s.span; i++ {
+			have += sizes[i]
+			if trackAt(tracks, i).kind != trackFixed {
+				flexible = append(flexible, i)
+			}
+		}
+		if extra := need(s) - have; extra > 0 && len(flexible) > 0 {
+			for _, i := range flexible {
+				sizes[i] += extra / float64(len(flexible))
+			}
+		}
+	}
+
+	// Fraction tracks keep their proportions.
+	var unit float64
+	for i, size := range sizes {
+		if t := trackAt(tracks, i); t.kind == trackFraction && t.value > 0 {
+			unit = math.Max(unit, size/t.value)
+		}
+	}
+	for i := range sizes {
+		if t := trackAt(tracks, i); t.kind == trackFraction {
+			sizes[i] = unit * t.value
+		}
+	}
+	return sizes
+}
+
+// resolveTracks sizes the tracks of one axis to fit the available space.
+// Fixed tracks get their sizes and auto tracks their preferred sizes, shrunk
+// toward their minimum sizes if there is too little space, and fraction
+// tracks share what is left.
+func resolveTracks(tracks []Track, spans []gridSpan, n int, gap, available float64) []float64 {
+	sizes := contentSizes(tracks, spans, n, gap, true)
+
+	remaining := available - gap*float64(maxInt(n-1, 0))
+	var fractions float64
+	for i := range sizes {
+		if t := trackAt(tracks, i); t.kind == trackFraction {
+			fractions += t.value
+		} else {
+			remaining -= sizes[i]
+		}
+	}
+	if remaining < 0 {
+		// Auto tracks give up space in proportion to how far they are above
+		// their minimum sizes.
+		mins := contentSizes(tracks, spans, n, gap, false)
+		var slack float64
+		for i := range sizes {
+			if trackAt(tracks, i).kind == trackAuto {
+				slack += sizes[i] - mins[i]
+			}
+		}
+		if slack > 0 {
+			shrink := math.Min(-remaining, slack)
+			for i := range sizes {
+				if trackAt(tracks, i).kind == trackAuto {
+					sizes[i] -= (sizes[i] - mins[i]) * shrink / slack
+				}
+			}
+			remaining += shrink
+		}
+	}
+	for i := range sizes {
+		if t := trackAt(tracks, i); t.kind == trackFraction {
+			sizes[i] = 0
+			if fractions > 0 && remaining > 0 {
+				sizes[i] = remaining * t.value / fractions
+			}
+		}
+	}
+	return sizes
+}
+
+func sumTracks(sizes []float64, gap float64) float64 {
+	total := gap * float64(maxInt(len(sizes)-1, 0))
+	for _, s := range sizes {
+		total += s
+	}
+	return total
+}
+
+// SizePolicy returns the policy set with SetSizePolicy or, if there is none,
+// a policy computed from the tracks and the children's policies.
+func (g *Grid) SizePolicy() SizePolicy {
+	if g.sizePolicySet {
+		return g.View.SizePolicy()
+	}
+
+	items := g.items()
+	cols := g.spans(items, true)
+	rows := g.spans(items, false)
+	nc := trackCount(g.columns, cols)
+	nr := trackCount(g.rows, rows)
+
+	return SizePolicy{
+		Min: Size{
+			sumTracks(contentSizes(g.columns, cols, nc, g.columnGap, false), g.columnGap),
+			sumTracks(contentSizes(g.rows, rows, nr, g.rowGap, false), g.rowGap),
+		},
+		Preferred: Size{
+			sumTracks(contentSizes(g.columns, cols, nc, g.columnGap, true), g.columnGap),
+			sumTracks(contentSizes(g.rows, rows, nr, g.rowGap, true), g.rowGap),
+		},
+	}
+}
+
+func (g *Grid) SetSizePolicy(p SizePolicy) {
+	g.sizePolicySet = true
+	g.View.SetSizePolicy(p)
+}
+
+func (g *Grid) Layout() {
+	items := g.items()
+	cols := g.spans(items, true)
+	rows := g.spans(items, false)
+	r := g.Rect()
+
+	colSizes := resolveTracks(g.columns, cols, trackCount(g.columns, cols), g.columnGap, r.Width())
+	rowSizes := resolveTracks(g.rows, rows, trackCount(g.rows, rows), g.rowGap, r.Height())
+	colPos := trackPositions(colSizes, g.columnGap)
+	rowPos := trackPositions(rowSizes, g.rowGap)
+
+	for i, it := range items {
+		x, width := cellExtent(colPos, colSizes, cols[i], g.columnGap)
+		y, height := cellExtent(rowPos, rowSizes, rows[i], g.rowGap)
+		x, width = alignInCell(x, width, cols[i].pref, it.policy.Min.Width, it.policy.Max.Width, it.cell.Horizontal)
+		y, height = alignInCell(y, height, rows[i].pref, it.policy.Min.Height, it.policy.Max.Height, it.cell.Vertical)
+		it.view.Move(r.Min.Add(Size{x, y}))
+		it.view.Resize(Size{width, height})
+	}
+}
+
+func trackPositions(sizes []float64, gap float64) []float64 {
+	pos := make([]float64, len(sizes))
+	var p float64
+	for i, s := range sizes {
+		pos[i] = p
+		p += s + gap
+	}
+	return pos
+}
+
+func cellExtent(pos, sizes []float64, s gridSpan, gap float64) (start, size float64) {
+	start = pos[s.start]
+	size = gap * float64(s.span-1)
+	for i := s.start; i < s.start+s.span; i++ {
+		size += sizes[i]
+	}
+	return start, size
+}
+
+// alignInCell returns the position and size of an item along one axis of a
+// cell.  Unless stretched, the item takes its preferred size or less.
+func alignInCell(start, cell, pref, min, max float64, a Align) (float64, float64) {
+	size := cell
+	if a != AlignStretch {
+		size = math.Min(cell, pref)
+	}
+	size = clampDimension(size, min, max)
+	return start + a.offset(cell-size), size
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
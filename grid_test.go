@@ -0,0 +1,141 @@
+package ui_test
+
+import (
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// A gridChild is a leaf placed in cell, or auto-placed if cell is nil.
+type gridChild struct {
+	policy ui.SizePolicy
+	cell   *ui.GridCell
+}
+
+func TestGridLayout(t *testing.T) {
+	minPref := func(min, pref float64) ui.SizePolicy {
+		return ui.SizePolicy{Min: ui.Size{Width: min}, Preferred: ui.Size{Width: pref, Height: 10}}
+	}
+	for _, test := range []struct {
+		name          string
+		columns, rows []ui.Track
+		columnGap     float64
+		rowGap        float64
+		size          ui.Size
+		children      []gridChild
+		want          []ui.Rectangle
+	}{
+		{
+			name:    "fixed, auto and fraction tracks",
+			columns: []ui.Track{ui.FixedTrack(20), ui.AutoTrack(), ui.FractionTrack(1), ui.FractionTrack(3)},
+			size:    ui.Size{Width: 100, Height: 100},
+			children: []gridChild{
+				{pref(10, 10), nil},
+				{pref(10, 10), nil},
+				{pref(10, 10), nil},
+				{pref(10, 10), nil},
+			},
+			want: []ui.Rectangle{rect(0, 0, 20, 10), rect(20, 0, 30, 10), rect(30, 0, 47.5, 10), rect(47.5, 0, 100, 10)},
+		},
+		{
+			name:      "spans, gaps and auto-placement around placed children",
+			columns:   []ui.Track{ui.FractionTrack(1), ui.FractionTrack(1), ui.FractionTrack(1)},
+			columnGap: 10,
+			rowGap:    10,
+			size:      ui.Size{Width: 110, Height: 100},
+			children: []gridChild{
+				{pref(10, 10), &ui.GridCell{Row: 0, Column: 1, RowSpan: 1, ColumnSpan: 2}},
+				{pref(10, 10), nil},
+				{pref(10, 10), nil},
+				{pref(10, 10), nil},
+			},
+			want: []ui.Rectangle{rect(40, 0, 110, 10), rect(0, 0, 30, 10), rect(0, 20, 30, 30), rect(40, 20, 70, 30)},
+		},
+		{
+			name: "a spanning child grows the auto tracks it covers",
+			size: ui.Size{Width: 100, Height: 100},
+			children: []gridChild{
+				{pref(10, 10), &ui.GridCell{Row: 0, Column: 0, RowSpan: 1, ColumnSpan: 1}},
+				{pref(10, 10), &ui.GridCell{Row: 0, Column: 1, RowSpan: 1, ColumnSpan: 1}},
+				{pref(40, 30), &ui.GridCell{Row: 1, Column: 0, RowSpan: 1, ColumnSpan: 2}},
+			},
+			want: []ui.Rectangle{rect(0, 0, 20, 10), rect(20, 0, 40, 10), rect(0, 10, 40, 40)},
+		},
+		{
+			name:    "auto tracks shrink toward their minimum sizes",
+			columns: []ui.Track{ui.AutoTrack(), ui.AutoTrack()},
+			size:    ui.Size{Width: 50, Height: 100},
+			children: []gridChild{
+				{minPref(10, 40), nil},
+				{minPref(30, 40), nil},
+			},
+			want: []ui.Rectangle{rect(0, 0, 17.5, 10), rect(17.5, 0, 50, 10)},
+		},
+		{
+			name:    "auto tracks shrink no further than their minimum sizes",
+			columns: []ui.Track{ui.AutoTrack(), ui.AutoTrack()},
+			size:    ui.Size{Width: 20, Height: 100},
+			children: []gridChild{
+				{minPref(10, 40), nil},
+				{minPref(30, 40), nil},
+			},
+			want: []ui.Rectangle{rect(0, 0, 10, 10), rect(10, 0, 40, 10)},
+		},
+		{
+			name:    "fixed tracks keep their sizes",
+			columns: []ui.Track{ui.FixedTrack(10), ui.AutoTrack()},
+			size:    ui.Size{Width: 30, Height: 100},
+			children: []gridChild{
+				{pref(10, 10), nil},
+				{minPref(5, 40), nil},
+			},
+			want: []ui.Rectangle{rect(0, 0, 10, 10), rect(10, 0, 30, 10)},
+		},
+		{
+			name:    "alignment in the cell",
+			columns: []ui.Track{ui.FixedTrack(30)},
+			rows:    []ui.Track{ui.FixedTrack(30)},
+			size:    ui.Size{Width: 100, Height: 100},
+			children: []gridChild{
+				{pref(10, 10), &ui.GridCell{RowSpan: 1, ColumnSpan: 1, Horizontal: ui.AlignCenter, Vertical: ui.AlignEnd}},
+			},
+			want: []ui.Rectangle{rect(10, 20, 20, 30)},
+		},
+	} {
+		g := ui.NewGrid(nil)
+		g.SetColumns(test.columns...)
+		g.SetRows(test.rows...)
+		g.SetGap(test.columnGap, test.rowGap)
+		var children []ui.View
+		for _, c := range test.children {
+			l := newLeaf(g, c.policy)
+			if c.cell != nil {
+				g.SetCell(l, *c.cell)
+			}
+			children = append(children, l)
+		}
+		g.Resize(test.size)
+		g.Layout()
+		if got := childRects(children); !equalRects(got, test.want) {
+			t.Errorf("%s: got child rects %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGridSetCell(t *testing.T) {
+	g := ui.NewGrid(nil)
+	l := newLeaf(g, pref(10, 10))
+	if _, ok := g.Cell(l); ok {
+		t.Error("an unplaced child has a cell")
+	}
+	g.SetCell(l, ui.GridCell{Row: -2, Column: -1, RowSpan: 0, ColumnSpan: -3})
+	want := ui.GridCell{RowSpan: 1, ColumnSpan: 1}
+	if c, ok := g.Cell(l); !ok || c != want {
+		t.Errorf("got cell %+v, want %+v", c, want)
+	}
+	g.Resize(ui.Size{Width: 100, Height: 100})
+	g.Layout()
+	if got := childRects([]ui.View{l}); !equalRects(got, []ui.Rectangle{rect(0, 0, 10, 10)}) {
+		t.Errorf("got rect %v, want the first cell", got)
+	}
+}
@@ -0,0 +1,258 @@
+// +build !android,!ios
+
+package ui
+
+import (
+	"image"
+	"math"
+	"runtime"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+)
+
+// DefaultHeadlessResolution is the initial resolution of a HeadlessWindow,
+// in pixels per millimetre.  It corresponds to 96 pixels per inch.
+const DefaultHeadlessResolution = 96 / 25.4
+
+// A HeadlessWindow is a Window that renders to an offscreen framebuffer
+// without a display server.  Nothing happens on its own:  events are injected
+// and frames are drawn explicitly, which makes it suitable for tests.
+type HeadlessWindow struct {
+	*windowBase
+	drawEvents chan drawEvent
+
+	size       Size
+	resolution float64
+
+	software bool
+	img      *image.RGBA
+
+	ctx         *offscreenContext
+	framebuffer uint32
+	colorbuffer uint32
+	fbSize      [2]int32
+	closed      bool
+}
+
+// NewHeadlessWindow returns a HeadlessWindow of the given size containing v.
+// It renders with GL if an offscreen context is available and falls back to
+// the software renderer otherwise.
+func NewHeadlessWindow(size Size, v View) (*HeadlessWindow, error) {
+	return newHeadlessWindow(size, v, false)
+}
+
+// NewSoftwareHeadlessWindow returns a HeadlessWindow of the given size
+// containing v that always uses the software renderer, so that its output is
+// the same on every machine.
+func NewSoftwareHeadlessWindow(size Size, v View) (*HeadlessWindow, error) {
+	return newHeadlessWindow(size, v, true)
+}
+
+func newHeadlessWindow(size Size, v View, software bool) (*HeadlessWindow, error) {
+	w := &HeadlessWindow{
+		drawEvents: make(chan drawEvent, 1),
+		resolution: DefaultHeadlessResolution,
+		software:   software,
+	}
+	w.windowBase = newWindowBase(w, v)
+	v.SetParent(w)
+
+	errc := make(chan error)
+	go w.loop(errc)
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	w.Do(func() { w.Resize(size) })
+	return w, nil
+}
+
+func (w *HeadlessWindow) loop(errc chan<- error) {
+	if !w.software {
+		runtime.LockOSThread()
+
+		ctx, err := newOffscreenContext()
+		if err == nil {
+			w.ctx = ctx
+			defer w.ctx.release()
+			err = gl.Init()
+		}
+		if err != nil {
+			w.software = true
+		}
+	}
+
+	if w.software {
+		w.img = image.NewRGBA(image.Rectangle{})
+		w.gfx = newGraphicsWithRenderer(&softwareRenderer{dst: w.img})
+		errc <- nil
+
+		for !w.closed {
+			f := <-w.do
+			f()
+		}
+		return
+	}
+
+	var va uint32
+	gl.GenVertexArrays(1, &va)
+	gl.BindVertexArray(va)
+
+	gl.GenFramebuffers(1, &w.framebuffer)
+	gl.GenRenderbuffers(1, &w.colorbuffer)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, w.framebuffer)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, w.colorbuffer)
+	defer gl.DeleteFramebuffers(1, &w.framebuffer)
+	defer gl.DeleteRenderbuffers(1, &w.colorbuffer)
+
+	w.gfx = newGraphics(glContext{})
+	defer w.gfx.release()
+
+	errc <- nil
+
+	for !w.closed {
+		f := <-w.do
+		f()
+	}
+}
+
+// Close releases the window's offscreen resources.  The window must not be
+// used afterwards.
+func (w *HeadlessWindow) Close() {
+	w.Do(func() { w.closed = true })
+}
+
+func (w *HeadlessWindow) Resize(s Size) {
+	w.size = s
+	w.View.Resize(s)
+	w.gfx.Size(s)
+	w.Layout()
+	w.Redraw()
+}
+
+// SetResolution sets the resolution of the offscreen framebuffer, in pixels
+// per millimetre.
+func (w *HeadlessWindow) SetResolution(pixelsPerMM float64) {
+	w.resolution = pixelsPerMM
+	w.Redraw()
+}
+
+func (w *HeadlessWindow) Redraw() {
+	select {
+	case w.drawEvents <- drawEvent{}:
+	default:
+	}
+}
+
+// Frame draws the window if a redraw is pending and reports whether it drew.
+func (w *HeadlessWindow) Frame() bool {
+	drawn := false
+	w.Do(func() {
+		select {
+		case <-w.drawEvents:
+		default:
+			return
+		}
+		w.updateFramebuffer()
+		w.windowBase.draw()
+		if !w.software {
+			gl.Finish()
+		}
+		drawn = true
+	})
+	return drawn
+}
+
+func (w *HeadlessWindow) Capture() (*image.RGBA, error) {
+	w.Do(w.updateFramebuffer)
+	return w.windowBase.Capture()
+}
+
+func (w *HeadlessWindow) CaptureView(v View) (*image.RGBA, error) {
+	w.Do(w.updateFramebuffer)
+	return w.windowBase.CaptureView(v)
+}
+
+// updateFramebuffer sizes the offscreen framebuffer to the window.
+func (w *HeadlessWindow) updateFramebuffer() {
+	width := int32(math.Max(1, math.Round(w.size.Width*w.resolution)))
+	height := int32(math.Max(1, math.Round(w.size.Height*w.resolution)))
+	if w.fbSize == [2]int32{width, height} {
+		return
+	}
+	w.fbSize = [2]int32{width, height}
+
+	if w.software {
+		w.img = image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+		w.gfx.renderer.(*softwareRenderer).dst = w.img
+		return
+	}
+
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.RGBA8, width, height)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, w.colorbuffer)
+	gl.Viewport(0, 0, width, height)
+}
+
+// InjectPointerDown delivers a pointer down event at a position in the window's coordinates.
+func (w *HeadlessWindow) InjectPointerDown(p Pointer) {
+	w.Do(func() { w.windowBase.pointerDown(p) })
+}
+
+// InjectPointerMove delivers a pointer move event at a position in the window's coordinates.
+func (w *HeadlessWindow) InjectPointerMove(p Pointer) {
+	w.Do(func() { w.windowBase.pointerMove(p) })
+}
+
+// InjectPointerUp delivers a pointer up event at a position in the window's coordinates.
+func (w *HeadlessWindow) InjectPointerUp(p Pointer) {
+	w.Do(func() { w.windowBase.pointerUp(p) })
+}
+
+// InjectPointerLeave tells the window that a pointer has left it.
+func (w *HeadlessWindow) InjectPointerLeave(p Pointer) {
+	w.Do(func() { w.windowBase.pointerLeave(p) })
+}
+
+// InjectPointerCancel tells the view capturing a pointer that the system has
+// taken it away.
+func (w *HeadlessWindow) InjectPointerCancel(p Pointer) {
+	w.Do(func() { w.windowBase.pointerCancel(p) })
+}
+
+// InjectScroll delivers a scroll event with its position and delta in the
+// window's coordinates, or its delta in lines if it is not precise.
+func (w *HeadlessWindow) InjectScroll(s Scroll) {
+	w.Do(func() { w.windowBase.scroll(s) })
+}
+
+// InjectKeyDown delivers a key press.
+func (w *HeadlessWindow) InjectKeyDown(k Key) {
+	k.Direction = KeyPress
+	w.Do(func() { w.windowBase.keyDown(k) })
+}
+
+// InjectKeyUp delivers a key release.
+func (w *HeadlessWindow) InjectKeyUp(k Key) {
+	k.Direction = KeyRelease
+	w.Do(func() { w.windowBase.keyUp(k) })
+}
+
+// InjectText delivers committed text, as from an input method, to the
+// focused TextInput.
+func (w *HeadlessWindow) InjectText(text string) {
+	w.Do(func() { w.windowBase.insertText(text) })
+}
+
+// InjectComposition delivers an in-progress composition to the focused
+// TextInput.
+func (w *HeadlessWindow) InjectComposition(c Composition) {
+	w.Do(func() { w.windowBase.setComposition(c) })
+}
+
+// TextInputRect returns where an input method would place its candidate
+// window: the focused TextInput's composition rectangle in window
+// coordinates.  It reports false if no TextInput has the focus.
+func (w *HeadlessWindow) TextInputRect() (r Rectangle, ok bool) {
+	w.Do(func() { r, ok = w.windowBase.textInputRect() })
+	return r, ok
+}
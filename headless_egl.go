@@ -0,0 +1,92 @@
+// +build linux
+// +build !android
+
+package ui
+
+/*
+#cgo pkg-config: egl
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+#include <stdlib.h>
+
+// createContext creates an OpenGL 3.2 core context without any surface and
+// makes it current.  It prefers Mesa's surfaceless platform, which needs
+// neither a display server nor a GPU.
+static EGLint createContext(EGLDisplay *dpy, EGLContext *ctx) {
+	static const EGLint configAttr[] = {
+		EGL_SURFACE_TYPE,    EGL_PBUFFER_BIT,
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+		EGL_RED_SIZE,        8,
+		EGL_GREEN_SIZE,      8,
+		EGL_BLUE_SIZE,       8,
+		EGL_ALPHA_SIZE,      8,
+		EGL_NONE
+	};
+	static const EGLint ctxAttr[] = {
+		EGL_CONTEXT_MAJOR_VERSION,       3,
+		EGL_CONTEXT_MINOR_VERSION,       2,
+		EGL_CONTEXT_OPENGL_PROFILE_MASK, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+		EGL_NONE
+	};
+
+	*dpy = EGL_NO_DISPLAY;
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (getPlatformDisplay) {
+		*dpy = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+	}
+	if (*dpy == EGL_NO_DISPLAY) {
+		*dpy = eglGetDisplay(EGL_DEFAULT_DISPLAY);
+	}
+	if (*dpy == EGL_NO_DISPLAY || !eglInitialize(*dpy, NULL, NULL)) {
+		return eglGetError();
+	}
+	if (!eglBindAPI(EGL_OPENGL_API)) {
+		return eglGetError();
+	}
+
+	EGLConfig config;
+	EGLint n;
+	if (!eglChooseConfig(*dpy, configAttr, &config, 1, &n)) {
+		return eglGetError();
+	}
+	if (n == 0) {
+		return EGL_BAD_CONFIG;
+	}
+	*ctx = eglCreateContext(*dpy, config, EGL_NO_CONTEXT, ctxAttr);
+	if (*ctx == EGL_NO_CONTEXT) {
+		return eglGetError();
+	}
+	if (!eglMakeCurrent(*dpy, EGL_NO_SURFACE, EGL_NO_SURFACE, *ctx)) {
+		return eglGetError();
+	}
+	return EGL_SUCCESS;
+}
+
+static void destroyContext(EGLDisplay dpy, EGLContext ctx) {
+	eglMakeCurrent(dpy, EGL_NO_SURFACE, EGL_NO_SURFACE, EGL_NO_CONTEXT);
+	eglDestroyContext(dpy, ctx);
+	eglTerminate(dpy);
+}
+*/
+import "C"
+
+import "fmt"
+
+type offscreenContext struct {
+	dpy C.EGLDisplay
+	ctx C.EGLContext
+}
+
+// newOffscreenContext creates a GL context and makes it current on the calling thread.
+func newOffscreenContext() (*offscreenContext, error) {
+	c := &offscreenContext{}
+	if err := C.createContext(&c.dpy, &c.ctx); err != C.EGL_SUCCESS {
+		return nil, fmt.Errorf("error creating offscreen EGL context: 0x%x", int(err))
+	}
+	return c, nil
+}
+
+func (c *offscreenContext) release() {
+	C.destroyContext(c.dpy, c.ctx)
+}
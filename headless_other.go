@@ -0,0 +1,14 @@
+// +build !linux
+// +build !android,!ios
+
+package ui
+
+import "errors"
+
+type offscreenContext struct{}
+
+func newOffscreenContext() (*offscreenContext, error) {
+	return nil, errors.New("headless windows are not supported on this platform")
+}
+
+func (c *offscreenContext) release() {}
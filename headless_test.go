@@ -0,0 +1,400 @@
+// +build !android,!ios
+
+package ui_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// box is a view filled with a color that records the pointer events it
+// receives.
+type box struct {
+	ui.View
+	color ui.Color
+
+	events []string
+	at     []ui.Position
+}
+
+func newBox(c ui.Color, parent ui.View) *box {
+	b := &box{color: c}
+	b.View = ui.NewView(b, parent)
+	return b
+}
+
+func (b *box) Draw(gfx *ui.Graphics) {
+	gfx.Fill(rectPath(b.Rect()), ui.Paint{Color: b.color})
+}
+
+func (b *box) record(event string, p ui.Pointer) bool {
+	b.events = append(b.events, event)
+	b.at = append(b.at, p.Position)
+	return true
+}
+
+func (b *box) PointerDown(p ui.Pointer) bool { return b.record("down", p) }
+func (b *box) PointerMove(p ui.Pointer) bool { return b.record("move", p) }
+func (b *box) PointerUp(p ui.Pointer) bool   { return b.record("up", p) }
+
+func rectPath(r ui.Rectangle) *ui.Path {
+	var p ui.Path
+	p.MoveTo(r.Min)
+	p.LineTo(ui.Position{X: r.Max.X, Y: r.Min.Y})
+	p.LineTo(r.Max)
+	p.LineTo(ui.Position{X: r.Min.X, Y: r.Max.Y})
+	p.Close()
+	return &p
+}
+
+func newHeadless(t *testing.T, size ui.Size, v ui.View) *ui.HeadlessWindow {
+	w, err := ui.NewSoftwareHeadlessWindow(size, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestHeadlessCaptureSize(t *testing.T) {
+	w := newHeadless(t, ui.Size{Width: 10, Height: 5}, newBox(ui.Color{}, nil))
+	defer w.Close()
+
+	img, err := w.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10mm by 5mm at 96 pixels per inch.
+	if s := img.Bounds().Size(); s != image.Pt(38, 19) {
+		t.Errorf("size at the default resolution is %v, want %v", s, image.Pt(38, 19))
+	}
+
+	w.SetResolution(2)
+	if img, err = w.Capture(); err != nil {
+		t.Fatal(err)
+	}
+	if s := img.Bounds().Size(); s != image.Pt(20, 10) {
+		t.Errorf("size at 2px/mm is %v, want %v", s, image.Pt(20, 10))
+	}
+
+	w.Do(func() { w.Resize(ui.Size{Width: 3, Height: 4}) })
+	if img, err = w.Capture(); err != nil {
+		t.Fatal(err)
+	}
+	if s := img.Bounds().Size(); s != image.Pt(6, 8) {
+		t.Errorf("size after resizing is %v, want %v", s, image.Pt(6, 8))
+	}
+}
+
+func TestHeadlessFrame(t *testing.T) {
+	v := newBox(ui.Color{}, nil)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 5}, v)
+	defer w.Close()
+
+	if !w.Frame() {
+		t.Error("a new window did not draw its first frame")
+	}
+	if w.Frame() {
+		t.Error("a window drew without a pending redraw")
+	}
+	v.Redraw()
+	if !w.Frame() {
+		t.Error("a window did not draw after a redraw was requested")
+	}
+}
+
+func TestHeadlessDraw(t *testing.T) {
+	root := newBox(ui.Color{B: 1, A: 1}, nil)
+	child := newBox(ui.Color{R: 1, A: 1}, root)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 5}, root)
+	defer w.Close()
+	w.SetResolution(2)
+	w.Do(func() {
+		child.Move(ui.Position{X: 2, Y: 1})
+		child.Resize(ui.Size{Width: 3, Height: 2})
+	})
+
+	img, err := w.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	for _, test := range []struct {
+		x, y int
+		c    color.RGBA
+	}{
+		{0, 0, blue},
+		{3, 2, blue},
+		{4, 2, red},
+		{9, 5, red},
+		{10, 5, blue},
+		{9, 6, blue},
+		{19, 9, blue},
+	} {
+		if c := img.RGBAAt(test.x, test.y); c != test.c {
+			t.Errorf("pixel (%d, %d) is %v, want %v", test.x, test.y, c, test.c)
+		}
+	}
+
+	img, err = w.CaptureView(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := img.Bounds().Size(); s != image.Pt(6, 4) {
+		t.Errorf("view capture size is %v, want %v", s, image.Pt(6, 4))
+	}
+	if c := img.RGBAAt(0, 0); c != red {
+		t.Errorf("view capture is %v, want %v", c, red)
+	}
+}
+
+func TestHeadlessInjectPointer(t *testing.T) {
+	root := newBox(ui.Color{}, nil)
+	child := newBox(ui.Color{}, root)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 5}, root)
+	defer w.Close()
+	w.Do(func() {
+		child.Move(ui.Position{X: 2, Y: 1})
+		child.Resize(ui.Size{Width: 3, Height: 2})
+	})
+
+	p := ui.Pointer{ID: 1, Type: ui.PointerTypeTouch, Button: ui.PointerButtonTouchContact, Buttons: ui.PointerButtonTouchContact}
+	p.Position = ui.Position{X: 3, Y: 2}
+	w.InjectPointerDown(p)
+	// The child captures the pointer, so it gets moves outside it.
+	p.Position = ui.Position{X: 8, Y: 4}
+	w.InjectPointerMove(p)
+	p.Button, p.Buttons = ui.PointerButtonTouchContact, ui.PointerButtonNone
+	w.InjectPointerUp(p)
+
+	var events []string
+	var at []ui.Position
+	w.Do(func() { events, at = child.events, child.at })
+	wantEvents := []string{"down", "move", "up"}
+	wantAt := []ui.Position{{X: 1, Y: 1}, {X: 6, Y: 3}, {X: 6, Y: 3}}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("child got events %v, want %v", events, wantEvents)
+	}
+	for i := range events {
+		if events[i] != wantEvents[i] || at[i] != wantAt[i] {
+			t.Errorf("event %d is %s at %v, want %s at %v", i, events[i], at[i], wantEvents[i], wantAt[i])
+		}
+	}
+	w.Do(func() { events = root.events })
+	if len(events) != 0 {
+		t.Errorf("root got events %v for its handled child's pointer", events)
+	}
+}
+
+// interceptor is a box that intercepts pointer events of the given types.
+type interceptor struct {
+	*box
+	down, move bool
+}
+
+func newInterceptor(parent ui.View, down, move bool) *interceptor {
+	i := &interceptor{box: &box{}, down: down, move: move}
+	i.View = ui.NewView(i, parent)
+	return i
+}
+
+func (i *interceptor) InterceptPointerDown(p ui.Pointer) bool { return i.down }
+func (i *interceptor) InterceptPointerMove(p ui.Pointer) bool { return i.move }
+func (i *interceptor) PointerCancel(p ui.Pointer)             { i.record("cancel", p) }
+
+// canceler is a box that records PointerCancel.
+type canceler struct{ *box }
+
+func newCanceler(parent ui.View) *canceler {
+	c := &canceler{&box{}}
+	c.View = ui.NewView(c, parent)
+	return c
+}
+
+func (c *canceler) PointerCancel(p ui.Pointer) { c.record("cancel", p) }
+
+func TestInterceptPointer(t *testing.T) {
+	for _, test := range []struct {
+		name                string
+		down, move          bool
+		parentEvents, child []string
+	}{
+		{"none", false, false, nil, []string{"down", "move", "up"}},
+		// The child never sees the pointer, so it isn't canceled.
+		{"down", true, false, []string{"down", "move", "up"}, nil},
+		{"move", false, true, []string{"move", "up"}, []string{"down", "cancel"}},
+	} {
+		root := newBox(ui.Color{}, nil)
+		parent := newInterceptor(root, test.down, test.move)
+		child := newCanceler(parent)
+		w := newHeadless(t, ui.Size{Width: 10, Height: 5}, root)
+		w.Do(func() {
+			parent.Resize(ui.Size{Width: 10, Height: 5})
+			child.Resize(ui.Size{Width: 10, Height: 5})
+		})
+
+		p := ui.Pointer{ID: 1, Type: ui.PointerTypeTouch, Button: ui.PointerButtonTouchContact, Buttons: ui.PointerButtonTouchContact}
+		p.Position = ui.Position{X: 1, Y: 1}
+		w.InjectPointerDown(p)
+		p.Position = ui.Position{X: 2, Y: 1}
+		w.InjectPointerMove(p)
+		p.Buttons = ui.PointerButtonNone
+		w.InjectPointerUp(p)
+
+		var parentEvents, childEvents []string
+		w.Do(func() { parentEvents, childEvents = parent.events, child.events })
+		w.Close()
+		if !equalStrings(parentEvents, test.parentEvents) || !equalStrings(childEvents, test.child) {
+			t.Errorf("%s: parent got %v and child %v, want %v and %v", test.name, parentEvents, childEvents, test.parentEvents, test.child)
+		}
+	}
+}
+
+func TestPointerCapture(t *testing.T) {
+	root := newBox(ui.Color{}, nil)
+	a := newCanceler(root)
+	b := newCanceler(root)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 5}, root)
+	defer w.Close()
+	w.Do(func() {
+		a.Resize(ui.Size{Width: 5, Height: 5})
+		b.Move(ui.Position{X: 5})
+		b.Resize(ui.Size{Width: 5, Height: 5})
+	})
+	take := func() (aEvents, bEvents []string, aAt, bAt []ui.Position) {
+		w.Do(func() {
+			aEvents, bEvents, aAt, bAt = a.events, b.events, a.at, b.at
+			a.events, b.events, a.at, b.at = nil, nil, nil, nil
+		})
+		return
+	}
+
+	p := ui.Pointer{ID: 1, Type: ui.PointerTypeMouse, Button: ui.PointerButtonLeftMouse, Buttons: ui.PointerButtonLeftMouse}
+	p.Position = ui.Position{X: 1, Y: 1}
+	// A pointer that is not down can't be captured.
+	w.Do(func() { w.SetPointerCapture(p.ID, b) })
+	w.InjectPointerDown(p)
+	var capture ui.View
+	w.Do(func() { capture = w.PointerCapture(p.ID) })
+	if capture != a {
+		t.Fatalf("the pointer is captured by %v, want the view it went down on", capture)
+	}
+
+	// Transferring the capture cancels the pointer in the old view, at its
+	// position there, and sends its later events to the new view.
+	w.Do(func() {
+		w.SetPointerCapture(p.ID, b)
+		// A view outside the window can't capture the pointer.
+		w.SetPointerCapture(p.ID, newBox(ui.Color{}, nil))
+	})
+	p.Position = ui.Position{X: 2, Y: 1}
+	w.InjectPointerMove(p)
+	aEvents, bEvents, aAt, bAt := take()
+	if !equalStrings(aEvents, []string{"down", "cancel"}) || aAt[1] != (ui.Position{X: 1, Y: 1}) {
+		t.Errorf("the old holder got %v at %v, want down and cancel at (1,1)", aEvents, aAt)
+	}
+	if !equalStrings(bEvents, []string{"move"}) || bAt[0] != (ui.Position{X: -3, Y: 1}) {
+		t.Errorf("the new holder got %v at %v, want a move at (-3,1)", bEvents, bAt)
+	}
+
+	// Once released, the pointer's events go to the view under it, without
+	// canceling the old holder.
+	w.Do(func() { w.ReleasePointerCapture(p.ID) })
+	p.Position = ui.Position{X: 3, Y: 1}
+	w.InjectPointerMove(p)
+	p.Position = ui.Position{X: 7, Y: 1}
+	w.InjectPointerMove(p)
+	p.Buttons = ui.PointerButtonNone
+	w.InjectPointerUp(p)
+	aEvents, bEvents, _, _ = take()
+	if !equalStrings(aEvents, []string{"move"}) || !equalStrings(bEvents, []string{"move", "up"}) {
+		t.Errorf("after the release, a got %v and b %v, want move and move, up", aEvents, bEvents)
+	}
+	w.Do(func() { capture = w.PointerCapture(p.ID) })
+	if capture != nil {
+		t.Errorf("a pointer that is up is captured by %v", capture)
+	}
+}
+
+// hoverView records the pointers entering and leaving it in a log shared
+// with other views.
+type hoverView struct {
+	ui.View
+	name string
+	log  *[]string
+}
+
+func newHoverView(parent ui.View, name string, log *[]string) *hoverView {
+	v := &hoverView{name: name, log: log}
+	v.View = ui.NewView(v, parent)
+	return v
+}
+
+func (v *hoverView) PointerEnter(p ui.Pointer) { *v.log = append(*v.log, "enter "+v.name) }
+func (v *hoverView) PointerLeave(p ui.Pointer) { *v.log = append(*v.log, "leave "+v.name) }
+
+func TestHover(t *testing.T) {
+	var log []string
+	root := newBox(ui.Color{}, nil)
+	outer := newHoverView(root, "outer", &log)
+	inner := newHoverView(outer, "inner", &log)
+	other := newHoverView(root, "other", &log)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 5}, root)
+	defer w.Close()
+	w.Do(func() {
+		outer.Resize(ui.Size{Width: 5, Height: 5})
+		inner.Resize(ui.Size{Width: 5, Height: 3})
+		other.Move(ui.Position{X: 5})
+		other.Resize(ui.Size{Width: 5, Height: 5})
+	})
+	check := func(what string, want ...string) {
+		t.Helper()
+		var got []string
+		w.Do(func() { got, log = log, nil })
+		if !equalStrings(got, want) {
+			t.Errorf("%s: got %q, want %q", what, got, want)
+		}
+	}
+
+	// Views are entered outermost first and left innermost first.
+	mouse := ui.Pointer{ID: 1, Type: ui.PointerTypeMouse}
+	move := func(x, y float64) {
+		mouse.Position = ui.Position{X: x, Y: y}
+		w.InjectPointerMove(mouse)
+	}
+	move(1, 1)
+	check("enter", "enter outer", "enter inner")
+	move(2, 1)
+	check("move within")
+	move(1, 4)
+	check("leave the inner view", "leave inner")
+	move(7, 1)
+	check("move across", "leave outer", "enter other")
+	move(1, 1)
+	check("move back", "leave other", "enter outer", "enter inner")
+	w.InjectPointerLeave(mouse)
+	check("leave the window", "leave inner", "leave outer")
+
+	// Touches hover only while they are down.
+	touch := ui.Pointer{ID: 2, Type: ui.PointerTypeTouch, Button: ui.PointerButtonTouchContact, Buttons: ui.PointerButtonTouchContact}
+	touch.Position = ui.Position{X: 1, Y: 1}
+	w.InjectPointerDown(touch)
+	check("touch down", "enter outer", "enter inner")
+	touch.Buttons = ui.PointerButtonNone
+	w.InjectPointerUp(touch)
+	check("touch up", "leave inner", "leave outer")
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
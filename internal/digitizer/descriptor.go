@@ -0,0 +1,523 @@
+package digitizer
+
+import (
+	"errors"
+	"math"
+)
+
+// Usage pages and usages of the HID Usage Tables that describe digitizers.
+const (
+	pageGenericDesktop = 0x01
+	pageDigitizer      = 0x0d
+
+	usageX = 0x30
+	usageY = 0x31
+
+	usageDigitizer      = 0x01
+	usagePen            = 0x02
+	usageTouchScreen    = 0x04
+	usageStylus         = 0x20
+	usagePuck           = 0x21
+	usageFinger         = 0x22
+	usageTipPressure    = 0x30
+	usageBarrelPressure = 0x31
+	usageInRange        = 0x32
+	usageInvert         = 0x3c
+	usageXTilt          = 0x3d
+	usageYTilt          = 0x3e
+	usageAzimuth        = 0x3f
+	usageAltitude       = 0x40
+	usageTwist          = 0x41
+	usageTipSwitch      = 0x42
+	usageBarrelSwitch   = 0x44
+	usageEraser         = 0x45
+	usageWidth          = 0x48
+	usageHeight         = 0x49
+	usageContactID      = 0x51
+	usageContactCount   = 0x54
+)
+
+// A Descriptor describes the contacts in the input reports of a digitizer,
+// as read from its HID report descriptor.
+type Descriptor struct {
+	// numbered is set if reports begin with a report ID.
+	numbered bool
+	reports  map[uint8]*report
+}
+
+// A report holds the fields of one input report.
+type report struct {
+	contacts     []*contactFields
+	contactCount *field
+}
+
+// contactFields holds the fields of one contact in a report, any of which
+// may be missing.
+type contactFields struct {
+	pen bool
+
+	id, tip, inRange, invert, eraser, barrel *field
+	x, y, width, height                      *field
+	pressure, barrelPressure                 *field
+	tiltX, tiltY, azimuth, altitude, twist   *field
+}
+
+// A field is a value in a report.
+type field struct {
+	// offset and size are in bits, from the start of the report after
+	// any report ID.
+	offset, size       uint
+	logicalMin         int64
+	logicalMax         int64
+	physicalMin        int64
+	physicalMax        int64
+	unitExponent, unit int64
+}
+
+// globals holds the global items of a descriptor, which the Push and Pop
+// items save and restore.
+type globals struct {
+	usagePage                int64
+	logicalMin, logicalMax   int64
+	physicalMin, physicalMax int64
+	unitExponent, unit       int64
+	reportSize, reportCount  int64
+	reportID                 uint8
+}
+
+// A collection is one opened by a Collection item.
+type collection struct {
+	typ   int64
+	usage uint32
+	// contact holds the fields of the contact the collection describes,
+	// if any.
+	contact *contactFields
+}
+
+const collectionApplication = 1
+
+// ParseDescriptor parses a HID report descriptor.  It returns an error if
+// the descriptor doesn't describe a digitizer.
+func ParseDescriptor(b []byte) (*Descriptor, error) {
+	d := &Descriptor{reports: map[uint8]*report{}}
+	var (
+		g                  globals
+		stack              []globals
+		usages             []uint32
+		usageMin, usageMax uint32
+		hasUsageRange      bool
+		collections        []*collection
+		offsets            = map[uint8]uint{}
+	)
+
+	for len(b) > 0 {
+		prefix := b[0]
+		b = b[1:]
+		if prefix == 0xfe {
+			// Long items are reserved and carry no data that we use.
+			if len(b) < 2 || len(b) < 2+int(b[0]) {
+				return nil, errors.New("truncated long item")
+			}
+			b = b[2+int(b[0]):]
+			continue
+		}
+		size := int(prefix & 3)
+		if size == 3 {
+			size = 4
+		}
+		if len(b) < size {
+			return nil, errors.New("truncated item")
+		}
+		var u uint32
+		for i := 0; i < size; i++ {
+			u |= uint32(b[i]) << (8 * uint(i))
+		}
+		// s is the item's data as a signed number.
+		s := int64(u)
+		if size > 0 && size < 4 && u&(1<<(8*uint(size)-1)) != 0 || size == 4 && int32(u) < 0 {
+			s -= 1 << (8 * uint(size))
+		}
+		b = b[size:]
+
+		switch typ, tag := prefix>>2&3, prefix>>4; typ {
+		case 0: // main
+			switch tag {
+			case 0x8: // input
+				var r *report
+				if c := innermostContact(collections); c != nil || inDigitizer(collections) {
+					r = d.reports[g.reportID]
+					if r == nil {
+						r = &report{}
+						d.reports[g.reportID] = r
+					}
+					if c != nil && !containsContact(r.contacts, c) {
+						r.contacts = append(r.contacts, c)
+					}
+				}
+				constant, variable := u&1 != 0, u&2 != 0
+				for i := int64(0); i < g.reportCount; i++ {
+					f := &field{
+						offset:       offsets[g.reportID],
+						size:         uint(g.reportSize),
+						logicalMin:   g.logicalMin,
+						logicalMax:   g.logicalMax,
+						physicalMin:  g.physicalMin,
+						physicalMax:  g.physicalMax,
+						unitExponent: g.unitExponent,
+						unit:         g.unit,
+					}
+					offsets[g.reportID] += uint(g.reportSize)
+					if r == nil || constant || !variable {
+						continue
+					}
+					var usage uint32
+					switch {
+					case hasUsageRange && usageMin+uint32(i) <= usageMax:
+						usage = usageMin + uint32(i)
+					case int(i) < len(usages):
+						usage = usages[i]
+					case len(usages) > 0:
+						usage = usages[len(usages)-1]
+					default:
+						continue
+					}
+					assignField(r, innermostContact(collections), usage, f)
+				}
+			case 0xa: // collection
+				c := &collection{typ: s}
+				if len(usages) > 0 {
+					c.usage = usages[0]
+				}
+				collections = append(collections, c)
+				if c.usage>>16 == pageDigitizer && inDigitizer(collections) {
+					switch c.usage & 0xffff {
+					case usageFinger, usageStylus, usagePuck:
+						c.contact = &contactFields{pen: c.usage&0xffff != usageFinger}
+					case usagePen:
+						if c.typ == collectionApplication {
+							c.contact = &contactFields{pen: true}
+						}
+					}
+				}
+			case 0xc: // end collection
+				if len(collections) > 0 {
+					collections = collections[:len(collections)-1]
+				}
+			}
+			usages = usages[:0]
+			hasUsageRange = false
+		case 1: // global
+			switch tag {
+			case 0x0:
+				g.usagePage = int64(u)
+			case 0x1:
+				g.logicalMin = s
+			case 0x2:
+				g.logicalMax = s
+				if g.logicalMin >= 0 && s < g.logicalMin {
+					// Devices often omit the sign, meaning an unsigned maximum.
+					g.logicalMax = int64(u)
+				}
+			case 0x3:
+				g.physicalMin = s
+			case 0x4:
+				g.physicalMax = s
+				if g.physicalMin >= 0 && s < g.physicalMin {
+					g.physicalMax = int64(u)
+				}
+			case 0x5:
+				// The exponent is a 4-bit signed number.
+				g.unitExponent = int64(u & 0xf)
+				if g.unitExponent >= 8 {
+					g.unitExponent -= 16
+				}
+			case 0x6:
+				g.unit = int64(u)
+			case 0x7:
+				g.reportSize = int64(u)
+			case 0x8:
+				g.reportID = uint8(u)
+				d.numbered = true
+			case 0x9:
+				g.reportCount = int64(u)
+			case 0xa:
+				stack = append(stack, g)
+			case 0xb:
+				if len(stack) > 0 {
+					g = stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+				}
+			}
+		case 2: // local
+			usage := u
+			if size < 4 {
+				usage |= uint32(g.usagePage) << 16
+			}
+			switch tag {
+			case 0x0:
+				usages = append(usages, usage)
+			case 0x1:
+				usageMin = usage
+				hasUsageRange = true
+			case 0x2:
+				usageMax = usage
+				hasUsageRange = true
+			}
+		}
+	}
+
+	for id, r := range d.reports {
+		for i := 0; i < len(r.contacts); i++ {
+			if c := r.contacts[i]; c.x == nil || c.y == nil {
+				r.contacts = append(r.contacts[:i], r.contacts[i+1:]...)
+				i--
+			}
+		}
+		if len(r.contacts) == 0 {
+			delete(d.reports, id)
+		}
+	}
+	if len(d.reports) == 0 {
+		return nil, errors.New("not a digitizer")
+	}
+	return d, nil
+}
+
+// innermostContact returns the fields of the innermost collection that
+// describes a contact.
+func innermostContact(collections []*collection) *contactFields {
+	for i := len(collections) - 1; i >= 0; i-- {
+		if c := collections[i].contact; c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// inDigitizer reports whether the innermost application collection is that
+// of a digitizer mapped to the screen.  Touch pads are left to the system.
+func inDigitizer(collections []*collection) bool {
+	for i := len(collections) - 1; i >= 0; i-- {
+		if c := collections[i]; c.typ == collectionApplication {
+			if c.usage>>16 != pageDigitizer {
+				return false
+			}
+			switch c.usage & 0xffff {
+			case usageDigitizer, usagePen, usageTouchScreen:
+				return true
+			}
+			return false
+		}
+	}
+	return false
+}
+
+func containsContact(cs []*contactFields, c *contactFields) bool {
+	for _, x := range cs {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
+
+func assignField(r *report, c *contactFields, usage uint32, f *field) {
+	if usage == pageDigitizer<<16|usageContactCount {
+		r.contactCount = f
+		return
+	}
+	if c == nil {
+		return
+	}
+	var dst **field
+	switch usage {
+	case pageGenericDesktop<<16 | usageX:
+		dst = &c.x
+	case pageGenericDesktop<<16 | usageY:
+		dst = &c.y
+	case pageDigitizer<<16 | usageContactID:
+		dst = &c.id
+	case pageDigitizer<<16 | usageTipSwitch:
+		dst = &c.tip
+	case pageDigitizer<<16 | usageInRange:
+		dst = &c.inRange
+	case pageDigitizer<<16 | usageInvert:
+		dst = &c.invert
+	case pageDigitizer<<16 | usageEraser:
+		dst = &c.eraser
+	case pageDigitizer<<16 | usageBarrelSwitch:
+		dst = &c.barrel
+	case pageDigitizer<<16 | usageWidth:
+		dst = &c.width
+	case pageDigitizer<<16 | usageHeight:
+		dst = &c.height
+	case pageDigitizer<<16 | usageTipPressure:
+		dst = &c.pressure
+	case pageDigitizer<<16 | usageBarrelPressure:
+		dst = &c.barrelPressure
+	case pageDigitizer<<16 | usageXTilt:
+		dst = &c.tiltX
+	case pageDigitizer<<16 | usageYTilt:
+		dst = &c.tiltY
+	case pageDigitizer<<16 | usageAzimuth:
+		dst = &c.azimuth
+	case pageDigitizer<<16 | usageAltitude:
+		dst = &c.altitude
+	case pageDigitizer<<16 | usageTwist:
+		dst = &c.twist
+	default:
+		return
+	}
+	if *dst == nil {
+		*dst = f
+	}
+}
+
+// raw returns the field's bits in a report, and whether the report holds
+// them.
+func (f *field) raw(b []byte) (uint64, bool) {
+	if f == nil || f.size == 0 || f.size > 64 || (f.offset+f.size+7)/8 > uint(len(b)) {
+		return 0, false
+	}
+	var v uint64
+	for i := uint(0); i < f.size; i++ {
+		bit := f.offset + i
+		if b[bit/8]&(1<<(bit%8)) != 0 {
+			v |= 1 << i
+		}
+	}
+	return v, true
+}
+
+// value returns the field's logical value in a report.
+func (f *field) value(b []byte) (int64, bool) {
+	v, ok := f.raw(b)
+	if !ok {
+		return 0, false
+	}
+	if f.logicalMin < 0 && f.size < 64 && v&(1<<(f.size-1)) != 0 {
+		return int64(v) - 1<<f.size, true
+	}
+	return int64(v), true
+}
+
+// normalized returns the field's value in a report scaled from its logical
+// range to 0 to 1.
+func (f *field) normalized(b []byte) (float64, bool) {
+	v, ok := f.value(b)
+	if !ok || f.logicalMax <= f.logicalMin {
+		return 0, false
+	}
+	return float64(v-f.logicalMin) / float64(f.logicalMax-f.logicalMin), true
+}
+
+// physical converts a logical value of the field to physical units.
+func (f *field) physical(v int64) float64 {
+	x := float64(v)
+	if f.physicalMax != f.physicalMin && f.logicalMax != f.logicalMin {
+		x = float64(f.physicalMin) + float64(v-f.logicalMin)*float64(f.physicalMax-f.physicalMin)/float64(f.logicalMax-f.logicalMin)
+	}
+	return x * math.Pow(10, float64(f.unitExponent))
+}
+
+// degrees returns the field's value in a report as an angle in degrees.
+func (f *field) degrees(b []byte) (float64, bool) {
+	v, ok := f.value(b)
+	if !ok {
+		return 0, false
+	}
+	a := f.physical(v)
+	// The low nibble of the unit is its system, where 2 measures angles in
+	// radians and 4 in degrees.
+	if f.unit&0xf == 2 {
+		a *= 180 / math.Pi
+	}
+	return a, true
+}
+
+// extent returns the size of the field's physical range.
+func (f *field) extent() float64 {
+	return f.physical(f.logicalMax) - f.physical(f.logicalMin)
+}
+
+// flag returns whether a one-bit field is set, or def if it is missing.
+func (f *field) flag(b []byte, def bool) bool {
+	v, ok := f.raw(b)
+	if !ok {
+		return def
+	}
+	return v != 0
+}
+
+// decode returns the contact described by c in a report, and whether its
+// slot in the report is in use.
+func (c *contactFields) decode(b []byte, index int) (Contact, bool) {
+	used := false
+	for _, f := range []*field{c.id, c.tip, c.inRange, c.x, c.y} {
+		if v, ok := f.raw(b); ok && v != 0 {
+			used = true
+		}
+	}
+
+	k := Contact{
+		ID:       uint32(index),
+		Pen:      c.pen,
+		Pressure: -1,
+	}
+	if v, ok := c.id.raw(b); ok {
+		k.ID = uint32(v)
+	}
+	k.X, _ = c.x.normalized(b)
+	k.Y, _ = c.y.normalized(b)
+	// Contacts that don't report being in range are while they touch.
+	k.InRange = c.inRange.flag(b, c.tip.flag(b, true))
+	k.Tip = k.InRange && c.tip.flag(b, true)
+	k.Eraser = c.eraser.flag(b, false) || c.invert.flag(b, false)
+	k.Barrel = c.barrel.flag(b, false)
+
+	k.Width = c.size(c.width, c.x, b)
+	k.Height = c.size(c.height, c.y, b)
+	if p, ok := c.pressure.normalized(b); ok {
+		k.Pressure = p
+	}
+	if f := c.barrelPressure; f != nil {
+		if f.logicalMin < 0 {
+			v, _ := f.value(b)
+			k.BarrelPressure = float64(v) / math.Max(float64(-f.logicalMin), float64(f.logicalMax))
+		} else {
+			k.BarrelPressure, _ = f.normalized(b)
+		}
+	}
+	tx, okX := c.tiltX.degrees(b)
+	ty, okY := c.tiltY.degrees(b)
+	if okX || okY {
+		k.TiltX, k.TiltY = tx, ty
+	} else if az, ok := c.azimuth.degrees(b); ok {
+		if alt, ok := c.altitude.degrees(b); ok {
+			// Convert the pen's spherical orientation to tilt.
+			az, alt = az*math.Pi/180, alt*math.Pi/180
+			k.TiltX = math.Atan(math.Cos(az)/math.Tan(alt)) * 180 / math.Pi
+			k.TiltY = math.Atan(math.Sin(az)/math.Tan(alt)) * 180 / math.Pi
+		}
+	}
+	if t, ok := c.twist.degrees(b); ok {
+		k.Twist = math.Mod(math.Mod(t, 360)+360, 360)
+	}
+	return k, used
+}
+
+// size returns the value of a width or height field in a report relative to
+// the range of the corresponding coordinate.
+func (c *contactFields) size(f, coord *field, b []byte) float64 {
+	v, ok := f.value(b)
+	if !ok {
+		return 0
+	}
+	if e := coord.extent(); e > 0 && f.physicalMax != f.physicalMin {
+		return f.physical(v) / e
+	}
+	if r := coord.logicalMax - coord.logicalMin; r > 0 {
+		return float64(v) / float64(r)
+	}
+	return 0
+}
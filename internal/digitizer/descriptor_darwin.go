@@ -0,0 +1,96 @@
+package digitizer
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework IOKit
+#include <IOKit/hid/IOHIDManager.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+static CFTypeRef prop(IOHIDDeviceRef d, const char *key) {
+	CFStringRef k = CFStringCreateWithCString(NULL, key, kCFStringEncodingUTF8);
+	CFTypeRef v = IOHIDDeviceGetProperty(d, k);
+	CFRelease(k);
+	return v;
+}
+
+static int32_t intProp(IOHIDDeviceRef d, const char *key) {
+	int32_t n = 0;
+	CFTypeRef v = prop(d, key);
+	if (v && CFGetTypeID(v) == CFNumberGetTypeID()) {
+		CFNumberGetValue((CFNumberRef)v, kCFNumberSInt32Type, &n);
+	}
+	return n;
+}
+
+static void stringProp(IOHIDDeviceRef d, const char *key, char *buf, CFIndex len) {
+	buf[0] = 0;
+	CFTypeRef v = prop(d, key);
+	if (v && CFGetTypeID(v) == CFStringGetTypeID()) {
+		CFStringGetCString((CFStringRef)v, buf, len, kCFStringEncodingUTF8);
+	}
+}
+
+// copyReportDescriptor copies the report descriptor of the device with the
+// given path, as the hid package names devices, and returns its length, or
+// -1 if there is no such device.
+static int copyReportDescriptor(const char *path, uint8_t *buf, int len) {
+	IOHIDManagerRef mgr = IOHIDManagerCreate(kCFAllocatorDefault, kIOHIDOptionsTypeNone);
+	IOHIDManagerSetDeviceMatching(mgr, NULL);
+	IOHIDManagerOpen(mgr, kIOHIDOptionsTypeNone);
+	CFSetRef set = IOHIDManagerCopyDevices(mgr);
+	int n = -1;
+	if (set) {
+		CFIndex i, count = CFSetGetCount(set);
+		const void **devs = malloc(count * sizeof *devs);
+		CFSetGetValues(set, devs);
+		for (i = 0; i < count && n < 0; i++) {
+			IOHIDDeviceRef d = (IOHIDDeviceRef)devs[i];
+			char transport[64], p[128];
+			stringProp(d, kIOHIDTransportKey, transport, sizeof transport);
+			snprintf(p, sizeof p, "%s_%04x_%04x_%08x", transport,
+				(uint16_t)intProp(d, kIOHIDVendorIDKey), (uint16_t)intProp(d, kIOHIDProductIDKey),
+				(uint32_t)intProp(d, kIOHIDLocationIDKey));
+			if (strcmp(p, path) != 0) {
+				continue;
+			}
+			CFTypeRef desc = prop(d, kIOHIDReportDescriptorKey);
+			if (desc && CFGetTypeID(desc) == CFDataGetTypeID()) {
+				n = CFDataGetLength((CFDataRef)desc);
+				if (n > len) {
+					n = len;
+				}
+				CFDataGetBytes((CFDataRef)desc, CFRangeMake(0, n), buf);
+			}
+		}
+		free(devs);
+		CFRelease(set);
+	}
+	IOHIDManagerClose(mgr, kIOHIDOptionsTypeNone);
+	CFRelease(mgr);
+	return n;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/flynn/hid"
+)
+
+// maxDescriptorSize is the largest report descriptor that HID allows.
+const maxDescriptorSize = 4096
+
+func reportDescriptor(info *hid.DeviceInfo) ([]byte, error) {
+	path := C.CString(info.Path)
+	defer C.free(unsafe.Pointer(path))
+	buf := make([]byte, maxDescriptorSize)
+	n := C.copyReportDescriptor(path, (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.int(len(buf)))
+	if n < 0 {
+		return nil, errors.New("device not found")
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,12 @@
+package digitizer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/flynn/hid"
+)
+
+func reportDescriptor(info *hid.DeviceInfo) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join("/sys/class/hidraw", filepath.Base(info.Path), "device/report_descriptor"))
+}
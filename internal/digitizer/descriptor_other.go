@@ -0,0 +1,13 @@
+// +build !linux,!darwin
+
+package digitizer
+
+import (
+	"errors"
+
+	"github.com/flynn/hid"
+)
+
+func reportDescriptor(info *hid.DeviceInfo) ([]byte, error) {
+	return nil, errors.New("report descriptors are unavailable on this platform")
+}
@@ -1,54 +1,217 @@
+// Package digitizer reads touches and pens from HID digitizers, such as
+// multi-touch screens, without going through the system.
 package digitizer
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/flynn/hid"
 )
 
-func Run(callback func(id uint8, pressed bool, x, y uint16)) {
+// A Contact is the state of a touch or pen on a digitizer.
+type Contact struct {
+	// Device identifies the digitizer, and ID the contact on it.
+	Device int
+	ID     uint32
+	// Pen distinguishes pens from touches, and Eraser is set if the pen is
+	// the eraser end.  Barrel is set while its barrel button is pressed.
+	Pen, Eraser, Barrel bool
+	// InRange reports whether the contact is tracked, which is while it
+	// touches for touches but includes hovering for pens.  Tip reports
+	// whether it is touching the surface.
+	InRange, Tip bool
+	// X, Y, Width and Height are relative to the digitizer's surface, from
+	// 0 to 1.  Width and Height are zero if the digitizer doesn't report
+	// them.
+	X, Y, Width, Height float64
+	// Pressure is from 0 to 1, or -1 if the digitizer doesn't report it.
+	Pressure float64
+	// BarrelPressure is from -1 to 1.
+	BarrelPressure float64
+	// TiltX, TiltY and Twist are in degrees.
+	TiltX, TiltY, Twist float64
+}
+
+// Run reads the contacts of every digitizer attached, calling callback as
+// they change.  Calls are serialized, though devices are read concurrently.
+// Run returns when all the devices are closed.
+func Run(callback func(Contact)) {
 	devices, err := hid.Devices()
 	if err != nil {
 		fmt.Println("error getting devices:", err)
 		return
 	}
-	var dev hid.Device
-	for _, d := range devices {
-		if d.ProductID == 0x109 {
-			dev, err = d.Open()
-			if err != nil {
-				fmt.Println("error opening device :", err)
-				return
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	n := 0
+	for _, info := range devices {
+		b, err := reportDescriptor(info)
+		if err != nil {
+			continue
+		}
+		desc, err := ParseDescriptor(b)
+		if err != nil {
+			continue
+		}
+		dev, err := info.Open()
+		if err != nil {
+			fmt.Println("error opening device:", err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer dev.Close()
+			send := func(cs []Contact) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, c := range cs {
+					c.Device = index
+					callback(c)
+				}
+			}
+			d := NewDecoder(desc)
+			for report := range dev.ReadCh() {
+				send(d.Decode(report))
 			}
+			send(d.Reset())
+		}(n)
+		n++
+	}
+	wg.Wait()
+}
+
+// A Decoder tracks the contacts of a digitizer across its input reports.
+type Decoder struct {
+	desc *Descriptor
+	// frames holds the state of each numbered report, whose contacts are
+	// tracked separately so that, say, pen reports don't end touch frames.
+	frames map[uint8]*frame
+}
+
+// A frame is the set of contacts that one kind of report describes.
+type frame struct {
+	// remaining is the number of contacts yet to come in the current frame
+	// of a digitizer that splits frames across reports.
+	remaining int
+	seen      map[contactKey]bool
+	active    map[contactKey]Contact
+}
+
+type contactKey struct {
+	pen bool
+	id  uint32
+}
+
+func NewDecoder(d *Descriptor) *Decoder {
+	return &Decoder{
+		desc:   d,
+		frames: map[uint8]*frame{},
+	}
+}
+
+// Decode returns the contacts that changed in an input report, including
+// those that went out of range without being reported.
+func (d *Decoder) Decode(b []byte) []Contact {
+	var id uint8
+	if d.desc.numbered {
+		if len(b) == 0 {
+			return nil
 		}
+		id, b = b[0], b[1:]
 	}
-	if dev == nil {
-		// fmt.Println("touchscreen device not found")
-		return
+	r := d.desc.reports[id]
+	if r == nil {
+		return nil
+	}
+	fr := d.frames[id]
+	if fr == nil {
+		fr = &frame{
+			seen:   map[contactKey]bool{},
+			active: map[contactKey]Contact{},
+		}
+		d.frames[id] = fr
 	}
 
-	type touch struct {
-		down bool
-		x, y uint16
+	n := len(r.contacts)
+	if count, ok := r.contactCount.value(b); ok {
+		// The first report of a frame holds the number of contacts in it,
+		// and the rest zero.
+		if count > 0 {
+			fr.remaining = int(count)
+		}
+		if n > fr.remaining {
+			n = fr.remaining
+		}
+		fr.remaining -= n
 	}
-	var touches [10]touch
 
-	for report := range dev.ReadCh() {
-		for i := 1; i < 51; i += 5 {
-			if report[i] == 0 {
-				break
-			}
+	var changed []Contact
+	for i, f := range r.contacts[:n] {
+		c, used := f.decode(b, i)
+		if !used && r.contactCount == nil {
+			continue
+		}
+		k := contactKey{c.Pen, c.ID}
+		fr.seen[k] = true
+		if old, ok := fr.active[k]; ok && old == c || !ok && !c.InRange {
+			continue
+		}
+		changed = append(changed, c)
+		if c.InRange {
+			fr.active[k] = c
+		} else {
+			delete(fr.active, k)
+		}
+	}
 
-			id := report[i]>>3 - 1
-			t := touch{
-				down: report[i]%2 == 1,
-				x:    uint16(report[i+1]) + uint16(report[i+2])<<8,
-				y:    uint16(report[i+3]) + uint16(report[i+4])<<8,
-			}
-			if touches[id] != t {
-				touches[id] = t
-				callback(id, t.down, t.x, t.y)
-			}
+	if fr.remaining == 0 {
+		changed = append(changed, fr.release(func(k contactKey) bool { return !fr.seen[k] })...)
+		fr.seen = map[contactKey]bool{}
+	}
+	return changed
+}
+
+// Reset forgets the contacts in range, returning them as out of range.
+func (d *Decoder) Reset() []Contact {
+	var ids []int
+	for id := range d.frames {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	var changed []Contact
+	for _, id := range ids {
+		changed = append(changed, d.frames[uint8(id)].release(func(contactKey) bool { return true })...)
+	}
+	d.frames = map[uint8]*frame{}
+	return changed
+}
+
+// release takes the active contacts selected by f out of range and returns
+// them, touches before pens and in order of ID.
+func (fr *frame) release(f func(contactKey) bool) []Contact {
+	var keys []contactKey
+	for k := range fr.active {
+		if f(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pen != keys[j].pen {
+			return keys[j].pen
 		}
+		return keys[i].id < keys[j].id
+	})
+	var released []Contact
+	for _, k := range keys {
+		c := fr.active[k]
+		c.InRange, c.Tip = false, false
+		released = append(released, c)
+		delete(fr.active, k)
 	}
+	return released
 }
@@ -0,0 +1,254 @@
+package digitizer
+
+import (
+	"bufio"
+	"encoding/hex"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readHex reads a file of hexadecimal bytes with # comments, returning the
+// bytes of each line that has any.
+func readHex(t *testing.T, name string) [][]byte {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		b, err := hex.DecodeString(strings.Join(strings.Fields(line), ""))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(b) > 0 {
+			lines = append(lines, b)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func readDescriptor(t *testing.T, name string) []byte {
+	var b []byte
+	for _, line := range readHex(t, name) {
+		b = append(b, line...)
+	}
+	return b
+}
+
+func TestParseDescriptorErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		desc []byte
+		err  string
+	}{
+		{"mouse", readDescriptor(t, "mouse.rdesc"), "not a digitizer"},
+		{"touch pad", readDescriptor(t, "touchpad.rdesc"), "not a digitizer"},
+		{"empty", nil, "not a digitizer"},
+		{"truncated", readDescriptor(t, "panel.rdesc")[:34], "truncated item"},
+		{"truncated long item", []byte{0xfe, 4, 0, 1, 2}, "truncated long item"},
+	} {
+		_, err := ParseDescriptor(test.desc)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestParseDescriptor(t *testing.T) {
+	d, err := ParseDescriptor(readDescriptor(t, "touchscreen_pen.rdesc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.numbered {
+		t.Error("reports are not numbered")
+	}
+	if len(d.reports) != 2 || d.reports[1] == nil || d.reports[2] == nil {
+		t.Fatalf("got reports %v, want 1 and 2", d.reports)
+	}
+
+	touch, pen := d.reports[1], d.reports[2]
+	if len(touch.contacts) != 2 || len(pen.contacts) != 1 {
+		t.Fatalf("got %d touch and %d pen contacts, want 2 and 1", len(touch.contacts), len(pen.contacts))
+	}
+	if touch.contacts[0].pen || !pen.contacts[0].pen {
+		t.Error("touches and pens are confused")
+	}
+	f0, f1, p := touch.contacts[0], touch.contacts[1], pen.contacts[0]
+	for _, test := range []struct {
+		name string
+		f    *field
+		want field
+	}{
+		{"contact count", touch.contactCount, field{offset: 160, size: 8, logicalMax: 127, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"tip", f0.tip, field{offset: 0, size: 1, logicalMax: 1}},
+		{"in range", f0.inRange, field{offset: 1, size: 1, logicalMax: 1}},
+		// The descriptor omits the sign of the maximum.
+		{"contact ID", f0.id, field{offset: 8, size: 8, logicalMax: 255}},
+		{"x", f0.x, field{offset: 16, size: 16, logicalMax: 4095, physicalMax: 14000, unitExponent: -2, unit: 0x11}},
+		{"y", f0.y, field{offset: 32, size: 16, logicalMax: 4095, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"width", f0.width, field{offset: 48, size: 16, logicalMax: 4095, physicalMax: 14000, unitExponent: -2, unit: 0x11}},
+		{"height", f0.height, field{offset: 64, size: 16, logicalMax: 4095, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"second tip", f1.tip, field{offset: 80, size: 1, logicalMax: 1, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"second x", f1.x, field{offset: 96, size: 16, logicalMax: 4095, physicalMax: 14000, unitExponent: -2, unit: 0x11}},
+		{"pen tip", p.tip, field{offset: 0, size: 1, logicalMax: 1, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"pen in range", p.inRange, field{offset: 4, size: 1, logicalMax: 1, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"pen x", p.x, field{offset: 8, size: 16, logicalMax: 32767, physicalMax: 14000, unitExponent: -2, unit: 0x11}},
+		{"pressure", p.pressure, field{offset: 40, size: 12, logicalMax: 4095, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"barrel pressure", p.barrelPressure, field{offset: 56, size: 8, logicalMin: -127, logicalMax: 127, physicalMax: 7876, unitExponent: -2, unit: 0x11}},
+		{"x tilt", p.tiltX, field{offset: 64, size: 16, logicalMin: -9000, logicalMax: 9000, physicalMin: -9000, physicalMax: 9000, unitExponent: -2, unit: 0x14}},
+		{"y tilt", p.tiltY, field{offset: 80, size: 16, logicalMin: -9000, logicalMax: 9000, physicalMin: -9000, physicalMax: 9000, unitExponent: -2, unit: 0x14}},
+		{"twist", p.twist, field{offset: 96, size: 16, logicalMax: 3599, physicalMax: 3599, unitExponent: -1, unit: 0x14}},
+	} {
+		if test.f == nil {
+			t.Errorf("%s: missing", test.name)
+		} else if *test.f != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.name, *test.f, test.want)
+		}
+	}
+
+	d, err = ParseDescriptor(readDescriptor(t, "panel.rdesc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.reports[0]
+	if d.numbered || r == nil || len(r.contacts) != 1 || r.contactCount != nil || r.contacts[0].id != nil {
+		t.Fatalf("got descriptor %+v, want one unnumbered report of one contact", d)
+	}
+	for _, test := range []struct {
+		name string
+		f    *field
+		want field
+	}{
+		{"x", r.contacts[0].x, field{offset: 8, size: 16, logicalMin: -2048, logicalMax: 2047}},
+		{"y", r.contacts[0].y, field{offset: 24, size: 16, logicalMin: -2048, logicalMax: 2047}},
+	} {
+		if *test.f != test.want {
+			t.Errorf("panel %s: got %+v, want %+v", test.name, *test.f, test.want)
+		}
+	}
+}
+
+func touch(id uint32, x, y, w, h float64) Contact {
+	return Contact{ID: id, InRange: true, Tip: true, X: x / 4095, Y: y / 4095, Width: w / 4095, Height: h / 4095, Pressure: -1}
+}
+
+func lifted(c Contact) Contact {
+	c.InRange, c.Tip = false, false
+	return c
+}
+
+func TestDecode(t *testing.T) {
+	hover := Contact{Pen: true, InRange: true, X: 16384. / 32767, Y: 8192. / 32767}
+	pen := Contact{Pen: true, InRange: true, Tip: true, Barrel: true, X: 16384. / 32767, Y: 8192. / 32767,
+		Pressure: 2048. / 4095, BarrelPressure: -64. / 127, TiltX: -45, TiltY: 30, Twist: 90}
+	eraser := Contact{Pen: true, InRange: true, Tip: true, Eraser: true, X: 1,
+		Pressure: 1, BarrelPressure: 1, TiltX: 90, TiltY: -90, Twist: 359.9}
+	panel := func(x, y float64) Contact {
+		return Contact{InRange: true, Tip: true, X: (x + 2048) / 4095, Y: (y + 2048) / 4095, Pressure: -1}
+	}
+
+	for _, test := range []struct {
+		desc, reports string
+		// want holds the contacts that change with each report and then
+		// those that Reset releases.
+		want [][]Contact
+	}{
+		{"touchscreen_pen.rdesc", "touchscreen_pen.reports", [][]Contact{
+			{touch(3, 1000, 2000, 100, 50)},
+			// The frame continues in the next report.
+			{touch(3, 1100, 2000, 100, 50), touch(4, 3000, 1000, 80, 80)},
+			{touch(5, 4095, 4095, 0, 0)},
+			{lifted(touch(4, 3000, 1000, 80, 80))},
+			{},
+			// Pen reports don't end touch frames, or the reverse.
+			{hover},
+			{lifted(touch(3, 1100, 2000, 100, 50))},
+			{pen},
+			{lifted(touch(5, 4095, 4095, 0, 0))},
+			{eraser},
+			{Contact{Pen: true, X: 1}},
+			{},
+		}},
+		{"panel.rdesc", "panel.reports", [][]Contact{
+			{panel(-2048, 2047)},
+			{panel(0, 0)},
+			// An empty report lifts the touch.
+			{lifted(panel(0, 0))},
+			{panel(2047, -2048)},
+			{lifted(panel(2047, -2048))},
+		}},
+	} {
+		desc, err := ParseDescriptor(readDescriptor(t, test.desc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports := readHex(t, test.reports)
+		if len(reports)+1 != len(test.want) {
+			t.Fatalf("%s: %d reports for %d expectations", test.reports, len(reports), len(test.want))
+		}
+		d := NewDecoder(desc)
+		for i, want := range test.want {
+			var got []Contact
+			if i < len(reports) {
+				got = d.Decode(reports[i])
+			} else {
+				got = d.Reset()
+			}
+			if !equalContacts(got, want) {
+				t.Errorf("%s, report %d: got\n\t%+v\nwant\n\t%+v", test.reports, i+1, got, want)
+			}
+		}
+	}
+}
+
+// TestDecodeOtherReports checks that reports the descriptor doesn't describe
+// are ignored.
+func TestDecodeOtherReports(t *testing.T) {
+	desc, err := ParseDescriptor(readDescriptor(t, "touchscreen_pen.rdesc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDecoder(desc)
+	for _, r := range [][]byte{nil, {3, 10}, {4, 1, 2, 3}} {
+		if got := d.Decode(r); len(got) != 0 {
+			t.Errorf("report % x gave contacts %+v", r, got)
+		}
+	}
+}
+
+func equalContacts(a, b []Contact) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	for i := range a {
+		x, y := a[i], b[i]
+		if x.Device != y.Device || x.ID != y.ID || x.Pen != y.Pen || x.Eraser != y.Eraser || x.Barrel != y.Barrel ||
+			x.InRange != y.InRange || x.Tip != y.Tip {
+			return false
+		}
+		for _, v := range [][2]float64{
+			{x.X, y.X}, {x.Y, y.Y}, {x.Width, y.Width}, {x.Height, y.Height},
+			{x.Pressure, y.Pressure}, {x.BarrelPressure, y.BarrelPressure},
+			{x.TiltX, y.TiltX}, {x.TiltY, y.TiltY}, {x.Twist, y.Twist},
+		} {
+			if !near(v[0], v[1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
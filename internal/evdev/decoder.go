@@ -0,0 +1,323 @@
+// Package evdev reads touchscreens and pens from Linux input devices, using
+// the multi-touch protocol type B for touches.
+package evdev
+
+import (
+	"math"
+
+	"github.com/gordonklaus/ui/internal/digitizer"
+)
+
+// Event types and codes from linux/input-event-codes.h.
+const (
+	evSyn = 0x00
+	evKey = 0x01
+	evAbs = 0x03
+
+	synReport  = 0
+	synDropped = 3
+
+	btnToolPen    = 0x140
+	btnToolRubber = 0x141
+	btnTouch      = 0x14a
+	btnStylus     = 0x14b
+	btnStylus2    = 0x14c
+
+	absX          = 0x00
+	absY          = 0x01
+	absZ          = 0x02
+	absWheel      = 0x08
+	absPressure   = 0x18
+	absTiltX      = 0x1a
+	absTiltY      = 0x1b
+	absMTSlot     = 0x2f
+	absMTMajor    = 0x30
+	absMTMinor    = 0x31
+	absMTX        = 0x35
+	absMTY        = 0x36
+	absMTTool     = 0x37
+	absMTID       = 0x39
+	absMTPressure = 0x3a
+	absCnt        = 0x40
+
+	mtToolPen = 1
+)
+
+// An Event is an input event.
+type Event struct {
+	Type, Code uint16
+	Value      int32
+}
+
+// AbsInfo describes an absolute axis.  Resolution is in units per
+// millimetre, or per radian for angles.
+type AbsInfo struct {
+	Value, Min, Max, Fuzz, Flat, Resolution int32
+}
+
+// normalized scales v from the axis's range to 0 to 1.
+func (a AbsInfo) normalized(v int32) float64 {
+	if a.Max <= a.Min {
+		return 0
+	}
+	return float64(v-a.Min) / float64(a.Max-a.Min)
+}
+
+// A Decoder turns the events of a device into changes to its contacts.
+type Decoder struct {
+	abs map[uint16]AbsInfo
+
+	slots []slot
+	slot  int
+	pen   penState
+	// dropped is set after events were lost, until the next report.
+	dropped bool
+}
+
+// A slot is a multi-touch contact.
+type slot struct {
+	id                    int32
+	x, y, major, minor, p int32
+	tool                  int32
+	// reported is set if the contact was in range in the last report, as
+	// last was with tracking ID reportedID.
+	reported, changed bool
+	reportedID        int32
+	last              digitizer.Contact
+}
+
+// penState is the state of a device's pen, which is reported with the
+// single-touch axes.
+type penState struct {
+	inRange, eraser, tip, barrel    bool
+	x, y, p, tiltX, tiltY, z, wheel int32
+	reported, changed               bool
+}
+
+// NewDecoder returns a Decoder for a device with the given absolute axes.
+func NewDecoder(abs map[uint16]AbsInfo) *Decoder {
+	d := &Decoder{abs: abs}
+	n := 1
+	if a, ok := abs[absMTSlot]; ok {
+		n = int(a.Max) + 1
+	}
+	d.slots = make([]slot, n)
+	for i := range d.slots {
+		d.slots[i].id = -1
+	}
+	return d
+}
+
+// Event handles an event, returning the contacts that changed if it ends a
+// report.
+func (d *Decoder) Event(e Event) []digitizer.Contact {
+	if d.dropped {
+		if e.Type == evSyn && e.Code == synReport {
+			d.dropped = false
+		}
+		return nil
+	}
+
+	switch e.Type {
+	case evSyn:
+		switch e.Code {
+		case synReport:
+			return d.report()
+		case synDropped:
+			// The state of the device is unknown until the next report, after
+			// which the reader must replay it as events, as Run does.  Until
+			// then, everything is taken out of range, so that contacts the
+			// replay doesn't mention are lifted in the next report while the
+			// rest carry on.
+			d.dropped = true
+			for i := range d.slots {
+				d.slots[i].id = -1
+				d.slots[i].changed = true
+			}
+			d.pen.inRange = false
+			d.pen.changed = true
+			return nil
+		}
+	case evKey:
+		p := &d.pen
+		on := e.Value != 0
+		switch e.Code {
+		case btnToolPen:
+			p.inRange, p.eraser = on, false
+		case btnToolRubber:
+			p.inRange, p.eraser = on, on
+		case btnTouch:
+			p.tip = on
+		case btnStylus, btnStylus2:
+			p.barrel = on
+		default:
+			return nil
+		}
+		p.changed = true
+	case evAbs:
+		if e.Code == absMTSlot {
+			d.slot = int(e.Value)
+			return nil
+		}
+		if e.Code >= absMTMajor {
+			if d.slot < 0 || d.slot >= len(d.slots) {
+				return nil
+			}
+			s := &d.slots[d.slot]
+			switch e.Code {
+			case absMTID:
+				s.id = e.Value
+			case absMTX:
+				s.x = e.Value
+			case absMTY:
+				s.y = e.Value
+			case absMTMajor:
+				s.major = e.Value
+			case absMTMinor:
+				s.minor = e.Value
+			case absMTPressure:
+				s.p = e.Value
+			case absMTTool:
+				s.tool = e.Value
+			default:
+				return nil
+			}
+			s.changed = true
+			return nil
+		}
+		p := &d.pen
+		switch e.Code {
+		case absX:
+			p.x = e.Value
+		case absY:
+			p.y = e.Value
+		case absPressure:
+			p.p = e.Value
+		case absTiltX:
+			p.tiltX = e.Value
+		case absTiltY:
+			p.tiltY = e.Value
+		case absZ:
+			p.z = e.Value
+		case absWheel:
+			p.wheel = e.Value
+		default:
+			return nil
+		}
+		p.changed = true
+	}
+	return nil
+}
+
+// Reset takes every contact out of range, returning those that were in it.
+func (d *Decoder) Reset() []digitizer.Contact {
+	for i := range d.slots {
+		d.slots[i].id = -1
+		d.slots[i].changed = true
+	}
+	d.pen.inRange = false
+	d.pen.changed = true
+	return d.report()
+}
+
+func (d *Decoder) report() []digitizer.Contact {
+	var cs []digitizer.Contact
+	if _, mt := d.abs[absMTX]; mt {
+		for i := range d.slots {
+			s := &d.slots[i]
+			inRange := s.id >= 0
+			if !s.changed || !inRange && !s.reported {
+				s.changed = false
+				continue
+			}
+			if inRange && s.reported && s.id != s.reportedID {
+				// Another contact took the slot within the report, so the
+				// one that held it lifted.
+				lifted := s.last
+				lifted.InRange, lifted.Tip = false, false
+				cs = append(cs, lifted)
+			}
+			s.last = d.touch(i, s)
+			cs = append(cs, s.last)
+			s.reported, s.changed, s.reportedID = inRange, false, s.id
+		}
+	}
+	if p := &d.pen; p.changed && (p.inRange || p.reported) {
+		cs = append(cs, d.penContact())
+		p.reported = p.inRange
+	}
+	d.pen.changed = false
+	return cs
+}
+
+func (d *Decoder) touch(i int, s *slot) digitizer.Contact {
+	// Contacts are identified by their slots, which are reused only after
+	// the contact that held them lifts, as report makes sure.
+	c := digitizer.Contact{
+		ID:       uint32(i),
+		Pen:      s.tool == mtToolPen,
+		InRange:  s.id >= 0,
+		Tip:      s.id >= 0,
+		X:        d.abs[absMTX].normalized(s.x),
+		Y:        d.abs[absMTY].normalized(s.y),
+		Pressure: -1,
+	}
+	if w := d.abs[absMTX].Max - d.abs[absMTX].Min; w > 0 {
+		c.Width = float64(s.major) / float64(w)
+	}
+	if h := d.abs[absMTY].Max - d.abs[absMTY].Min; h > 0 {
+		minor := s.minor
+		if _, ok := d.abs[absMTMinor]; !ok {
+			minor = s.major
+		}
+		c.Height = float64(minor) / float64(h)
+	}
+	if a, ok := d.abs[absMTPressure]; ok {
+		c.Pressure = a.normalized(s.p)
+	}
+	return c
+}
+
+func (d *Decoder) penContact() digitizer.Contact {
+	p := &d.pen
+	c := digitizer.Contact{
+		// The ID follows those of the slots, so that it can't collide with
+		// a pen reported in one.
+		ID:       uint32(len(d.slots)),
+		Pen:      true,
+		Eraser:   p.eraser,
+		Barrel:   p.barrel,
+		InRange:  p.inRange,
+		Tip:      p.inRange && p.tip,
+		X:        d.abs[absX].normalized(p.x),
+		Y:        d.abs[absY].normalized(p.y),
+		Pressure: -1,
+		TiltX:    d.degrees(absTiltX, p.tiltX),
+		TiltY:    d.degrees(absTiltY, p.tiltY),
+	}
+	if a, ok := d.abs[absPressure]; ok {
+		c.Pressure = a.normalized(p.p)
+	}
+	if a, ok := d.abs[absWheel]; ok {
+		// The wheel is an airbrush's finger wheel.
+		c.BarrelPressure = a.normalized(p.wheel)
+	}
+	if a, ok := d.abs[absZ]; ok {
+		// Z is the rotation of pens that sense it.
+		c.Twist = 360 * a.normalized(p.z)
+	}
+	return c
+}
+
+// degrees converts the value of a tilt axis to degrees.  Axes without a
+// resolution are taken to be in degrees already.
+func (d *Decoder) degrees(code uint16, v int32) float64 {
+	a, ok := d.abs[code]
+	if !ok {
+		return 0
+	}
+	if a.Resolution > 0 {
+		return float64(v) / float64(a.Resolution) * 180 / math.Pi
+	}
+	return float64(v)
+}
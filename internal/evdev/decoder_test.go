@@ -0,0 +1,158 @@
+package evdev
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gordonklaus/ui/internal/digitizer"
+)
+
+func readEvemu(t *testing.T, name string) (map[uint16]AbsInfo, []Event) {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	abs, events, err := ReadEvemu(f)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return abs, events
+}
+
+func TestReadEvemu(t *testing.T) {
+	abs, events := readEvemu(t, "tablet.evemu")
+	if len(abs) != 6 {
+		t.Errorf("got %d axes, want 6", len(abs))
+	}
+	if a, want := abs[absX], (AbsInfo{Min: -200, Max: 20000, Fuzz: 4, Resolution: 100}); a != want {
+		t.Errorf("got X axis %+v, want %+v", a, want)
+	}
+	if len(events) != 29 {
+		t.Errorf("got %d events, want 29", len(events))
+	}
+	if e, want := events[7], (Event{evAbs, absTiltY, -30}); e != want {
+		t.Errorf("got event %+v, want %+v", e, want)
+	}
+
+	for _, test := range []struct{ in, err string }{
+		{"A: 00 0", "malformed evemu axis"},
+		{"E: 0.000000 0003 0000", "malformed evemu event"},
+		{"E: 0.000000 0003 0000 x", `strconv.ParseInt: parsing "x": invalid syntax`},
+	} {
+		if _, _, err := ReadEvemu(strings.NewReader(test.in)); err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %q", test.in, err, test.err)
+		}
+	}
+}
+
+func touch(id uint32, x, y, major float64) digitizer.Contact {
+	return digitizer.Contact{ID: id, InRange: true, Tip: true, X: x / 4095, Y: y / 2303, Width: major / 4095, Height: major / 2303, Pressure: -1}
+}
+
+func lifted(c digitizer.Contact) digitizer.Contact {
+	c.InRange, c.Tip = false, false
+	return c
+}
+
+func TestDecoder(t *testing.T) {
+	hover := digitizer.Contact{ID: 1, Pen: true, InRange: true, X: .5, Y: .5, Twist: 360 * 900. / 1799}
+	down := hover
+	down.Tip, down.Pressure, down.TiltX, down.TiltY, down.Twist = true, 4096./8191, 180/math.Pi, -30, 360
+	barrel := down
+	barrel.Barrel, barrel.X = true, 1
+	up := barrel
+	up.Tip, up.Barrel, up.Pressure = false, false, 0
+	eraser := up
+	eraser.Eraser, eraser.X, eraser.Y, eraser.TiltX, eraser.TiltY = true, 0, 0, 0, 0
+	eraserOut := eraser
+	eraserOut.InRange, eraserOut.Eraser = false, false
+
+	for _, test := range []struct {
+		name string
+		// want holds the contacts that change with each synchronization
+		// event and then those that Reset releases.
+		want [][]digitizer.Contact
+	}{
+		{"touchscreen.evemu", [][]digitizer.Contact{
+			{touch(0, 1000, 500, 40)},
+			{touch(0, 1010, 500, 40), touch(1, 3000, 2000, 30)},
+			// A tracking ID of -1 lifts the contact in the current slot.
+			{lifted(touch(0, 1010, 500, 40)), touch(1, 3000, 2010, 30)},
+			// Contacts are identified by slot.
+			{touch(0, 2000, 1000, 50)},
+			// The single-touch axes are not a pen.
+			{},
+			// A new tracking ID in a slot lifts its contact before the next
+			// one touches.
+			{lifted(touch(0, 2000, 1000, 50)), touch(0, 2200, 1000, 50)},
+			// SYN_DROPPED, and the report ending the discarded events.
+			{},
+			{},
+			// The replayed state moves slot 0 without lifting it.
+			{touch(0, 2600, 1000, 50), lifted(touch(1, 3000, 2010, 30))},
+			{lifted(touch(0, 2600, 1000, 50))},
+			{},
+		}},
+		{"tablet.evemu", [][]digitizer.Contact{
+			{hover},
+			{down},
+			{barrel},
+			{up},
+			{lifted(up)},
+			{eraser},
+			{eraserOut},
+			// Nothing is reported out of proximity.
+			{},
+			{},
+		}},
+	} {
+		abs, events := readEvemu(t, test.name)
+		d := NewDecoder(abs)
+		var got [][]digitizer.Contact
+		for _, e := range events {
+			cs := d.Event(e)
+			if e.Type == evSyn {
+				got = append(got, cs)
+			} else if cs != nil {
+				t.Errorf("%s: non-synchronization event %+v gave contacts %+v", test.name, e, cs)
+			}
+		}
+		got = append(got, d.Reset())
+		if len(got) != len(test.want) {
+			t.Fatalf("%s: got %d reports, want %d", test.name, len(got), len(test.want))
+		}
+		for i := range got {
+			if !equalContacts(got[i], test.want[i]) {
+				t.Errorf("%s, report %d: got\n\t%+v\nwant\n\t%+v", test.name, i+1, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func equalContacts(a, b []digitizer.Contact) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	for i := range a {
+		x, y := a[i], b[i]
+		if x.Device != y.Device || x.ID != y.ID || x.Pen != y.Pen || x.Eraser != y.Eraser || x.Barrel != y.Barrel ||
+			x.InRange != y.InRange || x.Tip != y.Tip {
+			return false
+		}
+		for _, v := range [][2]float64{
+			{x.X, y.X}, {x.Y, y.Y}, {x.Width, y.Width}, {x.Height, y.Height},
+			{x.Pressure, y.Pressure}, {x.BarrelPressure, y.BarrelPressure},
+			{x.TiltX, y.TiltX}, {x.TiltY, y.TiltY}, {x.Twist, y.Twist},
+		} {
+			if !near(v[0], v[1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,211 @@
+package evdev
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/gordonklaus/ui/internal/digitizer"
+)
+
+const (
+	inputPropDirect = 0x01
+	keyCnt          = 0x300
+)
+
+// ioctl request numbers from linux/input.h.
+func eviocgprop(n uintptr) uintptr    { return ioc(2, 0x09, n) }
+func eviocgbit(ev, n uintptr) uintptr { return ioc(2, 0x20+ev, n) }
+func eviocgabs(abs uintptr) uintptr   { return ioc(2, 0x40+abs, unsafe.Sizeof(AbsInfo{})) }
+func eviocgkey(n uintptr) uintptr     { return ioc(2, 0x18, n) }
+func eviocgmtslots(n uintptr) uintptr { return ioc(2, 0x0a, n) }
+
+func ioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'E'<<8 | nr
+}
+
+func ioctl(f *os.File, req uintptr, p unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(p)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// inputEvent is struct input_event.
+type inputEvent struct {
+	time  syscall.Timeval
+	typ   uint16
+	code  uint16
+	value int32
+}
+
+// Run reads the contacts of every touchscreen and pen tablet attached,
+// calling callback as they change.  Calls are serialized, though devices are
+// read concurrently.  Run returns when all the devices are closed.
+func Run(callback func(digitizer.Contact)) {
+	paths, err := filepath.Glob("/dev/input/event*")
+	if err != nil {
+		fmt.Println("error getting devices:", err)
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	n := 0
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		abs, ok := probe(f)
+		if !ok {
+			f.Close()
+			continue
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer f.Close()
+			send := func(cs []digitizer.Contact) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, c := range cs {
+					c.Device = index
+					callback(c)
+				}
+			}
+			d := NewDecoder(abs)
+			dropped := false
+			var buf [64]inputEvent
+			b := (*[len(buf) * int(unsafe.Sizeof(inputEvent{}))]byte)(unsafe.Pointer(&buf))[:]
+			for {
+				m, err := f.Read(b)
+				if err != nil {
+					break
+				}
+				for _, e := range buf[:m/int(unsafe.Sizeof(inputEvent{}))] {
+					send(d.Event(Event{e.typ, e.code, e.value}))
+					switch {
+					case e.typ == evSyn && e.code == synDropped:
+						dropped = true
+					case e.typ == evSyn && e.code == synReport && dropped:
+						// The events since the drop are discarded, so
+						// replay the state the device is in now.
+						dropped = false
+						for _, e := range state(f, abs) {
+							send(d.Event(e))
+						}
+					}
+				}
+			}
+			send(d.Reset())
+		}(n)
+		n++
+	}
+	wg.Wait()
+}
+
+// probe returns the absolute axes of a device if it is a touchscreen or a pen
+// tablet.  Touchpads, which move the cursor rather than pointing on the
+// screen, are left to the system.
+func probe(f *os.File) (map[uint16]AbsInfo, bool) {
+	var props [4]byte
+	if err := ioctl(f, eviocgprop(uintptr(len(props))), unsafe.Pointer(&props)); err != nil {
+		return nil, false
+	}
+	var keys [keyCnt / 8]byte
+	if err := ioctl(f, eviocgbit(evKey, uintptr(len(keys))), unsafe.Pointer(&keys)); err != nil {
+		return nil, false
+	}
+	var axes [absCnt / 8]byte
+	if err := ioctl(f, eviocgbit(evAbs, uintptr(len(axes))), unsafe.Pointer(&axes)); err != nil {
+		return nil, false
+	}
+	has := func(bits []byte, i int) bool { return bits[i/8]&(1<<uint(i%8)) != 0 }
+
+	direct := has(props[:], inputPropDirect)
+	pen := has(keys[:], btnToolPen)
+	touch := direct && has(axes[:], absMTX) && has(axes[:], absMTSlot)
+	if !pen && !touch {
+		return nil, false
+	}
+
+	abs := map[uint16]AbsInfo{}
+	for i := 0; i < absCnt; i++ {
+		if !has(axes[:], i) {
+			continue
+		}
+		var a AbsInfo
+		if err := ioctl(f, eviocgabs(uintptr(i)), unsafe.Pointer(&a)); err != nil {
+			return nil, false
+		}
+		abs[uint16(i)] = a
+	}
+	return abs, true
+}
+
+// state returns events that take a Decoder to the current state of a device
+// with the given absolute axes, ending with a report.
+func state(f *os.File, abs map[uint16]AbsInfo) []Event {
+	var events []Event
+	var keys [keyCnt / 8]byte
+	if err := ioctl(f, eviocgkey(uintptr(len(keys))), unsafe.Pointer(&keys)); err == nil {
+		// Released buttons go first, so that releasing one tool doesn't
+		// take the pen that is using the other out of range.
+		for _, on := range []bool{false, true} {
+			for _, k := range []uint16{btnToolPen, btnToolRubber, btnTouch, btnStylus, btnStylus2} {
+				if pressed := keys[k/8]&(1<<(k%8)) != 0; pressed != on {
+					continue
+				}
+				v := int32(0)
+				if on {
+					v = 1
+				}
+				events = append(events, Event{evKey, k, v})
+			}
+		}
+	}
+
+	var codes []uint16
+	for code := range abs {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	slots := map[uint16][]int32{}
+	for _, code := range codes {
+		switch {
+		case code < absMTSlot:
+			var a AbsInfo
+			if err := ioctl(f, eviocgabs(uintptr(code)), unsafe.Pointer(&a)); err == nil {
+				events = append(events, Event{evAbs, code, a.Value})
+			}
+		case code > absMTSlot:
+			// The values of every slot follow the code.
+			v := make([]int32, 1+abs[absMTSlot].Max+1)
+			v[0] = int32(code)
+			if err := ioctl(f, eviocgmtslots(uintptr(4*len(v))), unsafe.Pointer(&v[0])); err == nil {
+				slots[code] = v[1:]
+			}
+		}
+	}
+	if a, ok := abs[absMTSlot]; ok {
+		for i := int32(0); i <= a.Max; i++ {
+			events = append(events, Event{evAbs, absMTSlot, i})
+			for _, code := range codes {
+				if v, ok := slots[code]; ok {
+					events = append(events, Event{evAbs, code, v[i]})
+				}
+			}
+		}
+		var cur AbsInfo
+		if err := ioctl(f, eviocgabs(absMTSlot), unsafe.Pointer(&cur)); err == nil {
+			events = append(events, Event{evAbs, absMTSlot, cur.Value})
+		}
+	}
+	return append(events, Event{evSyn, synReport, 0})
+}
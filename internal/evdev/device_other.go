@@ -0,0 +1,10 @@
+// +build !linux
+
+package evdev
+
+import (
+	"github.com/gordonklaus/ui/internal/digitizer"
+)
+
+// Run does nothing, as evdev is only on Linux.
+func Run(callback func(digitizer.Contact)) {}
@@ -0,0 +1,66 @@
+package evdev
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadEvemu reads a recording made by evemu-record, returning the device's
+// absolute axes and the events recorded, which can be replayed through a
+// Decoder.
+func ReadEvemu(r io.Reader) (map[uint16]AbsInfo, []Event, error) {
+	abs := map[uint16]AbsInfo{}
+	var events []Event
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) == 0 {
+			continue
+		}
+		switch f[0] {
+		case "A:":
+			// A: code min max fuzz flat resolution
+			if len(f) < 6 {
+				return nil, nil, errors.New("malformed evemu axis")
+			}
+			code, err := strconv.ParseUint(f[1], 16, 16)
+			if err != nil {
+				return nil, nil, err
+			}
+			var v [5]int32
+			for i := range v {
+				if i+2 >= len(f) {
+					break
+				}
+				n, err := strconv.ParseInt(f[i+2], 10, 32)
+				if err != nil {
+					return nil, nil, err
+				}
+				v[i] = int32(n)
+			}
+			abs[uint16(code)] = AbsInfo{Min: v[0], Max: v[1], Fuzz: v[2], Flat: v[3], Resolution: v[4]}
+		case "E:":
+			// E: time type code value
+			if len(f) != 5 {
+				return nil, nil, errors.New("malformed evemu event")
+			}
+			typ, err := strconv.ParseUint(f[2], 16, 16)
+			if err != nil {
+				return nil, nil, err
+			}
+			code, err := strconv.ParseUint(f[3], 16, 16)
+			if err != nil {
+				return nil, nil, err
+			}
+			value, err := strconv.ParseInt(f[4], 10, 32)
+			if err != nil {
+				return nil, nil, err
+			}
+			events = append(events, Event{uint16(typ), uint16(code), int32(value)})
+		}
+	}
+	return abs, events, s.Err()
+}
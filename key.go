@@ -0,0 +1,175 @@
+package ui
+
+type Key struct {
+	Code KeyCode
+	// Rune is the character the key produces, or -1 if there is none.
+	Rune      rune
+	Direction KeyDirection
+	Modifiers KeyModifiers
+	// Repeat is set on presses generated by holding the key down.
+	Repeat bool
+}
+
+type KeyDirection uint8
+
+const (
+	KeyPress KeyDirection = iota
+	KeyRelease
+)
+
+type KeyModifiers uint8
+
+const (
+	KeyModifierShift KeyModifiers = 1 << iota
+	KeyModifierControl
+	KeyModifierAlt
+	// KeyModifierMeta is the Command key on macOS and the Super or Windows key elsewhere.
+	KeyModifierMeta
+)
+
+func (m KeyModifiers) Shift() bool   { return m&KeyModifierShift != 0 }
+func (m KeyModifiers) Control() bool { return m&KeyModifierControl != 0 }
+func (m KeyModifiers) Alt() bool     { return m&KeyModifierAlt != 0 }
+func (m KeyModifiers) Meta() bool    { return m&KeyModifierMeta != 0 }
+
+// A KeyCode identifies a physical key, independent of the keyboard layout.
+// The values are the USB HID usage IDs of the Keyboard/Keypad page, which are
+// also those of golang.org/x/mobile/event/key.
+type KeyCode uint32
+
+const (
+	KeyCodeUnknown KeyCode = 0
+
+	KeyCodeA KeyCode = 4
+	KeyCodeB KeyCode = 5
+	KeyCodeC KeyCode = 6
+	KeyCodeD KeyCode = 7
+	KeyCodeE KeyCode = 8
+	KeyCodeF KeyCode = 9
+	KeyCodeG KeyCode = 10
+	KeyCodeH KeyCode = 11
+	KeyCodeI KeyCode = 12
+	KeyCodeJ KeyCode = 13
+	KeyCodeK KeyCode = 14
+	KeyCodeL KeyCode = 15
+	KeyCodeM KeyCode = 16
+	KeyCodeN KeyCode = 17
+	KeyCodeO KeyCode = 18
+	KeyCodeP KeyCode = 19
+	KeyCodeQ KeyCode = 20
+	KeyCodeR KeyCode = 21
+	KeyCodeS KeyCode = 22
+	KeyCodeT KeyCode = 23
+	KeyCodeU KeyCode = 24
+	KeyCodeV KeyCode = 25
+	KeyCodeW KeyCode = 26
+	KeyCodeX KeyCode = 27
+	KeyCodeY KeyCode = 28
+	KeyCodeZ KeyCode = 29
+
+	KeyCode1 KeyCode = 30
+	KeyCode2 KeyCode = 31
+	KeyCode3 KeyCode = 32
+	KeyCode4 KeyCode = 33
+	KeyCode5 KeyCode = 34
+	KeyCode6 KeyCode = 35
+	KeyCode7 KeyCode = 36
+	KeyCode8 KeyCode = 37
+	KeyCode9 KeyCode = 38
+	KeyCode0 KeyCode = 39
+
+	KeyCodeReturnEnter        KeyCode = 40
+	KeyCodeEscape             KeyCode = 41
+	KeyCodeDeleteBackspace    KeyCode = 42
+	KeyCodeTab                KeyCode = 43
+	KeyCodeSpacebar           KeyCode = 44
+	KeyCodeHyphenMinus        KeyCode = 45 // -
+	KeyCodeEqualSign          KeyCode = 46 // =
+	KeyCodeLeftSquareBracket  KeyCode = 47 // [
+	KeyCodeRightSquareBracket KeyCode = 48 // ]
+	KeyCodeBackslash          KeyCode = 49 // \
+	KeyCodeSemicolon          KeyCode = 51 // ;
+	KeyCodeApostrophe         KeyCode = 52 // '
+	KeyCodeGraveAccent        KeyCode = 53 // `
+	KeyCodeComma              KeyCode = 54 // ,
+	KeyCodeFullStop           KeyCode = 55 // .
+	KeyCodeSlash              KeyCode = 56 // /
+	KeyCodeCapsLock           KeyCode = 57
+
+	KeyCodeF1  KeyCode = 58
+	KeyCodeF2  KeyCode = 59
+	KeyCodeF3  KeyCode = 60
+	KeyCodeF4  KeyCode = 61
+	KeyCodeF5  KeyCode = 62
+	KeyCodeF6  KeyCode = 63
+	KeyCodeF7  KeyCode = 64
+	KeyCodeF8  KeyCode = 65
+	KeyCodeF9  KeyCode = 66
+	KeyCodeF10 KeyCode = 67
+	KeyCodeF11 KeyCode = 68
+	KeyCodeF12 KeyCode = 69
+
+	KeyCodePrintScreen   KeyCode = 70
+	KeyCodeScrollLock    KeyCode = 71
+	KeyCodePause         KeyCode = 72
+	KeyCodeInsert        KeyCode = 73
+	KeyCodeHome          KeyCode = 74
+	KeyCodePageUp        KeyCode = 75
+	KeyCodeDeleteForward KeyCode = 76
+	KeyCodeEnd           KeyCode = 77
+	KeyCodePageDown      KeyCode = 78
+
+	KeyCodeRightArrow KeyCode = 79
+	KeyCodeLeftArrow  KeyCode = 80
+	KeyCodeDownArrow  KeyCode = 81
+	KeyCodeUpArrow    KeyCode = 82
+
+	KeyCodeKeypadNumLock     KeyCode = 83
+	KeyCodeKeypadSlash       KeyCode = 84 // /
+	KeyCodeKeypadAsterisk    KeyCode = 85 // *
+	KeyCodeKeypadHyphenMinus KeyCode = 86 // -
+	KeyCodeKeypadPlusSign    KeyCode = 87 // +
+	KeyCodeKeypadEnter       KeyCode = 88
+	KeyCodeKeypad1           KeyCode = 89
+	KeyCodeKeypad2           KeyCode = 90
+	KeyCodeKeypad3           KeyCode = 91
+	KeyCodeKeypad4           KeyCode = 92
+	KeyCodeKeypad5           KeyCode = 93
+	KeyCodeKeypad6           KeyCode = 94
+	KeyCodeKeypad7           KeyCode = 95
+	KeyCodeKeypad8           KeyCode = 96
+	KeyCodeKeypad9           KeyCode = 97
+	KeyCodeKeypad0           KeyCode = 98
+	KeyCodeKeypadFullStop    KeyCode = 99  // .
+	KeyCodeNonUSBackslash    KeyCode = 100 // \ and | next to left Shift on ISO keyboards
+	KeyCodeApplication       KeyCode = 101 // the context menu key
+	KeyCodeKeypadEqualSign   KeyCode = 103 // =
+
+	KeyCodeF13 KeyCode = 104
+	KeyCodeF14 KeyCode = 105
+	KeyCodeF15 KeyCode = 106
+	KeyCodeF16 KeyCode = 107
+	KeyCodeF17 KeyCode = 108
+	KeyCodeF18 KeyCode = 109
+	KeyCodeF19 KeyCode = 110
+	KeyCodeF20 KeyCode = 111
+	KeyCodeF21 KeyCode = 112
+	KeyCodeF22 KeyCode = 113
+	KeyCodeF23 KeyCode = 114
+	KeyCodeF24 KeyCode = 115
+
+	KeyCodeHelp KeyCode = 117
+
+	KeyCodeMute       KeyCode = 127
+	KeyCodeVolumeUp   KeyCode = 128
+	KeyCodeVolumeDown KeyCode = 129
+
+	KeyCodeLeftControl  KeyCode = 224
+	KeyCodeLeftShift    KeyCode = 225
+	KeyCodeLeftAlt      KeyCode = 226
+	KeyCodeLeftGUI      KeyCode = 227
+	KeyCodeRightControl KeyCode = 228
+	KeyCodeRightShift   KeyCode = 229
+	KeyCodeRightAlt     KeyCode = 230
+	KeyCodeRightGUI     KeyCode = 231
+)
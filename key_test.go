@@ -0,0 +1,195 @@
+// +build !android,!ios
+
+package ui_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// keyView is a view that records the key and focus events it receives.
+type keyView struct {
+	ui.View
+	events []string
+}
+
+func newKeyView(parent ui.View, focusable bool) *keyView {
+	v := &keyView{}
+	v.View = ui.NewView(v, parent)
+	v.SetFocusable(focusable)
+	return v
+}
+
+func (v *keyView) KeyDown(k ui.Key) { v.recordKey("down", k) }
+func (v *keyView) KeyUp(k ui.Key)   { v.recordKey("up", k) }
+func (v *keyView) FocusIn()         { v.events = append(v.events, "in") }
+func (v *keyView) FocusOut()        { v.events = append(v.events, "out") }
+
+func (v *keyView) recordKey(event string, k ui.Key) {
+	if (event == "down") != (k.Direction == ui.KeyPress) {
+		event += " with the wrong direction"
+	}
+	e := fmt.Sprintf("%s %c", event, k.Rune)
+	if k.Repeat {
+		e += " repeat"
+	}
+	v.events = append(v.events, e)
+}
+
+// takeEvents returns and clears the events of views.
+func takeEvents(w *ui.HeadlessWindow, views ...*keyView) [][]string {
+	events := make([][]string, len(views))
+	w.Do(func() {
+		for i, v := range views {
+			events[i] = v.events
+			v.events = nil
+		}
+	})
+	return events
+}
+
+func TestKeyRouting(t *testing.T) {
+	root := newKeyView(nil, false)
+	child := newKeyView(root, true)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 10}, root)
+	defer w.Close()
+
+	a := ui.Key{Code: ui.KeyCodeA, Rune: 'a'}
+	check := func(what string, wantRoot, wantChild []string) {
+		t.Helper()
+		e := takeEvents(w, root, child)
+		if !equalStrings(e[0], wantRoot) || !equalStrings(e[1], wantChild) {
+			t.Errorf("%s: root got %q and child %q, want %q and %q", what, e[0], e[1], wantRoot, wantChild)
+		}
+	}
+
+	// Without a focused view, keys go to the content view.
+	w.InjectKeyDown(a)
+	w.InjectKeyUp(a)
+	check("no focus", []string{"down a", "up a"}, nil)
+
+	w.Do(func() { w.SetFocus(child) })
+	takeEvents(w, child)
+	w.InjectKeyDown(a)
+	w.InjectKeyDown(ui.Key{Code: ui.KeyCodeA, Rune: 'a', Repeat: true})
+	w.InjectKeyUp(a)
+	check("focused child", nil, []string{"down a", "down a repeat", "up a"})
+
+	// Keys return to the content view when the focused view is removed.
+	w.Do(func() { child.SetParent(nil) })
+	w.InjectKeyDown(a)
+	check("removed child", []string{"down a"}, nil)
+}
+
+// reversedFocus is a keyView whose children are visited by Tab in reverse.
+type reversedFocus struct {
+	*keyView
+	children []ui.View
+}
+
+func (v *reversedFocus) FocusOrder() []ui.View {
+	var order []ui.View
+	for i := len(v.children) - 1; i >= 0; i-- {
+		order = append(order, v.children[i])
+	}
+	return order
+}
+
+func TestFocusTraversal(t *testing.T) {
+	root := newKeyView(nil, false)
+	a := newKeyView(root, true)
+	group := &reversedFocus{keyView: &keyView{}}
+	group.View = ui.NewView(group, root)
+	b := newKeyView(group, true)
+	c := newKeyView(group, true)
+	group.children = []ui.View{b, c}
+	d := newKeyView(root, true)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 10}, root)
+	defer w.Close()
+
+	names := map[ui.View]string{a: "a", b: "b", c: "c", d: "d", nil: "none"}
+	focused := func() (name string) {
+		w.Do(func() { name = names[w.Focused()] })
+		return
+	}
+	tab := ui.Key{Code: ui.KeyCodeTab, Rune: '\t'}
+	shiftTab := tab
+	shiftTab.Modifiers = ui.KeyModifierShift
+
+	// Tab visits the focusable views in focus order and wraps around.
+	for _, want := range []string{"a", "c", "b", "d", "a"} {
+		w.InjectKeyDown(tab)
+		w.InjectKeyUp(tab)
+		if f := focused(); f != want {
+			t.Fatalf("Tab focused %s, want %s", f, want)
+		}
+	}
+	for _, want := range []string{"d", "b", "c", "a"} {
+		w.InjectKeyDown(shiftTab)
+		if f := focused(); f != want {
+			t.Fatalf("Shift-Tab focused %s, want %s", f, want)
+		}
+	}
+	e := takeEvents(w, root, a, d)
+	if !equalStrings(e[0], nil) || !equalStrings(e[1], []string{"in", "out", "in", "out", "in"}) || !equalStrings(e[2], []string{"in", "out", "in", "out"}) {
+		t.Errorf("root, a and d got events %q, want only focus changes", e)
+	}
+
+	// Tab with other modifiers is a key like any other.
+	w.InjectKeyDown(ui.Key{Code: ui.KeyCodeTab, Rune: '\t', Modifiers: ui.KeyModifierControl})
+	if f := focused(); f != "a" {
+		t.Errorf("Control-Tab moved the focus to %s", f)
+	}
+	if e := takeEvents(w, a); !equalStrings(e[0], []string{"down \t"}) {
+		t.Errorf("a got %q for Control-Tab, want a key down", e[0])
+	}
+
+	// A removed view loses the focus, and Tab starts over.
+	w.Do(func() { a.SetParent(nil) })
+	if f := focused(); f != "none" {
+		t.Errorf("a removed view still has the focus")
+	}
+	w.InjectKeyDown(shiftTab)
+	if f := focused(); f != "d" {
+		t.Errorf("Shift-Tab without a focus focused %s, want the last view, d", f)
+	}
+}
+
+func TestClickToFocus(t *testing.T) {
+	root := newKeyView(nil, false)
+	a := newKeyView(root, true)
+	inner := newKeyView(a, false)
+	other := newKeyView(root, false)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 10}, root)
+	defer w.Close()
+	w.Do(func() {
+		a.Resize(ui.Size{Width: 5, Height: 10})
+		inner.Resize(ui.Size{Width: 5, Height: 5})
+		other.Move(ui.Position{X: 5})
+		other.Resize(ui.Size{Width: 5, Height: 10})
+	})
+
+	click := func(x, y float64) {
+		p := ui.Pointer{ID: 1, Type: ui.PointerTypeMouse, Button: ui.PointerButtonLeftMouse, Buttons: ui.PointerButtonLeftMouse}
+		p.Position = ui.Position{X: x, Y: y}
+		w.InjectPointerDown(p)
+		p.Buttons = ui.PointerButtonNone
+		w.InjectPointerUp(p)
+	}
+	var focused ui.View
+	// A click focuses the nearest focusable view at or above the one
+	// clicked.
+	click(2, 2)
+	w.Do(func() { focused = w.Focused() })
+	if focused != a {
+		t.Errorf("clicking a child of a focusable view focused %v, want the view", focused)
+	}
+	// Clicking a view that can't take the focus leaves it where it is.
+	click(7, 2)
+	w.Do(func() { focused = w.Focused() })
+	if focused != a {
+		t.Errorf("clicking an unfocusable view moved the focus to %v", focused)
+	}
+}
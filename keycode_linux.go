@@ -0,0 +1,139 @@
+// +build linux
+// +build !android
+
+package ui
+
+// evdevKeyCode converts a Linux input event key code, as used by evdev,
+// Wayland and (offset by 8) X11, into a KeyCode.
+func evdevKeyCode(code uint32) KeyCode {
+	if code < uint32(len(evdevKeyCodes)) {
+		return evdevKeyCodes[code]
+	}
+	return KeyCodeUnknown
+}
+
+// evdevKeyCodes is indexed by the KEY_* constants of linux/input-event-codes.h.
+var evdevKeyCodes = [...]KeyCode{
+	1:   KeyCodeEscape,
+	2:   KeyCode1,
+	3:   KeyCode2,
+	4:   KeyCode3,
+	5:   KeyCode4,
+	6:   KeyCode5,
+	7:   KeyCode6,
+	8:   KeyCode7,
+	9:   KeyCode8,
+	10:  KeyCode9,
+	11:  KeyCode0,
+	12:  KeyCodeHyphenMinus,
+	13:  KeyCodeEqualSign,
+	14:  KeyCodeDeleteBackspace,
+	15:  KeyCodeTab,
+	16:  KeyCodeQ,
+	17:  KeyCodeW,
+	18:  KeyCodeE,
+	19:  KeyCodeR,
+	20:  KeyCodeT,
+	21:  KeyCodeY,
+	22:  KeyCodeU,
+	23:  KeyCodeI,
+	24:  KeyCodeO,
+	25:  KeyCodeP,
+	26:  KeyCodeLeftSquareBracket,
+	27:  KeyCodeRightSquareBracket,
+	28:  KeyCodeReturnEnter,
+	29:  KeyCodeLeftControl,
+	30:  KeyCodeA,
+	31:  KeyCodeS,
+	32:  KeyCodeD,
+	33:  KeyCodeF,
+	34:  KeyCodeG,
+	35:  KeyCodeH,
+	36:  KeyCodeJ,
+	37:  KeyCodeK,
+	38:  KeyCodeL,
+	39:  KeyCodeSemicolon,
+	40:  KeyCodeApostrophe,
+	41:  KeyCodeGraveAccent,
+	42:  KeyCodeLeftShift,
+	43:  KeyCodeBackslash,
+	44:  KeyCodeZ,
+	45:  KeyCodeX,
+	46:  KeyCodeC,
+	47:  KeyCodeV,
+	48:  KeyCodeB,
+	49:  KeyCodeN,
+	50:  KeyCodeM,
+	51:  KeyCodeComma,
+	52:  KeyCodeFullStop,
+	53:  KeyCodeSlash,
+	54:  KeyCodeRightShift,
+	55:  KeyCodeKeypadAsterisk,
+	56:  KeyCodeLeftAlt,
+	57:  KeyCodeSpacebar,
+	58:  KeyCodeCapsLock,
+	59:  KeyCodeF1,
+	60:  KeyCodeF2,
+	61:  KeyCodeF3,
+	62:  KeyCodeF4,
+	63:  KeyCodeF5,
+	64:  KeyCodeF6,
+	65:  KeyCodeF7,
+	66:  KeyCodeF8,
+	67:  KeyCodeF9,
+	68:  KeyCodeF10,
+	69:  KeyCodeKeypadNumLock,
+	70:  KeyCodeScrollLock,
+	71:  KeyCodeKeypad7,
+	72:  KeyCodeKeypad8,
+	73:  KeyCodeKeypad9,
+	74:  KeyCodeKeypadHyphenMinus,
+	75:  KeyCodeKeypad4,
+	76:  KeyCodeKeypad5,
+	77:  KeyCodeKeypad6,
+	78:  KeyCodeKeypadPlusSign,
+	79:  KeyCodeKeypad1,
+	80:  KeyCodeKeypad2,
+	81:  KeyCodeKeypad3,
+	82:  KeyCodeKeypad0,
+	83:  KeyCodeKeypadFullStop,
+	86:  KeyCodeNonUSBackslash,
+	87:  KeyCodeF11,
+	88:  KeyCodeF12,
+	96:  KeyCodeKeypadEnter,
+	97:  KeyCodeRightControl,
+	98:  KeyCodeKeypadSlash,
+	99:  KeyCodePrintScreen,
+	100: KeyCodeRightAlt,
+	102: KeyCodeHome,
+	103: KeyCodeUpArrow,
+	104: KeyCodePageUp,
+	105: KeyCodeLeftArrow,
+	106: KeyCodeRightArrow,
+	107: KeyCodeEnd,
+	108: KeyCodeDownArrow,
+	109: KeyCodePageDown,
+	110: KeyCodeInsert,
+	111: KeyCodeDeleteForward,
+	113: KeyCodeMute,
+	114: KeyCodeVolumeDown,
+	115: KeyCodeVolumeUp,
+	117: KeyCodeKeypadEqualSign,
+	119: KeyCodePause,
+	125: KeyCodeLeftGUI,
+	126: KeyCodeRightGUI,
+	127: KeyCodeApplication,
+	138: KeyCodeHelp,
+	183: KeyCodeF13,
+	184: KeyCodeF14,
+	185: KeyCodeF15,
+	186: KeyCodeF16,
+	187: KeyCodeF17,
+	188: KeyCodeF18,
+	189: KeyCodeF19,
+	190: KeyCodeF20,
+	191: KeyCodeF21,
+	192: KeyCodeF22,
+	193: KeyCodeF23,
+	194: KeyCodeF24,
+}
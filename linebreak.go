@@ -0,0 +1,361 @@
+package ui
+
+//go:generate go run linebreak_gen.go
+
+import (
+	"sort"
+	"unicode"
+)
+
+// A breakClass is a Line_Break property value of UAX #14.
+type breakClass uint8
+
+const (
+	lbXX breakClass = iota
+	lbAI
+	lbAK
+	lbAL
+	lbAP
+	lbAS
+	lbB2
+	lbBA
+	lbBB
+	lbBK
+	lbCB
+	lbCJ
+	lbCL
+	lbCM
+	lbCP
+	lbCR
+	lbEB
+	lbEM
+	lbEX
+	lbGL
+	lbH2
+	lbH3
+	lbHH
+	lbHL
+	lbHY
+	lbID
+	lbIN
+	lbIS
+	lbJL
+	lbJT
+	lbJV
+	lbLF
+	lbNL
+	lbNS
+	lbNU
+	lbOP
+	lbPO
+	lbPR
+	lbQU
+	lbRI
+	lbSA
+	lbSG
+	lbSP
+	lbSY
+	lbVF
+	lbVI
+	lbWJ
+	lbZW
+	lbZWJ
+)
+
+type lineBreakRange struct {
+	lo, hi rune
+	class  breakClass
+}
+
+// lineBreakClass returns the Line_Break class of r.
+func lineBreakClass(r rune) breakClass {
+	i := sort.Search(len(lineBreakRanges), func(i int) bool { return lineBreakRanges[i].hi >= r })
+	if i < len(lineBreakRanges) && lineBreakRanges[i].lo <= r {
+		return lineBreakRanges[i].class
+	}
+	return lbXX
+}
+
+// resolvedBreakClass returns the class of r after rule LB1, which assigns
+// the classes that the rules leave to tailoring.
+func resolvedBreakClass(r rune) breakClass {
+	switch c := lineBreakClass(r); c {
+	case lbAI, lbSG, lbXX:
+		return lbAL
+	case lbSA:
+		if unicode.Is(complexContextMarks, r) {
+			return lbCM
+		}
+		return lbAL
+	case lbCJ:
+		return lbNS
+	default:
+		return c
+	}
+}
+
+// A lineBreak is the kind of line break allowed after a character.
+type lineBreak uint8
+
+const (
+	breakProhibited lineBreak = iota
+	breakAllowed
+	breakMandatory
+)
+
+// A breakUnit is a character with the combining marks that rule LB9
+// attaches to it.
+type breakUnit struct {
+	class breakClass
+	// r is the character, whose properties the unit has.
+	r rune
+	// end is the index of the unit's last character.
+	end int
+}
+
+// lineBreaks returns the kind of line break after each of runes, following
+// the Unicode Line Breaking Algorithm (UAX #14) without tailoring.  Breaks
+// are mandatory after the last character (LB3) and after line separators
+// such as a carriage return.
+func lineBreaks(runes []rune) []lineBreak {
+	breaks := make([]lineBreak, len(runes))
+	if len(runes) == 0 {
+		return breaks
+	}
+	classes := make([]breakClass, len(runes))
+	for i, r := range runes {
+		classes[i] = resolvedBreakClass(r)
+	}
+
+	// Rule LB9 treats a character followed by combining marks as the
+	// character, unless it is a space or line break, in which case LB10
+	// treats the marks as letters.
+	var units []breakUnit
+	for i, c := range classes {
+		if c == lbCM || c == lbZWJ {
+			if n := len(units); n > 0 && units[n-1].end == i-1 && !breakOrSpace(units[n-1].class) {
+				units[n-1].end = i
+				continue
+			}
+			c = lbAL
+		}
+		units = append(units, breakUnit{class: c, r: runes[i]})
+		units[len(units)-1].end = i
+	}
+
+	u := 0
+	for i := range runes[:len(runes)-1] {
+		a, b := classes[i], classes[i+1]
+		switch {
+		case a == lbBK, a == lbCR && b != lbLF, a == lbLF, a == lbNL: // LB4, LB5
+			breaks[i] = breakMandatory
+		case a == lbCR, b == lbBK, b == lbCR, b == lbLF, b == lbNL: // LB5, LB6
+		case b == lbSP, b == lbZW: // LB7
+		case afterZW(classes, i): // LB8
+			breaks[i] = breakAllowed
+		case a == lbZWJ: // LB8a
+		case units[u].end != i: // LB9
+		default:
+			if unitBreak(units, u) {
+				breaks[i] = breakAllowed
+			}
+		}
+		if units[u].end == i {
+			u++
+		}
+	}
+	breaks[len(runes)-1] = breakMandatory
+	return breaks
+}
+
+func breakOrSpace(c breakClass) bool {
+	switch c {
+	case lbBK, lbCR, lbLF, lbNL, lbSP, lbZW:
+		return true
+	}
+	return false
+}
+
+// afterZW reports whether the character at i is a zero width space followed
+// by spaces.
+func afterZW(classes []breakClass, i int) bool {
+	for ; i >= 0 && classes[i] == lbSP; i-- {
+	}
+	return i >= 0 && classes[i] == lbZW
+}
+
+// unitBreak reports whether a line may break between units k and k+1,
+// following rules LB11 to LB31.
+func unitBreak(units []breakUnit, k int) bool {
+	a, b := units[k].class, units[k+1].class
+	// class returns the class of the unit at i, or XX if there is none,
+	// which is at the start or end of the text.
+	class := func(i int) breakClass {
+		if i < 0 || i >= len(units) {
+			return lbXX
+		}
+		return units[i].class
+	}
+	// eastAsian reports whether the unit at i is wide East Asian.
+	eastAsian := func(i int) bool {
+		return i >= 0 && i < len(units) && unicode.Is(eastAsianWide, units[i].r)
+	}
+	// beforeSpaces is the class of the last unit up to k that is not a
+	// space, and j its index.
+	j := k
+	for j >= 0 && units[j].class == lbSP {
+		j--
+	}
+	beforeSpaces := class(j)
+
+	switch {
+	case a == lbWJ, b == lbWJ: // LB11
+		return false
+	case a == lbGL: // LB12
+		return false
+	case b == lbGL && a != lbSP && a != lbBA && a != lbHY && a != lbHH: // LB12a
+		return false
+	case b == lbCL, b == lbCP, b == lbEX, b == lbSY: // LB13
+		return false
+	case beforeSpaces == lbOP: // LB14
+		return false
+	case beforeSpaces == lbQU && unicode.Is(initialQuotes, units[j].r) && (j == 0 || in(class(j-1), lbBK, lbCR, lbLF, lbNL, lbOP, lbQU, lbGL, lbSP, lbZW)): // LB15a
+		return false
+	case b == lbQU && unicode.Is(finalQuotes, units[k+1].r) && (k+2 == len(units) || in(class(k+2), lbSP, lbGL, lbWJ, lbCL, lbQU, lbCP, lbEX, lbIS, lbSY, lbBK, lbCR, lbLF, lbNL, lbZW)): // LB15b
+		return false
+	case a == lbSP && b == lbIS && class(k+2) == lbNU: // LB15c
+		return true
+	case b == lbIS: // LB15d
+		return false
+	case (beforeSpaces == lbCL || beforeSpaces == lbCP) && b == lbNS: // LB16
+		return false
+	case beforeSpaces == lbB2 && b == lbB2: // LB17
+		return false
+	case a == lbSP: // LB18
+		return true
+	case b == lbQU && !unicode.Is(initialQuotes, units[k+1].r), a == lbQU && !unicode.Is(finalQuotes, units[k].r): // LB19
+		return false
+	case b == lbQU && (!eastAsian(k) || !eastAsian(k+2)), a == lbQU && (!eastAsian(k+1) || !eastAsian(k-1)): // LB19a
+		return false
+	case a == lbCB, b == lbCB: // LB20
+		return true
+	case (a == lbHY || a == lbHH) && (b == lbAL || b == lbHL) && (k == 0 || in(class(k-1), lbBK, lbCR, lbLF, lbNL, lbSP, lbZW, lbCB, lbGL)): // LB20a
+		return false
+	case b == lbBA, b == lbHH, b == lbHY, b == lbNS, a == lbBB: // LB21
+		return false
+	case class(k-1) == lbHL && (a == lbHY || a == lbHH) && b != lbHL: // LB21a
+		return false
+	case a == lbSY && b == lbHL: // LB21b
+		return false
+	case b == lbIN: // LB22
+		return false
+	case (a == lbAL || a == lbHL) && b == lbNU, a == lbNU && (b == lbAL || b == lbHL): // LB23
+		return false
+	case a == lbPR && in(b, lbID, lbEB, lbEM), in(a, lbID, lbEB, lbEM) && b == lbPO: // LB23a
+		return false
+	case (a == lbPR || a == lbPO) && (b == lbAL || b == lbHL), (a == lbAL || a == lbHL) && (b == lbPR || b == lbPO): // LB24
+		return false
+	case numberBreak(units, k): // LB25
+		return false
+	case a == lbJL && in(b, lbJL, lbJV, lbH2, lbH3), (a == lbJV || a == lbH2) && (b == lbJV || b == lbJT), (a == lbJT || a == lbH3) && b == lbJT: // LB26
+		return false
+	case in(a, lbJL, lbJV, lbJT, lbH2, lbH3) && b == lbPO, a == lbPR && in(b, lbJL, lbJV, lbJT, lbH2, lbH3): // LB27
+		return false
+	case (a == lbAL || a == lbHL) && (b == lbAL || b == lbHL): // LB28
+		return false
+	case aksaraBreak(units, k): // LB28a
+		return false
+	case a == lbIS && (b == lbAL || b == lbHL): // LB29
+		return false
+	case in(a, lbAL, lbHL, lbNU) && b == lbOP && !eastAsian(k+1), a == lbCP && !eastAsian(k) && in(b, lbAL, lbHL, lbNU): // LB30
+		return false
+	case a == lbRI && b == lbRI: // LB30a
+		n := 0
+		for i := k; i >= 0 && units[i].class == lbRI; i-- {
+			n++
+		}
+		return n%2 == 0
+	case b == lbEM && (a == lbEB || unicode.Is(unassignedPictographic, units[k].r)): // LB30b
+		return false
+	}
+	return true // LB31
+}
+
+func in(c breakClass, cs ...breakClass) bool {
+	for _, d := range cs {
+		if c == d {
+			return true
+		}
+	}
+	return false
+}
+
+// numberBreak reports whether rule LB25 keeps the units of a number
+// together between k and k+1.
+func numberBreak(units []breakUnit, k int) bool {
+	a, b := units[k].class, units[k+1].class
+	next := lbXX
+	if k+2 < len(units) {
+		next = units[k+2].class
+	}
+	// number reports whether the units up to i end NU (NU | SY | IS)*.
+	number := func(i int) bool {
+		for ; i >= 0 && in(units[i].class, lbNU, lbSY, lbIS); i-- {
+			if units[i].class == lbNU {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case (a == lbPR || a == lbPO) && (b == lbNU || (b == lbOP || b == lbHY) && next == lbNU):
+		return true
+	case in(a, lbOP, lbHY, lbIS) && b == lbNU:
+		return true
+	case number(k) && in(b, lbNU, lbSY, lbIS, lbCL, lbCP):
+		return true
+	case b == lbPO || b == lbPR:
+		if a == lbCL || a == lbCP {
+			return number(k - 1)
+		}
+		return number(k)
+	}
+	return false
+}
+
+// aksaraBreak reports whether rule LB28a keeps the units of an orthographic
+// syllable of a Brahmic script together between k and k+1.
+func aksaraBreak(units []breakUnit, k int) bool {
+	// aksara reports whether the unit at i is AK, AS or a dotted circle.
+	aksara := func(i int) bool {
+		return i >= 0 && i < len(units) && (units[i].class == lbAK || units[i].class == lbAS || units[i].r == '◌')
+	}
+	a, b := units[k].class, units[k+1].class
+	switch {
+	case a == lbAP && aksara(k+1):
+		return true
+	case aksara(k) && (b == lbVF || b == lbVI):
+		return true
+	case aksara(k-1) && a == lbVI && (b == lbAK || units[k+1].r == '◌'):
+		return true
+	case aksara(k) && aksara(k+1) && k+2 < len(units) && units[k+2].class == lbVF:
+		return true
+	}
+	return false
+}
+
+// breakComplexContext allows breaks in text of class SA, such as Thai, which
+// is written without spaces between words.  Finding the words would take a
+// dictionary, so breaks are allowed before the vowels of Thai and Lao that
+// are written before the consonant that starts a syllable.
+func breakComplexContext(runes []rune, breaks []lineBreak) {
+	for i := 1; i < len(runes); i++ {
+		if leadingVowel(runes[i]) && !leadingVowel(runes[i-1]) && lineBreakClass(runes[i-1]) == lbSA && breaks[i-1] == breakProhibited {
+			breaks[i-1] = breakAllowed
+		}
+	}
+}
+
+func leadingVowel(r rune) bool {
+	return r >= 0x0E40 && r <= 0x0E44 || r >= 0x0EC0 && r <= 0x0EC4
+}
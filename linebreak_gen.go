@@ -0,0 +1,217 @@
+// +build ignore
+
+// This program generates linebreak_tables.go from the Unicode Character
+// Database.  Download LineBreak.txt, EastAsianWidth.txt,
+// extracted/DerivedGeneralCategory.txt and emoji/emoji-data.txt from
+// https://www.unicode.org/Public/17.0.0/ucd/ into one directory and run
+//
+//	go run linebreak_gen.go -ucd dir
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ucd     = flag.String("ucd", ".", "directory holding the Unicode Character Database files")
+	version = flag.String("version", "17.0.0", "Unicode version of the files")
+	output  = flag.String("o", "linebreak_tables.go", "output file")
+)
+
+// classes are the Line_Break classes in the order of their constants.
+var classes = []string{
+	"XX", "AI", "AK", "AL", "AP", "AS", "B2", "BA", "BB", "BK", "CB", "CJ", "CL", "CM", "CP", "CR",
+	"EB", "EM", "EX", "GL", "H2", "H3", "HH", "HL", "HY", "ID", "IN", "IS", "JL", "JT", "JV",
+	"LF", "NL", "NS", "NU", "OP", "PO", "PR", "QU", "RI", "SA", "SG", "SP", "SY", "VF", "VI",
+	"WJ", "ZW", "ZWJ",
+}
+
+func main() {
+	flag.Parse()
+	lb := read("LineBreak.txt")
+	ea := read("EastAsianWidth.txt")
+	gc := read("DerivedGeneralCategory.txt")
+	emoji := read("emoji-data.txt")
+
+	known := map[string]bool{}
+	for _, c := range classes {
+		known[c] = true
+	}
+	for _, c := range lb {
+		if !known[c] {
+			log.Fatalf("unknown line break class %s", c)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by linebreak_gen.go from Unicode %s. DO NOT EDIT.\n\n", *version)
+	fmt.Fprintf(&b, "package ui\n\nimport \"unicode\"\n\n")
+	fmt.Fprintf(&b, "// lineBreakRanges are the ranges of characters of each Line_Break class\n")
+	fmt.Fprintf(&b, "// other than XX, in order.\n")
+	fmt.Fprintf(&b, "var lineBreakRanges = []lineBreakRange{\n")
+	for _, r := range ranges(lb, func(v string) bool { return v != "XX" }) {
+		fmt.Fprintf(&b, "\t{0x%04X, 0x%04X, lb%s},\n", r.lo, r.hi, lb[r.lo])
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	table(&b, "eastAsianWide", "holds the characters whose East_Asian_Width is F, W or H.",
+		ranges(ea, func(v string) bool { return v == "F" || v == "W" || v == "H" }))
+	table(&b, "initialQuotes", "holds the quotation marks (QU) that are initial\n// punctuation (Pi).",
+		ranges(lb, func(r rune) bool { return lb[r] == "QU" && gc[r] == "Pi" }))
+	table(&b, "finalQuotes", "holds the quotation marks (QU) that are final\n// punctuation (Pf).",
+		ranges(lb, func(r rune) bool { return lb[r] == "QU" && gc[r] == "Pf" }))
+	table(&b, "complexContextMarks", "holds the characters of class SA that are marks\n// (Mn or Mc).",
+		ranges(lb, func(r rune) bool { return lb[r] == "SA" && (gc[r] == "Mn" || gc[r] == "Mc") }))
+	table(&b, "unassignedPictographic", "holds the unassigned characters (Cn) that are\n// Extended_Pictographic.",
+		ranges(emoji, func(r rune) bool { return emoji[r] == "Extended_Pictographic" && gc[r] == "" }))
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*output, src, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// read returns the values of the characters listed in a file of the
+// database.  Each line of such a file holds a character or a range of them,
+// a semicolon and a value.  Lines marked @missing give the value of
+// characters that are not listed.
+func read(name string) map[rune]string {
+	f, err := os.Open(filepath.Join(*ucd, name))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	values := map[rune]string{}
+	missing := map[rune]string{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		m := values
+		if strings.HasPrefix(line, "# @missing:") {
+			line = strings.TrimPrefix(line, "# @missing:")
+			m = missing
+		} else if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) < 2 {
+			continue
+		}
+		lo, hi := parseRange(strings.TrimSpace(fields[0]))
+		v := strings.TrimSpace(fields[1])
+		for r := lo; r <= hi; r++ {
+			m[r] = v
+		}
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+	for r, v := range missing {
+		if _, ok := values[r]; !ok && v != "Unassigned" && v != "Cn" && v != "No" && v != "N" {
+			values[r] = v
+		}
+	}
+	return values
+}
+
+func parseRange(s string) (lo, hi rune) {
+	parse := func(s string) rune {
+		x, err := strconv.ParseUint(s, 16, 32)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return rune(x)
+	}
+	if i := strings.Index(s, ".."); i >= 0 {
+		return parse(s[:i]), parse(s[i+2:])
+	}
+	lo = parse(s)
+	return lo, lo
+}
+
+type runeRange struct{ lo, hi rune }
+
+// ranges returns the ranges of characters for which in is true, where in
+// takes either a character or its value in m, and consecutive characters in
+// a range have the same value in m.
+func ranges(m map[rune]string, in interface{}) []runeRange {
+	test := func(r rune) bool {
+		switch in := in.(type) {
+		case func(string) bool:
+			v, ok := m[r]
+			return ok && in(v)
+		case func(rune) bool:
+			return in(r)
+		}
+		panic("bad predicate")
+	}
+	var rs []runeRange
+	for r := rune(0); r <= 0x10FFFF; r++ {
+		if !test(r) {
+			continue
+		}
+		if n := len(rs); n > 0 && rs[n-1].hi == r-1 && m[r] == m[r-1] {
+			rs[n-1].hi = r
+		} else {
+			rs = append(rs, runeRange{r, r})
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].lo < rs[j].lo })
+	return rs
+}
+
+// table writes a unicode.RangeTable holding rs.
+func table(b *bytes.Buffer, name, doc string, rs []runeRange) {
+	fmt.Fprintf(b, "// %s %s\nvar %s = &unicode.RangeTable{\n", name, doc, name)
+	var r16, r32 []runeRange
+	for _, r := range rs {
+		if r.hi <= 0xFFFF {
+			r16 = append(r16, r)
+		} else if r.lo > 0xFFFF {
+			r32 = append(r32, r)
+		} else {
+			r16 = append(r16, runeRange{r.lo, 0xFFFF})
+			r32 = append(r32, runeRange{0x10000, r.hi})
+		}
+	}
+	if len(r16) > 0 {
+		fmt.Fprintf(b, "\tR16: []unicode.Range16{\n")
+		for _, r := range r16 {
+			fmt.Fprintf(b, "\t\t{0x%04X, 0x%04X, 1},\n", r.lo, r.hi)
+		}
+		fmt.Fprintf(b, "\t},\n")
+	}
+	if len(r32) > 0 {
+		fmt.Fprintf(b, "\tR32: []unicode.Range32{\n")
+		for _, r := range r32 {
+			fmt.Fprintf(b, "\t\t{0x%04X, 0x%04X, 1},\n", r.lo, r.hi)
+		}
+		fmt.Fprintf(b, "\t},\n")
+	}
+	latin := 0
+	for _, r := range r16 {
+		if r.hi <= unicode.MaxLatin1 {
+			latin++
+		}
+	}
+	if latin > 0 {
+		fmt.Fprintf(b, "\tLatinOffset: %d,\n", latin)
+	}
+	fmt.Fprintf(b, "}\n\n")
+}
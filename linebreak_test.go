@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestLineBreakTest checks the break opportunities of the samples of the
+// Unicode conformance test in testdata.
+func TestLineBreakTest(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "LineBreakTest.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<16)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		// The fields alternate between break opportunities, × for none and
+		// ÷ for one, and characters, starting with the opportunity before
+		// the first character, which is always ×.
+		var runes []rune
+		var want []string
+		for i, x := range fields {
+			if i%2 == 0 {
+				if i > 0 {
+					want = append(want, x)
+				}
+				continue
+			}
+			r, err := strconv.ParseUint(x, 16, 32)
+			if err != nil {
+				t.Fatalf("line %d: %v", n, err)
+			}
+			runes = append(runes, rune(r))
+		}
+		var got []string
+		for _, b := range lineBreaks(runes) {
+			if b == breakProhibited {
+				got = append(got, "×")
+			} else {
+				got = append(got, "÷")
+			}
+		}
+		if g, w := strings.Join(got, " "), strings.Join(want, " "); g != w {
+			t.Errorf("line %d: %s: got breaks %s, want %s", n, strings.Join(fields[1:], " "), g, w)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTextLayoutLineBreaks(t *testing.T) {
+	font := bidiFont(t)
+	for _, test := range []struct {
+		text  string
+		width float64
+		lines []string
+	}{
+		{"ab-cd", 15, []string{"ab-", "cd"}},
+		// A hyphen before a number is a minus sign.
+		{"ab -12", 15, []string{"ab ", "-12"}},
+		{"(ab) cd", 25, []string{"(ab) ", "cd"}},
+		{"中文字", 10, []string{"中文", "字"}},
+		// Lines end after a line separator, even if they are not wrapped.
+		{"ab\u2028cd", 0, []string{"ab\u2028", "cd"}},
+		// Thai breaks before vowels that start a syllable.
+		{"กาเกา", 15, []string{"กา", "เกา"}},
+	} {
+		l := NewTextLayout(test.text, font, 10)
+		l.SetWidth(test.width)
+		l.layout()
+		if got := lineText(l); !equalLines(got, test.lines) {
+			t.Errorf("%q at width %g: got lines %q, want %q", test.text, test.width, got, test.lines)
+		}
+	}
+}
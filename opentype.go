@@ -0,0 +1,1087 @@
+package ui
+
+import (
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// otData is part of an OpenType table.  Reads outside it return zero, so
+// that malformed fonts are shaped badly rather than crashing.
+type otData []byte
+
+func (d otData) u16(i int) uint16 {
+	if i < 0 || i+2 > len(d) {
+		return 0
+	}
+	return uint16(d[i])<<8 | uint16(d[i+1])
+}
+
+func (d otData) i16(i int) int16 { return int16(d.u16(i)) }
+
+func (d otData) u32(i int) uint32 {
+	return uint32(d.u16(i))<<16 | uint32(d.u16(i+2))
+}
+
+// at returns the data at an offset from the start of d.  An offset of zero
+// is null.
+func (d otData) at(offset int) otData {
+	if offset <= 0 || offset >= len(d) {
+		return nil
+	}
+	return d[offset:]
+}
+
+// atU16 returns the data at the 16-bit offset stored at i.
+func (d otData) atU16(i int) otData { return d.at(int(d.u16(i))) }
+
+// coverage returns the index of g in a coverage table, or -1 if it is not
+// covered.
+func (d otData) coverage(g sfnt.GlyphIndex) int {
+	n := int(d.u16(2))
+	switch d.u16(0) {
+	case 1:
+		i := sort.Search(n, func(i int) bool { return d.u16(4+2*i) >= uint16(g) })
+		if i < n && d.u16(4+2*i) == uint16(g) {
+			return i
+		}
+	case 2:
+		i := sort.Search(n, func(i int) bool { return d.u16(4+6*i+2) >= uint16(g) })
+		if r := 4 + 6*i; i < n && d.u16(r) <= uint16(g) {
+			return int(d.u16(r+4)) + int(g) - int(d.u16(r))
+		}
+	}
+	return -1
+}
+
+// class returns the class of g in a class definition table.
+func (d otData) class(g sfnt.GlyphIndex) uint16 {
+	switch d.u16(0) {
+	case 1:
+		start, n := d.u16(2), d.u16(4)
+		if uint16(g) >= start && uint16(g)-start < n {
+			return d.u16(6 + 2*int(uint16(g)-start))
+		}
+	case 2:
+		n := int(d.u16(2))
+		i := sort.Search(n, func(i int) bool { return d.u16(4+6*i+2) >= uint16(g) })
+		if r := 4 + 6*i; i < n && d.u16(r) <= uint16(g) {
+			return d.u16(r + 4)
+		}
+	}
+	return 0
+}
+
+// otTables are the OpenType tables that sfnt doesn't read:  those of
+// glyph substitution and positioning (GSUB, GPOS and GDEF) and of color
+// glyphs (COLR and CPAL).
+type otTables struct {
+	gsub, gpos otLayout
+	gdef       otData
+	colr, cpal otData
+	// plans caches the shaping plans of scripts.
+	plans map[*otScript]*otPlan
+}
+
+// parseOTTables finds the tables in the data of a font.
+func parseOTTables(data []byte) *otTables {
+	d := otData(data)
+	tables := map[string]otData{}
+	for i, n := 0, int(d.u16(4)); i < n; i++ {
+		r := 12 + 16*i
+		offset, length := int(d.u32(r+8)), int(d.u32(r+12))
+		if offset < 0 || length < 0 || offset > len(d) || length > len(d)-offset {
+			continue
+		}
+		tables[string(d[r:r+4])] = d[offset : offset+length]
+	}
+	return &otTables{
+		gsub:  newOTLayout(tables["GSUB"], otLookupExtensionSubst),
+		gpos:  newOTLayout(tables["GPOS"], otLookupExtensionPos),
+		gdef:  tables["GDEF"],
+		colr:  tables["COLR"],
+		cpal:  tables["CPAL"],
+		plans: map[*otScript]*otPlan{},
+	}
+}
+
+// An otLayout is a GSUB or GPOS table.
+type otLayout struct {
+	scripts, features otData
+	lookups           []otLookup
+}
+
+// An otLookup is a lookup of a GSUB or GPOS table, with any extension
+// subtables resolved.
+type otLookup struct {
+	kind, flag uint16
+	// filter is the mark glyph set used if flag has lookupUseMarkFilteringSet.
+	filter    uint16
+	subtables []otData
+}
+
+// Lookup flags.
+const (
+	lookupRightToLeft         = 0x0001
+	lookupIgnoreBaseGlyphs    = 0x0002
+	lookupIgnoreLigatures     = 0x0004
+	lookupIgnoreMarks         = 0x0008
+	lookupUseMarkFilteringSet = 0x0010
+	lookupMarkAttachmentType  = 0xFF00
+)
+
+// Lookup types.
+const (
+	otLookupSingleSubst       = 1
+	otLookupMultipleSubst     = 2
+	otLookupAlternateSubst    = 3
+	otLookupLigatureSubst     = 4
+	otLookupContextSubst      = 5
+	otLookupChainContextSubst = 6
+	otLookupExtensionSubst    = 7
+	otLookupReverseChainSubst = 8
+	otLookupSinglePos         = 1
+	otLookupPairPos           = 2
+	otLookupCursivePos        = 3
+	otLookupMarkBasePos       = 4
+	otLookupMarkLigaturePos   = 5
+	otLookupMarkMarkPos       = 6
+	otLookupContextPos        = 7
+	otLookupChainContextPos   = 8
+	otLookupExtensionPos      = 9
+)
+
+func newOTLayout(d otData, extension uint16) otLayout {
+	// Only versions 1.0 and 1.1 are known; 1.1 adds feature variations,
+	// which are ignored.
+	if d.u16(0) != 1 {
+		return otLayout{}
+	}
+	l := otLayout{scripts: d.atU16(4), features: d.atU16(6)}
+	list := d.atU16(8)
+	for i, n := 0, int(list.u16(0)); i < n; i++ {
+		t := list.atU16(2 + 2*i)
+		lookup := otLookup{kind: t.u16(0), flag: t.u16(2)}
+		count := int(t.u16(4))
+		if lookup.flag&lookupUseMarkFilteringSet != 0 {
+			lookup.filter = t.u16(6 + 2*count)
+		}
+		for j := 0; j < count; j++ {
+			s := t.atU16(6 + 2*j)
+			if lookup.kind == extension && s.u16(0) == 1 {
+				lookup.kind = s.u16(2)
+				s = s.at(int(s.u32(4)))
+			}
+			lookup.subtables = append(lookup.subtables, s)
+		}
+		l.lookups = append(l.lookups, lookup)
+	}
+	return l
+}
+
+// langSys returns the default language system of the first of a script's
+// tags that the table has.
+func (l *otLayout) langSys(tags []string) otData {
+	for _, tag := range tags {
+		for i, n := 0, int(l.scripts.u16(0)); i < n; i++ {
+			r := 2 + 6*i
+			if string(l.scripts[r:r+4]) == tag {
+				return l.scripts.atU16(r + 4).atU16(0)
+			}
+		}
+	}
+	return nil
+}
+
+// featureLookups returns the lookups of each feature of a language system,
+// by tag.  Those of its required feature are under the empty tag.
+func (l *otLayout) featureLookups(langSys otData) map[string][]int {
+	lookups := map[string][]int{}
+	add := func(tag string, index int) {
+		r := 2 + 6*index
+		if index >= int(l.features.u16(0)) {
+			return
+		}
+		f := l.features.atU16(r + 4)
+		for i, n := 0, int(f.u16(2)); i < n; i++ {
+			lookups[tag] = append(lookups[tag], int(f.u16(4+2*i)))
+		}
+	}
+	if langSys == nil {
+		return lookups
+	}
+	if i := langSys.u16(2); i != 0xFFFF {
+		add("", int(i))
+	}
+	for i, n := 0, int(langSys.u16(4)); i < n; i++ {
+		index := int(langSys.u16(6 + 2*i))
+		r := 2 + 6*index
+		if r+4 <= len(l.features) {
+			add(string(l.features[r:r+4]), index)
+		}
+	}
+	return lookups
+}
+
+// GDEF glyph classes.
+const (
+	glyphClassBase     = 1
+	glyphClassLigature = 2
+	glyphClassMark     = 3
+)
+
+// glyphClass returns the GDEF class of g, or 0 if it has none.
+func (t *otTables) glyphClass(g sfnt.GlyphIndex) uint16 {
+	return t.gdef.atU16(4).class(g)
+}
+
+func (t *otTables) markAttachClass(g sfnt.GlyphIndex) uint16 {
+	return t.gdef.atU16(10).class(g)
+}
+
+// inMarkGlyphSet reports whether g is in a GDEF mark glyph set.
+func (t *otTables) inMarkGlyphSet(set uint16, g sfnt.GlyphIndex) bool {
+	if t.gdef.u32(0) < 0x10002 {
+		return false
+	}
+	sets := t.gdef.atU16(12)
+	if int(set) >= int(sets.u16(2)) {
+		return false
+	}
+	return sets.at(int(sets.u32(4+4*int(set)))).coverage(g) >= 0
+}
+
+// An otGlyph is a glyph being shaped.
+type otGlyph struct {
+	g sfnt.GlyphIndex
+	// r is the character the glyph was mapped from.
+	r rune
+	// cluster is the index of the glyph's cluster in the text.
+	cluster int
+	// mask holds the features that apply to the glyph.
+	mask             uint32
+	class, markClass uint16
+	// A ligature has a ligature ID unique in the text and its number of
+	// components in ligComp.  A mark that was skipped over when it was
+	// formed has its ID and the number of the component it follows.
+	ligID, ligComp int
+	// substituted is set when a substitution produces the glyph.
+	substituted bool
+
+	// The advance and offset of the glyph, in the units of the text's size.
+	xAdvance, yAdvance, xOffset, yOffset float64
+	// attach is the index of the glyph that this one is attached to, or -1.
+	// Marks are offset from it and cursive attachments only vertically.
+	attach  int
+	cursive bool
+}
+
+// An otApplier applies the lookups of a GSUB or GPOS table to glyphs.
+type otApplier struct {
+	tables *otTables
+	layout *otLayout
+	pos    bool
+	buf    []otGlyph
+	// scale converts font units to the units of the text's size.
+	scale float64
+	// rtl is set for right-to-left text.
+	rtl bool
+	// joined records ranges of clusters that are merged.
+	joined [][2]int
+	// nextLigID is the ID of the next ligature formed.
+	nextLigID int
+
+	// The flag, mark filter set and mask of the lookup being applied.
+	flag, filter uint16
+	mask         uint32
+}
+
+// setClass sets the GDEF classes of the glyph at i.  Without GDEF, marks
+// are told from other glyphs by their characters.
+func (a *otApplier) setClass(i int) {
+	g := &a.buf[i]
+	if a.tables.gdef == nil {
+		g.class = glyphClassBase
+		if isMark(g.r) {
+			g.class = glyphClassMark
+		}
+		return
+	}
+	g.class = a.tables.glyphClass(g.g)
+	g.markClass = a.tables.markAttachClass(g.g)
+}
+
+// skip reports whether the current lookup skips the glyph at i.
+func (a *otApplier) skip(i int) bool {
+	g := &a.buf[i]
+	switch g.class {
+	case glyphClassBase:
+		return a.flag&lookupIgnoreBaseGlyphs != 0
+	case glyphClassLigature:
+		return a.flag&lookupIgnoreLigatures != 0
+	case glyphClassMark:
+		if a.flag&lookupIgnoreMarks != 0 {
+			return true
+		}
+		if a.flag&lookupUseMarkFilteringSet != 0 {
+			return !a.tables.inMarkGlyphSet(a.filter, g.g)
+		}
+		if t := a.flag & lookupMarkAttachmentType >> 8; t != 0 {
+			return g.markClass != t
+		}
+	}
+	return false
+}
+
+// next returns the index of the next glyph after i that the lookup doesn't
+// skip, or -1.
+func (a *otApplier) next(i int) int {
+	for i++; i < len(a.buf); i++ {
+		if !a.skip(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// prev returns the index of the previous glyph before i that the lookup
+// doesn't skip, or -1.
+func (a *otApplier) prev(i int) int {
+	for i--; i >= 0; i-- {
+		if !a.skip(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchInput returns the positions of the n glyphs of an input sequence
+// starting at i, if match accepts the glyph at each index after the first.
+// Input glyphs must have the lookup's mask when substituting.
+func (a *otApplier) matchInput(i, n int, match func(k int, g sfnt.GlyphIndex) bool) ([]int, bool) {
+	pos := []int{i}
+	for k := 1; k < n; k++ {
+		i = a.next(i)
+		if i < 0 || !match(k, a.buf[i].g) || !a.pos && a.buf[i].mask&a.mask == 0 {
+			return nil, false
+		}
+		pos = append(pos, i)
+	}
+	return pos, true
+}
+
+// matchBacktrack reports whether the n glyphs before i are accepted by
+// match, nearest first.
+func (a *otApplier) matchBacktrack(i, n int, match func(k int, g sfnt.GlyphIndex) bool) bool {
+	for k := 0; k < n; k++ {
+		if i = a.prev(i); i < 0 || !match(k, a.buf[i].g) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLookahead reports whether the n glyphs after i are accepted by
+// match.
+func (a *otApplier) matchLookahead(i, n int, match func(k int, g sfnt.GlyphIndex) bool) bool {
+	for k := 0; k < n; k++ {
+		if i = a.next(i); i < 0 || !match(k, a.buf[i].g) {
+			return false
+		}
+	}
+	return true
+}
+
+// merge joins the clusters of the glyphs from i to j inclusive.
+func (a *otApplier) merge(i, j int) {
+	for i > 0 && a.buf[i-1].cluster == a.buf[i].cluster {
+		i--
+	}
+	for j+1 < len(a.buf) && a.buf[j+1].cluster == a.buf[j].cluster {
+		j++
+	}
+	min, max := a.buf[i].cluster, a.buf[i].cluster
+	for _, g := range a.buf[i : j+1] {
+		if g.cluster < min {
+			min = g.cluster
+		}
+		if g.cluster > max {
+			max = g.cluster
+		}
+	}
+	if min == max {
+		return
+	}
+	for k := i; k <= j; k++ {
+		a.buf[k].cluster = min
+	}
+	a.joined = append(a.joined, [2]int{min, max})
+}
+
+// apply applies a lookup with the given mask to every glyph, or every glyph
+// with the mask when substituting.
+func (a *otApplier) apply(lookup int, mask uint32) {
+	if lookup >= len(a.layout.lookups) {
+		return
+	}
+	l := &a.layout.lookups[lookup]
+	a.flag, a.filter, a.mask = l.flag, l.filter, mask
+	if !a.pos && l.kind == otLookupReverseChainSubst {
+		for i := len(a.buf) - 1; i >= 0; i-- {
+			if a.buf[i].mask&mask != 0 && !a.skip(i) {
+				a.applyAt(l, i)
+			}
+		}
+		return
+	}
+	for i := 0; i < len(a.buf); {
+		if a.buf[i].mask&mask == 0 || a.skip(i) {
+			i++
+			continue
+		}
+		if next, ok := a.applyAt(l, i); ok {
+			i = next
+		} else {
+			i++
+		}
+	}
+}
+
+// applyAt applies the first subtable of a lookup that matches at i, and
+// returns the index to continue from.
+func (a *otApplier) applyAt(l *otLookup, i int) (int, bool) {
+	for _, s := range l.subtables {
+		var next int
+		var ok bool
+		if a.pos {
+			next, ok = a.position(l.kind, s, i)
+		} else {
+			next, ok = a.substitute(l.kind, s, i)
+		}
+		if ok {
+			return next, true
+		}
+	}
+	return 0, false
+}
+
+// applyNested applies the lookups of a contextual rule to the glyphs of the
+// input sequence it matched at pos, whose records are at r in d.
+func (a *otApplier) applyNested(pos []int, d otData, r, count int) int {
+	flag, filter, mask := a.flag, a.filter, a.mask
+	end := pos[len(pos)-1] + 1
+	for k := 0; k < count; k++ {
+		seq, lookup := int(d.u16(r+4*k)), int(d.u16(r+4*k+2))
+		if seq >= len(pos) || lookup >= len(a.layout.lookups) {
+			continue
+		}
+		l := &a.layout.lookups[lookup]
+		a.flag, a.filter = l.flag, l.filter
+		n := len(a.buf)
+		i := pos[seq]
+		if i >= len(a.buf) {
+			continue
+		}
+		if _, ok := a.applyAt(l, i); !ok {
+			continue
+		}
+		// Keep the positions of the rest of the sequence, which a
+		// substitution may have moved.
+		if delta := len(a.buf) - n; delta != 0 {
+			end += delta
+			for j := range pos {
+				if pos[j] > i {
+					pos[j] += delta
+					if pos[j] <= i {
+						pos[j] = i
+					}
+				}
+			}
+		}
+	}
+	a.flag, a.filter, a.mask = flag, filter, mask
+	if end < pos[0]+1 {
+		end = pos[0] + 1
+	}
+	return end
+}
+
+// context applies a contextual or chained contextual subtable at i.  These
+// have the same format in GSUB and GPOS.
+func (a *otApplier) context(chain bool, d otData, i int) (int, bool) {
+	g := a.buf[i].g
+	switch d.u16(0) {
+	case 1:
+		c := d.atU16(2).coverage(g)
+		if c < 0 || c >= int(d.u16(4)) {
+			return 0, false
+		}
+		set := d.atU16(6 + 2*c)
+		for j, n := 0, int(set.u16(0)); j < n; j++ {
+			rule := set.atU16(2 + 2*j)
+			glyphs := func(r int) func(int, sfnt.GlyphIndex) bool {
+				return func(k int, g sfnt.GlyphIndex) bool { return rule.u16(r+2*k) == uint16(g) }
+			}
+			if next, ok := a.rule(chain, rule, i, glyphs, glyphs, glyphs); ok {
+				return next, true
+			}
+		}
+	case 2:
+		if d.atU16(2).coverage(g) < 0 {
+			return 0, false
+		}
+		backtrack, input, lookahead := d.atU16(4), d.atU16(4), d.atU16(4)
+		sets := 6
+		if chain {
+			backtrack, input, lookahead = d.atU16(4), d.atU16(6), d.atU16(8)
+			sets = 10
+		}
+		c := int(input.class(g))
+		if c >= int(d.u16(sets)) {
+			return 0, false
+		}
+		set := d.atU16(sets + 2 + 2*c)
+		for j, n := 0, int(set.u16(0)); j < n; j++ {
+			rule := set.atU16(2 + 2*j)
+			classes := func(def otData) func(r int) func(int, sfnt.GlyphIndex) bool {
+				return func(r int) func(int, sfnt.GlyphIndex) bool {
+					return func(k int, g sfnt.GlyphIndex) bool { return rule.u16(r+2*k) == def.class(g) }
+				}
+			}
+			if next, ok := a.rule(chain, rule, i, classes(backtrack), classes(input), classes(lookahead)); ok {
+				return next, true
+			}
+		}
+	case 3:
+		coverages := func(r int) func(int, sfnt.GlyphIndex) bool {
+			return func(k int, g sfnt.GlyphIndex) bool { return d.atU16(r+2*k).coverage(g) >= 0 }
+		}
+		if !chain {
+			n, count := int(d.u16(2)), int(d.u16(4))
+			if n == 0 || d.atU16(6).coverage(g) < 0 {
+				return 0, false
+			}
+			pos, ok := a.matchInput(i, n, coverages(6))
+			if !ok {
+				return 0, false
+			}
+			return a.applyNested(pos, d, 6+2*n, count), true
+		}
+		nb := int(d.u16(2))
+		r := 4 + 2*nb
+		ni := int(d.u16(r))
+		if ni == 0 || d.atU16(r+2).coverage(g) < 0 {
+			return 0, false
+		}
+		pos, ok := a.matchInput(i, ni, coverages(r+2))
+		if !ok || !a.matchBacktrack(i, nb, coverages(4)) {
+			return 0, false
+		}
+		r += 2 + 2*ni
+		nl := int(d.u16(r))
+		if !a.matchLookahead(pos[len(pos)-1], nl, coverages(r+2)) {
+			return 0, false
+		}
+		r += 2 + 2*nl
+		return a.applyNested(pos, d, r+2, int(d.u16(r))), true
+	}
+	return 0, false
+}
+
+// rule applies a rule of a contextual subtable of format 1 or 2 at i.  The
+// functions return matchers for the glyphs or classes of each sequence at
+// an offset in the rule.  The input sequence omits its first glyph, which
+// the subtable's coverage has matched.
+func (a *otApplier) rule(chain bool, rule otData, i int, backtrack, input, lookahead func(r int) func(int, sfnt.GlyphIndex) bool) (int, bool) {
+	// in matches the input sequence stored from r, whose first glyph, at
+	// index 0, is omitted.
+	in := func(r int) func(int, sfnt.GlyphIndex) bool { return input(r - 2) }
+	if !chain {
+		n, count := int(rule.u16(0)), int(rule.u16(2))
+		if n == 0 {
+			return 0, false
+		}
+		pos, ok := a.matchInput(i, n, in(4))
+		if !ok {
+			return 0, false
+		}
+		return a.applyNested(pos, rule, 4+2*(n-1), count), true
+	}
+	nb := int(rule.u16(0))
+	if !a.matchBacktrack(i, nb, backtrack(2)) {
+		return 0, false
+	}
+	r := 2 + 2*nb
+	ni := int(rule.u16(r))
+	if ni == 0 {
+		return 0, false
+	}
+	pos, ok := a.matchInput(i, ni, in(r+2))
+	if !ok {
+		return 0, false
+	}
+	r += 2 + 2*(ni-1)
+	nl := int(rule.u16(r))
+	if !a.matchLookahead(pos[len(pos)-1], nl, lookahead(r+2)) {
+		return 0, false
+	}
+	r += 2 + 2*nl
+	return a.applyNested(pos, rule, r+2, int(rule.u16(r))), true
+}
+
+// substitute applies a GSUB subtable at i.
+func (a *otApplier) substitute(kind uint16, d otData, i int) (int, bool) {
+	g := a.buf[i].g
+	switch kind {
+	case otLookupSingleSubst:
+		c := d.atU16(2).coverage(g)
+		if c < 0 {
+			return 0, false
+		}
+		switch d.u16(0) {
+		case 1:
+			a.replace(i, sfnt.GlyphIndex(uint16(g)+d.u16(4)))
+		case 2:
+			if c >= int(d.u16(4)) {
+				return 0, false
+			}
+			a.replace(i, sfnt.GlyphIndex(d.u16(6+2*c)))
+		default:
+			return 0, false
+		}
+		return i + 1, true
+
+	case otLookupMultipleSubst, otLookupAlternateSubst:
+		c := d.atU16(2).coverage(g)
+		if c < 0 || c >= int(d.u16(4)) {
+			return 0, false
+		}
+		seq := d.atU16(6 + 2*c)
+		n := int(seq.u16(0))
+		if kind == otLookupAlternateSubst {
+			// The first alternate is used.
+			if n == 0 {
+				return 0, false
+			}
+			n = 1
+		}
+		glyphs := make([]otGlyph, n)
+		for k := range glyphs {
+			glyphs[k] = a.buf[i]
+			glyphs[k].g = sfnt.GlyphIndex(seq.u16(2 + 2*k))
+			glyphs[k].substituted = true
+		}
+		a.buf = append(a.buf[:i], append(glyphs, a.buf[i+1:]...)...)
+		for k := range glyphs {
+			a.setClass(i + k)
+		}
+		return i + n, true
+
+	case otLookupLigatureSubst:
+		c := d.atU16(2).coverage(g)
+		if c < 0 || c >= int(d.u16(4)) {
+			return 0, false
+		}
+		set := d.atU16(6 + 2*c)
+		for j, n := 0, int(set.u16(0)); j < n; j++ {
+			lig := set.atU16(2 + 2*j)
+			count := int(lig.u16(2))
+			pos, ok := a.matchInput(i, count, func(k int, g sfnt.GlyphIndex) bool {
+				return lig.u16(4+2*(k-1)) == uint16(g)
+			})
+			if ok {
+				a.ligate(pos, sfnt.GlyphIndex(lig.u16(0)))
+				return i + 1, true
+			}
+		}
+
+	case otLookupContextSubst:
+		return a.context(false, d, i)
+	case otLookupChainContextSubst:
+		return a.context(true, d, i)
+
+	case otLookupReverseChainSubst:
+		c := d.atU16(2).coverage(g)
+		if c < 0 || d.u16(0) != 1 {
+			return 0, false
+		}
+		coverages := func(r int) func(int, sfnt.GlyphIndex) bool {
+			return func(k int, g sfnt.GlyphIndex) bool { return d.atU16(r+2*k).coverage(g) >= 0 }
+		}
+		nb := int(d.u16(4))
+		r := 6 + 2*nb
+		nl := int(d.u16(r))
+		if !a.matchBacktrack(i, nb, coverages(6)) || !a.matchLookahead(i, nl, coverages(r+2)) {
+			return 0, false
+		}
+		r += 2 + 2*nl
+		if c >= int(d.u16(r)) {
+			return 0, false
+		}
+		a.replace(i, sfnt.GlyphIndex(d.u16(r+2+2*c)))
+		return i + 1, true
+	}
+	return 0, false
+}
+
+// replace replaces the glyph at i.
+func (a *otApplier) replace(i int, g sfnt.GlyphIndex) {
+	a.buf[i].g = g
+	a.buf[i].substituted = true
+	a.setClass(i)
+}
+
+// ligate replaces the glyphs at pos with a ligature.  Marks skipped between
+// them are kept after it, remembering the component they follow.
+func (a *otApplier) ligate(pos []int, g sfnt.GlyphIndex) {
+	first, last := pos[0], pos[len(pos)-1]
+	a.merge(first, last)
+	a.nextLigID++
+	id := a.nextLigID
+	marks := true
+	for _, p := range pos {
+		if a.buf[p].class != glyphClassMark {
+			marks = false
+		}
+	}
+	out := a.buf[:first+1]
+	k := 1
+	for j := first + 1; j <= last; j++ {
+		if k < len(pos) && j == pos[k] {
+			k++
+			continue
+		}
+		m := a.buf[j]
+		m.ligID, m.ligComp = id, k
+		out = append(out, m)
+	}
+	lig := &a.buf[first]
+	lig.g = g
+	lig.substituted = true
+	lig.ligID, lig.ligComp = id, len(pos)
+	a.setClass(first)
+	if a.tables.gdef == nil && !marks {
+		lig.class = glyphClassLigature
+	}
+	a.buf = append(out, a.buf[last+1:]...)
+}
+
+// valueRecord reads a value record of the given format at r in d and adds
+// it to the glyph at i.  It returns the record's size.
+func (a *otApplier) valueRecord(d otData, r int, format uint16, i int) int {
+	size := 0
+	for bit := uint16(1); bit <= 0x80; bit <<= 1 {
+		if format&bit == 0 {
+			continue
+		}
+		v := float64(d.i16(r+size)) * a.scale
+		if i >= 0 {
+			g := &a.buf[i]
+			switch bit {
+			case 0x01:
+				g.xOffset += v
+			case 0x02:
+				g.yOffset += v
+			case 0x04:
+				g.xAdvance += v
+			case 0x08:
+				g.yAdvance += v
+			}
+		}
+		// Device tables, for hinting, are ignored.
+		size += 2
+	}
+	return size
+}
+
+func recordSize(format uint16) int {
+	n := 0
+	for ; format != 0; format &= format - 1 {
+		n++
+	}
+	return 2 * n
+}
+
+// anchor returns the point of an anchor table.
+func (a *otApplier) anchor(d otData) (x, y float64) {
+	return float64(d.i16(2)) * a.scale, float64(d.i16(4)) * a.scale
+}
+
+// position applies a GPOS subtable at i.
+func (a *otApplier) position(kind uint16, d otData, i int) (int, bool) {
+	g := a.buf[i].g
+	switch kind {
+	case otLookupSinglePos:
+		c := d.atU16(2).coverage(g)
+		if c < 0 {
+			return 0, false
+		}
+		format := d.u16(4)
+		switch d.u16(0) {
+		case 1:
+			a.valueRecord(d, 6, format, i)
+		case 2:
+			if c >= int(d.u16(6)) {
+				return 0, false
+			}
+			a.valueRecord(d, 8+c*recordSize(format), format, i)
+		default:
+			return 0, false
+		}
+		return i + 1, true
+
+	case otLookupPairPos:
+		c := d.atU16(2).coverage(g)
+		j := a.next(i)
+		if c < 0 || j < 0 {
+			return 0, false
+		}
+		f1, f2 := d.u16(4), d.u16(6)
+		s1, s2 := recordSize(f1), recordSize(f2)
+		g2 := a.buf[j].g
+		var r int
+		var rec otData
+		switch d.u16(0) {
+		case 1:
+			if c >= int(d.u16(8)) {
+				return 0, false
+			}
+			set := d.atU16(10 + 2*c)
+			n, size := int(set.u16(0)), 2+s1+s2
+			k := sort.Search(n, func(k int) bool { return set.u16(2+size*k) >= uint16(g2) })
+			if k == n || set.u16(2+size*k) != uint16(g2) {
+				return 0, false
+			}
+			rec, r = set, 2+size*k+2
+		case 2:
+			c1, c2 := int(d.atU16(8).class(g)), int(d.atU16(10).class(g2))
+			n1, n2 := int(d.u16(12)), int(d.u16(14))
+			if c1 >= n1 || c2 >= n2 {
+				return 0, false
+			}
+			rec, r = d, 16+(c1*n2+c2)*(s1+s2)
+		default:
+			return 0, false
+		}
+		a.valueRecord(rec, r, f1, i)
+		a.valueRecord(rec, r+s1, f2, j)
+		if f2 != 0 {
+			// The second glyph was positioned, so it is not the first of
+			// another pair.
+			return j + 1, true
+		}
+		return j, true
+
+	case otLookupCursivePos:
+		c := d.atU16(2).coverage(g)
+		j := a.next(i)
+		if c < 0 || j < 0 || c >= int(d.u16(4)) {
+			return 0, false
+		}
+		exit := d.atU16(6 + 4*c + 2)
+		c2 := d.atU16(2).coverage(a.buf[j].g)
+		if exit == nil || c2 < 0 || c2 >= int(d.u16(4)) {
+			return 0, false
+		}
+		entry := d.atU16(6 + 4*c2)
+		if entry == nil {
+			return 0, false
+		}
+		ex, ey := a.anchor(exit)
+		nx, ny := a.anchor(entry)
+		// The exit of the first glyph meets the entry of the second, in
+		// the direction of the text.  The lookup's flag says which of them
+		// is attached to the other.
+		gi, gj := &a.buf[i], &a.buf[j]
+		if a.rtl {
+			dx := ex + gi.xOffset
+			gi.xAdvance -= dx
+			gi.xOffset -= dx
+			gj.xAdvance = nx + gj.xOffset
+		} else {
+			gi.xAdvance = ex + gi.xOffset
+			dx := nx + gj.xOffset
+			gj.xAdvance -= dx
+			gj.xOffset -= dx
+		}
+		if a.flag&lookupRightToLeft != 0 {
+			gi.attach, gi.cursive, gi.yOffset = j, true, ny-ey
+		} else {
+			gj.attach, gj.cursive, gj.yOffset = i, true, ey-ny
+		}
+		return j, true
+
+	case otLookupMarkBasePos, otLookupMarkLigaturePos, otLookupMarkMarkPos:
+		c := d.atU16(2).coverage(g)
+		if c < 0 {
+			return 0, false
+		}
+		// The glyph to attach to is the previous one that is not a mark,
+		// or for marks on marks, the previous one of any kind.
+		j := i
+		for {
+			if j = a.prev(j); j < 0 {
+				return 0, false
+			}
+			if kind == otLookupMarkMarkPos || a.buf[j].class != glyphClassMark {
+				break
+			}
+		}
+		base := &a.buf[j]
+		if kind == otLookupMarkMarkPos {
+			m := &a.buf[i]
+			if base.class != glyphClassMark || base.ligID != m.ligID || base.ligComp != m.ligComp {
+				return 0, false
+			}
+		}
+		c2 := d.atU16(4).coverage(base.g)
+		classes := int(d.u16(6))
+		marks := d.atU16(8)
+		if c2 < 0 || c >= int(marks.u16(0)) {
+			return 0, false
+		}
+		class := int(marks.u16(2 + 4*c))
+		if class >= classes {
+			return 0, false
+		}
+		mark := marks.atU16(2 + 4*c + 2)
+		bases := d.atU16(10)
+		if c2 >= int(bases.u16(0)) {
+			return 0, false
+		}
+		var anchor otData
+		if kind == otLookupMarkLigaturePos {
+			lig := bases.atU16(2 + 2*c2)
+			n := int(lig.u16(0))
+			if n == 0 {
+				return 0, false
+			}
+			comp := n
+			if m := &a.buf[i]; m.ligID == base.ligID && m.ligComp > 0 && m.ligComp < n {
+				comp = m.ligComp
+			}
+			anchor = lig.atU16(2 + 2*((comp-1)*classes+class))
+		} else {
+			anchor = bases.atU16(2 + 2*(c2*classes+class))
+		}
+		if anchor == nil || mark == nil {
+			return 0, false
+		}
+		bx, by := a.anchor(anchor)
+		mx, my := a.anchor(mark)
+		m := &a.buf[i]
+		m.attach, m.cursive = j, false
+		m.xOffset, m.yOffset = bx-mx, by-my
+		return i + 1, true
+
+	case otLookupContextPos:
+		return a.context(false, d, i)
+	case otLookupChainContextPos:
+		return a.context(true, d, i)
+	}
+	return 0, false
+}
+
+// colorLayers returns the glyphs and palette indices of the layers of a
+// color glyph, from its COLR table, or nil if it is not one.  Only version
+// 0 of the table, which draws layers in flat colors, is supported.
+func (t *otTables) colorLayers(g sfnt.GlyphIndex) (glyphs []sfnt.GlyphIndex, palette []uint16) {
+	d := t.colr
+	if d == nil || t.cpal == nil {
+		return nil, nil
+	}
+	n := int(d.u16(2))
+	bases := d.at(int(d.u32(4)))
+	i := sort.Search(n, func(i int) bool { return bases.u16(6*i) >= uint16(g) })
+	if i == n || bases.u16(6*i) != uint16(g) {
+		return nil, nil
+	}
+	first, count := int(bases.u16(6*i+2)), int(bases.u16(6*i+4))
+	layers := d.at(int(d.u32(8)))
+	for k := first; k < first+count && k < int(d.u16(12)); k++ {
+		glyphs = append(glyphs, sfnt.GlyphIndex(layers.u16(4*k)))
+		palette = append(palette, layers.u16(4*k+2))
+	}
+	return glyphs, palette
+}
+
+// paletteColor returns a color of the first palette of the CPAL table, and
+// false if it is out of range.
+func (t *otTables) paletteColor(i uint16) (Color, bool) {
+	d := t.cpal
+	if int(i) >= int(d.u16(2)) || d.u16(4) == 0 {
+		return Color{}, false
+	}
+	r := int(d.u32(8)) + 4*(int(d.u16(12))+int(i))
+	if r < 0 || r+4 > len(d) {
+		return Color{}, false
+	}
+	// Colors are stored as BGRA.
+	return Color{float64(d[r+2]) / 255, float64(d[r+1]) / 255, float64(d[r]) / 255, float64(d[r+3]) / 255}, true
+}
+
+// An otPlan is the lookups that shape a script in a font.
+type otPlan struct {
+	// gsub are the stages of substitution, in order, and gpos the lookups
+	// of positioning.
+	gsub []otStage
+	gpos []otPlanLookup
+	// joins is set if the font has the joining forms of Arabic letters.
+	joins bool
+}
+
+// An otStage is a set of lookups applied together.
+type otStage struct {
+	// tag is that of the first of the stage's features.
+	tag     string
+	lookups []otPlanLookup
+}
+
+// An otPlanLookup is a lookup applied to the glyphs with its mask.
+type otPlanLookup struct {
+	index int
+	mask  uint32
+}
+
+// plan returns the shaping plan of a script, which font.mu guards.
+func (t *otTables) plan(s *otScript) *otPlan {
+	if p, ok := t.plans[s]; ok {
+		return p
+	}
+	tags := append(append([]string(nil), s.tags...), "DFLT", "dflt", "latn")
+	gsub := t.gsub.featureLookups(t.gsub.langSys(tags))
+	gpos := t.gpos.featureLookups(t.gpos.langSys(tags))
+	p := &otPlan{}
+	for _, features := range shaperFeatures[s.shaper] {
+		p.gsub = append(p.gsub, otStage{features[0], planLookups(gsub, features)})
+	}
+	p.gpos = planLookups(gpos, positionFeatures)
+	for _, tag := range []string{"isol", "fina", "medi", "init"} {
+		if len(gsub[tag]) > 0 {
+			p.joins = true
+		}
+	}
+	t.plans[s] = p
+	return p
+}
+
+// planLookups returns the lookups of features in index order, each with the
+// masks of the features it belongs to.
+func planLookups(lookups map[string][]int, features []string) []otPlanLookup {
+	masks := map[int]uint32{}
+	for _, tag := range features {
+		mask, ok := featureMasks[tag]
+		if !ok {
+			mask = maskGlobal
+		}
+		for _, l := range lookups[tag] {
+			masks[l] |= mask
+		}
+	}
+	var ls []otPlanLookup
+	for l, mask := range masks {
+		ls = append(ls, otPlanLookup{l, mask})
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].index < ls[j].index })
+	return ls
+}
@@ -0,0 +1,364 @@
+package ui
+
+import (
+	"math"
+	"sort"
+)
+
+// A Path is a shape made of lines and curves, in the coordinates of the view
+// that draws it.  The zero value is an empty path.
+type Path struct {
+	ops []pathOp
+
+	// start is the first point of the current subpath, and current is where
+	// the next segment starts.
+	start, current Position
+	// open is set while there is a current subpath.
+	open bool
+}
+
+type pathOpKind int
+
+const (
+	pathMoveTo pathOpKind = iota
+	pathLineTo
+	pathQuadTo
+	pathCubicTo
+	pathClose
+)
+
+type pathOp struct {
+	kind pathOpKind
+	// p holds the control points and the end point, in that order.
+	p [3]Position
+}
+
+// MoveTo starts a new subpath at p.
+func (p *Path) MoveTo(to Position) {
+	p.ops = append(p.ops, pathOp{kind: pathMoveTo, p: [3]Position{to}})
+	p.start, p.current, p.open = to, to, true
+}
+
+// ensureOpen starts a subpath at the current point if there is none, as after
+// Close.
+func (p *Path) ensureOpen() {
+	if !p.open {
+		p.MoveTo(p.current)
+	}
+}
+
+// LineTo adds a line from the current point to to.
+func (p *Path) LineTo(to Position) {
+	p.ensureOpen()
+	p.ops = append(p.ops, pathOp{kind: pathLineTo, p: [3]Position{to}})
+	p.current = to
+}
+
+// QuadTo adds a quadratic Bézier curve from the current point to to, with
+// control point c.
+func (p *Path) QuadTo(c, to Position) {
+	p.ensureOpen()
+	p.ops = append(p.ops, pathOp{kind: pathQuadTo, p: [3]Position{c, to}})
+	p.current = to
+}
+
+// CubicTo adds a cubic Bézier curve from the current point to to, with
+// control points c1 and c2.
+func (p *Path) CubicTo(c1, c2, to Position) {
+	p.ensureOpen()
+	p.ops = append(p.ops, pathOp{kind: pathCubicTo, p: [3]Position{c1, c2, to}})
+	p.current = to
+}
+
+// ArcTo adds an arc of the ellipse with the given center and radii, from
+// angle start through sweep radians.  Angles are measured clockwise from the
+// positive X axis, as Y points down.  A line joins the current point to the
+// start of the arc, unless there is no current subpath, in which case one
+// starts there.
+func (p *Path) ArcTo(center Position, radius Size, start, sweep float64) {
+	at := func(a float64) Position {
+		return Position{center.X + radius.Width*math.Cos(a), center.Y + radius.Height*math.Sin(a)}
+	}
+	if p.open {
+		p.LineTo(at(start))
+	} else {
+		p.MoveTo(at(start))
+	}
+
+	// Each piece of at most a quarter turn is approximated by a cubic curve
+	// whose control points lie along the tangents at its ends.
+	n := int(math.Ceil(math.Abs(sweep) / (math.Pi / 2)))
+	if n == 0 {
+		return
+	}
+	da := sweep / float64(n)
+	k := 4. / 3 * math.Tan(da/4)
+	for i := 0; i < n; i++ {
+		a0 := start + float64(i)*da
+		a1 := a0 + da
+		sin0, cos0 := math.Sincos(a0)
+		sin1, cos1 := math.Sincos(a1)
+		p.CubicTo(
+			Position{center.X + radius.Width*(cos0-k*sin0), center.Y + radius.Height*(sin0+k*cos0)},
+			Position{center.X + radius.Width*(cos1+k*sin1), center.Y + radius.Height*(sin1-k*cos1)},
+			at(a1),
+		)
+	}
+}
+
+// Close joins the current point to the start of the subpath with a line and
+// ends the subpath.
+func (p *Path) Close() {
+	if !p.open {
+		return
+	}
+	p.ops = append(p.ops, pathOp{kind: pathClose})
+	p.current, p.open = p.start, false
+}
+
+// A subpath is a flattened subpath.
+type subpath struct {
+	points []Position
+	closed bool
+}
+
+// flatten approximates the curves of p with lines that stray from them by no
+// more than tolerance.
+func (p *Path) flatten(tolerance float64) []subpath {
+	var subpaths []subpath
+	var cur *subpath
+	var last Position
+	for _, op := range p.ops {
+		switch op.kind {
+		case pathMoveTo:
+			subpaths = append(subpaths, subpath{points: []Position{op.p[0]}})
+			cur = &subpaths[len(subpaths)-1]
+			last = op.p[0]
+			continue
+		case pathLineTo:
+			cur.points = append(cur.points, op.p[0])
+		case pathQuadTo:
+			c, to := op.p[0], op.p[1]
+			n := segments(distance(Position{}, Position{last.X - 2*c.X + to.X, last.Y - 2*c.Y + to.Y})/4, tolerance)
+			for i := 1; i <= n; i++ {
+				t := float64(i) / float64(n)
+				u := 1 - t
+				cur.points = append(cur.points, Position{
+					u*u*last.X + 2*u*t*c.X + t*t*to.X,
+					u*u*last.Y + 2*u*t*c.Y + t*t*to.Y,
+				})
+			}
+		case pathCubicTo:
+			c1, c2, to := op.p[0], op.p[1], op.p[2]
+			dd := math.Max(
+				distance(Position{}, Position{last.X - 2*c1.X + c2.X, last.Y - 2*c1.Y + c2.Y}),
+				distance(Position{}, Position{c1.X - 2*c2.X + to.X, c1.Y - 2*c2.Y + to.Y}),
+			)
+			n := segments(dd*3/4, tolerance)
+			for i := 1; i <= n; i++ {
+				t := float64(i) / float64(n)
+				u := 1 - t
+				cur.points = append(cur.points, Position{
+					u*u*u*last.X + 3*u*u*t*c1.X + 3*u*t*t*c2.X + t*t*t*to.X,
+					u*u*u*last.Y + 3*u*u*t*c1.Y + 3*u*t*t*c2.Y + t*t*t*to.Y,
+				})
+			}
+		case pathClose:
+			cur.closed = true
+			continue
+		}
+		last = cur.points[len(cur.points)-1]
+	}
+	return subpaths
+}
+
+// segments returns the number of lines needed to approximate a curve whose
+// second differences are bounded by dd to within tolerance.
+func segments(dd, tolerance float64) int {
+	n := int(math.Ceil(math.Sqrt(dd / tolerance)))
+	if n < 1 {
+		return 1
+	}
+	if n > 1000 {
+		return 1000
+	}
+	return n
+}
+
+// A FillRule decides which regions enclosed by a path are inside it.
+type FillRule int
+
+const (
+	// FillRuleNonZero fills regions that the path winds around a nonzero
+	// number of times.
+	FillRuleNonZero FillRule = iota
+	// FillRuleEvenOdd fills regions that the path winds around an odd number
+	// of times.
+	FillRuleEvenOdd
+)
+
+func (r FillRule) inside(winding int) bool {
+	if r == FillRuleEvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// A Paint describes how a shape is drawn.
+type Paint struct {
+	Color    Color
+	FillRule FillRule
+}
+
+// Fill draws the inside of path with paint.  Curves are flattened finely
+// enough that their error is invisible at the current scale.
+func (g *Graphics) Fill(path *Path, paint Paint) {
+	var polygons [][]Position
+	for _, s := range path.flatten(g.tolerance()) {
+		polygons = append(polygons, s.points)
+	}
+	g.drawTriangles(tessellate(polygons, paint.FillRule), paint.Color)
+}
+
+// tolerance returns the distance in view coordinates that covers a quarter of
+// a pixel.
+func (g *Graphics) tolerance() float64 {
+	const pixels = .25
+	scale := math.Max(g.pixelScale())
+	if scale <= 0 || math.IsInf(scale, 0) || math.IsNaN(scale) {
+		return pixels
+	}
+	return pixels / scale
+}
+
+// drawTriangles draws triangles, given as consecutive triples of vertices in
+// view coordinates, in a single color.
+func (g *Graphics) drawTriangles(vertices []Position, c Color) {
+	if len(vertices) == 0 {
+		return
+	}
+	data := make([]float32, 0, len(vertices)*coordsPerVertex)
+	for _, v := range vertices {
+		data = append(data,
+			float32(v.X), float32(v.Y),
+			float32(c.R), float32(c.G), float32(c.B), float32(c.A),
+		)
+	}
+	b := g.renderer.newBuffer(data)
+	defer b.release()
+	g.renderer.drawTriangles(b, g.proj.Mul4(g.view))
+}
+
+// An edge is a line of a polygon, oriented downward, with dir recording its
+// original direction.
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+func (e edge) x(y float64) float64 {
+	return e.x0 + (e.x1-e.x0)*(y-e.y0)/(e.y1-e.y0)
+}
+
+// tessellate returns triangles covering the inside of the polygons, as
+// consecutive triples of vertices.
+//
+// The plane is cut into horizontal slabs at every vertex and crossing of
+// edges, so that no two edges cross within a slab.  Within a slab, the edges
+// are ordered from left to right and the winding number between each pair
+// decides whether the trapezoid between them is filled.
+func tessellate(polygons [][]Position, rule FillRule) []Position {
+	var edges []edge
+	var ys []float64
+	for _, poly := range polygons {
+		for i, p := range poly {
+			q := poly[(i+1)%len(poly)]
+			ys = append(ys, p.Y)
+			switch {
+			case p.Y < q.Y:
+				edges = append(edges, edge{p.X, p.Y, q.X, q.Y, 1})
+			case p.Y > q.Y:
+				edges = append(edges, edge{q.X, q.Y, p.X, p.Y, -1})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	for i, e := range edges {
+		for _, f := range edges[i+1:] {
+			if f.y0 >= e.y1 {
+				break
+			}
+			if y, ok := crossing(e, f); ok {
+				ys = append(ys, y)
+			}
+		}
+	}
+	sort.Float64s(ys)
+
+	var triangles []Position
+	var active []edge
+	type span struct {
+		x0, x1 float64
+		dir    int
+	}
+	var spans []span
+	next := 0
+	for i := 0; i+1 < len(ys); i++ {
+		y0, y1 := ys[i], ys[i+1]
+		if y0 == y1 {
+			continue
+		}
+		for next < len(edges) && edges[next].y0 <= y0 {
+			active = append(active, edges[next])
+			next++
+		}
+		spans = spans[:0]
+		n := 0
+		for _, e := range active {
+			if e.y1 <= y0 {
+				continue
+			}
+			active[n] = e
+			n++
+			spans = append(spans, span{e.x(y0), e.x(y1), e.dir})
+		}
+		active = active[:n]
+		sort.Slice(spans, func(i, j int) bool {
+			return spans[i].x0+spans[i].x1 < spans[j].x0+spans[j].x1
+		})
+
+		winding := 0
+		var left span
+		for _, s := range spans {
+			wasInside := rule.inside(winding)
+			winding += s.dir
+			switch inside := rule.inside(winding); {
+			case inside && !wasInside:
+				left = s
+			case !inside && wasInside:
+				triangles = append(triangles,
+					Position{left.x0, y0}, Position{s.x0, y0}, Position{s.x1, y1},
+					Position{left.x0, y0}, Position{s.x1, y1}, Position{left.x1, y1},
+				)
+			}
+		}
+	}
+	return triangles
+}
+
+// crossing returns the height at which two edges cross, if they do so
+// strictly between their ends.
+func crossing(e, f edge) (float64, bool) {
+	y0 := math.Max(e.y0, f.y0)
+	y1 := math.Min(e.y1, f.y1)
+	if y0 >= y1 {
+		return 0, false
+	}
+	d0 := e.x(y0) - f.x(y0)
+	d1 := e.x(y1) - f.x(y1)
+	if d0 == 0 || d1 == 0 || d0 < 0 == (d1 < 0) {
+		return 0, false
+	}
+	return y0 + (y1-y0)*d0/(d0-d1), true
+}
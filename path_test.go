@@ -0,0 +1,273 @@
+package ui
+
+import (
+	"math"
+	"testing"
+)
+
+// distanceToPolyline returns the distance from p to the nearest line of a
+// polyline.
+func distanceToPolyline(p Position, points []Position) float64 {
+	d := math.Inf(1)
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		dx, dy := b.X-a.X, b.Y-a.Y
+		t := 0.0
+		if l := dx*dx + dy*dy; l > 0 {
+			t = math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/l))
+		}
+		d = math.Min(d, distance(p, Position{a.X + t*dx, a.Y + t*dy}))
+	}
+	return d
+}
+
+func TestFlattenTolerance(t *testing.T) {
+	start := Position{0, 0}
+	quad := func(c, to Position) func(float64) Position {
+		return func(t float64) Position {
+			u := 1 - t
+			return Position{u*u*start.X + 2*u*t*c.X + t*t*to.X, u*u*start.Y + 2*u*t*c.Y + t*t*to.Y}
+		}
+	}
+	cubic := func(c1, c2, to Position) func(float64) Position {
+		return func(t float64) Position {
+			u := 1 - t
+			return Position{
+				u*u*u*start.X + 3*u*u*t*c1.X + 3*u*t*t*c2.X + t*t*t*to.X,
+				u*u*u*start.Y + 3*u*u*t*c1.Y + 3*u*t*t*c2.Y + t*t*t*to.Y,
+			}
+		}
+	}
+	for _, test := range []struct {
+		name  string
+		path  func(*Path)
+		curve func(float64) Position
+	}{
+		{"quad", func(p *Path) { p.QuadTo(Position{50, 100}, Position{100, 0}) }, quad(Position{50, 100}, Position{100, 0})},
+		{"cubic", func(p *Path) { p.CubicTo(Position{0, 100}, Position{100, 100}, Position{100, 0}) }, cubic(Position{0, 100}, Position{100, 100}, Position{100, 0})},
+		{"cubic with a cusp", func(p *Path) { p.CubicTo(Position{100, 50}, Position{0, 50}, Position{100, 0}) }, cubic(Position{100, 50}, Position{0, 50}, Position{100, 0})},
+	} {
+		for _, tolerance := range []float64{1, .1, .01} {
+			var p Path
+			p.MoveTo(start)
+			test.path(&p)
+			s := p.flatten(tolerance)
+			if len(s) != 1 {
+				t.Fatalf("%s: got %d subpaths, want 1", test.name, len(s))
+			}
+			points := s[0].points
+			if end := test.curve(1); points[len(points)-1] != end {
+				t.Errorf("%s: flattened curve ends at %v, want %v", test.name, points[len(points)-1], end)
+			}
+			worst := 0.0
+			for i := 0; i <= 1000; i++ {
+				worst = math.Max(worst, distanceToPolyline(test.curve(float64(i)/1000), points))
+			}
+			if worst > tolerance {
+				t.Errorf("%s at tolerance %g: the curve strays %g from its flattening", test.name, tolerance, worst)
+			}
+		}
+	}
+}
+
+// signedArea returns the area enclosed by a polygon, positive if it turns
+// clockwise with Y pointing down.
+func signedArea(points []Position) float64 {
+	a := 0.0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return a / 2
+}
+
+func TestArcTo(t *testing.T) {
+	center := Position{10, 10}
+	radius := Size{5, 3}
+	near := func(p, q Position) bool { return distance(p, q) < 1e-9 }
+	for _, test := range []struct {
+		name         string
+		start, sweep float64
+		from, to     Position
+		area         float64
+	}{
+		{"quarter clockwise", 0, math.Pi / 2, Position{15, 10}, Position{10, 13}, 0},
+		{"quarter counterclockwise", 0, -math.Pi / 2, Position{15, 10}, Position{10, 7}, 0},
+		{"half from the top", -math.Pi / 2, math.Pi, Position{10, 7}, Position{10, 13}, 0},
+		{"full clockwise", 0, 2 * math.Pi, Position{15, 10}, Position{15, 10}, math.Pi * 5 * 3},
+		{"full counterclockwise", 0, -2 * math.Pi, Position{15, 10}, Position{15, 10}, -math.Pi * 5 * 3},
+	} {
+		var p Path
+		p.ArcTo(center, radius, test.start, test.sweep)
+		if op := p.ops[0]; op.kind != pathMoveTo || !near(op.p[0], test.from) {
+			t.Errorf("%s: starts with %+v, want a move to %v", test.name, op, test.from)
+		}
+		if !near(p.current, test.to) {
+			t.Errorf("%s: ends at %v, want %v", test.name, p.current, test.to)
+		}
+
+		const tolerance = .001
+		points := p.flatten(tolerance)[0].points
+		for _, q := range points {
+			// Every point is on the ellipse and within the sweep.
+			dx, dy := (q.X-center.X)/radius.Width, (q.Y-center.Y)/radius.Height
+			if r := math.Hypot(dx, dy); math.Abs(r-1) > .01 {
+				t.Errorf("%s: point %v is off the ellipse", test.name, q)
+				break
+			}
+			a := math.Atan2(dy, dx) - test.start
+			if test.sweep < 0 {
+				a = -a
+			}
+			if a = math.Mod(a+4*math.Pi+1e-9, 2*math.Pi) - 1e-9; a > math.Abs(test.sweep)+1e-9 {
+				t.Errorf("%s: point %v is outside the sweep", test.name, q)
+				break
+			}
+		}
+		if test.area != 0 {
+			if a := signedArea(points); math.Abs(a-test.area) > .01*math.Abs(test.area) {
+				t.Errorf("%s: encloses area %g, want %g", test.name, a, test.area)
+			}
+		}
+	}
+
+	// An arc continues the current subpath with a line to its start.
+	var p Path
+	p.MoveTo(Position{0, 0})
+	p.ArcTo(center, radius, 0, math.Pi/2)
+	if op := p.ops[1]; op.kind != pathLineTo || op.p[0] != (Position{15, 10}) {
+		t.Errorf("arc in a subpath begins with %+v, want a line to (15, 10)", op)
+	}
+}
+
+// triangleArea returns the unsigned area of the triangles.
+func triangleArea(tris []Position) float64 {
+	a := 0.0
+	for i := 0; i+2 < len(tris); i += 3 {
+		a += math.Abs(signedArea(tris[i : i+3]))
+	}
+	return a
+}
+
+// covers returns the number of triangles that contain p.
+func covers(tris []Position, p Position) int {
+	n := 0
+	for i := 0; i+2 < len(tris); i += 3 {
+		a, b, c := tris[i], tris[i+1], tris[i+2]
+		d0 := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+		d1 := (c.X-b.X)*(p.Y-b.Y) - (c.Y-b.Y)*(p.X-b.X)
+		d2 := (a.X-c.X)*(p.Y-c.Y) - (a.Y-c.Y)*(p.X-c.X)
+		if (d0 > 0 && d1 > 0 && d2 > 0) || (d0 < 0 && d1 < 0 && d2 < 0) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestTessellate(t *testing.T) {
+	square := func(x0, y0, x1, y1 float64) []Position {
+		return []Position{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+	}
+	reversed := func(ps []Position) []Position {
+		var r []Position
+		for i := len(ps) - 1; i >= 0; i-- {
+			r = append(r, ps[i])
+		}
+		return r
+	}
+	// A five-pointed star drawn in one stroke, whose center the path winds
+	// around twice.
+	var star []Position
+	for i := 0; i < 5; i++ {
+		a := -math.Pi/2 + float64(i)*4*math.Pi/5
+		star = append(star, Position{10 * math.Cos(a), 10 * math.Sin(a)})
+	}
+	// The radius of the star's inner corners, its area and that of the
+	// pentagon at its center.
+	r := 10 * math.Cos(2*math.Pi/5) / math.Cos(math.Pi/5)
+	starArea := 5 * 10 * r * math.Sin(math.Pi/5)
+	pentagonArea := 5. / 2 * r * r * math.Sin(2*math.Pi/5)
+
+	for _, test := range []struct {
+		name              string
+		polygons          [][]Position
+		nonZero, evenOdd  float64
+		inside, outside   []Position
+		insideNonZeroOnly []Position
+	}{
+		{
+			name:     "horizontal edges",
+			polygons: [][]Position{{{0, 0}, {10, 0}, {10, 5}, {5, 5}, {5, 10}, {0, 10}}},
+			nonZero:  75,
+			evenOdd:  75,
+			inside:   []Position{{2, 2}, {8, 2}, {2, 8}},
+			outside:  []Position{{8, 8}},
+		},
+		{
+			name:     "coincident vertices",
+			polygons: [][]Position{{{0, 0}, {0, 0}, {10, 0}, {10, 10}, {10, 10}, {0, 10}}, square(10, 10, 20, 20)},
+			nonZero:  200,
+			evenOdd:  200,
+			inside:   []Position{{3, 6}, {13, 16}},
+			outside:  []Position{{15, 5}, {5, 15}},
+		},
+		{
+			name:     "bow tie",
+			polygons: [][]Position{{{0, 0}, {10, 10}, {10, 0}, {0, 10}}},
+			nonZero:  50,
+			evenOdd:  50,
+			inside:   []Position{{2, 4}, {8, 6}},
+			outside:  []Position{{5, 2}, {5, 8}},
+		},
+		{
+			name:              "star",
+			polygons:          [][]Position{star},
+			nonZero:           starArea,
+			evenOdd:           starArea - pentagonArea,
+			inside:            []Position{{0, -8}},
+			insideNonZeroOnly: []Position{{0, 0}},
+		},
+		{
+			name:              "overlapping squares",
+			polygons:          [][]Position{square(0, 0, 10, 10), square(5, 5, 15, 15)},
+			nonZero:           175,
+			evenOdd:           150,
+			inside:            []Position{{2, 2}, {12, 12}},
+			insideNonZeroOnly: []Position{{7, 7}},
+		},
+		{
+			name:     "overlapping squares of opposite direction",
+			polygons: [][]Position{square(0, 0, 10, 10), reversed(square(5, 5, 15, 15))},
+			nonZero:  150,
+			evenOdd:  150,
+			inside:   []Position{{2, 2}, {12, 12}},
+			outside:  []Position{{7, 7}},
+		},
+	} {
+		for _, rule := range []FillRule{FillRuleNonZero, FillRuleEvenOdd} {
+			tris := tessellate(test.polygons, rule)
+			want := test.nonZero
+			if rule == FillRuleEvenOdd {
+				want = test.evenOdd
+			}
+			// Triangles that overlapped would cover more than the area.
+			if a := triangleArea(tris); math.Abs(a-want) > 1e-6 {
+				t.Errorf("%s, rule %d: triangles cover area %g, want %g", test.name, rule, a, want)
+			}
+			check := func(points []Position, want int) {
+				for _, p := range points {
+					if n := covers(tris, p); n != want {
+						t.Errorf("%s, rule %d: %v is covered %d times, want %d", test.name, rule, p, n, want)
+					}
+				}
+			}
+			check(test.inside, 1)
+			check(test.outside, 0)
+			if rule == FillRuleNonZero {
+				check(test.insideNonZeroOnly, 1)
+			} else {
+				check(test.insideNonZeroOnly, 0)
+			}
+		}
+	}
+}
@@ -9,6 +9,49 @@ type Pointer struct {
 	Position
 	Button  PointerButtons
 	Buttons PointerButtons
+
+	// Pressure is the normalized pressure of the pointer, from 0 to 1.
+	// Pointers that don't sense pressure report 0.5 while a button is down
+	// and 0 otherwise.
+	Pressure float64
+	// TangentialPressure is the normalized pressure on a pen's barrel
+	// control, such as an airbrush's finger wheel, from -1 to 1.
+	TangentialPressure float64
+	// TiltX and TiltY are the angles, in degrees from -90 to 90, between
+	// a pen and the normal to the surface in the X-Z and Y-Z planes.
+	// Positive TiltX leans right and positive TiltY leans down, toward the
+	// user.
+	TiltX, TiltY float64
+	// Twist is the clockwise rotation of a pen about its axis, in degrees
+	// from 0 to 360.
+	Twist float64
+	// ContactSize is the size of the area that a touch covers, or zero if
+	// unknown.
+	ContactSize Size
+
+	propagation *propagation
+}
+
+// setDefaultPressure sets the pressure of a pointer that doesn't sense it.
+func (p *Pointer) setDefaultPressure() {
+	p.Pressure = 0
+	if p.Buttons != PointerButtonNone {
+		p.Pressure = 0.5
+	}
+}
+
+// propagation is shared by the copies of a Pointer passed to the views that
+// an event visits.
+type propagation struct {
+	stopped bool
+}
+
+// StopPropagation keeps the event from reaching any more views, without
+// taking the pointer's later events as handling it would.
+func (p Pointer) StopPropagation() {
+	if p.propagation != nil {
+		p.propagation.stopped = true
+	}
 }
 
 type PointerID uint16
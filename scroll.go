@@ -0,0 +1,52 @@
+package ui
+
+// A Scroll is an event from a mouse wheel, trackpad or other scrolling device.
+type Scroll struct {
+	// Position is the pointer position in the view's coordinates.
+	Position
+	// Delta is how far the content under the pointer should move, in the
+	// view's coordinates.  Scrolling a mouse wheel up gives a positive
+	// Delta.Height, moving the content down.
+	Delta Size
+	// Precise is set for devices that scroll smoothly, such as trackpads.
+	// Other devices scroll in lines of ScrollLineHeight.
+	Precise bool
+	// Phase tracks the fingers on a trackpad, and is ScrollPhaseNone for
+	// devices without gestures.
+	Phase ScrollPhase
+	// Momentum is set on the events that continue scrolling after the
+	// fingers lift, and Phase then tracks the momentum instead.
+	Momentum  bool
+	Modifiers KeyModifiers
+}
+
+// ScrollLineHeight is how far a line-based device, such as a mouse wheel,
+// scrolls per line.
+const ScrollLineHeight = 4.0
+
+type ScrollPhase uint8
+
+const (
+	ScrollPhaseNone ScrollPhase = iota
+	ScrollPhaseBegin
+	ScrollPhaseChange
+	ScrollPhaseEnd
+)
+
+// scroll delivers s, in window coordinates, to the view under the pointer and
+// then to its ancestors until one handles it.  The delta of s is in lines if
+// it is not precise.
+func (w *windowBase) scroll(s Scroll) {
+	if !s.Precise {
+		s.Delta = s.Delta.Mul(ScrollLineHeight)
+	}
+	p, d := s.Position, s.Delta
+	for v := w.ViewAt(p); v != nil && v.view() != w.view(); v = v.Parent() {
+		t := v.view().getTransformToWindow().invert()
+		s.Position = t.transform(p)
+		s.Delta = t.transform(p.Add(d)).Sub(s.Position)
+		if v.Scroll(s) {
+			return
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// +build !android,!ios
+
+package ui_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// scrollView is a view that records the scrolls it receives and handles
+// them if handle is set.
+type scrollView struct {
+	ui.View
+	handle bool
+	events []string
+}
+
+func newScrollView(parent ui.View, handle bool) *scrollView {
+	v := &scrollView{handle: handle}
+	v.View = ui.NewView(v, parent)
+	return v
+}
+
+func (v *scrollView) Scroll(s ui.Scroll) bool {
+	v.events = append(v.events, fmt.Sprintf("(%g,%g) (%g,%g)", s.Position.X, s.Position.Y, s.Delta.Width, s.Delta.Height))
+	return v.handle
+}
+
+func TestScroll(t *testing.T) {
+	root := newScrollView(nil, false)
+	outer := newScrollView(root, true)
+	inner := newScrollView(outer, false)
+	w := newHeadless(t, ui.Size{Width: 20, Height: 20}, root)
+	defer w.Close()
+	w.Do(func() {
+		// The outer view shows its content at half size.
+		outer.Move(ui.Position{X: 2, Y: 2})
+		outer.Resize(ui.Size{Width: 10, Height: 10})
+		outer.SetRect(rect(0, 0, 20, 20))
+		inner.Move(ui.Position{X: 4, Y: 4})
+		inner.Resize(ui.Size{Width: 4, Height: 4})
+	})
+	events := func() (e [3][]string) {
+		w.Do(func() {
+			for i, v := range []*scrollView{root, outer, inner} {
+				e[i] = v.events
+				v.events = nil
+			}
+		})
+		return
+	}
+	check := func(what string, want [3][]string) {
+		t.Helper()
+		got := events()
+		for i := range got {
+			if !equalStrings(got[i], want[i]) {
+				t.Errorf("%s: root, outer and inner got %q, want %q", what, got, want)
+				return
+			}
+		}
+	}
+
+	// A scroll unhandled by the view under the pointer bubbles up to the
+	// first ancestor that handles it, in the coordinates of each.
+	w.InjectScroll(ui.Scroll{Position: ui.Position{X: 5, Y: 5}, Delta: ui.Size{Width: 1, Height: -1}, Precise: true})
+	check("precise", [3][]string{nil, {"(6,6) (2,-2)"}, {"(2,2) (2,-2)"}})
+
+	// Lines are ScrollLineHeight high.
+	w.InjectScroll(ui.Scroll{Position: ui.Position{X: 5, Y: 5}, Delta: ui.Size{Height: 1}})
+	check("lines", [3][]string{nil, {fmt.Sprintf("(6,6) (0,%g)", 2*ui.ScrollLineHeight)}, {fmt.Sprintf("(2,2) (0,%g)", 2*ui.ScrollLineHeight)}})
+
+	// Outside the outer view, the scroll reaches the content view.
+	w.InjectScroll(ui.Scroll{Position: ui.Position{X: 15, Y: 15}, Delta: ui.Size{Width: 1}, Precise: true})
+	check("outside", [3][]string{{"(15,15) (1,0)"}, nil, nil})
+}
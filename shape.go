@@ -0,0 +1,877 @@
+package ui
+
+import (
+	"unicode"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// A textRune is a character of a paragraph.
+type textRune struct {
+	r rune
+	// offset is the byte offset of r in the text.
+	offset int
+	class  bidi.Class
+	level  uint8
+	// brk is the kind of line break allowed after r.
+	brk lineBreak
+}
+
+// A textCluster is a run of characters that is laid out, selected and
+// broken between lines as a unit:  a base character with its combining
+// marks, an emoji sequence, an Indic syllable or a ligature.
+type textCluster struct {
+	// start and end are the byte range of the cluster in the text.
+	start, end int
+	// r and class are those of the first character.
+	r     rune
+	class bidi.Class
+	level uint8
+	// brk is the kind of line break allowed after the cluster.
+	brk lineBreak
+
+	glyphs []sfnt.GlyphIndex
+	// origins are those of glyphs from the cluster's left edge on the
+	// baseline.
+	origins []Position
+	width   float64
+
+	// x is the cluster's left edge in the layout.
+	x float64
+}
+
+func (c *textCluster) rtl() bool { return c.level%2 == 1 }
+
+// space reports whether c is white space, which hangs past the end of a
+// line.
+func (c *textCluster) space() bool {
+	return c.class == bidi.WS
+}
+
+// shape groups the characters of a paragraph into clusters and maps them to
+// glyphs of font at size.  font.mu must be held.
+//
+// Each run of one level and script is shaped with the lookups of the font's
+// substitution and positioning tables (GSUB and GPOS) for its script, with
+// the joining forms of Arabic and the reordering of Indic syllables.  A
+// ligature that spans clusters merges them.  Fonts without GSUB have Arabic
+// joined using presentation forms, and those without GPOS are kerned with
+// their kern table.
+func shape(runes []textRune, font *Font, size float64) []textCluster {
+	s := &shaper{font: font, size: size, runes: runes, cluster: make([]int, len(runes))}
+	for i := 0; i < len(runes); {
+		j := i + 1
+		for j < len(runes) && extendsCluster(runes[i:j], runes[j].r) {
+			j++
+		}
+		end := runes[len(runes)-1].offset + len(string(runes[len(runes)-1].r))
+		if j < len(runes) {
+			end = runes[j].offset
+		}
+		for k := i; k < j; k++ {
+			s.cluster[k] = len(s.clusters)
+		}
+		s.owner = append(s.owner, len(s.clusters))
+		s.clusters = append(s.clusters, textCluster{
+			start: runes[i].offset,
+			end:   end,
+			r:     runes[i].r,
+			class: runes[i].class,
+			level: runes[i].level,
+			brk:   runes[j-1].brk,
+		})
+		i = j
+	}
+	for _, r := range shapingRuns(runes, s.cluster) {
+		s.run(r.start, r.end, r.script)
+	}
+
+	var clusters []textCluster
+	for i, c := range s.clusters {
+		if s.owner[i] != i {
+			clusters[len(clusters)-1].end = c.end
+			clusters[len(clusters)-1].brk = c.brk
+			continue
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters
+}
+
+// A shaper shapes the runs of a paragraph.
+type shaper struct {
+	font  *Font
+	size  float64
+	runes []textRune
+	// cluster holds the index of each character's cluster in clusters.
+	cluster  []int
+	clusters []textCluster
+	// owner holds the index of the cluster each cluster was merged into,
+	// which is before it, or its own.
+	owner []int
+}
+
+// run shapes the characters from start to end, which have one level and
+// script, and adds their glyphs to their clusters.
+func (s *shaper) run(start, end int, script *otScript) {
+	t := s.font.ot
+	plan := t.plan(script)
+	runes := s.runes[start:end]
+	rtl := runes[0].level%2 == 1
+	a := &otApplier{tables: t, rtl: rtl, scale: s.size / float64(s.font.f.UnitsPerEm())}
+	add := func(r rune, g sfnt.GlyphIndex, cluster int, mask uint32) {
+		a.buf = append(a.buf, otGlyph{g: g, r: r, cluster: cluster, mask: mask, attach: -1})
+		a.setClass(len(a.buf) - 1)
+	}
+	for k := 0; k < len(runes); k++ {
+		r := runes[k].r
+		cluster := s.cluster[start+k]
+		mask := uint32(maskGlobal)
+		switch {
+		case script.shaper == shaperArabic && plan.joins:
+			mask |= arabicMask(runes, k)
+		case script.shaper == shaperArabic:
+			if isLam(r) && k+1 < len(runes) && s.cluster[start+k+1] == cluster && lamAlef(runes[k+1].r) != 0 {
+				// The ligature's forms follow those of lam's final form.
+				form := lamAlef(runes[k+1].r)
+				if joinsBefore(runes, k) {
+					form++
+				}
+				if g := s.font.glyph(form); g != 0 {
+					add(r, g, cluster, mask)
+					k++
+					continue
+				}
+			}
+			if form := arabicForm(runes, k, s.font); form != r {
+				add(r, s.font.glyph(form), cluster, mask)
+				continue
+			}
+		case script.shaper == shaperIndic:
+			if d, ok := indicDecompositions[r]; ok && s.font.glyph(d[0]) != 0 && s.font.glyph(d[1]) != 0 {
+				add(d[0], s.font.glyph(d[0]), cluster, mask)
+				add(d[1], s.font.glyph(d[1]), cluster, mask)
+				continue
+			}
+		}
+		g := s.font.glyph(r)
+		if m := mirror(r); rtl && m != r && s.font.glyph(m) != 0 {
+			g = s.font.glyph(m)
+		}
+		if g == 0 && ignorable(r) {
+			continue
+		}
+		add(r, g, cluster, mask)
+	}
+	if script.shaper == shaperIndic {
+		forSyllables(a.buf, setupIndicSyllable)
+	}
+
+	a.layout = &t.gsub
+	for _, stage := range plan.gsub {
+		if script.shaper == shaperIndic && stage.tag == "rphf" {
+			for i := range a.buf {
+				a.buf[i].substituted = false
+			}
+		}
+		for _, l := range stage.lookups {
+			a.apply(l.index, l.mask)
+		}
+		if script.shaper == shaperIndic {
+			switch stage.tag {
+			case "rphf":
+				for i := range a.buf {
+					if g := &a.buf[i]; g.mask&maskRphf != 0 && g.substituted {
+						g.mask |= maskReph
+					}
+				}
+			case "cjct":
+				forSyllables(a.buf, reorderIndicSyllable)
+			}
+		}
+	}
+
+	for i := range a.buf {
+		g := &a.buf[i]
+		g.xAdvance = s.font.advance(g.g, s.size)
+		if g.class == glyphClassMark && script.shaper != shaperIndic {
+			g.xAdvance = 0
+		}
+	}
+	if len(t.gpos.lookups) > 0 {
+		a.layout, a.pos = &t.gpos, true
+		for _, l := range plan.gpos {
+			a.apply(l.index, l.mask)
+		}
+	} else {
+		// Kerning between glyphs widens the first of them.
+		prev := -1
+		for i := range a.buf {
+			if a.buf[i].class == glyphClassMark {
+				continue
+			}
+			if prev >= 0 {
+				g0, g1 := a.buf[prev].g, a.buf[i].g
+				if rtl {
+					g0, g1 = g1, g0
+				}
+				a.buf[prev].xAdvance += s.font.kern(g0, g1, s.size)
+			}
+			prev = i
+		}
+	}
+
+	for _, j := range a.joined {
+		for c := j[0] + 1; c <= j[1]; c++ {
+			if s.owner[c] == c {
+				s.owner[c] = j[0]
+			}
+		}
+	}
+	s.place(a.buf, rtl)
+}
+
+// place adds shaped glyphs, in logical order, to their clusters.
+func (s *shaper) place(buf []otGlyph, rtl bool) {
+	n := len(buf)
+	pen := make([]float64, n)
+	x := 0.
+	for k := 0; k < n; k++ {
+		i := k
+		if rtl {
+			i = n - 1 - k
+		}
+		pen[i] = x
+		x += buf[i].xAdvance
+	}
+
+	// Marks are placed from the glyphs they are attached to, and cursive
+	// attachments follow their parents up and down.  The depth of
+	// attachment is limited, in case a malformed font makes a cycle.
+	pos := make([]Position, n)
+	placed := make([]bool, n)
+	var position func(i, depth int) Position
+	position = func(i, depth int) Position {
+		if placed[i] {
+			return pos[i]
+		}
+		g := &buf[i]
+		p := Position{pen[i] + g.xOffset, g.yOffset}
+		if j := g.attach; j >= 0 && j < n && j != i && depth < n {
+			q := position(j, depth+1)
+			if g.cursive {
+				p.Y += q.Y
+			} else {
+				p = Position{q.X + g.xOffset, q.Y + g.yOffset}
+			}
+		}
+		pos[i], placed[i] = p, true
+		return pos[i]
+	}
+
+	root := func(c int) int {
+		for s.owner[c] != c {
+			c = s.owner[c]
+		}
+		return c
+	}
+	left := map[int]float64{}
+	for i := range buf {
+		c := root(buf[i].cluster)
+		if l, ok := left[c]; !ok || pen[i] < l {
+			left[c] = pen[i]
+		}
+	}
+	for i, g := range buf {
+		c := &s.clusters[root(g.cluster)]
+		p := position(i, 0)
+		c.glyphs = append(c.glyphs, g.g)
+		c.origins = append(c.origins, Position{p.X - left[root(g.cluster)], -p.Y})
+		c.width += g.xAdvance
+	}
+}
+
+// Feature masks.  Global features apply to every glyph, and the others to
+// glyphs chosen by the script's shaper.
+const (
+	maskGlobal = 1 << iota
+	maskIsol
+	maskFina
+	maskMedi
+	maskInit
+	maskRphf
+	maskHalf
+	maskBlwf
+	maskAbvf
+	maskPstf
+	maskPref
+	// maskReph marks a glyph that rphf made a reph, which no feature uses.
+	maskReph
+)
+
+var featureMasks = map[string]uint32{
+	"isol": maskIsol,
+	"fina": maskFina,
+	"medi": maskMedi,
+	"init": maskInit,
+	"rphf": maskRphf,
+	"half": maskHalf,
+	"blwf": maskBlwf,
+	"abvf": maskAbvf,
+	"pstf": maskPstf,
+	"pref": maskPref,
+}
+
+// Shapers.
+const (
+	shaperDefault = iota
+	shaperArabic
+	shaperIndic
+)
+
+// shaperFeatures holds the stages of GSUB features of each shaper.  The
+// empty tag is the required feature.
+var shaperFeatures = [][][]string{
+	shaperDefault: {
+		{"rvrn", "ccmp", "locl", ""},
+		{"rlig", "rclt", "calt", "clig", "liga"},
+	},
+	shaperArabic: {
+		{"rvrn", "ccmp", "locl", ""},
+		{"isol"}, {"fina"}, {"medi"}, {"init"},
+		{"rlig"},
+		{"calt"},
+		{"rclt", "clig", "liga", "mset"},
+	},
+	shaperIndic: {
+		{"rvrn", "locl", "ccmp", ""},
+		{"nukt"}, {"akhn"}, {"rphf"}, {"rkrf"}, {"pref"}, {"blwf"}, {"abvf"}, {"half"}, {"pstf"}, {"vatu"}, {"cjct"},
+		{"pres", "abvs", "blws", "psts", "haln"},
+		{"rlig", "rclt", "calt", "clig", "liga"},
+	},
+}
+
+// positionFeatures are the GPOS features of every shaper.
+var positionFeatures = []string{"", "abvm", "blwm", "curs", "dist", "kern", "mark", "mkmk"}
+
+// An otScript is a script shaped with the features of its tags.
+type otScript struct {
+	table *unicode.RangeTable
+	// tags are the OpenType tags of the script, in order of preference.
+	tags   []string
+	shaper int
+}
+
+var (
+	otScriptLatin   = &otScript{unicode.Latin, []string{"latn"}, shaperDefault}
+	otScriptDefault = &otScript{nil, nil, shaperDefault}
+
+	otScripts = []*otScript{
+		otScriptLatin,
+		{unicode.Greek, []string{"grek"}, shaperDefault},
+		{unicode.Cyrillic, []string{"cyrl"}, shaperDefault},
+		{unicode.Armenian, []string{"armn"}, shaperDefault},
+		{unicode.Hebrew, []string{"hebr"}, shaperDefault},
+		{unicode.Arabic, []string{"arab"}, shaperArabic},
+		{unicode.Thaana, []string{"thaa"}, shaperDefault},
+		{unicode.Devanagari, []string{"dev2", "deva"}, shaperIndic},
+		{unicode.Bengali, []string{"bng2", "beng"}, shaperIndic},
+		{unicode.Gurmukhi, []string{"gur2", "guru"}, shaperIndic},
+		{unicode.Gujarati, []string{"gjr2", "gujr"}, shaperIndic},
+		{unicode.Oriya, []string{"ory2", "orya"}, shaperIndic},
+		{unicode.Tamil, []string{"tml2", "taml"}, shaperIndic},
+		{unicode.Telugu, []string{"tel2", "telu"}, shaperIndic},
+		{unicode.Kannada, []string{"knd2", "knda"}, shaperIndic},
+		{unicode.Malayalam, []string{"mlm2", "mlym"}, shaperIndic},
+		{unicode.Sinhala, []string{"sinh"}, shaperDefault},
+		{unicode.Thai, []string{"thai"}, shaperDefault},
+		{unicode.Lao, []string{"lao "}, shaperDefault},
+		{unicode.Tibetan, []string{"tibt"}, shaperDefault},
+		{unicode.Myanmar, []string{"mym2", "mymr"}, shaperDefault},
+		{unicode.Georgian, []string{"geor"}, shaperDefault},
+		{unicode.Hangul, []string{"hang"}, shaperDefault},
+		{unicode.Ethiopic, []string{"ethi"}, shaperDefault},
+		{unicode.Khmer, []string{"khmr"}, shaperDefault},
+		{unicode.Mongolian, []string{"mong"}, shaperDefault},
+		{unicode.Hiragana, []string{"kana"}, shaperDefault},
+		{unicode.Katakana, []string{"kana"}, shaperDefault},
+		{unicode.Han, []string{"hani"}, shaperDefault},
+	}
+)
+
+// scriptOf returns the script of r, or nil if it is common to scripts or
+// inherits the script of the character before it.
+func scriptOf(r rune) *otScript {
+	if r < 0x80 {
+		if unicode.IsLetter(r) {
+			return otScriptLatin
+		}
+		return nil
+	}
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return nil
+	}
+	for _, s := range otScripts {
+		if unicode.Is(s.table, r) {
+			return s
+		}
+	}
+	return otScriptDefault
+}
+
+// A shapingRun is a range of characters of one level and script.
+type shapingRun struct {
+	start, end int
+	script     *otScript
+}
+
+// shapingRuns splits a paragraph into runs of one level and script, which
+// begin with clusters.  Characters common to scripts, such as spaces and
+// punctuation, take the script of those before them, or at the start, of
+// those after.
+func shapingRuns(runes []textRune, cluster []int) []shapingRun {
+	scripts := make([]*otScript, len(runes))
+	var script *otScript
+	for i, r := range runes {
+		if s := scriptOf(r.r); s != nil {
+			script = s
+		}
+		scripts[i] = script
+	}
+	for i := len(runes) - 2; i >= 0; i-- {
+		if scripts[i] == nil {
+			scripts[i] = scripts[i+1]
+		}
+	}
+	var runs []shapingRun
+	for i := 0; i < len(runes); {
+		j := i + 1
+		for j < len(runes) && (cluster[j] == cluster[j-1] || runes[j].level == runes[i].level && scripts[j] == scripts[i]) {
+			j++
+		}
+		s := scripts[i]
+		if s == nil {
+			s = otScriptDefault
+		}
+		runs = append(runs, shapingRun{i, j, s})
+		i = j
+	}
+	return runs
+}
+
+// isMark reports whether r is a nonspacing mark, which is drawn on the
+// character before it.
+func isMark(r rune) bool { return unicode.In(r, unicode.Mn, unicode.Me) }
+
+// extendsCluster reports whether r continues the cluster of the characters
+// before it.
+func extendsCluster(cluster []textRune, r rune) bool {
+	prev := cluster[len(cluster)-1].r
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == zwj || prev == zwj:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0100 && r <= 0xE01EF:
+		// Variation selectors.
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		// Emoji skin tone modifiers.
+		return true
+	case r >= 0xE0020 && r <= 0xE007F:
+		// Emoji tag sequences.
+		return true
+	case isVirama(prev) && unicode.IsLetter(r):
+		return true
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		// Flags are pairs of regional indicators.
+		n := 0
+		for _, c := range cluster {
+			if isRegionalIndicator(c.r) {
+				n++
+			}
+		}
+		return n%2 == 1
+	case len(cluster) == 1 && isLam(prev) && lamAlef(r) != 0:
+		return true
+	}
+	return false
+}
+
+const (
+	zwnj = '\u200c'
+	zwj  = '\u200d'
+)
+
+func isRegionalIndicator(r rune) bool { return r >= 0x1F1E6 && r <= 0x1F1FF }
+
+func isVirama(r rune) bool {
+	switch r {
+	case 0x094D, 0x09CD, 0x0A4D, 0x0ACD, 0x0B4D, 0x0BCD, 0x0C4D, 0x0CCD, 0x0D4D, 0x0DCA:
+		return true
+	}
+	return false
+}
+
+// Categories of the characters of Indic syllables.
+const (
+	indicOther = iota
+	indicConsonant
+	indicRa
+	indicVirama
+	indicPreBaseMatra
+	// indicModifier is a candrabindu, anusvara or visarga, which follows
+	// the rest of its syllable.
+	indicModifier
+)
+
+// indicCategory returns the category of a character of the Brahmic scripts
+// from Devanagari to Malayalam, whose blocks share a layout.
+func indicCategory(r rune) int {
+	if r < 0x0900 || r > 0x0D7F {
+		return indicOther
+	}
+	switch off := r & 0x7F; {
+	case isVirama(r):
+		return indicVirama
+	case off == 0x30, r == 0x09F0:
+		return indicRa
+	case off >= 0x15 && off <= 0x39, off >= 0x58 && off <= 0x5F, r == 0x09F1:
+		return indicConsonant
+	case off >= 0x01 && off <= 0x03:
+		return indicModifier
+	}
+	switch r {
+	case 0x093F, 0x094E, 0x09BF, 0x09C7, 0x09C8, 0x0A3F, 0x0ABF, 0x0B47,
+		0x0BC6, 0x0BC7, 0x0BC8, 0x0D46, 0x0D47, 0x0D48:
+		return indicPreBaseMatra
+	}
+	return indicOther
+}
+
+func isIndicConsonant(r rune) bool {
+	c := indicCategory(r)
+	return c == indicConsonant || c == indicRa
+}
+
+// indicDecompositions holds the vowel signs that are written partly before
+// their consonant and partly after it.
+var indicDecompositions = map[rune][2]rune{
+	0x09CB: {0x09C7, 0x09BE},
+	0x09CC: {0x09C7, 0x09D7},
+	0x0B48: {0x0B47, 0x0B56},
+	0x0B4B: {0x0B47, 0x0B3E},
+	0x0B4C: {0x0B47, 0x0B57},
+	0x0BCA: {0x0BC6, 0x0BBE},
+	0x0BCB: {0x0BC7, 0x0BBE},
+	0x0BCC: {0x0BC6, 0x0BD7},
+	0x0D4A: {0x0D46, 0x0D3E},
+	0x0D4B: {0x0D47, 0x0D3E},
+	0x0D4C: {0x0D46, 0x0D57},
+}
+
+// forSyllables calls f with the glyphs of each cluster, which in Indic text
+// are syllables.
+func forSyllables(buf []otGlyph, f func(syllable []otGlyph)) {
+	for i := 0; i < len(buf); {
+		j := i + 1
+		for j < len(buf) && buf[j].cluster == buf[i].cluster {
+			j++
+		}
+		f(buf[i:j])
+		i = j
+	}
+}
+
+// setupIndicSyllable masks the characters of a syllable with the features
+// that may form its reph, half forms and below- and post-base forms, and
+// moves pre-base vowel signs to its start.
+func setupIndicSyllable(syl []otGlyph) {
+	start := 0
+	if len(syl) >= 3 && indicCategory(syl[0].r) == indicRa && indicCategory(syl[1].r) == indicVirama && isIndicConsonant(syl[2].r) {
+		// An initial ra and virama may form a reph.
+		syl[0].mask |= maskRphf
+		syl[1].mask |= maskRphf
+		start = 2
+	}
+
+	// The base consonant is the last, unless that is a ra after a virama,
+	// which takes a below-base form.
+	base := -1
+	for i := len(syl) - 1; i >= start; i-- {
+		if isIndicConsonant(syl[i].r) {
+			base = i
+			break
+		}
+	}
+	if base > start && indicCategory(syl[base].r) == indicRa && indicCategory(syl[base-1].r) == indicVirama {
+		for i := base - 2; i >= start; i-- {
+			if isIndicConsonant(syl[i].r) {
+				base = i
+				break
+			}
+		}
+	}
+	if base >= 0 {
+		for i := start; i < base; i++ {
+			syl[i].mask |= maskHalf | maskBlwf
+		}
+		for i := base + 1; i < len(syl); i++ {
+			syl[i].mask |= maskBlwf | maskAbvf | maskPstf | maskPref
+		}
+	}
+
+	var pre, rest []otGlyph
+	for _, g := range syl {
+		if indicCategory(g.r) == indicPreBaseMatra {
+			pre = append(pre, g)
+		} else {
+			rest = append(rest, g)
+		}
+	}
+	copy(syl, append(pre, rest...))
+}
+
+// reorderIndicSyllable moves a syllable's reph to its end, before any
+// syllable modifiers.
+func reorderIndicSyllable(syl []otGlyph) {
+	for i, g := range syl {
+		if g.mask&maskReph == 0 {
+			continue
+		}
+		j := len(syl)
+		for j > i+1 && indicCategory(syl[j-1].r) == indicModifier {
+			j--
+		}
+		copy(syl[i:j-1], syl[i+1:j])
+		syl[j-1] = g
+		return
+	}
+}
+
+// ignorable reports whether r is invisible unless a font gives it a glyph.
+func ignorable(r rune) bool {
+	switch {
+	case r == 0x00AD, r == 0x034F, r == 0x061C, r == 0x180E:
+		return true
+	case r >= 0x200B && r <= 0x200F, r >= 0x202A && r <= 0x202E, r >= 0x2060 && r <= 0x206F:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r == 0xFEFF:
+		return true
+	case r >= 0xE0000 && r <= 0xE0FFF:
+		return true
+	}
+	return unicode.IsControl(r)
+}
+
+var mirrors = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<',
+	'«': '»', '»': '«', '‹': '›', '›': '‹',
+	'⁅': '⁆', '⁆': '⁅', '⁽': '⁾', '⁾': '⁽', '₍': '₎', '₎': '₍',
+	'≤': '≥', '≥': '≤', '∈': '∋', '∋': '∈',
+	'〈': '〉', '〉': '〈', '《': '》', '》': '《', '「': '」', '」': '「',
+	'『': '』', '』': '『', '【': '】', '】': '【',
+}
+
+// mirror returns the mirror image of r in right-to-left text.
+func mirror(r rune) rune {
+	if m, ok := mirrors[r]; ok {
+		return m
+	}
+	return r
+}
+
+// arabicForms maps Arabic letters to their first presentation form in the
+// Arabic Presentation Forms-B block.  Dual-joining letters have isolated,
+// final, initial and medial forms, in that order; right-joining letters
+// only the first two.
+var arabicForms = map[rune]struct {
+	form rune
+	dual bool
+}{
+	0x0621: {0xFE80, false}, // hamza, which doesn't join
+	0x0622: {0xFE81, false},
+	0x0623: {0xFE83, false},
+	0x0624: {0xFE85, false},
+	0x0625: {0xFE87, false},
+	0x0626: {0xFE89, true},
+	0x0627: {0xFE8D, false},
+	0x0628: {0xFE8F, true},
+	0x0629: {0xFE93, false},
+	0x062A: {0xFE95, true},
+	0x062B: {0xFE99, true},
+	0x062C: {0xFE9D, true},
+	0x062D: {0xFEA1, true},
+	0x062E: {0xFEA5, true},
+	0x062F: {0xFEA9, false},
+	0x0630: {0xFEAB, false},
+	0x0631: {0xFEAD, false},
+	0x0632: {0xFEAF, false},
+	0x0633: {0xFEB1, true},
+	0x0634: {0xFEB5, true},
+	0x0635: {0xFEB9, true},
+	0x0636: {0xFEBD, true},
+	0x0637: {0xFEC1, true},
+	0x0638: {0xFEC5, true},
+	0x0639: {0xFEC9, true},
+	0x063A: {0xFECD, true},
+	0x0641: {0xFED1, true},
+	0x0642: {0xFED5, true},
+	0x0643: {0xFED9, true},
+	0x0644: {0xFEDD, true},
+	0x0645: {0xFEE1, true},
+	0x0646: {0xFEE5, true},
+	0x0647: {0xFEE9, true},
+	0x0648: {0xFEED, false},
+	0x0649: {0xFEEF, false},
+	0x064A: {0xFEF1, true},
+}
+
+func isLam(r rune) bool { return r == 0x0644 }
+
+// lamAlef returns the isolated form of the ligature of lam with the alef r,
+// or 0 if r is not an alef.
+func lamAlef(r rune) rune {
+	switch r {
+	case 0x0622:
+		return 0xFEF5
+	case 0x0623:
+		return 0xFEF7
+	case 0x0625:
+		return 0xFEF9
+	case 0x0627:
+		return 0xFEFB
+	}
+	return 0
+}
+
+// joining types.
+const (
+	joinNone = iota
+	joinRight
+	joinDual
+	joinCausing
+	joinTransparent
+)
+
+func joiningType(r rune) int {
+	switch {
+	case r == 0x0640, r == zwj:
+		// Tatweel and the zero width joiner join on both sides.
+		return joinCausing
+	case r == zwnj:
+		return joinNone
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return joinTransparent
+	case r == 0x0621, r == 0x0674, !unicode.Is(unicode.Lo, r):
+		// Hamza and high hamza don't join.
+		return joinNone
+	case r >= 0x0620 && r <= 0x06FF, r >= 0x0750 && r <= 0x077F:
+		if unicode.Is(rightJoining, r) {
+			return joinRight
+		}
+		return joinDual
+	}
+	return joinNone
+}
+
+// rightJoining holds the Arabic letters that join only to the character
+// before them.
+var rightJoining = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x0622, 0x0625, 1},
+		{0x0627, 0x0629, 2},
+		{0x062F, 0x0632, 1},
+		{0x0648, 0x0648, 1},
+		{0x0671, 0x0673, 1},
+		{0x0675, 0x0677, 1},
+		{0x0688, 0x0699, 1},
+		{0x06C0, 0x06C0, 1},
+		{0x06C3, 0x06CB, 1},
+		{0x06CD, 0x06CF, 2},
+		{0x06D2, 0x06D3, 1},
+		{0x06D5, 0x06D5, 1},
+		{0x06EE, 0x06EF, 1},
+		{0x0759, 0x075B, 1},
+		{0x076B, 0x076C, 1},
+		{0x0771, 0x0771, 1},
+		{0x0773, 0x0774, 1},
+		{0x0778, 0x0779, 1},
+	},
+}
+
+// arabicMask returns the mask of the joining form of the character at i, or
+// zero if it doesn't join.
+func arabicMask(runes []textRune, i int) uint32 {
+	t := joiningType(runes[i].r)
+	if t != joinRight && t != joinDual {
+		return 0
+	}
+	before := joinsBefore(runes, i)
+	after := t == joinDual && joinsAfter(runes, i)
+	switch {
+	case before && after:
+		return maskMedi
+	case after:
+		return maskInit
+	case before:
+		return maskFina
+	}
+	return maskIsol
+}
+
+// joinsBefore reports whether the character at i is joined by the one
+// before it.
+func joinsBefore(runes []textRune, i int) bool {
+	for i--; i >= 0; i-- {
+		switch joiningType(runes[i].r) {
+		case joinTransparent:
+			continue
+		case joinDual, joinCausing:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// joinsAfter reports whether the character at i is joined by the one after
+// it.
+func joinsAfter(runes []textRune, i int) bool {
+	for i++; i < len(runes); i++ {
+		switch joiningType(runes[i].r) {
+		case joinTransparent:
+			continue
+		case joinRight, joinDual, joinCausing:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// arabicForm returns the presentation form of the character at i that fits
+// its neighbors, if font has it.
+func arabicForm(runes []textRune, i int, font *Font) rune {
+	r := runes[i].r
+	f, ok := arabicForms[r]
+	if !ok || r == 0x0621 {
+		return r
+	}
+	before := joinsBefore(runes, i)
+	after := f.dual && joinsAfter(runes, i)
+	form := f.form
+	switch {
+	case before && after:
+		form += 3
+	case after:
+		form += 2
+	case before:
+		form++
+	}
+	if font.glyph(form) == 0 {
+		return r
+	}
+	return form
+}
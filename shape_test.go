@@ -0,0 +1,276 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// An otNode is an OpenType table or subtable.  Its fields are integers,
+// tags as strings, and otNodes, which are written after it at 16-bit
+// offsets from its start, or as null offsets if empty.
+type otNode []interface{}
+
+func (n otNode) bytes() []byte {
+	var b bytes.Buffer
+	var children []otNode
+	var at []int
+	for _, f := range n {
+		switch f := f.(type) {
+		case otNode:
+			if len(f) > 0 {
+				children = append(children, f)
+				at = append(at, b.Len())
+			}
+			b.Write([]byte{0, 0})
+		case string:
+			b.WriteString(f)
+		default:
+			binary.Write(&b, binary.BigEndian, f)
+		}
+	}
+	out := b.Bytes()
+	for i, c := range children {
+		binary.BigEndian.PutUint16(out[at[i]:], uint16(len(out)))
+		out = append(out, c.bytes()...)
+	}
+	return out
+}
+
+type testFeature struct {
+	tag     string
+	lookups []uint16
+}
+
+type testLookup struct {
+	kind, flag uint16
+	subtable   otNode
+}
+
+// testLayout returns a GSUB or GPOS table with features of one script.
+func testLayout(script string, features []testFeature, lookups []testLookup) []byte {
+	langSys := otNode{uint16(0), uint16(0xFFFF), uint16(len(features))}
+	featureList := otNode{uint16(len(features))}
+	for i, f := range features {
+		langSys = append(langSys, uint16(i))
+		feature := otNode{uint16(0), uint16(len(f.lookups))}
+		for _, l := range f.lookups {
+			feature = append(feature, l)
+		}
+		featureList = append(featureList, f.tag, feature)
+	}
+	lookupList := otNode{uint16(len(lookups))}
+	for _, l := range lookups {
+		lookupList = append(lookupList, otNode{l.kind, l.flag, uint16(1), l.subtable})
+	}
+	scriptList := otNode{uint16(1), script, otNode{langSys, uint16(0)}}
+	return otNode{uint32(0x10000), scriptList, featureList, lookupList}.bytes()
+}
+
+func coverage(glyphs ...uint16) otNode {
+	n := otNode{uint16(1), uint16(len(glyphs))}
+	for _, g := range glyphs {
+		n = append(n, g)
+	}
+	return n
+}
+
+func singleSubst(from, to uint16) testLookup {
+	return testLookup{otLookupSingleSubst, 0, otNode{uint16(2), coverage(from), uint16(1), to}}
+}
+
+func ligatureSubst(lig uint16, components ...uint16) testLookup {
+	l := otNode{lig, uint16(len(components))}
+	for _, c := range components[1:] {
+		l = append(l, c)
+	}
+	set := otNode{uint16(1), l}
+	return testLookup{otLookupLigatureSubst, 0, otNode{uint16(1), coverage(components[0]), uint16(1), set}}
+}
+
+// shapeText shapes a paragraph of text at size 10.
+func shapeText(font *Font, text string) []textCluster {
+	var runes []textRune
+	var rs []rune
+	var classes []bidi.Class
+	for i, r := range text {
+		c := bidiClass(r)
+		runes = append(runes, textRune{r: r, offset: i, class: c})
+		rs = append(rs, r)
+		classes = append(classes, c)
+	}
+	for i, lv := range bidiLevels(rs, classes, baseLevel(classes)) {
+		runes[i].level = lv
+	}
+	font.mu.Lock()
+	defer font.mu.Unlock()
+	return shape(runes, font, 10)
+}
+
+// clusterGlyphs returns the glyphs of each cluster.
+func clusterGlyphs(cs []textCluster) [][]sfnt.GlyphIndex {
+	var gs [][]sfnt.GlyphIndex
+	for _, c := range cs {
+		gs = append(gs, c.glyphs)
+	}
+	return gs
+}
+
+func TestShapeLigature(t *testing.T) {
+	// Glyphs 1 to 3 are f, i and x, and 4 is the ligature of f and i.
+	glyphs := []testGlyph{{'f', 400}, {'i', 300}, {'x', 500}, {0, 600}}
+	gsub := testLayout("latn", []testFeature{{"liga", []uint16{0}}}, []testLookup{ligatureSubst(4, 1, 2)})
+	font := testFont(t, glyphs, map[string][]byte{"GSUB": gsub})
+
+	cs := shapeText(font, "fix")
+	if len(cs) != 2 || cs[0].start != 0 || cs[0].end != 2 || cs[1].start != 2 {
+		t.Fatalf("got clusters %+v, want fi and x", cs)
+	}
+	if got, want := clusterGlyphs(cs), [][]sfnt.GlyphIndex{{4}, {3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got glyphs %v, want %v", got, want)
+	}
+	if cs[0].width != 6 {
+		t.Errorf("got ligature width %g, want 6", cs[0].width)
+	}
+}
+
+func TestShapeContext(t *testing.T) {
+	// Glyph 1 is x, which becomes 3 before y, glyph 2.
+	glyphs := []testGlyph{{'x', 500}, {'y', 500}, {0, 500}}
+	chain := otNode{uint16(3), uint16(0), uint16(1), coverage(1), uint16(1), coverage(2), uint16(1), uint16(0), uint16(1)}
+	gsub := testLayout("latn", []testFeature{{"calt", []uint16{0}}}, []testLookup{
+		{otLookupChainContextSubst, 0, chain},
+		singleSubst(1, 3),
+	})
+	font := testFont(t, glyphs, map[string][]byte{"GSUB": gsub})
+
+	got := clusterGlyphs(shapeText(font, "xyxx"))
+	want := [][]sfnt.GlyphIndex{{3}, {2}, {1}, {1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got glyphs %v, want %v", got, want)
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	// Glyph 1 is beh, and 2 to 4 its initial, medial and final forms.
+	glyphs := []testGlyph{{'ب', 500}, {0, 500}, {0, 500}, {0, 500}, {' ', 500}}
+	gsub := testLayout("arab", []testFeature{
+		{"init", []uint16{0}},
+		{"medi", []uint16{1}},
+		{"fina", []uint16{2}},
+	}, []testLookup{singleSubst(1, 2), singleSubst(1, 3), singleSubst(1, 4)})
+	font := testFont(t, glyphs, map[string][]byte{"GSUB": gsub})
+
+	got := clusterGlyphs(shapeText(font, "ببب ب"))
+	want := [][]sfnt.GlyphIndex{{2}, {3}, {4}, {5}, {1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got glyphs %v, want %v", got, want)
+	}
+
+	// Without GSUB, the presentation forms are used.
+	font = testFont(t, []testGlyph{{'ب', 500}, {'ﺐ', 500}, {'ﺑ', 500}, {'ﺒ', 500}}, nil)
+	got = clusterGlyphs(shapeText(font, "ببب"))
+	want = [][]sfnt.GlyphIndex{{3}, {4}, {2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("without GSUB, got glyphs %v, want %v", got, want)
+	}
+}
+
+func TestShapeIndic(t *testing.T) {
+	// Glyphs 1 to 5 are ra, virama, ka, ssa and the vowel sign i, 6 is a
+	// reph and 7 a half ka.
+	glyphs := []testGlyph{
+		{'र', 500}, {'्', 0}, {'क', 500}, {'ष', 500}, {'ि', 200},
+		{0, 0}, {0, 300},
+	}
+	gsub := testLayout("dev2", []testFeature{
+		{"rphf", []uint16{0}},
+		{"half", []uint16{1}},
+	}, []testLookup{ligatureSubst(6, 1, 2), ligatureSubst(7, 3, 2)})
+	font := testFont(t, glyphs, map[string][]byte{"GSUB": gsub})
+
+	for _, test := range []struct {
+		text   string
+		glyphs [][]sfnt.GlyphIndex
+	}{
+		// The vowel sign moves before the consonants, and the reph to the
+		// end of the syllable.
+		{"र्कि", [][]sfnt.GlyphIndex{{5, 3, 6}}},
+		// A consonant before the base takes its half form.
+		{"क्ष", [][]sfnt.GlyphIndex{{7, 4}}},
+		// The base does not.
+		{"क्", [][]sfnt.GlyphIndex{{3, 2}}},
+		// Nor does a ra with nothing after it form a reph.
+		{"र्", [][]sfnt.GlyphIndex{{1, 2}}},
+	} {
+		if got := clusterGlyphs(shapeText(font, test.text)); !reflect.DeepEqual(got, test.glyphs) {
+			t.Errorf("%q: got glyphs %v, want %v", test.text, got, test.glyphs)
+		}
+	}
+}
+
+func TestShapePosition(t *testing.T) {
+	// Glyphs 1 to 4 are A, V, a and an acute accent.
+	glyphs := []testGlyph{{'A', 600}, {'V', 600}, {'a', 500}, {'́', 300}}
+	anchor := func(x, y int16) otNode { return otNode{uint16(1), x, y} }
+	pair := otNode{uint16(1), coverage(1), uint16(0x0004), uint16(0), uint16(1),
+		otNode{uint16(1), uint16(2), int16(-100)}}
+	markBase := otNode{uint16(1), coverage(4), coverage(3), uint16(1),
+		otNode{uint16(1), uint16(0), anchor(50, -20)},
+		otNode{uint16(1), anchor(250, 700)}}
+	gpos := testLayout("latn", []testFeature{{"kern", []uint16{0}}, {"mark", []uint16{1}}}, []testLookup{
+		{otLookupPairPos, 0, pair},
+		{otLookupMarkBasePos, 0, markBase},
+	})
+	classes := otNode{uint16(1), uint16(1), uint16(4), uint16(1), uint16(1), uint16(1), uint16(3)}
+	gdef := otNode{uint32(0x10000), classes, uint16(0), uint16(0), uint16(0)}.bytes()
+	font := testFont(t, glyphs, map[string][]byte{"GPOS": gpos, "GDEF": gdef})
+
+	cs := shapeText(font, "AVá")
+	if len(cs) != 3 {
+		t.Fatalf("got %d clusters, want 3", len(cs))
+	}
+	if cs[0].width != 5 || cs[1].width != 6 {
+		t.Errorf("got widths %g and %g of the kerned pair, want 5 and 6", cs[0].width, cs[1].width)
+	}
+	// The accent has no advance and is placed on the base's anchor.
+	c := cs[2]
+	want := []Position{{0, 0}, {2, -7.2}}
+	if c.width != 5 || len(c.origins) != 2 || c.origins[0] != want[0] ||
+		abs(c.origins[1].X-want[1].X) > 1e-9 || abs(c.origins[1].Y-want[1].Y) > 1e-9 {
+		t.Errorf("got origins %v and width %g, want %v and 5", c.origins, c.width, want)
+	}
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestColorLayers(t *testing.T) {
+	// Glyph 1 is drawn as glyph 2 in red and glyph 3 in the text's color.
+	glyphs := []testGlyph{{'x', 500}, {0, 500}, {0, 500}}
+	colr := otNode{uint16(0), uint16(1), uint32(14), uint32(20), uint16(2),
+		uint16(1), uint16(0), uint16(2),
+		uint16(2), uint16(0), uint16(3), uint16(0xFFFF)}.bytes()
+	cpal := otNode{uint16(0), uint16(1), uint16(1), uint16(1), uint32(14), uint16(0),
+		[4]uint8{0, 0, 255, 255}}.bytes()
+	font := testFont(t, glyphs, map[string][]byte{"COLR": colr, "CPAL": cpal})
+
+	layers, palette := font.ot.colorLayers(1)
+	if !reflect.DeepEqual(layers, []sfnt.GlyphIndex{2, 3}) || !reflect.DeepEqual(palette, []uint16{0, 0xFFFF}) {
+		t.Errorf("got layers %v with palette indices %v, want [2 3] and [0 65535]", layers, palette)
+	}
+	if c, ok := font.ot.paletteColor(0); !ok || c != (Color{1, 0, 0, 1}) {
+		t.Errorf("got palette color %v, want red", c)
+	}
+	if layers, _ := font.ot.colorLayers(2); layers != nil {
+		t.Errorf("got layers %v of a glyph without any", layers)
+	}
+}
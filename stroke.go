@@ -0,0 +1,302 @@
+package ui
+
+import (
+	"math"
+)
+
+// A StrokeStyle describes how the outline of a path is drawn.
+type StrokeStyle struct {
+	Color Color
+	// Width is the width of the line, centered on the path.
+	Width float64
+	Join  LineJoin
+	Cap   LineCap
+	// MiterLimit is the longest a miter join may be, as a multiple of Width,
+	// before it is beveled instead.  Zero means 4.
+	MiterLimit float64
+	// Dashes holds the lengths of alternating dashes and gaps, repeated along
+	// each subpath.  An odd number of lengths is repeated to make it even.
+	// The line is solid if Dashes is empty or its lengths add up to less
+	// than can be seen.
+	Dashes []float64
+	// DashOffset is how far into the dash pattern each subpath starts.
+	DashOffset float64
+}
+
+// A LineJoin is the shape of the corners of a stroke.
+type LineJoin int
+
+const (
+	LineJoinMiter LineJoin = iota
+	LineJoinRound
+	LineJoinBevel
+)
+
+// A LineCap is the shape of the ends of a stroke.
+type LineCap int
+
+const (
+	LineCapButt LineCap = iota
+	LineCapRound
+	LineCapSquare
+)
+
+// Stroke draws the outline of path in style.  Overlapping parts of the line
+// are drawn once, so translucent strokes are even.
+func (g *Graphics) Stroke(path *Path, style StrokeStyle) {
+	if style.Width <= 0 {
+		return
+	}
+	s := stroker{style: style, tolerance: g.tolerance()}
+	if s.style.MiterLimit == 0 {
+		s.style.MiterLimit = 4
+	}
+	for _, sp := range path.flatten(s.tolerance) {
+		s.subpath(sp)
+	}
+	g.drawTriangles(tessellate(s.polygons, FillRuleNonZero), style.Color)
+}
+
+// A stroker turns lines into polygons whose union is their stroke.  Each
+// polygon winds the same way, so that they are united by the nonzero fill
+// rule.
+type stroker struct {
+	style     StrokeStyle
+	tolerance float64
+	polygons  [][]Position
+}
+
+func (s *stroker) subpath(sp subpath) {
+	pts := dedupe(sp.points)
+	closed := sp.closed
+	if closed && len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+
+	if dashes, ok := s.dashes(); ok {
+		if closed {
+			pts = append(pts, pts[0])
+		}
+		for _, d := range dash(pts, dashes, s.style.DashOffset) {
+			s.polyline(dedupe(d), false)
+		}
+		return
+	}
+	s.polyline(pts, closed && len(pts) > 2)
+}
+
+// dashes returns the dash pattern, if the line is dashed.
+func (s *stroker) dashes() ([]float64, bool) {
+	d := s.style.Dashes
+	total := 0.
+	for _, x := range d {
+		if x < 0 {
+			return nil, false
+		}
+		total += x
+	}
+	// A pattern shorter than the tolerance is too fine to see, and would
+	// cut the line into countless pieces.
+	if total < s.tolerance {
+		return nil, false
+	}
+	if len(d)%2 == 1 {
+		d = append(d[:len(d):len(d)], d...)
+	}
+	return d, true
+}
+
+// dash cuts a polyline into the pieces covered by the dashes of a pattern.
+func dash(pts []Position, dashes []float64, offset float64) [][]Position {
+	total := 0.
+	for _, x := range dashes {
+		total += x
+	}
+	offset = math.Mod(offset, total)
+	if offset < 0 {
+		offset += total
+	}
+	i := 0
+	// A dash of zero length is a dot, so it is skipped only if it is passed.
+	for offset > dashes[i] || offset == dashes[i] && dashes[i] > 0 {
+		offset -= dashes[i]
+		i = (i + 1) % len(dashes)
+	}
+	// left is what remains of dash i.
+	left := dashes[i] - offset
+
+	var pieces [][]Position
+	var cur []Position
+	if i%2 == 0 {
+		cur = []Position{pts[0]}
+	}
+	for j := 1; j < len(pts); j++ {
+		p, q := pts[j-1], pts[j]
+		length := distance(p, q)
+		at := 0.
+		for length-at > left {
+			at += left
+			t := at / length
+			x := Position{p.X + (q.X-p.X)*t, p.Y + (q.Y-p.Y)*t}
+			if i%2 == 0 {
+				pieces = append(pieces, append(cur, x))
+				cur = nil
+			} else {
+				cur = []Position{x}
+			}
+			i = (i + 1) % len(dashes)
+			left = dashes[i]
+		}
+		left -= length - at
+		if i%2 == 0 {
+			cur = append(cur, q)
+		}
+	}
+	if len(cur) > 1 {
+		pieces = append(pieces, cur)
+	}
+	return pieces
+}
+
+// polyline adds the stroke of a polyline without repeated points.
+func (s *stroker) polyline(pts []Position, closed bool) {
+	hw := s.style.Width / 2
+	if len(pts) == 1 {
+		// A lone point is drawn only if its caps give it a shape.
+		p := pts[0]
+		switch s.style.Cap {
+		case LineCapRound:
+			s.arc(p, Size{hw, 0}, 2*math.Pi)
+		case LineCapSquare:
+			s.add(Position{p.X - hw, p.Y - hw}, Position{p.X + hw, p.Y - hw}, Position{p.X + hw, p.Y + hw}, Position{p.X - hw, p.Y + hw})
+		}
+		return
+	}
+
+	n := len(pts) - 1
+	if closed {
+		n++
+	}
+	for i := 0; i < n; i++ {
+		p, q := pts[i], pts[(i+1)%len(pts)]
+		d := unit(q.Sub(p))
+		m := Size{-d.Height * hw, d.Width * hw}
+		s.add(p.Add(m), q.Add(m), q.Add(m.Mul(-1)), p.Add(m.Mul(-1)))
+	}
+
+	for i := range pts {
+		if !closed && (i == 0 || i == len(pts)-1) {
+			continue
+		}
+		prev := pts[(i+len(pts)-1)%len(pts)]
+		next := pts[(i+1)%len(pts)]
+		s.join(pts[i], unit(pts[i].Sub(prev)), unit(next.Sub(pts[i])))
+	}
+
+	if !closed {
+		s.cap(pts[0], unit(pts[0].Sub(pts[1])))
+		s.cap(pts[len(pts)-1], unit(pts[len(pts)-1].Sub(pts[len(pts)-2])))
+	}
+}
+
+// join adds the corner at p between lines in the directions d0 and d1.
+func (s *stroker) join(p Position, d0, d1 Size) {
+	hw := s.style.Width / 2
+	cross := d0.Width*d1.Height - d0.Height*d1.Width
+	dot := d0.Width*d1.Width + d0.Height*d1.Height
+	if cross == 0 && dot > 0 {
+		return
+	}
+	// The corner is on the outside of the turn, where the lines' edges
+	// part.
+	side := 1.
+	if cross > 0 {
+		side = -1
+	}
+	n0 := Size{-d0.Height, d0.Width}.Mul(side * hw)
+	n1 := Size{-d1.Height, d1.Width}.Mul(side * hw)
+
+	switch s.style.Join {
+	case LineJoinRound:
+		s.arc(p, n0, math.Atan2(n0.Width*n1.Height-n0.Height*n1.Width, n0.Width*n1.Width+n0.Height*n1.Height))
+		return
+	case LineJoinMiter:
+		// The miter's length relative to the width is 1/cos(θ/2), where θ is
+		// the angle between the normals.
+		if c := (1 + dot) / 2; c > 0 && 1/math.Sqrt(c) <= s.style.MiterLimit {
+			m := Size{n0.Width + n1.Width, n0.Height + n1.Height}.Mul(1 / (1 + dot))
+			s.add(p, p.Add(n0), p.Add(m), p.Add(n1))
+			return
+		}
+	}
+	s.add(p, p.Add(n0), p.Add(n1))
+}
+
+// cap adds the end at p of a line heading in direction d.
+func (s *stroker) cap(p Position, d Size) {
+	hw := s.style.Width / 2
+	n := Size{-d.Height, d.Width}.Mul(hw)
+	switch s.style.Cap {
+	case LineCapRound:
+		s.arc(p, n, -math.Pi)
+	case LineCapSquare:
+		e := d.Mul(hw)
+		s.add(p.Add(n), p.Add(n).Add(e), p.Add(n.Mul(-1)).Add(e), p.Add(n.Mul(-1)))
+	}
+}
+
+// arc adds the sector of the circle about p from the radius r through sweep
+// radians.
+func (s *stroker) arc(p Position, r Size, sweep float64) {
+	radius := math.Hypot(r.Width, r.Height)
+	step := 2 * math.Acos(math.Max(-1, 1-s.tolerance/radius))
+	n := int(math.Ceil(math.Abs(sweep) / step))
+	if n < 1 {
+		n = 1
+	}
+	if n > 1000 {
+		n = 1000
+	}
+	poly := []Position{p}
+	a := math.Atan2(r.Height, r.Width)
+	for i := 0; i <= n; i++ {
+		sin, cos := math.Sincos(a + sweep*float64(i)/float64(n))
+		poly = append(poly, Position{p.X + radius*cos, p.Y + radius*sin})
+	}
+	s.add(poly...)
+}
+
+// add adds a polygon, reversing it if need be so that it winds the same way
+// as the others.
+func (s *stroker) add(poly ...Position) {
+	area := 0.
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	if area == 0 {
+		return
+	}
+	if area < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+	s.polygons = append(s.polygons, poly)
+}
+
+// dedupe returns a copy of pts without consecutive repeated points.
+func dedupe(pts []Position) []Position {
+	d := make([]Position, 1, len(pts))
+	d[0] = pts[0]
+	for _, p := range pts[1:] {
+		if p != d[len(d)-1] {
+			d = append(d, p)
+		}
+	}
+	return d
+}
+
+func unit(s Size) Size {
+	return s.Mul(1 / math.Hypot(s.Width, s.Height))
+}
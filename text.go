@@ -0,0 +1,248 @@
+package ui
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// DrawText draws text in font at size, starting from p on its baseline.
+// Glyphs are rasterized at the current scale, which may differ between the
+// axes, and cached, and their origins
+// are aligned to pixels so that they are crisp.
+func (g *Graphics) DrawText(text string, font *Font, size float64, color Color, p Position) {
+	var glyphs []sfnt.GlyphIndex
+	var origins []Position
+	font.mu.Lock()
+	font.layout(text, size, func(gi sfnt.GlyphIndex, x float64) {
+		glyphs = append(glyphs, gi)
+		origins = append(origins, Position{p.X + x, p.Y})
+	})
+	font.mu.Unlock()
+	g.drawGlyphs(font, size, color, glyphs, origins)
+}
+
+// drawGlyphs draws glyphs of font at size with their origins at the given
+// positions.  Color glyphs are drawn in the layers of the font's COLR table,
+// where the text's color is that of layers without their own.  Color
+// bitmaps (CBDT and sbix) and COLR version 1 are not supported, so glyphs
+// that have only those are drawn as outlines.
+func (g *Graphics) drawGlyphs(font *Font, size float64, color Color, glyphs []sfnt.GlyphIndex, origins []Position) {
+	if g.glyphs == nil {
+		g.glyphs = newGlyphAtlas(g.renderer)
+	}
+	scaleX, scaleY := g.pixelScale()
+	ppemX := fixed.Int26_6(math.Round(size * scaleX * 64))
+	ppemY := fixed.Int26_6(math.Round(size * scaleY * 64))
+	if ppemX <= 0 || ppemY <= 0 {
+		return
+	}
+	g.glyphs.releaseRetired()
+
+	pages := map[*atlasPage][]float32{}
+	add := func(gi sfnt.GlyphIndex, origin Position, color Color) {
+		ag := g.glyphs.glyph(glyphKey{font, gi, ppemX, ppemY})
+		if ag.page == nil {
+			return
+		}
+		x, y := g.toPixels(origin)
+		min := Position{math.Round(x) + float64(ag.offset.X), math.Round(y) + float64(ag.offset.Y)}
+		max := min.Add(Size{float64(ag.rect.Dx()), float64(ag.rect.Dy())})
+		p0 := g.fromPixels(min)
+		p1 := g.fromPixels(max)
+
+		size := float64(ag.page.size)
+		u0, v0 := float64(ag.rect.Min.X)/size, float64(ag.rect.Min.Y)/size
+		u1, v1 := float64(ag.rect.Max.X)/size, float64(ag.rect.Max.Y)/size
+		vertex := func(x, y, u, v float64) []float32 {
+			return []float32{
+				float32(x), float32(y), float32(u), float32(v),
+				float32(color.R), float32(color.G), float32(color.B), float32(color.A),
+			}
+		}
+		data := pages[ag.page]
+		for _, v := range [][]float32{
+			vertex(p0.X, p0.Y, u0, v0), vertex(p1.X, p0.Y, u1, v0), vertex(p1.X, p1.Y, u1, v1),
+			vertex(p0.X, p0.Y, u0, v0), vertex(p1.X, p1.Y, u1, v1), vertex(p0.X, p1.Y, u0, v1),
+		} {
+			data = append(data, v...)
+		}
+		pages[ag.page] = data
+	}
+	for i, gi := range glyphs {
+		layers, palette := font.ot.colorLayers(gi)
+		if layers == nil {
+			add(gi, origins[i], color)
+			continue
+		}
+		for k, l := range layers {
+			c := color
+			if pc, ok := font.ot.paletteColor(palette[k]); ok && palette[k] != 0xFFFF {
+				c = pc
+				c.A *= color.A
+			}
+			add(l, origins[i], c)
+		}
+	}
+
+	mvp := g.proj.Mul4(g.view)
+	for page, data := range pages {
+		b := g.renderer.newBuffer(data)
+		g.renderer.drawTexturedTriangles(b, page.texture, mvp)
+		b.release()
+	}
+}
+
+// pixelScale returns the number of framebuffer pixels per unit of view
+// coordinates along each axis.
+func (g *Graphics) pixelScale() (x, y float64) {
+	fbWidth, fbHeight := g.renderer.framebufferSize()
+	x = math.Abs(float64(g.view[0])) * float64(fbWidth) / g.bounds.Width()
+	y = math.Abs(float64(g.view[5])) * float64(fbHeight) / g.bounds.Height()
+	return x, y
+}
+
+// toPixels maps a position in view coordinates to framebuffer pixels, from
+// the top left.
+func (g *Graphics) toPixels(p Position) (x, y float64) {
+	fbWidth, fbHeight := g.renderer.framebufferSize()
+	wx := float64(g.view[0])*p.X + float64(g.view[12])
+	wy := float64(g.view[5])*p.Y + float64(g.view[13])
+	return (wx - g.bounds.Min.X) * float64(fbWidth) / g.bounds.Width(),
+		(wy - g.bounds.Min.Y) * float64(fbHeight) / g.bounds.Height()
+}
+
+// fromPixels is the inverse of toPixels.
+func (g *Graphics) fromPixels(p Position) Position {
+	fbWidth, fbHeight := g.renderer.framebufferSize()
+	wx := p.X*g.bounds.Width()/float64(fbWidth) + g.bounds.Min.X
+	wy := p.Y*g.bounds.Height()/float64(fbHeight) + g.bounds.Min.Y
+	return Position{
+		(wx - float64(g.view[12])) / float64(g.view[0]),
+		(wy - float64(g.view[13])) / float64(g.view[5]),
+	}
+}
+
+// A glyphAtlas caches rasterized glyphs in textures.  Its pages are filled
+// in rows, and when there are too many, the atlas is cleared and starts over.
+type glyphAtlas struct {
+	renderer renderer
+	pages    []*atlasPage
+	glyphs   map[glyphKey]atlasGlyph
+	// retired holds the pages of a cleared atlas until the text using them
+	// is drawn.
+	retired []*atlasPage
+}
+
+const (
+	atlasPageSize = 1024
+	atlasMaxPages = 4
+)
+
+// A glyphKey identifies a glyph rasterized at a size in pixels along each
+// axis.
+type glyphKey struct {
+	font         *Font
+	glyph        sfnt.GlyphIndex
+	ppemX, ppemY fixed.Int26_6
+}
+
+type atlasGlyph struct {
+	// page is nil for glyphs without pixels.
+	page *atlasPage
+	rect image.Rectangle
+	// offset is the position of rect's top left relative to the glyph's
+	// origin.
+	offset image.Point
+}
+
+type atlasPage struct {
+	texture rendererTexture
+	size    int
+	// The next glyph goes at (x, y) in a row that is rowHeight high.
+	x, y, rowHeight int
+}
+
+func newGlyphAtlas(r renderer) *glyphAtlas {
+	return &glyphAtlas{renderer: r, glyphs: map[glyphKey]atlasGlyph{}}
+}
+
+func (a *glyphAtlas) release() {
+	a.clear()
+	a.releaseRetired()
+}
+
+func (a *glyphAtlas) clear() {
+	a.retired = append(a.retired, a.pages...)
+	a.pages = nil
+	a.glyphs = map[glyphKey]atlasGlyph{}
+}
+
+func (a *glyphAtlas) releaseRetired() {
+	for _, p := range a.retired {
+		p.texture.release()
+	}
+	a.retired = nil
+}
+
+// glyph returns a cached glyph, rasterizing it if need be.
+func (a *glyphAtlas) glyph(k glyphKey) atlasGlyph {
+	if g, ok := a.glyphs[k]; ok {
+		return g
+	}
+	img, offset := k.font.rasterize(k.glyph, k.ppemX, k.ppemY)
+	if img == nil {
+		a.glyphs[k] = atlasGlyph{}
+		return atlasGlyph{}
+	}
+
+	size := img.Rect.Size()
+	page, pos := a.place(size)
+	g := atlasGlyph{
+		page:   page,
+		rect:   image.Rectangle{pos, pos.Add(size)},
+		offset: offset,
+	}
+	page.texture.upload(pos.X, pos.Y, img)
+	a.glyphs[k] = g
+	return g
+}
+
+// place finds room for an image of the given size.
+func (a *glyphAtlas) place(size image.Point) (*atlasPage, image.Point) {
+	for _, p := range a.pages {
+		if p.x+size.X > p.size {
+			p.x, p.y, p.rowHeight = 0, p.y+p.rowHeight, 0
+		}
+		if p.x+size.X <= p.size && p.y+size.Y <= p.size {
+			pos := image.Pt(p.x, p.y)
+			p.x += size.X
+			if size.Y > p.rowHeight {
+				p.rowHeight = size.Y
+			}
+			return p, pos
+		}
+	}
+
+	if len(a.pages) == atlasMaxPages {
+		a.clear()
+	}
+	// Glyphs too big for a page get one of their own.
+	s := atlasPageSize
+	if size.X > s || size.Y > s {
+		s = size.X
+		if size.Y > s {
+			s = size.Y
+		}
+	}
+	p := &atlasPage{
+		texture:   a.renderer.newTexture(s, s),
+		size:      s,
+		x:         size.X,
+		rowHeight: size.Y,
+	}
+	a.pages = append(a.pages, p)
+	return p, image.Point{}
+}
@@ -0,0 +1,77 @@
+package ui
+
+import "unicode/utf8"
+
+// TextInput is implemented by views that accept text.  When such a view has
+// the focus, text typed by the user, including text composed with an input
+// method (as for Chinese, Japanese and Korean, or with dead keys), is
+// delivered to it in addition to key events.  Views should use key events
+// only for commands and edit with the text they receive here.
+type TextInput interface {
+	View
+
+	// InsertText inserts committed text at the cursor, replacing any
+	// composition.
+	InsertText(text string)
+
+	// SetComposition shows the text being composed with an input method
+	// at the cursor, replacing any previous composition.  The composition
+	// ends when InsertText is called or when it is set to empty.
+	SetComposition(Composition)
+
+	// CompositionRect returns the area of the view, in its own coordinates,
+	// that input methods should avoid covering, usually the cursor or the
+	// composition.  Candidate windows are placed next to it.
+	CompositionRect() Rectangle
+}
+
+// A Composition is text being composed with an input method.
+type Composition struct {
+	Text string
+	// Cursor is a byte offset into Text, or -1 if the cursor is hidden.
+	Cursor int
+}
+
+// textInputView returns the focused view if it is a TextInput.
+func (w *windowBase) textInputView() (TextInput, bool) {
+	t, ok := w.Focused().(TextInput)
+	return t, ok
+}
+
+func (w *windowBase) insertText(text string) {
+	if t, ok := w.textInputView(); ok {
+		t.InsertText(text)
+	}
+}
+
+func (w *windowBase) setComposition(c Composition) {
+	if t, ok := w.textInputView(); ok {
+		t.SetComposition(c)
+	}
+}
+
+// textInputRect returns the composition rectangle of the focused TextInput
+// in window coordinates, and whether there is one.
+func (w *windowBase) textInputRect() (Rectangle, bool) {
+	t, ok := w.textInputView()
+	if !ok {
+		return Rectangle{}, false
+	}
+	r := t.CompositionRect()
+	tr := t.view().getTransformToWindow()
+	return Rectangle{tr.transform(r.Min), tr.transform(r.Max)}, true
+}
+
+// runeOffsetToByte converts an offset in runes into s into one in bytes.
+// Negative offsets are returned unchanged.
+func runeOffsetToByte(s string, n int) int {
+	if n < 0 {
+		return n
+	}
+	i := 0
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,75 @@
+// +build !android,!ios
+
+package ui_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// textField is a TextInput that records the text and compositions it
+// receives.
+type textField struct {
+	*keyView
+	rect ui.Rectangle
+}
+
+func newTextField(parent ui.View) *textField {
+	f := &textField{keyView: &keyView{}}
+	f.View = ui.NewView(f, parent)
+	f.SetFocusable(true)
+	return f
+}
+
+func (f *textField) InsertText(text string) {
+	f.events = append(f.events, "insert "+text)
+}
+
+func (f *textField) SetComposition(c ui.Composition) {
+	f.events = append(f.events, fmt.Sprintf("compose %s %d", c.Text, c.Cursor))
+}
+
+func (f *textField) CompositionRect() ui.Rectangle { return f.rect }
+
+func TestTextInput(t *testing.T) {
+	root := newKeyView(nil, false)
+	field := newTextField(root)
+	plain := newKeyView(root, true)
+	w := newHeadless(t, ui.Size{Width: 20, Height: 20}, root)
+	defer w.Close()
+	w.Do(func() {
+		field.Move(ui.Position{X: 2, Y: 3})
+		field.Resize(ui.Size{Width: 10, Height: 5})
+		field.rect = rect(1, 1, 2, 4)
+	})
+
+	// Without a focused TextInput, text goes nowhere.
+	w.InjectText("a")
+	if _, ok := w.TextInputRect(); ok {
+		t.Error("a window without a focused TextInput has a text input rect")
+	}
+	w.Do(func() { w.SetFocus(plain) })
+	w.InjectComposition(ui.Composition{Text: "b", Cursor: 1})
+	if _, ok := w.TextInputRect(); ok {
+		t.Error("a window whose focused view is not a TextInput has a text input rect")
+	}
+	if e := takeEvents(w, root, plain); len(e[0]) != 0 || !equalStrings(e[1], []string{"in"}) {
+		t.Errorf("views that are not TextInputs got %q", e)
+	}
+
+	w.Do(func() { w.SetFocus(field) })
+	w.InjectComposition(ui.Composition{Text: "に", Cursor: 3})
+	w.InjectComposition(ui.Composition{Text: "にほ", Cursor: -1})
+	w.InjectText("日本")
+	want := []string{"in", "compose に 3", "compose にほ -1", "insert 日本"}
+	if e := takeEvents(w, field.keyView); !equalStrings(e[0], want) {
+		t.Errorf("text field got %q, want %q", e[0], want)
+	}
+
+	// The composition rect is in window coordinates.
+	if r, ok := w.TextInputRect(); !ok || r != rect(3, 4, 4, 7) {
+		t.Errorf("got text input rect %v, %t, want %v", r, ok, rect(3, 4, 4, 7))
+	}
+}
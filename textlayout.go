@@ -0,0 +1,415 @@
+package ui
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// A TextLayout lays out text in lines, for drawing, measuring and
+// hit-testing.  Each line of the text is a paragraph, which is wrapped to the
+// layout's width and whose mixed left-to-right and right-to-left runs are
+// ordered by the Unicode Bidirectional Algorithm.
+//
+// Text is shaped with the font's substitution and positioning tables, which
+// join Arabic and form ligatures, Indic conjuncts and emoji sequences.  Indic
+// syllables are reordered by a simplification of the rules of OpenType's
+// Indic shaping, and color emoji are drawn only from COLR layers, not
+// bitmaps.  Lines break where the Unicode Line Breaking Algorithm (UAX #14)
+// allows.  Words in scripts written without spaces, such as Thai, are not
+// looked up in a dictionary, so their lines break only before the vowels
+// that start a syllable.
+type TextLayout struct {
+	text  string
+	font  *Font
+	size  float64
+	color Color
+	width float64
+	align TextAlign
+	dir   TextDirection
+
+	valid   bool
+	lines   []textLine
+	metrics FontMetrics
+	// boxWidth is the width the lines are aligned in.
+	boxWidth float64
+}
+
+// A TextAlign is how the lines of a TextLayout are aligned horizontally.
+type TextAlign int
+
+const (
+	// TextAlignStart aligns lines to the side their paragraph starts on:
+	// the left for left-to-right text and the right for right-to-left text.
+	TextAlignStart TextAlign = iota
+	TextAlignEnd
+	TextAlignLeft
+	TextAlignRight
+	TextAlignCenter
+)
+
+// A TextDirection is the base direction of paragraphs.
+type TextDirection int
+
+const (
+	// TextDirectionAuto takes the direction of each paragraph from its first
+	// letter.
+	TextDirectionAuto TextDirection = iota
+	TextDirectionLeftToRight
+	TextDirectionRightToLeft
+)
+
+type textLine struct {
+	// start and end are the byte range of the line in the text, excluding
+	// any line break.
+	start, end int
+	// last is set for the last line of a paragraph.
+	last bool
+	rtl  bool
+	// clusters are in visual order.
+	clusters []textCluster
+	// x is the left edge of the line's content, and top its top.
+	x, top float64
+}
+
+// NewTextLayout returns a TextLayout of text in font at size.
+func NewTextLayout(text string, font *Font, size float64) *TextLayout {
+	return &TextLayout{
+		text:  text,
+		font:  font,
+		size:  size,
+		color: Color{1, 1, 1, 1},
+	}
+}
+
+func (l *TextLayout) Text() string { return l.text }
+
+func (l *TextLayout) SetText(text string) {
+	l.text = text
+	l.valid = false
+}
+
+func (l *TextLayout) SetFont(font *Font, size float64) {
+	l.font, l.size = font, size
+	l.valid = false
+}
+
+func (l *TextLayout) SetColor(c Color) {
+	l.color = c
+}
+
+// SetWidth sets the width to wrap lines to.  Zero means lines are not
+// wrapped.
+func (l *TextLayout) SetWidth(width float64) {
+	l.width = width
+	l.valid = false
+}
+
+func (l *TextLayout) SetAlign(a TextAlign) {
+	l.align = a
+	l.valid = false
+}
+
+func (l *TextLayout) SetDirection(d TextDirection) {
+	l.dir = d
+	l.valid = false
+}
+
+// Size returns the size of the laid out text.  Its width is the layout's
+// width if it has one, and otherwise that of the longest line.
+func (l *TextLayout) Size() Size {
+	l.layout()
+	return Size{l.boxWidth, float64(len(l.lines)) * l.metrics.LineHeight}
+}
+
+// Draw draws the text with its top left corner at p.
+func (l *TextLayout) Draw(gfx *Graphics, p Position) {
+	l.layout()
+	var glyphs []sfnt.GlyphIndex
+	var origins []Position
+	for _, line := range l.lines {
+		y := p.Y + line.top + l.metrics.Ascent
+		for _, c := range line.clusters {
+			for i, g := range c.glyphs {
+				glyphs = append(glyphs, g)
+				o := c.origins[i]
+				origins = append(origins, Position{p.X + c.x + o.X, y + o.Y})
+			}
+		}
+	}
+	gfx.drawGlyphs(l.font, l.size, l.color, glyphs, origins)
+}
+
+// OffsetAt returns the byte offset in the text of the caret position nearest
+// to p, relative to the layout's top left corner.
+func (l *TextLayout) OffsetAt(p Position) int {
+	l.layout()
+	i := int(math.Floor(p.Y / l.metrics.LineHeight))
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(l.lines) {
+		i = len(l.lines) - 1
+	}
+	line := &l.lines[i]
+
+	// Where runs of opposite directions meet, the edge of a cluster may
+	// have no caret, so the nearest caret is searched for among all the
+	// line's offsets.
+	offset := line.start
+	best := math.Inf(1)
+	for _, c := range line.clusters {
+		for _, o := range [2]int{c.start, c.end} {
+			if o == line.end && !line.last {
+				// The offset belongs to the next line.
+				continue
+			}
+			if d := math.Abs(line.caret(o) - p.X); d < best {
+				offset, best = o, d
+			}
+		}
+	}
+	return offset
+}
+
+// Caret returns the caret at a byte offset in the text, as a rectangle of
+// no width spanning its line.
+func (l *TextLayout) Caret(offset int) Rectangle {
+	l.layout()
+	line := &l.lines[len(l.lines)-1]
+	for i := range l.lines {
+		ln := &l.lines[i]
+		if offset >= ln.start && (offset < ln.end || offset == ln.end && ln.last) {
+			line = ln
+			break
+		}
+	}
+	x := line.caret(offset)
+	return Rectangle{Position{x, line.top}, Position{x, line.top + l.metrics.LineHeight}}
+}
+
+// caret returns the horizontal position of the caret at an offset in the
+// line.
+func (line *textLine) caret(offset int) float64 {
+	// A caret before a cluster is at its leading edge; otherwise it is after
+	// one, at its trailing edge.
+	for _, c := range line.clusters {
+		if c.start == offset {
+			if c.rtl() {
+				return c.x + c.width
+			}
+			return c.x
+		}
+	}
+	for _, c := range line.clusters {
+		if c.end == offset || c.start < offset && offset < c.end {
+			if c.rtl() {
+				return c.x
+			}
+			return c.x + c.width
+		}
+	}
+	if line.rtl {
+		return line.x + line.width()
+	}
+	return line.x
+}
+
+// width returns the width of the line's content, without hanging spaces.
+func (line *textLine) width() float64 {
+	w := 0.
+	for _, c := range line.clusters {
+		if !c.space() {
+			w = math.Max(w, c.x+c.width-line.x)
+		}
+	}
+	return w
+}
+
+func (l *TextLayout) layout() {
+	if l.valid {
+		return
+	}
+	l.valid = true
+	l.lines = nil
+	l.metrics = l.font.Metrics(l.size)
+
+	l.font.mu.Lock()
+	for start := 0; ; {
+		end, next := paragraphEnd(l.text, start)
+		l.layoutParagraph(start, end)
+		if next < 0 {
+			break
+		}
+		start = next
+	}
+	l.font.mu.Unlock()
+
+	l.boxWidth = l.width
+	if l.boxWidth <= 0 {
+		l.boxWidth = 0
+		for i := range l.lines {
+			l.boxWidth = math.Max(l.boxWidth, l.lines[i].width())
+		}
+	}
+	for i := range l.lines {
+		line := &l.lines[i]
+		line.top = float64(i) * l.metrics.LineHeight
+
+		var dx float64
+		switch w := line.width(); l.align {
+		case TextAlignStart:
+			if line.rtl {
+				dx = l.boxWidth - w
+			}
+		case TextAlignEnd:
+			if !line.rtl {
+				dx = l.boxWidth - w
+			}
+		case TextAlignRight:
+			dx = l.boxWidth - w
+		case TextAlignCenter:
+			dx = (l.boxWidth - w) / 2
+		}
+		dx -= line.x
+		line.x += dx
+		for j := range line.clusters {
+			line.clusters[j].x += dx
+		}
+	}
+}
+
+// paragraphEnd returns the end of the paragraph starting at start and the
+// start of the next, or -1 if there is none.
+func paragraphEnd(text string, start int) (end, next int) {
+	for i, r := range text[start:] {
+		switch r {
+		case '\n', '\u2029':
+			end = start + i
+			if r == '\n' && end > start && text[end-1] == '\r' {
+				end--
+			}
+			return end, start + i + utf8.RuneLen(r)
+		}
+	}
+	return len(text), -1
+}
+
+func (l *TextLayout) layoutParagraph(start, end int) {
+	var runes []textRune
+	var rs []rune
+	var classes []bidi.Class
+	for i, r := range l.text[start:end] {
+		c := bidiClass(r)
+		runes = append(runes, textRune{r: r, offset: start + i, class: c})
+		rs = append(rs, r)
+		classes = append(classes, c)
+	}
+	var level uint8
+	switch l.dir {
+	case TextDirectionAuto:
+		level = baseLevel(classes)
+	case TextDirectionRightToLeft:
+		level = 1
+	}
+	for i, lv := range bidiLevels(rs, classes, level) {
+		runes[i].level = lv
+	}
+	breaks := lineBreaks(rs)
+	breakComplexContext(rs, breaks)
+	for i, b := range breaks {
+		runes[i].brk = b
+	}
+
+	clusters := shape(runes, l.font, l.size)
+	for _, r := range breakLines(clusters, l.width) {
+		line := textLine{start: start, end: end, rtl: level%2 == 1}
+		if r[0] < r[1] {
+			line.start = clusters[r[0]].start
+			line.end = clusters[r[1]-1].end
+		}
+		if r[1] == len(clusters) {
+			line.end = end
+			line.last = true
+		}
+		line.clusters = orderLine(clusters[r[0]:r[1]], level)
+		if line.rtl {
+			// Hanging spaces are on the left of right-to-left lines.
+			for _, c := range line.clusters {
+				if !c.space() {
+					break
+				}
+				line.x = c.x + c.width
+			}
+		}
+		l.lines = append(l.lines, line)
+	}
+}
+
+// breakLines returns the ranges of clusters in each line when wrapped to
+// width, or to no width if it is zero.  Lines end after mandatory breaks,
+// spaces hang past the end of a line, and a word too long for one is broken
+// wherever it must be.
+func breakLines(cs []textCluster, width float64) [][2]int {
+	var lines [][2]int
+	start := 0
+	x := 0.
+	// brk is the last cluster in the line after which it may break.
+	brk := -1
+	for i := range cs {
+		c := &cs[i]
+		if width > 0 && !c.space() && x+c.width > width && i > start {
+			end := i
+			if brk >= start {
+				end = brk + 1
+			}
+			lines = append(lines, [2]int{start, end})
+			start = end
+			x = 0
+			for _, c := range cs[start:i] {
+				x += c.width
+			}
+			brk = -1
+		}
+		x += c.width
+		if i+1 == len(cs) {
+			break
+		}
+		switch c.brk {
+		case breakMandatory:
+			lines = append(lines, [2]int{start, i + 1})
+			start = i + 1
+			x = 0
+			brk = -1
+		case breakAllowed:
+			brk = i
+		}
+	}
+	return append(lines, [2]int{start, len(cs)})
+}
+
+// orderLine returns the clusters of a line in visual order, placed from
+// zero.  Spaces at the end of the line take the paragraph's level, so that
+// they hang at its end (rule L1).
+func orderLine(cs []textCluster, level uint8) []textCluster {
+	classes := make([]bidi.Class, len(cs))
+	levels := make([]uint8, len(cs))
+	for i := range cs {
+		classes[i] = cs[i].class
+		levels[i] = cs[i].level
+	}
+	resetWhitespaceLevels(classes, levels, level)
+
+	ordered := make([]textCluster, len(cs))
+	x := 0.
+	for i, j := range visualOrder(levels) {
+		c := cs[j]
+		c.level = levels[j]
+		c.x = x
+		x += c.width
+		ordered[i] = c
+	}
+	return ordered
+}
@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+	"unicode"
+)
+
+// A testGlyph is a glyph of a font made by testFont.
+type testGlyph struct {
+	// r is the character that maps to the glyph, or 0 if none does.
+	r       rune
+	advance uint16
+}
+
+// testFont returns a font with 1000 units per em whose glyphs are the
+// missing glyph followed by glyphs, each a box the width of its advance, or
+// nothing if it is a space.  tables holds more tables by tag, such as GSUB.
+func testFont(t *testing.T, glyphs []testGlyph, tables map[string][]byte) *Font {
+	all := append([]testGlyph{{0, 500}}, glyphs...)
+	put := func(b *bytes.Buffer, vs ...interface{}) {
+		for _, v := range vs {
+			binary.Write(b, binary.BigEndian, v)
+		}
+	}
+	tabs := map[string][]byte{}
+	for tag, data := range tables {
+		tabs[tag] = data
+	}
+
+	var head, hhea, maxp, hmtx, loca, glyf, cmap, post bytes.Buffer
+	put(&head, uint32(0x10000), uint32(0), uint32(0), uint32(0x5F0F3CF5), uint16(0), uint16(1000),
+		uint64(0), uint64(0), int16(0), int16(-200), int16(1000), int16(800), uint16(0), uint16(8), int16(2), int16(1), int16(0))
+	put(&hhea, uint32(0x10000), int16(800), int16(-200), int16(0), uint16(1000), int16(0), int16(0), int16(1000),
+		int16(1), int16(0), int16(0), [5]int16{}, uint16(len(all)))
+	put(&maxp, uint32(0x10000), uint16(len(all)), [13]uint16{})
+	var runes []rune
+	gid := map[rune]int{}
+	for i, g := range all {
+		put(&hmtx, g.advance, int16(0))
+		put(&loca, uint32(glyf.Len()))
+		if g.r != 0 {
+			runes = append(runes, g.r)
+			gid[g.r] = i
+		}
+		if unicode.IsSpace(g.r) || g.advance == 0 {
+			continue
+		}
+		w := int16(g.advance)
+		put(&glyf, int16(1), int16(0), int16(0), w, int16(700), uint16(3), uint16(0),
+			[4]uint8{1, 1, 1, 1}, [4]int16{0, w, 0, -w}, [4]int16{0, 0, 700, 0}, uint16(0))
+	}
+	put(&loca, uint32(glyf.Len()))
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	put(&cmap, uint16(0), uint16(1), uint16(3), uint16(10), uint32(12),
+		uint16(12), uint16(0), uint32(16+12*len(runes)), uint32(0), uint32(len(runes)))
+	for _, r := range runes {
+		put(&cmap, uint32(r), uint32(r), uint32(gid[r]))
+	}
+	put(&post, uint32(0x30000), [7]uint32{})
+	for tag, b := range map[string]*bytes.Buffer{"head": &head, "hhea": &hhea, "maxp": &maxp, "hmtx": &hmtx, "loca": &loca, "glyf": &glyf, "cmap": &cmap, "post": &post} {
+		tabs[tag] = b.Bytes()
+	}
+
+	var tags []string
+	for tag := range tabs {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var dir, data bytes.Buffer
+	put(&dir, uint32(0x10000), uint16(len(tags)), [3]uint16{})
+	offset := 12 + 16*len(tags)
+	for _, tag := range tags {
+		b := tabs[tag]
+		put(&dir, []byte(tag), uint32(0), uint32(offset+data.Len()), uint32(len(b)))
+		data.Write(b)
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+	f, err := ParseFont(append(dir.Bytes(), data.Bytes()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// bidiFont returns a font whose characters for Latin and Hebrew letters,
+// digits, spaces and punctuation are all 5 wide at size 10.
+func bidiFont(t *testing.T) *Font {
+	var glyphs []testGlyph
+	for _, r := range "abcdefghijklmnopqrstuvwxyz0123456789 .,!?()-אבגדהוזחטיכלמנסעפצקרשת" {
+		glyphs = append(glyphs, testGlyph{r, 500})
+	}
+	return testFont(t, glyphs, nil)
+}
+
+// lineText returns the text of the clusters of each line of l, in visual
+// order.
+func lineText(l *TextLayout) []string {
+	var lines []string
+	for _, line := range l.lines {
+		var s []rune
+		for _, c := range line.clusters {
+			s = append(s, []rune(l.text[c.start:c.end])...)
+		}
+		lines = append(lines, string(s))
+	}
+	return lines
+}
+
+func TestTextLayoutLines(t *testing.T) {
+	font := bidiFont(t)
+	for _, test := range []struct {
+		text  string
+		dir   TextDirection
+		width float64
+		lines []string
+	}{
+		{"abc אבג def", TextDirectionAuto, 0, []string{"abc גבא def"}},
+		{"אבג abc דה", TextDirectionAuto, 0, []string{"הד abc גבא"}},
+		{"אבג abc", TextDirectionLeftToRight, 0, []string{"גבא abc"}},
+		{"abc 123", TextDirectionRightToLeft, 0, []string{"abc 123"}},
+		{"אבג 123 דה", TextDirectionAuto, 0, []string{"הד 123 גבא"}},
+		{"(אב)", TextDirectionLeftToRight, 0, []string{"(בא)"}},
+		// Spaces at the ends of lines hang on the paragraph's side.
+		{"abc אבג def", TextDirectionAuto, 40, []string{"abc גבא ", "def"}},
+		{"אבג abc דה", TextDirectionAuto, 40, []string{" abc גבא", "הד"}},
+		{"אבג דהו abc", TextDirectionAuto, 20, []string{" גבא", " והד", "abc"}},
+		{"ab אבגדהוזח", TextDirectionAuto, 30, []string{"ab ", "והדגבא", "חז"}},
+	} {
+		l := NewTextLayout(test.text, font, 10)
+		l.SetDirection(test.dir)
+		l.SetWidth(test.width)
+		l.layout()
+		if got := lineText(l); !equalLines(got, test.lines) {
+			t.Errorf("%q at width %g: got lines %q, want %q", test.text, test.width, got, test.lines)
+		}
+	}
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBreakLinesMixed(t *testing.T) {
+	font := bidiFont(t)
+	// Breaks are found in logical order, whatever the direction of the
+	// words.
+	l := NewTextLayout("ab אבג דה cd", font, 10)
+	l.layout()
+	cs := l.lines[0].clusters
+	sort.Slice(cs, func(i, j int) bool { return cs[i].start < cs[j].start })
+	got := breakLines(cs, 35)
+	want := [][2]int{{0, 7}, {7, 12}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got lines %v, want %v", got, want)
+	}
+}
+
+func TestTextLayoutCaret(t *testing.T) {
+	font := bidiFont(t)
+	for _, test := range []struct {
+		text string
+		dir  TextDirection
+		// x holds the caret's position at each character boundary.
+		x []float64
+	}{
+		{"abc", TextDirectionAuto, []float64{0, 5, 10, 15}},
+		{"אבג", TextDirectionAuto, []float64{15, 10, 5, 0}},
+		// The caret between runs is at the leading edge of the character
+		// after it, so the trailing edge of the run before has none.
+		{"ab אב cd", TextDirectionAuto, []float64{0, 5, 10, 25, 20, 25, 30, 35, 40}},
+		{"אב ab גד", TextDirectionAuto, []float64{40, 35, 30, 15, 20, 15, 10, 5, 0}},
+		{"אב 12", TextDirectionAuto, []float64{25, 20, 15, 0, 5, 10}},
+		{"ab 12", TextDirectionRightToLeft, []float64{0, 5, 10, 15, 20, 25}},
+	} {
+		l := NewTextLayout(test.text, font, 10)
+		l.SetDirection(test.dir)
+		i := 0
+		for offset := range test.text + "." {
+			if offset > len(test.text) {
+				break
+			}
+			c := l.Caret(offset)
+			if c.Min.X != test.x[i] || c.Max.X != c.Min.X || c.Min.Y != 0 || c.Max.Y != 10 {
+				t.Errorf("%q: caret at %d is %v, want x %g", test.text, offset, c, test.x[i])
+			}
+			i++
+		}
+	}
+}
+
+// TestTextLayoutRoundTrip checks that the caret at every offset of mixed
+// text leads back, through OffsetAt, to an offset with a caret in the same
+// place, and that OffsetAt finds the nearest caret anywhere on a line.
+func TestTextLayoutRoundTrip(t *testing.T) {
+	font := bidiFont(t)
+	for _, test := range []struct {
+		text  string
+		dir   TextDirection
+		width float64
+	}{
+		{"abc אבג def", TextDirectionAuto, 0},
+		{"אבג abc דהו", TextDirectionAuto, 0},
+		{"abc 123 אבג 45 de", TextDirectionRightToLeft, 0},
+		{"(אבג) abc, דה!", TextDirectionLeftToRight, 0},
+		{"abc אבג def גדה hij", TextDirectionAuto, 40},
+		{"אבג abc דהו ghi זחט", TextDirectionAuto, 40},
+		{"ab אבגדהוזח cd", TextDirectionAuto, 30},
+		{"abc\nאבג def\n", TextDirectionAuto, 0},
+	} {
+		l := NewTextLayout(test.text, font, 10)
+		l.SetDirection(test.dir)
+		l.SetWidth(test.width)
+		var offsets []int
+		for o := range test.text {
+			offsets = append(offsets, o)
+		}
+		offsets = append(offsets, len(test.text))
+
+		carets := map[Position]bool{}
+		for _, o := range offsets {
+			c := l.Caret(o)
+			carets[c.Min] = true
+			p := Position{c.Min.X, (c.Min.Y + c.Max.Y) / 2}
+			o2 := l.OffsetAt(p)
+			if c2 := l.Caret(o2); c2 != c {
+				t.Errorf("%q: caret at %d is %v, but at OffsetAt(%v) = %d is %v", test.text, o, c, p, o2, c2)
+			}
+		}
+
+		// Between carets, OffsetAt is the offset of the nearest.
+		for i, line := range l.lines {
+			for x := line.x - 2; x < line.x+line.width()+2; x += .5 {
+				p := Position{x, float64(i)*10 + 5}
+				c := l.Caret(l.OffsetAt(p))
+				if c.Min.Y != float64(i)*10 {
+					t.Errorf("%q: OffsetAt(%v) is on the wrong line", test.text, p)
+					continue
+				}
+				best := math.Inf(1)
+				for q := range carets {
+					if q.Y == c.Min.Y {
+						best = math.Min(best, math.Abs(q.X-x))
+					}
+				}
+				if d := math.Abs(c.Min.X - x); d > best {
+					t.Errorf("%q: OffsetAt(%v) has its caret at %g, %g away, but one is %g away", test.text, p, c.Min.X, d, best)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"image"
+	"image/draw"
+)
+
+// A Texture is an image held by a Graphics for drawing.
+type Texture struct {
+	texture rendererTexture
+	size    image.Point
+}
+
+// A TextureFilter is how a texture is sampled between its texels.
+type TextureFilter int
+
+const (
+	// TextureFilterLinear blends neighboring texels, for smooth scaling.
+	TextureFilterLinear TextureFilter = iota
+	// TextureFilterNearest takes the nearest texel, for crisp pixel art.
+	TextureFilterNearest
+)
+
+// A TextureWrap is how a texture is sampled outside its bounds.
+type TextureWrap int
+
+const (
+	// TextureWrapClamp extends the texture's edges.
+	TextureWrapClamp TextureWrap = iota
+	// TextureWrapRepeat tiles the texture.  OpenGL ES 2 supports it only
+	// for textures whose sides are powers of two.
+	TextureWrapRepeat
+	// TextureWrapMirror tiles the texture, mirroring every other tile.  It
+	// has the same restriction as TextureWrapRepeat.
+	TextureWrapMirror
+)
+
+// NewTexture returns a Texture of the size of img holding a copy of it.
+func NewTexture(gfx *Graphics, img image.Image) *Texture {
+	size := img.Bounds().Size()
+	t := &Texture{
+		texture: gfx.renderer.newTexture(size.X, size.Y),
+		size:    size,
+	}
+	t.Update(image.Point{}, img)
+	return t
+}
+
+// Size returns the size of t in texels.
+func (t *Texture) Size() image.Point { return t.size }
+
+// Update copies img into t with its top left corner at p.  Parts outside t
+// are ignored.
+func (t *Texture) Update(p image.Point, img image.Image) {
+	r := image.Rectangle{p, p.Add(img.Bounds().Size())}.Intersect(image.Rectangle{Max: t.size})
+	if r.Empty() {
+		return
+	}
+	src, ok := img.(*image.NRGBA)
+	if !ok || r.Min != p {
+		src = image.NewNRGBA(image.Rectangle{Max: r.Size()})
+		draw.Draw(src, src.Rect, img, img.Bounds().Min.Add(r.Min.Sub(p)), draw.Src)
+	} else {
+		src = src.SubImage(image.Rectangle{src.Rect.Min, src.Rect.Min.Add(r.Size())}).(*image.NRGBA)
+	}
+	t.texture.upload(r.Min.X, r.Min.Y, src)
+}
+
+// SetFilter sets how t is sampled between texels when drawn.  The default is
+// TextureFilterLinear.
+func (t *Texture) SetFilter(f TextureFilter) {
+	t.texture.setFilter(f)
+}
+
+// SetWrap sets how t is sampled outside its bounds when drawn.  The default
+// is TextureWrapClamp.  OpenGL ES 2 supports the other modes only if both
+// sides of t are powers of two; otherwise, t draws as black.
+func (t *Texture) SetWrap(w TextureWrap) {
+	t.texture.setWrap(w)
+}
+
+// Release frees t's resources.  t must not be drawn after.
+func (t *Texture) Release() {
+	t.texture.release()
+}
+
+// DrawImage draws the part src of tex, in texels, stretched over dst, in view
+// coordinates, and faded by opacity from 0 to 1.  Parts of src outside tex
+// are sampled by its wrap mode.
+func (g *Graphics) DrawImage(tex *Texture, dst Rectangle, src image.Rectangle, opacity float64) {
+	w, h := float64(tex.size.X), float64(tex.size.Y)
+	if w == 0 || h == 0 {
+		return
+	}
+	u0, v0 := float64(src.Min.X)/w, float64(src.Min.Y)/h
+	u1, v1 := float64(src.Max.X)/w, float64(src.Max.Y)/h
+	a := float32(opacity)
+	vertex := func(p Position, u, v float64) []float32 {
+		return []float32{float32(p.X), float32(p.Y), float32(u), float32(v), 1, 1, 1, a}
+	}
+	p0, p1 := dst.Min, dst.Max
+	var data []float32
+	for _, v := range [][]float32{
+		vertex(p0, u0, v0), vertex(Position{p1.X, p0.Y}, u1, v0), vertex(p1, u1, v1),
+		vertex(p0, u0, v0), vertex(p1, u1, v1), vertex(Position{p0.X, p1.Y}, u0, v1),
+	} {
+		data = append(data, v...)
+	}
+	b := g.renderer.newBuffer(data)
+	defer b.release()
+	g.renderer.drawTexturedTriangles(b, tex.texture, g.proj.Mul4(g.view))
+}
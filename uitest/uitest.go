@@ -0,0 +1,178 @@
+// Package uitest provides golden-image tests for views.
+//
+// A golden test renders a view with the software renderer, so that its output
+// is the same on every machine, and compares it with a PNG file in the
+// testdata directory of the package under test:
+//
+//	func TestButton(t *testing.T) {
+//		uitest.Golden(t, "button", NewButton(nil), ui.Size{Width: 30, Height: 10}, nil)
+//	}
+//
+// Run the tests with -update to write the golden files from the current
+// output.  When a comparison fails, the actual output and an image
+// highlighting the differing pixels are written beside the golden file.
+package uitest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+var update = flag.Bool("update", false, "update golden images in testdata")
+
+// Options configure a golden test.
+type Options struct {
+	// Tolerance is the largest difference allowed in any color channel of
+	// a pixel.
+	Tolerance uint8
+
+	// Resolution is in pixels per millimetre.  If zero,
+	// ui.DefaultHeadlessResolution is used.
+	Resolution float64
+}
+
+// Render draws v at the given size in a software headless window and returns
+// the result.  v must not be in another window; it is removed from the
+// headless window afterwards.
+func Render(v ui.View, size ui.Size, resolution float64) (*image.RGBA, error) {
+	w, err := ui.NewSoftwareHeadlessWindow(size, v)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	defer w.Do(func() { v.SetParent(nil) })
+
+	if resolution != 0 {
+		w.SetResolution(resolution)
+	}
+	return w.Capture()
+}
+
+// Compare compares got with want and returns the number of pixels that differ
+// by more than tolerance in any channel, together with an image that shows
+// them in red over a faded copy of want.
+func Compare(got, want *image.RGBA, tolerance uint8) (n int, diff *image.RGBA) {
+	b := got.Bounds()
+	if b.Size() != want.Bounds().Size() {
+		return b.Dx() * b.Dy(), nil
+	}
+
+	diff = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			g := got.RGBAAt(b.Min.X+x, b.Min.Y+y)
+			w := want.RGBAAt(want.Rect.Min.X+x, want.Rect.Min.Y+y)
+			if channelDiff(g.R, w.R) > tolerance || channelDiff(g.G, w.G) > tolerance || channelDiff(g.B, w.B) > tolerance || channelDiff(g.A, w.A) > tolerance {
+				n++
+				diff.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			gray := uint8((uint32(w.R)*299 + uint32(w.G)*587 + uint32(w.B)*114) / 1000 / 4)
+			diff.SetRGBA(x, y, color.RGBA{gray, gray, gray, 255})
+		}
+	}
+	return n, diff
+}
+
+func channelDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// Golden renders v at the given size and compares the result with
+// testdata/name.png, failing t if they differ.  opts may be nil.
+func Golden(t testing.TB, name string, v ui.View, size ui.Size, opts *Options) {
+	t.Helper()
+
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	got, err := Render(v, size, opts.Resolution)
+	if err != nil {
+		t.Fatalf("rendering %s: %v", name, err)
+	}
+
+	path := filepath.Join("testdata", name+".png")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writePNG(path, got); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := readPNG(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden image %s does not exist; run the test with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, diff := Compare(got, want, opts.Tolerance)
+	if n == 0 {
+		return
+	}
+
+	gotPath := filepath.Join("testdata", name+".got.png")
+	if err := writePNG(gotPath, got); err != nil {
+		t.Error(err)
+	}
+	if diff == nil {
+		t.Fatalf("%s: size %v differs from golden size %v; actual output written to %s", name, got.Bounds().Size(), want.Bounds().Size(), gotPath)
+	}
+	diffPath := filepath.Join("testdata", name+".diff.png")
+	if err := writePNG(diffPath, diff); err != nil {
+		t.Error(err)
+	}
+	t.Fatalf("%s: %d pixels differ from %s; actual output written to %s, differences to %s", name, n, path, gotPath, diffPath)
+}
+
+func readPNG(path string) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", path, err)
+	}
+	if m, ok := m.(*image.RGBA); ok {
+		return m, nil
+	}
+	b := m.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			img.Set(x, y, m.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return img, nil
+}
+
+func writePNG(path string, m image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,274 @@
+package uitest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// box is a view filled with a color.
+type box struct {
+	ui.View
+	color ui.Color
+}
+
+func newBox(c ui.Color) *box {
+	b := &box{color: c}
+	b.View = ui.NewView(b, nil)
+	return b
+}
+
+func (b *box) Draw(gfx *ui.Graphics) {
+	r := b.Rect()
+	var p ui.Path
+	p.MoveTo(r.Min)
+	p.LineTo(ui.Position{X: r.Max.X, Y: r.Min.Y})
+	p.LineTo(r.Max)
+	p.LineTo(ui.Position{X: r.Min.X, Y: r.Max.Y})
+	p.Close()
+	gfx.Fill(&p, ui.Paint{Color: b.color})
+}
+
+func uniform(w, h int, c color.RGBA) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(m.Pix); i += 4 {
+		m.Pix[i], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return m
+}
+
+func TestCompareTolerance(t *testing.T) {
+	want := uniform(4, 3, color.RGBA{100, 100, 100, 255})
+	got := uniform(4, 3, color.RGBA{100, 100, 100, 255})
+	got.SetRGBA(1, 1, color.RGBA{100, 103, 100, 255})
+	got.SetRGBA(2, 2, color.RGBA{97, 100, 100, 255})
+
+	for _, test := range []struct {
+		tolerance uint8
+		n         int
+	}{
+		{0, 2},
+		{2, 2},
+		{3, 0},
+		{255, 0},
+	} {
+		if n, _ := Compare(got, want, test.tolerance); n != test.n {
+			t.Errorf("tolerance %d: got %d differing pixels, want %d", test.tolerance, n, test.n)
+		}
+	}
+}
+
+func TestCompareSize(t *testing.T) {
+	n, diff := Compare(uniform(4, 3, color.RGBA{}), uniform(3, 4, color.RGBA{}), 0)
+	if n != 12 || diff != nil {
+		t.Errorf("got %d differing pixels and diff %v, want 12 and nil", n, diff != nil)
+	}
+}
+
+// TestCompareOffset checks that images are compared by their positions
+// relative to their bounds.
+func TestCompareOffset(t *testing.T) {
+	want := uniform(4, 3, color.RGBA{10, 20, 30, 255})
+	got := uniform(6, 5, color.RGBA{10, 20, 30, 255}).SubImage(image.Rect(2, 2, 6, 5)).(*image.RGBA)
+	if n, _ := Compare(got, want, 0); n != 0 {
+		t.Errorf("got %d differing pixels, want 0", n)
+	}
+}
+
+func TestCompareDiffImage(t *testing.T) {
+	want := uniform(3, 2, color.RGBA{200, 200, 200, 255})
+	got := uniform(3, 2, color.RGBA{200, 200, 200, 255})
+	got.SetRGBA(2, 0, color.RGBA{0, 0, 0, 255})
+
+	n, diff := Compare(got, want, 0)
+	if n != 1 {
+		t.Fatalf("got %d differing pixels, want 1", n)
+	}
+	if b := diff.Bounds(); b != image.Rect(0, 0, 3, 2) {
+		t.Fatalf("diff bounds are %v, want %v", b, image.Rect(0, 0, 3, 2))
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			// Matching pixels are a faded gray copy of want.
+			want := color.RGBA{50, 50, 50, 255}
+			if x == 2 && y == 0 {
+				want = color.RGBA{255, 0, 0, 255}
+			}
+			if c := diff.RGBAAt(x, y); c != want {
+				t.Errorf("diff at (%d, %d) is %v, want %v", x, y, c, want)
+			}
+		}
+	}
+}
+
+// inTempDir runs f in a new temporary directory.
+func inTempDir(t *testing.T, f func()) {
+	dir, err := ioutil.TempDir("", "uitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	f()
+}
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.failed = true
+	r.msg += fmt.Sprint(args...)
+}
+
+func (r *recorder) Fatal(args ...interface{}) {
+	r.Error(args...)
+	runtime.Goexit()
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Fatal(fmt.Sprintf(format, args...))
+}
+
+// golden runs Golden with a recorder, which it returns.
+func golden(t *testing.T, name string, v ui.View, size ui.Size, opts *Options) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Golden(r, name, v, size, opts)
+	}()
+	<-done
+	return r
+}
+
+func setUpdate(u bool) func() {
+	old := *update
+	*update = u
+	return func() { *update = old }
+}
+
+var (
+	size = ui.Size{Width: 10, Height: 5}
+	res  = 2.
+)
+
+func TestGoldenUpdate(t *testing.T) {
+	inTempDir(t, func() {
+		defer setUpdate(true)()
+		opts := &Options{Resolution: res}
+		if r := golden(t, "box", newBox(ui.Color{R: 1, A: 1}), size, opts); r.failed {
+			t.Fatalf("updating failed: %s", r.msg)
+		}
+
+		want, err := Render(newBox(ui.Color{R: 1, A: 1}), size, res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := readPNG(filepath.Join("testdata", "box.png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := Compare(got, want, 0); n != 0 {
+			t.Errorf("written golden differs from the output in %d pixels", n)
+		}
+		if got.Bounds().Size() != image.Pt(20, 10) {
+			t.Errorf("golden size is %v, want %v", got.Bounds().Size(), image.Pt(20, 10))
+		}
+
+		*update = false
+		if r := golden(t, "box", newBox(ui.Color{R: 1, A: 1}), size, opts); r.failed {
+			t.Errorf("comparing with the updated golden failed: %s", r.msg)
+		}
+	})
+}
+
+func TestGoldenMismatch(t *testing.T) {
+	inTempDir(t, func() {
+		defer setUpdate(false)()
+		opts := &Options{Resolution: res}
+		if err := os.Mkdir("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writePNG(filepath.Join("testdata", "box.png"), uniform(20, 10, color.RGBA{0, 0, 255, 255})); err != nil {
+			t.Fatal(err)
+		}
+
+		r := golden(t, "box", newBox(ui.Color{R: 1, A: 1}), size, opts)
+		if !r.failed {
+			t.Fatal("a mismatched golden passed")
+		}
+		if !strings.Contains(r.msg, "200 pixels differ") {
+			t.Errorf("unexpected failure message %q", r.msg)
+		}
+		got, err := readPNG(filepath.Join("testdata", "box.got.png"))
+		if err != nil {
+			t.Fatalf("reading the actual output: %v", err)
+		}
+		if c := got.RGBAAt(5, 5); c != (color.RGBA{255, 0, 0, 255}) {
+			t.Errorf("actual output is %v, want red", c)
+		}
+		diff, err := readPNG(filepath.Join("testdata", "box.diff.png"))
+		if err != nil {
+			t.Fatalf("reading the difference image: %v", err)
+		}
+		if c := diff.RGBAAt(5, 5); c != (color.RGBA{255, 0, 0, 255}) {
+			t.Errorf("difference image is %v, want red", c)
+		}
+
+		// Within tolerance, the same output passes.
+		opts.Tolerance = 255
+		if r := golden(t, "box", newBox(ui.Color{R: 1, A: 1}), size, opts); r.failed {
+			t.Errorf("failed within tolerance: %s", r.msg)
+		}
+	})
+}
+
+func TestGoldenSizeMismatch(t *testing.T) {
+	inTempDir(t, func() {
+		defer setUpdate(false)()
+		if err := os.Mkdir("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writePNG(filepath.Join("testdata", "box.png"), uniform(3, 3, color.RGBA{255, 0, 0, 255})); err != nil {
+			t.Fatal(err)
+		}
+		r := golden(t, "box", newBox(ui.Color{R: 1, A: 1}), size, &Options{Resolution: res})
+		if !r.failed || !strings.Contains(r.msg, "differs from golden size") {
+			t.Errorf("unexpected result %v, %q", r.failed, r.msg)
+		}
+		if _, err := os.Stat(filepath.Join("testdata", "box.diff.png")); !os.IsNotExist(err) {
+			t.Errorf("a difference image was written for differing sizes")
+		}
+	})
+}
+
+func TestGoldenMissing(t *testing.T) {
+	inTempDir(t, func() {
+		defer setUpdate(false)()
+		r := golden(t, "box", newBox(ui.Color{R: 1, A: 1}), size, nil)
+		if !r.failed || !strings.Contains(r.msg, "-update") {
+			t.Errorf("unexpected result %v, %q", r.failed, r.msg)
+		}
+	})
+}
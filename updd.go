@@ -31,18 +31,17 @@ func init() {
 			delete(touches, id)
 		}
 		p.Position = Position{float64(x), float64(y)}
+		p.setDefaultPressure()
 
-		windowsMu.Lock()
-		for _, w := range windows {
+		for _, w := range openWindows() {
 			p := *p
 			// TODO: only send to the active window, otherwise activate the window, if in rect
 			p.Position = w.MapFromParent(p.Position)
-			w.pointerEvents <- pointerEvent{
+			w.send(pointerEvent{
 				down: down,
 				up:   up,
 				p:    p,
-			}
+			})
 		}
-		windowsMu.Unlock()
 	})
 }
@@ -8,8 +8,16 @@ type View interface {
 
 	Do(func())
 
-	// SizePolicy() SizePolicy
-	// SetSizePolicy(SizePolicy)
+	// SizePolicy describes the sizes the view can take.  Containers compute
+	// theirs from their children's.
+	SizePolicy() SizePolicy
+	SetSizePolicy(SizePolicy)
+
+	// Layout positions and sizes the view's children.  It is called before
+	// drawing whenever the view's layout is invalid.
+	Layout()
+	// InvalidateLayout marks the layout of the view and its ancestors invalid.
+	InvalidateLayout()
 
 	// Position in parent's coordinate system.
 	Position() Position
@@ -31,17 +39,87 @@ type View interface {
 
 	Draw(*Graphics)
 
-	PointerDown(Pointer)
-	PointerMove(Pointer)
-	PointerUp(Pointer)
+	// PointerDown, PointerMove and PointerUp handle a pointer event,
+	// returning false to pass it on to the parent.  The view that handles
+	// PointerDown receives the pointer's later events.  Moves of a pointer
+	// that is not down go to the view under it.
+	PointerDown(Pointer) bool
+	PointerMove(Pointer) bool
+	PointerUp(Pointer) bool
+	// InterceptPointerDown, InterceptPointerMove and InterceptPointerUp see
+	// pointer events bound for the view's descendants before they do, from
+	// the window down.  Returning true takes the event, and the pointer's
+	// later events, from the descendant; the view's own handler then
+	// receives it.
+	InterceptPointerDown(Pointer) bool
+	InterceptPointerMove(Pointer) bool
+	InterceptPointerUp(Pointer) bool
+	// PointerEnter and PointerLeave tell the view that a pointer has moved
+	// over or off it or one of its descendants.  They don't bubble.
+	PointerEnter(Pointer)
+	PointerLeave(Pointer)
+	// PointerCancel tells the view that it no longer receives a pointer's
+	// events, because another view captured the pointer or the system took
+	// it away.
+	PointerCancel(Pointer)
+
+	// SetPointerCapture directs the later events of a pointer that is down
+	// to v, until it goes up or the capture changes.
+	SetPointerCapture(id PointerID, v View)
+	// ReleasePointerCapture directs the later events of a pointer to
+	// whichever view is under it.
+	ReleasePointerCapture(id PointerID)
+	// PointerCapture returns the view capturing a pointer, if any.
+	PointerCapture(id PointerID) View
+	// AddGestureRecognizer attaches r to the view, to receive the pointer
+	// events of the view and its descendants.
+	AddGestureRecognizer(r GestureRecognizer)
+	RemoveGestureRecognizer(r GestureRecognizer)
+	GestureRecognizers() []GestureRecognizer
+
+	// Scroll handles a scroll event, returning false to pass it on to the
+	// parent.
+	Scroll(Scroll) bool
+
+	KeyDown(Key)
+	KeyUp(Key)
+
+	// SetFocus gives v the keyboard focus, or clears it if v is nil.
+	SetFocus(v View)
+	Focused() View
+	// Focusable reports whether the view takes focus when clicked or tabbed to.
+	Focusable() bool
+	SetFocusable(bool)
+	// FocusOrder returns the children in the order that Tab visits them.
+	FocusOrder() []View
+	FocusIn()
+	FocusOut()
 
 	Redraw()
 }
 
+// A SizePolicy constrains a view's size.  A zero Max dimension is unbounded.
 type SizePolicy struct {
 	Min, Max, Preferred Size
 }
 
+// Clamp returns s limited to the policy's Min and Max.
+func (p SizePolicy) Clamp(s Size) Size {
+	s.Width = clampDimension(s.Width, p.Min.Width, p.Max.Width)
+	s.Height = clampDimension(s.Height, p.Min.Height, p.Max.Height)
+	return s
+}
+
+func clampDimension(x, min, max float64) float64 {
+	if max > 0 && x > max {
+		x = max
+	}
+	if x < min {
+		x = min
+	}
+	return x
+}
+
 type view struct {
 	self     View
 	parent   *view
@@ -50,6 +128,13 @@ type view struct {
 	size     Size
 	rect     Rectangle
 
+	sizePolicy  SizePolicy
+	layoutValid bool
+
+	focusable bool
+
+	gestures []GestureRecognizer
+
 	transformToWindow      transform
 	transformToWindowValid bool
 }
@@ -62,6 +147,7 @@ func NewView(self, parent View) View {
 	if parent != nil {
 		v.parent = parent.view()
 		v.parent.children = append(v.parent.children, self)
+		parent.InvalidateLayout()
 	}
 
 	return v
@@ -78,10 +164,12 @@ func (v *view) SetParent(p View) {
 				break
 			}
 		}
+		v.parent.self.InvalidateLayout()
 	}
 	if p != nil {
 		v.parent = p.view()
 		v.parent.children = append(v.parent.children, v.self)
+		p.InvalidateLayout()
 	} else {
 		v.parent = nil
 	}
@@ -90,7 +178,7 @@ func (v *view) SetParent(p View) {
 
 func (v *view) Do(f func()) {
 	if v.parent != nil {
-		v.parent.Do(f)
+		v.parent.self.Do(f)
 	}
 }
 
@@ -102,10 +190,43 @@ func (v *view) Move(p Position) {
 
 func (v *view) Size() Size { return v.size }
 func (v *view) Resize(s Size) {
+	if s != v.size {
+		v.layoutValid = false
+	}
 	v.size = s
 	v.invalidateTransformToWindow()
 }
 
+func (v *view) SizePolicy() SizePolicy { return v.sizePolicy }
+func (v *view) SetSizePolicy(p SizePolicy) {
+	v.sizePolicy = p
+	if v.parent != nil {
+		v.parent.self.InvalidateLayout()
+	}
+}
+
+func (v *view) Layout() {}
+
+func (v *view) InvalidateLayout() {
+	v.layoutValid = false
+	if v.parent != nil {
+		v.parent.self.InvalidateLayout()
+	} else {
+		v.self.Redraw()
+	}
+}
+
+// layout lays out v and then its children, skipping views whose layout is valid.
+func (v *view) layout() {
+	if !v.layoutValid {
+		v.layoutValid = true
+		v.self.Layout()
+	}
+	for _, c := range v.children {
+		c.view().layout()
+	}
+}
+
 func (v *view) Rect() Rectangle {
 	if v.rect == (Rectangle{}) {
 		return Rectangle{Max: Position{v.size.Width, v.size.Height}}
@@ -127,9 +248,64 @@ func (v *view) draw(gfx *Graphics) {
 
 func (v *view) Draw(*Graphics) {}
 
-func (v *view) PointerDown(p Pointer) {}
-func (v *view) PointerMove(p Pointer) {}
-func (v *view) PointerUp(p Pointer)   {}
+func (v *view) PointerDown(p Pointer) bool { return false }
+func (v *view) PointerMove(p Pointer) bool { return false }
+func (v *view) PointerUp(p Pointer) bool   { return false }
+
+func (v *view) InterceptPointerDown(p Pointer) bool { return false }
+func (v *view) InterceptPointerMove(p Pointer) bool { return false }
+func (v *view) InterceptPointerUp(p Pointer) bool   { return false }
+
+func (v *view) PointerEnter(p Pointer)  {}
+func (v *view) PointerLeave(p Pointer)  {}
+func (v *view) PointerCancel(p Pointer) {}
+
+func (v *view) SetPointerCapture(id PointerID, c View) {
+	if v.parent != nil {
+		v.parent.self.SetPointerCapture(id, c)
+	}
+}
+
+func (v *view) ReleasePointerCapture(id PointerID) {
+	if v.parent != nil {
+		v.parent.self.ReleasePointerCapture(id)
+	}
+}
+
+func (v *view) PointerCapture(id PointerID) View {
+	if v.parent != nil {
+		return v.parent.self.PointerCapture(id)
+	}
+	return nil
+}
+
+func (v *view) Scroll(s Scroll) bool { return false }
+
+func (v *view) KeyDown(k Key) {}
+func (v *view) KeyUp(k Key)   {}
+
+func (v *view) SetFocus(f View) {
+	if v.parent != nil {
+		v.parent.self.SetFocus(f)
+	}
+}
+
+func (v *view) Focused() View {
+	if v.parent != nil {
+		return v.parent.self.Focused()
+	}
+	return nil
+}
+
+func (v *view) Focusable() bool     { return v.focusable }
+func (v *view) SetFocusable(f bool) { v.focusable = f }
+
+func (v *view) FocusOrder() []View {
+	return append([]View(nil), v.children...)
+}
+
+func (v *view) FocusIn()  {}
+func (v *view) FocusOut() {}
 
 func (v *view) Redraw() {
 	if v.parent != nil {
@@ -0,0 +1,100 @@
+// +build !android,!ios
+
+package ui_test
+
+import (
+	"testing"
+
+	"github.com/gordonklaus/ui"
+)
+
+// layoutCounter is a view that counts the calls to its Layout.
+type layoutCounter struct {
+	ui.View
+	layouts int
+}
+
+func newLayoutCounter(parent ui.View) *layoutCounter {
+	v := &layoutCounter{}
+	v.View = ui.NewView(v, parent)
+	return v
+}
+
+func (v *layoutCounter) Layout() { v.layouts++ }
+
+func TestWindowSizePolicy(t *testing.T) {
+	content := newBox(ui.Color{}, nil)
+	content.SetSizePolicy(ui.SizePolicy{
+		Min: ui.Size{Width: 20, Height: 20},
+		Max: ui.Size{Width: 50, Height: 40},
+	})
+	w := newHeadless(t, ui.Size{Width: 10, Height: 30}, content)
+	defer w.Close()
+
+	size := func() (s ui.Size) {
+		w.Do(func() { s = content.Size() })
+		return
+	}
+	if s, want := size(), (ui.Size{Width: 20, Height: 30}); s != want {
+		t.Errorf("content of a small window has size %v, want %v", s, want)
+	}
+	w.Do(func() { w.Resize(ui.Size{Width: 100, Height: 100}) })
+	if s, want := size(), (ui.Size{Width: 50, Height: 40}); s != want {
+		t.Errorf("content of a large window has size %v, want %v", s, want)
+	}
+
+	// A new policy takes effect at the next layout.
+	p := ui.SizePolicy{Max: ui.Size{Width: 60}}
+	var got ui.SizePolicy
+	w.Do(func() {
+		content.SetSizePolicy(p)
+		got = w.SizePolicy()
+	})
+	if got != p {
+		t.Errorf("window has size policy %v, want its content's %v", got, p)
+	}
+	if !w.Frame() {
+		t.Error("a window did not draw after its content's size policy changed")
+	}
+	if s, want := size(), (ui.Size{Width: 60, Height: 100}); s != want {
+		t.Errorf("content with a new policy has size %v, want %v", s, want)
+	}
+}
+
+func TestInvalidateLayout(t *testing.T) {
+	root := newLayoutCounter(nil)
+	a := newLayoutCounter(root)
+	b := newLayoutCounter(root)
+	child := newLayoutCounter(a)
+	w := newHeadless(t, ui.Size{Width: 10, Height: 10}, root)
+	defer w.Close()
+
+	counts := func() (n [4]int) {
+		w.Do(func() { n = [4]int{root.layouts, a.layouts, b.layouts, child.layouts} })
+		return
+	}
+	w.Frame()
+	if n := counts(); n != [4]int{1, 1, 1, 1} {
+		t.Fatalf("first frame laid out %v times, want once each", n)
+	}
+	if w.Frame() {
+		t.Error("a window drew without a pending redraw")
+	}
+
+	// Invalidating a view invalidates its ancestors, but not their other
+	// descendants, and requests a redraw.
+	w.Do(child.InvalidateLayout)
+	if !w.Frame() {
+		t.Error("a window did not draw after a layout was invalidated")
+	}
+	if n := counts(); n != [4]int{2, 2, 1, 2} {
+		t.Errorf("got layouts %v of the root, a, b and a's child, want [2 2 1 2]", n)
+	}
+
+	// So does a change to a size policy, starting with the parent.
+	w.Do(func() { b.SetSizePolicy(ui.SizePolicy{Preferred: ui.Size{Width: 1}}) })
+	w.Frame()
+	if n := counts(); n != [4]int{3, 2, 1, 2} {
+		t.Errorf("got layouts %v after a size policy changed, want [3 2 1 2]", n)
+	}
+}
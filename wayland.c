@@ -0,0 +1,826 @@
+// +build linux
+// +build !android,wayland
+
+#include "_cgo_export.h"
+#include <pthread.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/mman.h>
+#include <unistd.h>
+
+#include <wayland-client.h>
+#include <wayland-egl.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+#include <xkbcommon/xkbcommon.h>
+#include <xkbcommon/xkbcommon-compose.h>
+
+#include "wayland_text_input.h"
+#include "wayland_xdg_shell.h"
+
+typedef struct window {
+	struct wl_surface *surface;
+	struct xdg_surface *xdgSurface;
+	struct xdg_toplevel *toplevel;
+	struct wl_egl_window *eglWindow;
+	EGLSurface eglSurface;
+	EGLContext eglContext;
+	int prepared;
+	int width, height;
+	int pendingWidth, pendingHeight;
+
+	// Drawing is paced by frame callbacks.  While a frame is in flight,
+	// redraw requests are deferred until the compositor signals completion.
+	pthread_mutex_t frameMu;
+	int awaitingFrame;
+	int redrawPending;
+
+	// textInput is set while a TextInput has the focus, and cursor is its
+	// composition rectangle.  Both are guarded by textInputMu.
+	int textInput;
+	int cursorX, cursorY, cursorWidth, cursorHeight;
+
+	struct window *next;
+} window;
+
+static struct wl_display *display;
+static struct wl_compositor *compositor;
+static struct xdg_wm_base *wmBase;
+static struct wl_seat *seat;
+static struct wl_output *output;
+static struct wl_pointer *pointer;
+static struct wl_touch *touch;
+static struct wl_keyboard *keyboard;
+
+static EGLDisplay eglDisplay;
+static EGLConfig eglConfig;
+
+static pthread_mutex_t windowsMu = PTHREAD_MUTEX_INITIALIZER;
+static window *windows;
+
+// mmPerPx is the physical size of a pixel, taken from the first output.
+// It defaults to 96 pixels per inch until the output reports its geometry.
+static double mmPerPxX = 25.4 / 96, mmPerPxY = 25.4 / 96;
+static int physicalWidth, physicalHeight;
+static int outputWidth, outputHeight;
+
+static window *pointerFocus;
+static double pointerX, pointerY;
+
+static window *keyboardFocus;
+static struct xkb_context *xkbContext;
+static struct xkb_keymap *keymap;
+static struct xkb_state *xkbState;
+static int32_t repeatRate = 25, repeatDelay = 600;
+static struct xkb_compose_state *composeState;
+
+// textInput talks to the compositor's input method, if there is one.
+// textInputFocus is the window it has entered.
+static struct zwp_text_input_manager_v3 *textInputManager;
+static struct zwp_text_input_v3 *textInput;
+static pthread_mutex_t textInputMu = PTHREAD_MUTEX_INITIALIZER;
+static window *textInputFocus;
+// The pending state is applied on the done event.
+static char *pendingPreedit, *pendingCommit;
+static int32_t pendingCursor;
+static char *lastPreedit;
+
+#define MAX_TOUCHES 32
+static struct {
+	int32_t id;
+	window *w;
+	double x, y;
+} touches[MAX_TOUCHES];
+static int numTouches;
+
+static int keyModifiers();
+
+static window *findWindow(struct wl_surface *surface) {
+	window *w;
+	pthread_mutex_lock(&windowsMu);
+	for (w = windows; w; w = w->next) {
+		if (w->surface == surface) {
+			break;
+		}
+	}
+	pthread_mutex_unlock(&windowsMu);
+	return w;
+}
+
+static void callResize(window *w) {
+	resize((GoUintptr)w, mmPerPxX * w->width, mmPerPxY * w->height, mmPerPxX, mmPerPxY);
+}
+
+static void outputGeometry(void *data, struct wl_output *o, int32_t x, int32_t y,
+		int32_t physWidth, int32_t physHeight, int32_t subpixel,
+		const char *make, const char *model, int32_t transform) {
+	physicalWidth = physWidth;
+	physicalHeight = physHeight;
+}
+
+static void outputMode(void *data, struct wl_output *o, uint32_t flags, int32_t width, int32_t height, int32_t refresh) {
+	if (flags & WL_OUTPUT_MODE_CURRENT) {
+		outputWidth = width;
+		outputHeight = height;
+	}
+	if ((flags & WL_OUTPUT_MODE_CURRENT) && physicalWidth > 0 && physicalHeight > 0) {
+		mmPerPxX = (double)physicalWidth / width;
+		mmPerPxY = (double)physicalHeight / height;
+	}
+}
+
+static const struct wl_output_listener outputListener = {
+	outputGeometry,
+	outputMode,
+};
+
+static void pointerEnter(void *data, struct wl_pointer *p, uint32_t serial, struct wl_surface *surface, wl_fixed_t x, wl_fixed_t y) {
+	pointerFocus = findWindow(surface);
+	pointerX = wl_fixed_to_double(x);
+	pointerY = wl_fixed_to_double(y);
+	if (pointerFocus && pointerFocus->prepared) {
+		mouseEvent((GoUintptr)pointerFocus, pointerX, pointerFocus->height - pointerY, EVENT_MOVE, 0);
+	}
+}
+
+static void pointerLeave(void *data, struct wl_pointer *p, uint32_t serial, struct wl_surface *surface) {
+	if (pointerFocus && pointerFocus->prepared) {
+		mouseEvent((GoUintptr)pointerFocus, pointerX, pointerFocus->height - pointerY, EVENT_LEAVE, 0);
+	}
+	pointerFocus = NULL;
+}
+
+// Wayland's origin is the top-left corner; flip y to match Cocoa's.
+static void pointerMotion(void *data, struct wl_pointer *p, uint32_t time, wl_fixed_t x, wl_fixed_t y) {
+	pointerX = wl_fixed_to_double(x);
+	pointerY = wl_fixed_to_double(y);
+	if (pointerFocus && pointerFocus->prepared) {
+		mouseEvent((GoUintptr)pointerFocus, pointerX, pointerFocus->height - pointerY, EVENT_MOVE, 0);
+	}
+}
+
+static void pointerButton(void *data, struct wl_pointer *p, uint32_t serial, uint32_t time, uint32_t button, uint32_t state) {
+	if (pointerFocus && pointerFocus->prepared) {
+		int typ = state == WL_POINTER_BUTTON_STATE_PRESSED ? EVENT_DOWN : EVENT_UP;
+		mouseEvent((GoUintptr)pointerFocus, pointerX, pointerFocus->height - pointerY, typ, button);
+	}
+}
+
+static void pointerFrame(void *data, struct wl_pointer *p);
+
+// Axis events are collected until the end of the pointer frame.
+static struct {
+	int pending, stop;
+	uint32_t source;
+	double dx, dy;
+	int discrete, discreteX, discreteY;
+	// gesture is set between the first event of a finger scroll and its stop.
+	int gesture;
+} axis;
+
+static void pointerAxis(void *data, struct wl_pointer *p, uint32_t time, uint32_t a, wl_fixed_t value) {
+	axis.pending = 1;
+	if (a == WL_POINTER_AXIS_VERTICAL_SCROLL) {
+		axis.dy += wl_fixed_to_double(value);
+	} else {
+		axis.dx += wl_fixed_to_double(value);
+	}
+	// Before version 5, there are no frames.
+	if (wl_pointer_get_version(p) < WL_POINTER_FRAME_SINCE_VERSION) {
+		pointerFrame(data, p);
+	}
+}
+
+static void pointerAxisSource(void *data, struct wl_pointer *p, uint32_t source) {
+	axis.source = source;
+}
+
+static void pointerAxisStop(void *data, struct wl_pointer *p, uint32_t time, uint32_t a) {
+	axis.pending = 1;
+	axis.stop = 1;
+}
+
+static void pointerAxisDiscrete(void *data, struct wl_pointer *p, uint32_t a, int32_t discrete) {
+	axis.discrete = 1;
+	if (a == WL_POINTER_AXIS_VERTICAL_SCROLL) {
+		axis.discreteY += discrete;
+	} else {
+		axis.discreteX += discrete;
+	}
+}
+
+// Wayland scrolls content up for positive values; flip them to match Cocoa.
+static void pointerFrame(void *data, struct wl_pointer *p) {
+	if (axis.pending && pointerFocus && pointerFocus->prepared) {
+		double x = pointerX, y = pointerFocus->height - pointerY;
+		int mods = xkbState ? keyModifiers() : 0;
+		if (axis.source == WL_POINTER_AXIS_SOURCE_WHEEL || axis.source == WL_POINTER_AXIS_SOURCE_WHEEL_TILT) {
+			// A wheel click scrolls three lines, or ten units without discrete steps.
+			double dx = axis.discrete ? axis.discreteX : axis.dx / 10;
+			double dy = axis.discrete ? axis.discreteY : axis.dy / 10;
+			if (dx != 0 || dy != 0) {
+				scrollEvent((GoUintptr)pointerFocus, x, y, -3 * dx, -3 * dy, 0, SCROLL_NONE, mods);
+			}
+		} else if (axis.dx != 0 || axis.dy != 0) {
+			scrollEvent((GoUintptr)pointerFocus, x, y, -axis.dx, -axis.dy, 1, axis.gesture ? SCROLL_CHANGE : SCROLL_BEGIN, mods);
+			axis.gesture = 1;
+		}
+		if (axis.stop && axis.gesture) {
+			scrollEvent((GoUintptr)pointerFocus, x, y, 0, 0, 1, SCROLL_END, mods);
+			axis.gesture = 0;
+		}
+	}
+	axis.pending = axis.stop = 0;
+	axis.source = WL_POINTER_AXIS_SOURCE_WHEEL;
+	axis.dx = axis.dy = 0;
+	axis.discrete = axis.discreteX = axis.discreteY = 0;
+}
+
+static const struct wl_pointer_listener pointerListener = {
+	pointerEnter,
+	pointerLeave,
+	pointerMotion,
+	pointerButton,
+	pointerAxis,
+	pointerFrame,
+	pointerAxisSource,
+	pointerAxisStop,
+	pointerAxisDiscrete,
+};
+
+static int findTouch(int32_t id) {
+	int i;
+	for (i = 0; i < numTouches; i++) {
+		if (touches[i].id == id) {
+			return i;
+		}
+	}
+	return -1;
+}
+
+static void touchDown(void *data, struct wl_touch *t, uint32_t serial, uint32_t time, struct wl_surface *surface, int32_t id, wl_fixed_t x, wl_fixed_t y) {
+	window *w = findWindow(surface);
+	if (!w || !w->prepared || numTouches == MAX_TOUCHES) {
+		return;
+	}
+	int i = numTouches++;
+	touches[i].id = id;
+	touches[i].w = w;
+	touches[i].x = wl_fixed_to_double(x);
+	touches[i].y = w->height - wl_fixed_to_double(y);
+	touchEvent((GoUintptr)w, id, touches[i].x, touches[i].y, EVENT_DOWN);
+}
+
+static void touchUp(void *data, struct wl_touch *t, uint32_t serial, uint32_t time, int32_t id) {
+	int i = findTouch(id);
+	if (i < 0) {
+		return;
+	}
+	touchEvent((GoUintptr)touches[i].w, id, touches[i].x, touches[i].y, EVENT_UP);
+	touches[i] = touches[--numTouches];
+}
+
+static void touchMotion(void *data, struct wl_touch *t, uint32_t time, int32_t id, wl_fixed_t x, wl_fixed_t y) {
+	int i = findTouch(id);
+	if (i < 0) {
+		return;
+	}
+	touches[i].x = wl_fixed_to_double(x);
+	touches[i].y = touches[i].w->height - wl_fixed_to_double(y);
+	touchEvent((GoUintptr)touches[i].w, id, touches[i].x, touches[i].y, EVENT_MOVE);
+}
+
+static void touchFrame(void *data, struct wl_touch *t) {}
+
+// touchCancel means the compositor has taken over the touches, for a gesture
+// of its own.
+static void touchCancel(void *data, struct wl_touch *t) {
+	while (numTouches > 0) {
+		int i = --numTouches;
+		touchEvent((GoUintptr)touches[i].w, touches[i].id, touches[i].x, touches[i].y, EVENT_CANCEL);
+	}
+}
+
+static const struct wl_touch_listener touchListener = {
+	touchDown,
+	touchUp,
+	touchMotion,
+	touchFrame,
+	touchCancel,
+};
+
+static void keyboardKeymap(void *data, struct wl_keyboard *k, uint32_t format, int32_t fd, uint32_t size) {
+	if (format != WL_KEYBOARD_KEYMAP_FORMAT_XKB_V1) {
+		close(fd);
+		return;
+	}
+	char *str = mmap(NULL, size, PROT_READ, MAP_PRIVATE, fd, 0);
+	close(fd);
+	if (str == MAP_FAILED) {
+		return;
+	}
+	struct xkb_keymap *km = xkb_keymap_new_from_string(xkbContext, str, XKB_KEYMAP_FORMAT_TEXT_V1, XKB_KEYMAP_COMPILE_NO_FLAGS);
+	munmap(str, size);
+	if (!km) {
+		fprintf(stderr, "failed to compile the keymap\n");
+		return;
+	}
+	xkb_state_unref(xkbState);
+	xkb_keymap_unref(keymap);
+	keymap = km;
+	xkbState = xkb_state_new(keymap);
+}
+
+static void keyboardEnter(void *data, struct wl_keyboard *k, uint32_t serial, struct wl_surface *surface, struct wl_array *keys) {
+	keyboardFocus = findWindow(surface);
+}
+
+static void keyboardLeave(void *data, struct wl_keyboard *k, uint32_t serial, struct wl_surface *surface) {
+	keyboardFocus = NULL;
+	stopKeyRepeat();
+}
+
+static int keyModifiers() {
+	int mods = 0;
+	if (xkb_state_mod_name_is_active(xkbState, XKB_MOD_NAME_SHIFT, XKB_STATE_MODS_EFFECTIVE) > 0) {
+		mods |= MOD_SHIFT;
+	}
+	if (xkb_state_mod_name_is_active(xkbState, XKB_MOD_NAME_CTRL, XKB_STATE_MODS_EFFECTIVE) > 0) {
+		mods |= MOD_CONTROL;
+	}
+	if (xkb_state_mod_name_is_active(xkbState, XKB_MOD_NAME_ALT, XKB_STATE_MODS_EFFECTIVE) > 0) {
+		mods |= MOD_ALT;
+	}
+	if (xkb_state_mod_name_is_active(xkbState, XKB_MOD_NAME_LOGO, XKB_STATE_MODS_EFFECTIVE) > 0) {
+		mods |= MOD_META;
+	}
+	return mods;
+}
+
+// keyText sends the text typed with a key press to Go, composing dead keys.
+// Keys the input method handles never get here.
+static void keyText(window *w, xkb_keycode_t code) {
+	char buf[64];
+	int n = 0;
+	xkb_keysym_t sym = xkb_state_key_get_one_sym(xkbState, code);
+	if (composeState && xkb_compose_state_feed(composeState, sym) == XKB_COMPOSE_FEED_ACCEPTED) {
+		switch (xkb_compose_state_get_status(composeState)) {
+		case XKB_COMPOSE_COMPOSING:
+			return;
+		case XKB_COMPOSE_COMPOSED:
+			n = xkb_compose_state_get_utf8(composeState, buf, sizeof buf);
+			xkb_compose_state_reset(composeState);
+			break;
+		case XKB_COMPOSE_CANCELLED:
+			xkb_compose_state_reset(composeState);
+			return;
+		case XKB_COMPOSE_NOTHING:
+			n = xkb_state_key_get_utf8(xkbState, code, buf, sizeof buf);
+			break;
+		}
+	} else {
+		n = xkb_state_key_get_utf8(xkbState, code, buf, sizeof buf);
+	}
+	// Control characters are left to key events.
+	if (n > 0 && n < (int)sizeof buf && (unsigned char)buf[0] >= 0x20 && buf[0] != 0x7f) {
+		commitText((GoUintptr)w, buf);
+	}
+}
+
+static void keyboardKey(void *data, struct wl_keyboard *k, uint32_t serial, uint32_t time, uint32_t key, uint32_t state) {
+	if (!keyboardFocus || !keyboardFocus->prepared || !xkbState) {
+		return;
+	}
+	// XKB key codes are evdev codes offset by 8.
+	xkb_keycode_t code = key + 8;
+	uint32_t r = xkb_state_key_get_utf32(xkbState, code);
+	int typ = state == WL_KEYBOARD_KEY_STATE_PRESSED ? EVENT_DOWN : EVENT_UP;
+	// Wayland leaves key repeat to clients.
+	int rate = xkb_keymap_key_repeats(keymap, code) ? repeatRate : 0;
+	keyEvent((GoUintptr)keyboardFocus, typ, key, r ? (int32_t)r : -1, keyModifiers(), repeatDelay, rate);
+
+	pthread_mutex_lock(&textInputMu);
+	int wantText = keyboardFocus->textInput;
+	pthread_mutex_unlock(&textInputMu);
+	if (typ == EVENT_DOWN && wantText) {
+		keyText(keyboardFocus, code);
+	}
+}
+
+static void keyboardModifiers(void *data, struct wl_keyboard *k, uint32_t serial,
+		uint32_t depressed, uint32_t latched, uint32_t locked, uint32_t group) {
+	if (xkbState) {
+		xkb_state_update_mask(xkbState, depressed, latched, locked, 0, 0, group);
+	}
+}
+
+static void keyboardRepeatInfo(void *data, struct wl_keyboard *k, int32_t rate, int32_t delay) {
+	repeatRate = rate;
+	repeatDelay = delay;
+}
+
+static const struct wl_keyboard_listener keyboardListener = {
+	keyboardKeymap,
+	keyboardEnter,
+	keyboardLeave,
+	keyboardKey,
+	keyboardModifiers,
+	keyboardRepeatInfo,
+};
+
+// sendTextInputState tells the input method about w's TextInput, which may
+// have just been enabled.  It must be called with textInputMu held.
+static void sendTextInputState(window *w, int wasEnabled) {
+	if (w->textInput) {
+		if (!wasEnabled) {
+			zwp_text_input_v3_enable(textInput);
+		}
+		zwp_text_input_v3_set_cursor_rectangle(textInput, w->cursorX, w->cursorY, w->cursorWidth, w->cursorHeight);
+	} else if (wasEnabled) {
+		zwp_text_input_v3_disable(textInput);
+		free(lastPreedit);
+		lastPreedit = NULL;
+	} else {
+		return;
+	}
+	zwp_text_input_v3_commit(textInput);
+}
+
+void setTextInput(uintptr_t win, int enabled, int x, int y, int width, int height) {
+	window *w = (window*)win;
+	pthread_mutex_lock(&textInputMu);
+	int was = w->textInput;
+	w->textInput = enabled;
+	w->cursorX = x;
+	w->cursorY = y;
+	w->cursorWidth = width;
+	w->cursorHeight = height;
+	if (textInput && textInputFocus == w) {
+		sendTextInputState(w, was);
+	}
+	pthread_mutex_unlock(&textInputMu);
+	wl_display_flush(display);
+}
+
+static void textInputEnter(void *data, struct zwp_text_input_v3 *t, struct wl_surface *surface) {
+	window *w = findWindow(surface);
+	pthread_mutex_lock(&textInputMu);
+	textInputFocus = w;
+	if (w) {
+		sendTextInputState(w, 0);
+	}
+	pthread_mutex_unlock(&textInputMu);
+}
+
+static void textInputLeave(void *data, struct zwp_text_input_v3 *t, struct wl_surface *surface) {
+	pthread_mutex_lock(&textInputMu);
+	if (textInputFocus && textInputFocus->textInput) {
+		zwp_text_input_v3_disable(t);
+		zwp_text_input_v3_commit(t);
+	}
+	textInputFocus = NULL;
+	pthread_mutex_unlock(&textInputMu);
+}
+
+static void textInputPreeditString(void *data, struct zwp_text_input_v3 *t, const char *text, int32_t cursorBegin, int32_t cursorEnd) {
+	free(pendingPreedit);
+	pendingPreedit = text ? strdup(text) : NULL;
+	pendingCursor = cursorBegin;
+}
+
+static void textInputCommitString(void *data, struct zwp_text_input_v3 *t, const char *text) {
+	free(pendingCommit);
+	pendingCommit = text ? strdup(text) : NULL;
+}
+
+// We don't report surrounding text, so there is none to delete.
+static void textInputDeleteSurroundingText(void *data, struct zwp_text_input_v3 *t, uint32_t before, uint32_t after) {}
+
+static void textInputDone(void *data, struct zwp_text_input_v3 *t, uint32_t serial) {
+	pthread_mutex_lock(&textInputMu);
+	window *w = textInputFocus;
+	pthread_mutex_unlock(&textInputMu);
+
+	char *preedit = pendingPreedit ? pendingPreedit : "";
+	if (w && pendingCommit) {
+		commitText((GoUintptr)w, pendingCommit);
+	}
+	// Only changes to the composition are sent, as every done event repeats it.
+	if (w && (lastPreedit ? strcmp(lastPreedit, preedit) != 0 : preedit[0] != 0)) {
+		composeText((GoUintptr)w, preedit, pendingCursor);
+		free(lastPreedit);
+		lastPreedit = strdup(preedit);
+	}
+
+	free(pendingPreedit);
+	free(pendingCommit);
+	pendingPreedit = NULL;
+	pendingCommit = NULL;
+	pendingCursor = 0;
+}
+
+static const struct zwp_text_input_v3_listener textInputListener = {
+	textInputEnter,
+	textInputLeave,
+	textInputPreeditString,
+	textInputCommitString,
+	textInputDeleteSurroundingText,
+	textInputDone,
+};
+
+static void seatCapabilities(void *data, struct wl_seat *s, uint32_t caps) {
+	if ((caps & WL_SEAT_CAPABILITY_POINTER) && !pointer) {
+		pointer = wl_seat_get_pointer(s);
+		wl_pointer_add_listener(pointer, &pointerListener, NULL);
+	} else if (!(caps & WL_SEAT_CAPABILITY_POINTER) && pointer) {
+		wl_pointer_destroy(pointer);
+		pointer = NULL;
+		pointerFocus = NULL;
+	}
+	if ((caps & WL_SEAT_CAPABILITY_KEYBOARD) && !keyboard) {
+		keyboard = wl_seat_get_keyboard(s);
+		wl_keyboard_add_listener(keyboard, &keyboardListener, NULL);
+	} else if (!(caps & WL_SEAT_CAPABILITY_KEYBOARD) && keyboard) {
+		wl_keyboard_destroy(keyboard);
+		keyboard = NULL;
+		keyboardFocus = NULL;
+		stopKeyRepeat();
+	}
+	if ((caps & WL_SEAT_CAPABILITY_TOUCH) && !touch) {
+		touch = wl_seat_get_touch(s);
+		wl_touch_add_listener(touch, &touchListener, NULL);
+	} else if (!(caps & WL_SEAT_CAPABILITY_TOUCH) && touch) {
+		wl_touch_destroy(touch);
+		touch = NULL;
+		numTouches = 0;
+	}
+}
+
+static void seatName(void *data, struct wl_seat *s, const char *name) {}
+
+static const struct wl_seat_listener seatListener = {
+	seatCapabilities,
+	seatName,
+};
+
+static void wmBasePing(void *data, struct xdg_wm_base *b, uint32_t serial) {
+	xdg_wm_base_pong(b, serial);
+}
+
+static const struct xdg_wm_base_listener wmBaseListener = {
+	wmBasePing,
+};
+
+static uint32_t minVersion(uint32_t a, uint32_t b) { return a < b ? a : b; }
+
+static void registryGlobal(void *data, struct wl_registry *registry, uint32_t name, const char *interface, uint32_t version) {
+	if (strcmp(interface, wl_compositor_interface.name) == 0) {
+		compositor = wl_registry_bind(registry, name, &wl_compositor_interface, minVersion(version, 4));
+	} else if (strcmp(interface, xdg_wm_base_interface.name) == 0) {
+		wmBase = wl_registry_bind(registry, name, &xdg_wm_base_interface, 1);
+		xdg_wm_base_add_listener(wmBase, &wmBaseListener, NULL);
+	} else if (strcmp(interface, wl_seat_interface.name) == 0 && !seat) {
+		seat = wl_registry_bind(registry, name, &wl_seat_interface, minVersion(version, 5));
+		wl_seat_add_listener(seat, &seatListener, NULL);
+	} else if (strcmp(interface, wl_output_interface.name) == 0 && !output) {
+		output = wl_registry_bind(registry, name, &wl_output_interface, 1);
+		wl_output_add_listener(output, &outputListener, NULL);
+	} else if (strcmp(interface, zwp_text_input_manager_v3_interface.name) == 0) {
+		textInputManager = wl_registry_bind(registry, name, &zwp_text_input_manager_v3_interface, 1);
+	}
+}
+
+static void registryGlobalRemove(void *data, struct wl_registry *registry, uint32_t name) {}
+
+static const struct wl_registry_listener registryListener = {
+	registryGlobal,
+	registryGlobalRemove,
+};
+
+int openDisplay() {
+	display = wl_display_connect(NULL);
+	if (!display) {
+		return 0;
+	}
+	xkbContext = xkb_context_new(XKB_CONTEXT_NO_FLAGS);
+
+	const char *locale = getenv("LC_ALL");
+	if (!locale || !*locale) {
+		locale = getenv("LC_CTYPE");
+	}
+	if (!locale || !*locale) {
+		locale = getenv("LANG");
+	}
+	if (!locale || !*locale) {
+		locale = "C";
+	}
+	struct xkb_compose_table *composeTable = xkb_compose_table_new_from_locale(xkbContext, locale, XKB_COMPOSE_COMPILE_NO_FLAGS);
+	if (composeTable) {
+		composeState = xkb_compose_state_new(composeTable, XKB_COMPOSE_STATE_NO_FLAGS);
+		xkb_compose_table_unref(composeTable);
+	}
+
+	struct wl_registry *registry = wl_display_get_registry(display);
+	wl_registry_add_listener(registry, &registryListener, NULL);
+	// The first roundtrip binds the globals, the second receives their initial events.
+	wl_display_roundtrip(display);
+	wl_display_roundtrip(display);
+	if (!compositor || !wmBase) {
+		fprintf(stderr, "the Wayland compositor does not support xdg-shell\n");
+		return 0;
+	}
+	if (textInputManager && seat) {
+		textInput = zwp_text_input_manager_v3_get_text_input(textInputManager, seat);
+		zwp_text_input_v3_add_listener(textInput, &textInputListener, NULL);
+	}
+
+	eglDisplay = eglGetDisplay((EGLNativeDisplayType)display);
+	if (!eglInitialize(eglDisplay, NULL, NULL)) {
+		fprintf(stderr, "failed to initialize EGL: 0x%x\n", eglGetError());
+		return 0;
+	}
+	if (!eglBindAPI(EGL_OPENGL_API)) {
+		fprintf(stderr, "EGL does not support OpenGL\n");
+		return 0;
+	}
+
+	static const EGLint configAttr[] = {
+		EGL_SURFACE_TYPE,    EGL_WINDOW_BIT,
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+		EGL_RED_SIZE,        8,
+		EGL_GREEN_SIZE,      8,
+		EGL_BLUE_SIZE,       8,
+		EGL_ALPHA_SIZE,      8,
+		EGL_DEPTH_SIZE,      16,
+		EGL_NONE
+	};
+	EGLint n;
+	if (!eglChooseConfig(eglDisplay, configAttr, &eglConfig, 1, &n) || n == 0) {
+		fprintf(stderr, "no suitable EGL config\n");
+		return 0;
+	}
+	return 1;
+}
+
+static void frameDone(void *data, struct wl_callback *cb, uint32_t time) {
+	window *w = data;
+	wl_callback_destroy(cb);
+
+	pthread_mutex_lock(&w->frameMu);
+	w->awaitingFrame = 0;
+	int redraw = w->redrawPending;
+	w->redrawPending = 0;
+	pthread_mutex_unlock(&w->frameMu);
+
+	if (redraw) {
+		expose((GoUintptr)w);
+	}
+}
+
+static const struct wl_callback_listener frameListener = {
+	frameDone,
+};
+
+static void toplevelConfigure(void *data, struct xdg_toplevel *t, int32_t width, int32_t height, struct wl_array *states) {
+	window *w = data;
+	w->pendingWidth = width;
+	w->pendingHeight = height;
+}
+
+static void toplevelClose(void *data, struct xdg_toplevel *t) {
+	exit(0);
+}
+
+static const struct xdg_toplevel_listener toplevelListener = {
+	toplevelConfigure,
+	toplevelClose,
+};
+
+static void surfaceConfigure(void *data, struct xdg_surface *s, uint32_t serial) {
+	window *w = data;
+	xdg_surface_ack_configure(s, serial);
+
+	// A zero size leaves the choice to the client.
+	int changed = 0;
+	if (w->pendingWidth > 0 && w->pendingHeight > 0 &&
+			(w->pendingWidth != w->width || w->pendingHeight != w->height)) {
+		w->width = w->pendingWidth;
+		w->height = w->pendingHeight;
+		changed = 1;
+	}
+
+	if (!w->prepared) {
+		w->eglWindow = wl_egl_window_create(w->surface, w->width, w->height);
+		w->eglSurface = eglCreateWindowSurface(eglDisplay, eglConfig, (EGLNativeWindowType)w->eglWindow, NULL);
+		if (w->eglSurface == EGL_NO_SURFACE) {
+			fprintf(stderr, "failed to create EGL surface: 0x%x\n", eglGetError());
+			return;
+		}
+		w->prepared = 1;
+		preparedOpenGL((GoUintptr)w, (GoUintptr)w);
+		callResize(w);
+	} else if (changed) {
+		callResize(w);
+	}
+}
+
+static const struct xdg_surface_listener surfaceListener = {
+	surfaceConfigure,
+};
+
+uintptr_t newWindow(double width, double height) {
+	static const EGLint ctxAttr[] = {
+		EGL_CONTEXT_MAJOR_VERSION,       3,
+		EGL_CONTEXT_MINOR_VERSION,       2,
+		EGL_CONTEXT_OPENGL_PROFILE_MASK, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+		EGL_NONE
+	};
+
+	window *w = calloc(1, sizeof *w);
+	pthread_mutex_init(&w->frameMu, NULL);
+	w->width = (int)(width / mmPerPxX + 0.5);
+	w->height = (int)(height / mmPerPxY + 0.5);
+
+	w->eglContext = eglCreateContext(eglDisplay, eglConfig, EGL_NO_CONTEXT, ctxAttr);
+	if (w->eglContext == EGL_NO_CONTEXT) {
+		fprintf(stderr, "failed to create an OpenGL 3.2 core context: 0x%x\n", eglGetError());
+		free(w);
+		return 0;
+	}
+
+	w->surface = wl_compositor_create_surface(compositor);
+	w->xdgSurface = xdg_wm_base_get_xdg_surface(wmBase, w->surface);
+	xdg_surface_add_listener(w->xdgSurface, &surfaceListener, w);
+	w->toplevel = xdg_surface_get_toplevel(w->xdgSurface);
+	xdg_toplevel_add_listener(w->toplevel, &toplevelListener, w);
+
+	pthread_mutex_lock(&windowsMu);
+	w->next = windows;
+	windows = w;
+	pthread_mutex_unlock(&windowsMu);
+
+	return (uintptr_t)w;
+}
+
+// mapWindow makes the initial commit, without a buffer, which asks the
+// compositor for a configure event.  It is separate from newWindow so that Go
+// can register the window before the event arrives.
+void mapWindow(uintptr_t win) {
+	window *w = (window*)win;
+	wl_surface_commit(w->surface);
+	wl_display_flush(display);
+}
+
+void makeCurrentContext(uintptr_t ctx) {
+	window *w = (window*)ctx;
+	eglMakeCurrent(eglDisplay, w->eglSurface, w->eglSurface, w->eglContext);
+	// Frame callbacks pace drawing, so swaps must not block.
+	eglSwapInterval(eglDisplay, 0);
+}
+
+int beginFrame(uintptr_t ctx) {
+	window *w = (window*)ctx;
+	int ready;
+	pthread_mutex_lock(&w->frameMu);
+	ready = !w->awaitingFrame;
+	if (!ready) {
+		w->redrawPending = 1;
+	}
+	pthread_mutex_unlock(&w->frameMu);
+	return ready;
+}
+
+void flushContext(uintptr_t ctx) {
+	window *w = (window*)ctx;
+	struct wl_callback *cb = wl_surface_frame(w->surface);
+	wl_callback_add_listener(cb, &frameListener, w);
+
+	pthread_mutex_lock(&w->frameMu);
+	w->awaitingFrame = 1;
+	pthread_mutex_unlock(&w->frameMu);
+
+	eglSwapBuffers(eglDisplay, w->eglSurface);
+}
+
+void resizeContext(uintptr_t ctx, int width, int height) {
+	window *w = (window*)ctx;
+	wl_egl_window_resize(w->eglWindow, width, height, 0, 0);
+}
+
+void runApp() {
+	while (wl_display_dispatch(display) != -1) {
+	}
+	fprintf(stderr, "lost connection to the Wayland display\n");
+	exit(1);
+}
+
+void mapFromScreen(uintptr_t window, double *x, double *y) {
+	struct window *w = (struct window*)window;
+	*y = w->height - *y;
+}
+
+void screenSize(double *width, double *height) {
+	*width = outputWidth;
+	*height = outputHeight;
+}
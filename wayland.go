@@ -0,0 +1,395 @@
+// +build linux
+// +build !android,wayland
+
+package ui
+
+/*
+#cgo pkg-config: wayland-client wayland-egl egl xkbcommon
+#include <linux/input-event-codes.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+#define EVENT_MOVE 0
+#define EVENT_DOWN 1
+#define EVENT_UP   2
+#define EVENT_CANCEL 3
+#define EVENT_LEAVE  4
+
+#define SCROLL_NONE   0
+#define SCROLL_BEGIN  1
+#define SCROLL_CHANGE 2
+#define SCROLL_END    3
+
+#define MOD_SHIFT   1
+#define MOD_CONTROL 2
+#define MOD_ALT     4
+#define MOD_META    8
+
+int openDisplay();
+void runApp();
+uintptr_t newWindow(double width, double height);
+void mapWindow(uintptr_t window);
+void makeCurrentContext(uintptr_t ctx);
+int beginFrame(uintptr_t ctx);
+void flushContext(uintptr_t ctx);
+void resizeContext(uintptr_t ctx, int width, int height);
+void mapFromScreen(uintptr_t window, double *x, double *y);
+void screenSize(double *width, double *height);
+void setTextInput(uintptr_t window, int enabled, int x, int y, int width, int height);
+*/
+import "C"
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+)
+
+var displayOpen = false
+
+func run(cb func()) {
+	if C.openDisplay() == 0 {
+		log.Fatal("cannot connect to the Wayland display")
+	}
+	displayOpen = true
+
+	go cb()
+	C.runApp()
+}
+
+func newWindowImpl(size Size) (uintptr, error) {
+	if !displayOpen {
+		return 0, errors.New("the Wayland display is not connected; windows must be created from the ui.Run callback")
+	}
+	w := C.newWindow(C.double(size.Width), C.double(size.Height))
+	if w == 0 {
+		return 0, errors.New("failed to create Wayland window")
+	}
+	return uintptr(w), nil
+}
+
+func mapWindowImpl(w uintptr) {
+	C.mapWindow(C.uintptr_t(w))
+}
+
+//export preparedOpenGL
+func preparedOpenGL(window uintptr, ctx uintptr) {
+	go windowLoop(window, ctx)
+}
+
+func makeCurrentContext(ctx uintptr) {
+	C.makeCurrentContext(C.uintptr_t(ctx))
+}
+
+// beginFrame reports whether the compositor is ready for a new frame.
+// If it is not, the frame callback triggers a redraw once it is.
+func beginFrame(ctx uintptr) bool {
+	return C.beginFrame(C.uintptr_t(ctx)) != 0
+}
+
+func flushContext(ctx uintptr) {
+	C.flushContext(C.uintptr_t(ctx))
+}
+
+// resizeContext resizes the EGL window and the GL viewport to match the surface.
+func resizeContext(ctx uintptr, s sizeEvent) {
+	width := int32(math.Round(s.size.Width / s.px.Width))
+	height := int32(math.Round(s.size.Height / s.px.Height))
+	C.resizeContext(C.uintptr_t(ctx), C.int(width), C.int(height))
+	gl.Viewport(0, 0, width, height)
+}
+
+//export resize
+func resize(window uintptr, width, height, pxWidth, pxHeight float64) {
+	windowsMu.Lock()
+	w := windows[window]
+	windowsMu.Unlock()
+
+	w.send(sizeEvent{
+		size: Size{width, height},
+		px:   Size{pxWidth, pxHeight},
+	})
+}
+
+//export expose
+func expose(window uintptr) {
+	windowsMu.Lock()
+	w := windows[window]
+	windowsMu.Unlock()
+
+	w.Redraw()
+}
+
+var mousePointer = activePointers.new(Pointer{
+	Type: PointerTypeMouse,
+})
+
+// mouseEvent receives pointer positions in pixels relative to the bottom-left
+// corner of the window, matching the Cocoa backend.
+//
+//export mouseEvent
+func mouseEvent(window uintptr, x, y float64, typ, button int32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	var down, up, leave bool
+
+	mousePointer.X = x
+	mousePointer.Y = y
+
+	switch typ {
+	case C.EVENT_MOVE:
+		mousePointer.Button = PointerButtonNone
+	case C.EVENT_LEAVE:
+		leave = true
+		mousePointer.Button = PointerButtonNone
+	case C.EVENT_DOWN:
+		down = true
+		mousePointer.Button = waylandMouseButton(button)
+		mousePointer.Buttons |= mousePointer.Button
+	case C.EVENT_UP:
+		up = true
+		mousePointer.Button = waylandMouseButton(button)
+		mousePointer.Buttons &^= mousePointer.Button
+	}
+	mousePointer.setDefaultPressure()
+
+	w.send(pointerEvent{
+		down:  down,
+		up:    up,
+		leave: leave,
+		p:     *mousePointer,
+	})
+}
+
+// scrollEvent receives a position like mouseEvent and a delta in pixels if
+// precise and in lines otherwise.
+//
+//export scrollEvent
+func scrollEvent(window uintptr, x, y, dx, dy float64, precise bool, phase, mods int32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	w.send(Scroll{
+		Position:  Position{x, y},
+		Delta:     Size{dx, dy},
+		Precise:   precise,
+		Phase:     ScrollPhase(phase),
+		Modifiers: waylandMods(mods),
+	})
+}
+
+func waylandMouseButton(button int32) PointerButtons {
+	switch button {
+	default:
+		return PointerButtonNone
+	case C.BTN_LEFT:
+		return PointerButtonLeftMouse
+	case C.BTN_RIGHT:
+		return PointerButtonRightMouse
+	case C.BTN_MIDDLE:
+		return PointerButtonMiddleMouse
+	case C.BTN_SIDE:
+		return PointerButtonX1BackMouse
+	case C.BTN_EXTRA:
+		return PointerButtonX2ForwardMouse
+	}
+}
+
+var touches = map[int32]*Pointer{}
+
+//export touchEvent
+func touchEvent(window uintptr, id int32, x, y float64, typ int32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	var down, up, cancel bool
+
+	p := touches[id]
+	switch typ {
+	case C.EVENT_DOWN:
+		down = true
+		p = activePointers.new(Pointer{
+			externalID: uint32(id)<<2 | 1,
+			Type:       PointerTypeTouch,
+			Button:     PointerButtonTouchContact,
+			Buttons:    PointerButtonTouchContact,
+		})
+		touches[id] = p
+	case C.EVENT_MOVE:
+		if p == nil {
+			return
+		}
+		p.Button = PointerButtonNone
+	case C.EVENT_UP, C.EVENT_CANCEL:
+		if p == nil {
+			return
+		}
+		up = typ == C.EVENT_UP
+		cancel = typ == C.EVENT_CANCEL
+		p.Button = PointerButtonTouchContact
+		p.Buttons = PointerButtonNone
+		activePointers.delete(*p)
+		delete(touches, id)
+	}
+	p.X = x
+	p.Y = y
+	p.setDefaultPressure()
+
+	w.send(pointerEvent{
+		down:   down,
+		up:     up,
+		cancel: cancel,
+		p:      *p,
+	})
+}
+
+// keyRepeat generates repeated presses of the last key pressed while it is
+// held down.  It is guarded by windowsMu.
+var keyRepeat struct {
+	timer *time.Timer
+	code  uint32
+}
+
+//export keyEvent
+func keyEvent(window uintptr, typ int32, code uint32, r int32, mods int32, delay, rate int32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	k := Key{
+		Code:      evdevKeyCode(code),
+		Rune:      r,
+		Modifiers: waylandMods(mods),
+	}
+
+	switch typ {
+	case C.EVENT_DOWN:
+		k.Direction = KeyPress
+		stopKeyRepeatLocked()
+		if rate > 0 {
+			startKeyRepeat(w, k, code, time.Duration(delay)*time.Millisecond, time.Second/time.Duration(rate))
+		}
+	case C.EVENT_UP:
+		k.Direction = KeyRelease
+		if keyRepeat.code == code {
+			stopKeyRepeatLocked()
+		}
+	}
+
+	w.send(k)
+}
+
+func waylandMods(mods int32) (m KeyModifiers) {
+	if mods&C.MOD_SHIFT != 0 {
+		m |= KeyModifierShift
+	}
+	if mods&C.MOD_CONTROL != 0 {
+		m |= KeyModifierControl
+	}
+	if mods&C.MOD_ALT != 0 {
+		m |= KeyModifierAlt
+	}
+	if mods&C.MOD_META != 0 {
+		m |= KeyModifierMeta
+	}
+	return m
+}
+
+func startKeyRepeat(w *window, k Key, code uint32, delay, interval time.Duration) {
+	k.Repeat = true
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		windowsMu.Lock()
+		defer windowsMu.Unlock()
+		if keyRepeat.timer != t {
+			return
+		}
+		w.send(k)
+		t.Reset(interval)
+	})
+	keyRepeat.timer = t
+	keyRepeat.code = code
+}
+
+//export stopKeyRepeat
+func stopKeyRepeat() {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	stopKeyRepeatLocked()
+}
+
+func stopKeyRepeatLocked() {
+	if keyRepeat.timer != nil {
+		keyRepeat.timer.Stop()
+	}
+	keyRepeat.timer = nil
+	keyRepeat.code = 0
+}
+
+//export commitText
+func commitText(window uintptr, text *C.char) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	w.send(textEvent{c: Composition{Text: C.GoString(text)}})
+}
+
+// composeText receives the composition and its cursor position in bytes,
+// which is -1 if the cursor is hidden.
+//
+//export composeText
+func composeText(window uintptr, text *C.char, cursor int32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	w.send(textEvent{
+		compose: true,
+		c:       Composition{Text: C.GoString(text), Cursor: int(cursor)},
+	})
+}
+
+func setTextInput(window uintptr, t textInputState) {
+	enabled := C.int(0)
+	if t.enabled {
+		enabled = 1
+	}
+	C.setTextInput(C.uintptr_t(window), enabled, C.int(t.x), C.int(t.y), C.int(t.w), C.int(t.h))
+}
+
+// screenSize returns the size of the output in pixels.
+func screenSize() Size {
+	var width, height C.double
+	C.screenSize(&width, &height)
+	return Size{float64(width), float64(height)}
+}
+
+// MapFromParent treats the window as if it were at the screen's origin,
+// because Wayland does not reveal window positions to clients.
+func (w *window) MapFromParent(p Position) Position {
+	x, y := C.double(p.X), C.double(p.Y)
+	C.mapFromScreen(C.uintptr_t(w.w), &x, &y)
+	return Position{
+		X: float64(x),
+		Y: float64(y),
+	}
+}
+
+// sendButton sends w a press or release of the left mouse button at a
+// position in pixels from its bottom left, as the compositor would.  It is
+// for tests.
+func (w *window) sendButton(press bool, x, y float64) {
+	typ := int32(C.EVENT_UP)
+	if press {
+		typ = C.EVENT_DOWN
+	}
+	mouseEvent(w.w, x, y, typ, C.BTN_LEFT)
+}
@@ -0,0 +1,71 @@
+// +build linux
+// +build !android,wayland
+
+package ui
+
+import (
+	"image/color"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestWayland opens a window on a real Wayland compositor, such as weston
+// started with --backend=headless-backend.so, draws it and clicks it.  The
+// window is not closed, because a closed toplevel ends the process.
+func TestWayland(t *testing.T) {
+	if os.Getenv("WAYLAND_DISPLAY") == "" {
+		t.Skip("no Wayland display; run the tests under a headless weston")
+	}
+
+	v := &backendView{drawn: make(chan struct{}, 1), down: make(chan Position, 1)}
+	v.View = NewView(v, nil)
+	created := make(chan *window, 1)
+	go run(func() {
+		w, err := newWindow(Size{Width: 40, Height: 30}, v)
+		if err != nil {
+			t.Error(err)
+			created <- nil
+			return
+		}
+		created <- w.(*window)
+	})
+
+	timeout := time.After(10 * time.Second)
+	var w *window
+	select {
+	case w = <-created:
+		if w == nil {
+			return
+		}
+	case <-timeout:
+		t.Fatal("timed out creating the window")
+	}
+	select {
+	case <-v.drawn:
+	case <-timeout:
+		t.Fatal("timed out waiting for the window to draw")
+	}
+
+	img, err := w.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := img.Bounds()
+	if c := img.RGBAAt(b.Dx()/2, b.Dy()/2); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("got color %v in the middle of the window, want red", c)
+	}
+
+	// A click near the top left of the window lands near the view's origin.
+	y := float64(b.Dy() - 2)
+	w.sendButton(true, 2, y)
+	w.sendButton(false, 2, y)
+	select {
+	case p := <-v.down:
+		if s := v.Size(); p.X < 0 || p.X > s.Width/2 || p.Y < 0 || p.Y > s.Height/2 {
+			t.Errorf("got pointer down at %v, want it near the top left of a view of size %v", p, s)
+		}
+	case <-timeout:
+		t.Fatal("timed out waiting for the pointer down")
+	}
+}
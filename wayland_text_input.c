@@ -0,0 +1,54 @@
+// +build linux
+// +build !android,wayland
+
+#include "wayland_text_input.h"
+
+static const struct wl_interface *null_types[] = {
+	NULL, NULL, NULL, NULL,
+};
+
+static const struct wl_interface *get_text_input_types[] = {
+	&zwp_text_input_v3_interface,
+	&wl_seat_interface,
+};
+
+static const struct wl_interface *surface_types[] = {
+	&wl_surface_interface,
+};
+
+static const struct wl_message zwp_text_input_manager_v3_requests[] = {
+	{ "destroy", "", null_types },
+	{ "get_text_input", "no", get_text_input_types },
+};
+
+const struct wl_interface zwp_text_input_manager_v3_interface = {
+	"zwp_text_input_manager_v3", 1,
+	2, zwp_text_input_manager_v3_requests,
+	0, NULL,
+};
+
+static const struct wl_message zwp_text_input_v3_requests[] = {
+	{ "destroy", "", null_types },
+	{ "enable", "", null_types },
+	{ "disable", "", null_types },
+	{ "set_surrounding_text", "sii", null_types },
+	{ "set_text_change_cause", "u", null_types },
+	{ "set_content_type", "uu", null_types },
+	{ "set_cursor_rectangle", "iiii", null_types },
+	{ "commit", "", null_types },
+};
+
+static const struct wl_message zwp_text_input_v3_events[] = {
+	{ "enter", "o", surface_types },
+	{ "leave", "o", surface_types },
+	{ "preedit_string", "?sii", null_types },
+	{ "commit_string", "?s", null_types },
+	{ "delete_surrounding_text", "uu", null_types },
+	{ "done", "u", null_types },
+};
+
+const struct wl_interface zwp_text_input_v3_interface = {
+	"zwp_text_input_v3", 1,
+	8, zwp_text_input_v3_requests,
+	6, zwp_text_input_v3_events,
+};
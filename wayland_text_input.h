@@ -0,0 +1,63 @@
+// Client-side glue for the text-input-unstable-v3 protocol, equivalent to
+// what wayland-scanner generates from text-input-unstable-v3.xml.  Only the
+// requests and events used by the Wayland backend have wrappers here.
+
+#ifndef UI_WAYLAND_TEXT_INPUT_H
+#define UI_WAYLAND_TEXT_INPUT_H
+
+#include <stdint.h>
+#include <wayland-client.h>
+
+struct zwp_text_input_manager_v3;
+struct zwp_text_input_v3;
+
+extern const struct wl_interface zwp_text_input_manager_v3_interface;
+extern const struct wl_interface zwp_text_input_v3_interface;
+
+#define ZWP_TEXT_INPUT_MANAGER_V3_DESTROY 0
+#define ZWP_TEXT_INPUT_MANAGER_V3_GET_TEXT_INPUT 1
+
+#define ZWP_TEXT_INPUT_V3_DESTROY 0
+#define ZWP_TEXT_INPUT_V3_ENABLE 1
+#define ZWP_TEXT_INPUT_V3_DISABLE 2
+#define ZWP_TEXT_INPUT_V3_SET_SURROUNDING_TEXT 3
+#define ZWP_TEXT_INPUT_V3_SET_TEXT_CHANGE_CAUSE 4
+#define ZWP_TEXT_INPUT_V3_SET_CONTENT_TYPE 5
+#define ZWP_TEXT_INPUT_V3_SET_CURSOR_RECTANGLE 6
+#define ZWP_TEXT_INPUT_V3_COMMIT 7
+
+struct zwp_text_input_v3_listener {
+	void (*enter)(void *data, struct zwp_text_input_v3 *text_input, struct wl_surface *surface);
+	void (*leave)(void *data, struct zwp_text_input_v3 *text_input, struct wl_surface *surface);
+	void (*preedit_string)(void *data, struct zwp_text_input_v3 *text_input, const char *text, int32_t cursor_begin, int32_t cursor_end);
+	void (*commit_string)(void *data, struct zwp_text_input_v3 *text_input, const char *text);
+	void (*delete_surrounding_text)(void *data, struct zwp_text_input_v3 *text_input, uint32_t before_length, uint32_t after_length);
+	void (*done)(void *data, struct zwp_text_input_v3 *text_input, uint32_t serial);
+};
+
+static inline struct zwp_text_input_v3 *zwp_text_input_manager_v3_get_text_input(struct zwp_text_input_manager_v3 *manager, struct wl_seat *seat) {
+	return (struct zwp_text_input_v3 *)wl_proxy_marshal_constructor((struct wl_proxy *)manager,
+		ZWP_TEXT_INPUT_MANAGER_V3_GET_TEXT_INPUT, &zwp_text_input_v3_interface, NULL, seat);
+}
+
+static inline int zwp_text_input_v3_add_listener(struct zwp_text_input_v3 *text_input, const struct zwp_text_input_v3_listener *listener, void *data) {
+	return wl_proxy_add_listener((struct wl_proxy *)text_input, (void (**)(void))listener, data);
+}
+
+static inline void zwp_text_input_v3_enable(struct zwp_text_input_v3 *text_input) {
+	wl_proxy_marshal((struct wl_proxy *)text_input, ZWP_TEXT_INPUT_V3_ENABLE);
+}
+
+static inline void zwp_text_input_v3_disable(struct zwp_text_input_v3 *text_input) {
+	wl_proxy_marshal((struct wl_proxy *)text_input, ZWP_TEXT_INPUT_V3_DISABLE);
+}
+
+static inline void zwp_text_input_v3_set_cursor_rectangle(struct zwp_text_input_v3 *text_input, int32_t x, int32_t y, int32_t width, int32_t height) {
+	wl_proxy_marshal((struct wl_proxy *)text_input, ZWP_TEXT_INPUT_V3_SET_CURSOR_RECTANGLE, x, y, width, height);
+}
+
+static inline void zwp_text_input_v3_commit(struct zwp_text_input_v3 *text_input) {
+	wl_proxy_marshal((struct wl_proxy *)text_input, ZWP_TEXT_INPUT_V3_COMMIT);
+}
+
+#endif
@@ -0,0 +1,134 @@
+// +build linux
+// +build !android,wayland
+
+#include "wayland_xdg_shell.h"
+
+static const struct wl_interface *null_types[] = {
+	NULL, NULL, NULL, NULL,
+};
+
+static const struct wl_interface *create_positioner_types[] = {
+	&xdg_positioner_interface,
+};
+
+static const struct wl_interface *get_xdg_surface_types[] = {
+	&xdg_surface_interface,
+	&wl_surface_interface,
+};
+
+static const struct wl_interface *get_toplevel_types[] = {
+	&xdg_toplevel_interface,
+};
+
+static const struct wl_interface *get_popup_types[] = {
+	&xdg_popup_interface,
+	&xdg_surface_interface,
+	&xdg_positioner_interface,
+};
+
+static const struct wl_interface *set_parent_types[] = {
+	&xdg_toplevel_interface,
+};
+
+static const struct wl_interface *seat_serial_types[] = {
+	&wl_seat_interface, NULL, NULL, NULL,
+};
+
+static const struct wl_interface *set_fullscreen_types[] = {
+	&wl_output_interface,
+};
+
+static const struct wl_message xdg_wm_base_requests[] = {
+	{ "destroy", "", null_types },
+	{ "create_positioner", "n", create_positioner_types },
+	{ "get_xdg_surface", "no", get_xdg_surface_types },
+	{ "pong", "u", null_types },
+};
+
+static const struct wl_message xdg_wm_base_events[] = {
+	{ "ping", "u", null_types },
+};
+
+const struct wl_interface xdg_wm_base_interface = {
+	"xdg_wm_base", 1,
+	4, xdg_wm_base_requests,
+	1, xdg_wm_base_events,
+};
+
+static const struct wl_message xdg_positioner_requests[] = {
+	{ "destroy", "", null_types },
+	{ "set_size", "ii", null_types },
+	{ "set_anchor_rect", "iiii", null_types },
+	{ "set_anchor", "u", null_types },
+	{ "set_gravity", "u", null_types },
+	{ "set_constraint_adjustment", "u", null_types },
+	{ "set_offset", "ii", null_types },
+};
+
+const struct wl_interface xdg_positioner_interface = {
+	"xdg_positioner", 1,
+	7, xdg_positioner_requests,
+	0, NULL,
+};
+
+static const struct wl_message xdg_surface_requests[] = {
+	{ "destroy", "", null_types },
+	{ "get_toplevel", "n", get_toplevel_types },
+	{ "get_popup", "n?oo", get_popup_types },
+	{ "set_window_geometry", "iiii", null_types },
+	{ "ack_configure", "u", null_types },
+};
+
+static const struct wl_message xdg_surface_events[] = {
+	{ "configure", "u", null_types },
+};
+
+const struct wl_interface xdg_surface_interface = {
+	"xdg_surface", 1,
+	5, xdg_surface_requests,
+	1, xdg_surface_events,
+};
+
+static const struct wl_message xdg_toplevel_requests[] = {
+	{ "destroy", "", null_types },
+	{ "set_parent", "?o", set_parent_types },
+	{ "set_title", "s", null_types },
+	{ "set_app_id", "s", null_types },
+	{ "show_window_menu", "ouii", seat_serial_types },
+	{ "move", "ou", seat_serial_types },
+	{ "resize", "ouu", seat_serial_types },
+	{ "set_max_size", "ii", null_types },
+	{ "set_min_size", "ii", null_types },
+	{ "set_maximized", "", null_types },
+	{ "unset_maximized", "", null_types },
+	{ "set_fullscreen", "?o", set_fullscreen_types },
+	{ "unset_fullscreen", "", null_types },
+	{ "set_minimized", "", null_types },
+};
+
+static const struct wl_message xdg_toplevel_events[] = {
+	{ "configure", "iia", null_types },
+	{ "close", "", null_types },
+};
+
+const struct wl_interface xdg_toplevel_interface = {
+	"xdg_toplevel", 1,
+	14, xdg_toplevel_requests,
+	2, xdg_toplevel_events,
+};
+
+static const struct wl_message xdg_popup_requests[] = {
+	{ "destroy", "", null_types },
+	{ "grab", "ou", seat_serial_types },
+};
+
+static const struct wl_message xdg_popup_events[] = {
+	{ "configure", "iiii", null_types },
+	{ "popup_done", "", null_types },
+};
+
+const struct wl_interface xdg_popup_interface = {
+	"xdg_popup", 1,
+	2, xdg_popup_requests,
+	2, xdg_popup_events,
+};
@@ -0,0 +1,86 @@
+// Client-side glue for the stable xdg-shell protocol, equivalent to what
+// wayland-scanner generates from xdg-shell.xml.  Only the requests and
+// events used by the Wayland backend have wrappers here.
+
+#ifndef UI_WAYLAND_XDG_SHELL_H
+#define UI_WAYLAND_XDG_SHELL_H
+
+#include <stdint.h>
+#include <wayland-client.h>
+
+struct xdg_wm_base;
+struct xdg_positioner;
+struct xdg_surface;
+struct xdg_toplevel;
+struct xdg_popup;
+
+extern const struct wl_interface xdg_wm_base_interface;
+extern const struct wl_interface xdg_positioner_interface;
+extern const struct wl_interface xdg_surface_interface;
+extern const struct wl_interface xdg_toplevel_interface;
+extern const struct wl_interface xdg_popup_interface;
+
+#define XDG_WM_BASE_DESTROY 0
+#define XDG_WM_BASE_CREATE_POSITIONER 1
+#define XDG_WM_BASE_GET_XDG_SURFACE 2
+#define XDG_WM_BASE_PONG 3
+
+#define XDG_SURFACE_DESTROY 0
+#define XDG_SURFACE_GET_TOPLEVEL 1
+#define XDG_SURFACE_GET_POPUP 2
+#define XDG_SURFACE_SET_WINDOW_GEOMETRY 3
+#define XDG_SURFACE_ACK_CONFIGURE 4
+
+#define XDG_TOPLEVEL_DESTROY 0
+#define XDG_TOPLEVEL_SET_PARENT 1
+#define XDG_TOPLEVEL_SET_TITLE 2
+#define XDG_TOPLEVEL_SET_APP_ID 3
+
+struct xdg_wm_base_listener {
+	void (*ping)(void *data, struct xdg_wm_base *xdg_wm_base, uint32_t serial);
+};
+
+struct xdg_surface_listener {
+	void (*configure)(void *data, struct xdg_surface *xdg_surface, uint32_t serial);
+};
+
+struct xdg_toplevel_listener {
+	void (*configure)(void *data, struct xdg_toplevel *xdg_toplevel, int32_t width, int32_t height, struct wl_array *states);
+	void (*close)(void *data, struct xdg_toplevel *xdg_toplevel);
+};
+
+static inline int xdg_wm_base_add_listener(struct xdg_wm_base *xdg_wm_base, const struct xdg_wm_base_listener *listener, void *data) {
+	return wl_proxy_add_listener((struct wl_proxy *)xdg_wm_base, (void (**)(void))listener, data);
+}
+
+static inline struct xdg_surface *xdg_wm_base_get_xdg_surface(struct xdg_wm_base *xdg_wm_base, struct wl_surface *surface) {
+	return (struct xdg_surface *)wl_proxy_marshal_constructor((struct wl_proxy *)xdg_wm_base,
+		XDG_WM_BASE_GET_XDG_SURFACE, &xdg_surface_interface, NULL, surface);
+}
+
+static inline void xdg_wm_base_pong(struct xdg_wm_base *xdg_wm_base, uint32_t serial) {
+	wl_proxy_marshal((struct wl_proxy *)xdg_wm_base, XDG_WM_BASE_PONG, serial);
+}
+
+static inline int xdg_surface_add_listener(struct xdg_surface *xdg_surface, const struct xdg_surface_listener *listener, void *data) {
+	return wl_proxy_add_listener((struct wl_proxy *)xdg_surface, (void (**)(void))listener, data);
+}
+
+static inline struct xdg_toplevel *xdg_surface_get_toplevel(struct xdg_surface *xdg_surface) {
+	return (struct xdg_toplevel *)wl_proxy_marshal_constructor((struct wl_proxy *)xdg_surface,
+		XDG_SURFACE_GET_TOPLEVEL, &xdg_toplevel_interface, NULL);
+}
+
+static inline void xdg_surface_ack_configure(struct xdg_surface *xdg_surface, uint32_t serial) {
+	wl_proxy_marshal((struct wl_proxy *)xdg_surface, XDG_SURFACE_ACK_CONFIGURE, serial);
+}
+
+static inline int xdg_toplevel_add_listener(struct xdg_toplevel *xdg_toplevel, const struct xdg_toplevel_listener *listener, void *data) {
+	return wl_proxy_add_listener((struct wl_proxy *)xdg_toplevel, (void (**)(void))listener, data);
+}
+
+static inline void xdg_toplevel_set_title(struct xdg_toplevel *xdg_toplevel, const char *title) {
+	wl_proxy_marshal((struct wl_proxy *)xdg_toplevel, XDG_TOPLEVEL_SET_TITLE, title);
+}
+
+#endif
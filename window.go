@@ -1,7 +1,22 @@
 package ui
 
+import (
+	"errors"
+	"image"
+)
+
 type Window interface {
 	View
+
+	// Capture draws the window and returns its contents.  Like CaptureView,
+	// it runs on the window's goroutine and waits for it, so it must not be
+	// called from there, such as from the methods of the window's views,
+	// where it would never return.
+	Capture() (*image.RGBA, error)
+
+	// CaptureView draws v and its descendants, which must be in the window,
+	// over a transparent background and returns the area covered by v.Rect().
+	CaptureView(v View) (*image.RGBA, error)
 }
 
 func NewWindow(size Size, v View) (Window, error) {
@@ -14,6 +29,10 @@ type windowBase struct {
 	do           chan func()
 	gfx          *Graphics
 	pointerViews map[PointerID]View
+	pointers     map[PointerID]Pointer
+	hovered      map[PointerID][]View // the views under each pointer, outermost first
+	gestures     *gestureArena
+	focused      View
 }
 
 func newWindowBase(self Window, v View) *windowBase {
@@ -21,11 +40,16 @@ func newWindowBase(self Window, v View) *windowBase {
 		theView:      v,
 		do:           make(chan func()),
 		pointerViews: map[PointerID]View{},
+		pointers:     map[PointerID]Pointer{},
+		hovered:      map[PointerID][]View{},
 	}
 	w.View = NewView(self, nil)
+	w.gestures = newGestureArena(w)
 	return w
 }
 
+// Do runs f on the window's goroutine and waits for it to return.  It must
+// not be called from that goroutine.
 func (w *windowBase) Do(f func()) {
 	done := make(chan struct{})
 	w.do <- func() {
@@ -35,30 +59,381 @@ func (w *windowBase) Do(f func()) {
 	<-done
 }
 
+// SizePolicy returns the policy of the window's content view.
+func (w *windowBase) SizePolicy() SizePolicy {
+	return w.theView.SizePolicy()
+}
+
+// Layout sizes the content view to the window, within its size policy.
+func (w *windowBase) Layout() {
+	if s := w.theView.SizePolicy().Clamp(w.Size()); s != w.theView.Size() {
+		w.theView.Resize(s)
+	}
+}
+
 func (w *windowBase) draw() {
+	w.view().layout()
 	w.gfx.clear()
 	w.view().draw(w.gfx)
 }
 
+func (w *windowBase) Capture() (img *image.RGBA, err error) {
+	w.Do(func() {
+		if w.gfx == nil {
+			err = errors.New("window has no graphics context")
+			return
+		}
+		w.draw()
+		img, err = w.gfx.readPixels()
+	})
+	return
+}
+
+func (w *windowBase) CaptureView(v View) (img *image.RGBA, err error) {
+	w.Do(func() {
+		if w.gfx == nil {
+			err = errors.New("window has no graphics context")
+			return
+		}
+		if !w.contains(v) {
+			err = errors.New("view is not in this window")
+			return
+		}
+		w.view().layout()
+		t := v.view().getTransformToWindow()
+		r := v.Rect()
+		r = Rectangle{t.transform(r.Min), t.transform(r.Max)}
+		img, err = w.gfx.capture(r, func() { v.view().draw(w.gfx) })
+	})
+	return
+}
+
+func (w *windowBase) contains(v View) bool {
+	for p := v.view(); p != nil; p = p.parent {
+		if p == w.view() {
+			return true
+		}
+	}
+	return false
+}
+
+// Pointer events go to gesture recognizers first, and then to the views
+// unless a recognizer has claimed the pointer.
 func (w *windowBase) pointerDown(p Pointer) {
+	w.hover(p)
 	if v := w.ViewAt(p.Position); v != nil {
-		p.Position = v.view().mapFromWindow(p.Position)
+		w.focusFromPointer(v)
+		w.pointers[p.ID] = p
 		w.pointerViews[p.ID] = v
-		v.PointerDown(p)
+		w.gestures.pointerDown(p, v)
+		if !w.gestures.claimed[p.ID] {
+			w.dispatchPointer(p, v, pointerDownEvent)
+		}
 	}
 }
 
+// pointerMove delivers a move to the view capturing the pointer or, if it
+// is not down, to the view under it.
 func (w *windowBase) pointerMove(p Pointer) {
-	if v, ok := w.pointerViews[p.ID]; ok {
-		p.Position = v.view().mapFromWindow(p.Position)
-		v.PointerMove(p)
+	w.hover(p)
+	if _, down := w.pointers[p.ID]; !down {
+		if v := w.ViewAt(p.Position); v != nil {
+			w.dispatchPointer(p, v, pointerMoveEvent)
+		}
+		return
+	}
+	w.pointers[p.ID] = p
+	w.gestures.pointerMove(p)
+	if w.gestures.claimed[p.ID] {
+		return
+	}
+	if v, ok := w.pointerTarget(p); ok {
+		w.dispatchPointer(p, v, pointerMoveEvent)
 	}
 }
 
 func (w *windowBase) pointerUp(p Pointer) {
-	if v, ok := w.pointerViews[p.ID]; ok {
+	w.hover(p)
+	if _, down := w.pointers[p.ID]; down {
+		w.pointers[p.ID] = p
+		w.gestures.pointerUp(p)
+		if v, ok := w.pointerTarget(p); ok && !w.gestures.claimed[p.ID] {
+			w.dispatchPointer(p, v, pointerUpEvent)
+		}
+		w.gestures.release(p.ID)
+	}
+	delete(w.pointers, p.ID)
+	delete(w.pointerViews, p.ID)
+	// Touches don't hover.
+	if p.Type.Touch() {
+		w.pointerLeave(p)
+	}
+}
+
+// pointerLeave is called when a pointer leaves the window.
+func (w *windowBase) pointerLeave(p Pointer) {
+	w.setHovered(p, nil)
+}
+
+// hover updates the views under p.
+func (w *windowBase) hover(p Pointer) {
+	var chain []View
+	for v := w.ViewAt(p.Position); v != nil && v.view() != w.view(); v = v.Parent() {
+		chain = append([]View{v}, chain...)
+	}
+	w.setHovered(p, chain)
+}
+
+// setHovered delivers PointerLeave to the views that are no longer under p,
+// innermost first, and PointerEnter to those that now are, outermost first.
+func (w *windowBase) setHovered(p Pointer, chain []View) {
+	old := w.hovered[p.ID]
+	n := 0
+	for n < len(old) && n < len(chain) && old[n] == chain[n] {
+		n++
+	}
+	if len(chain) > 0 {
+		w.hovered[p.ID] = chain
+	} else {
+		delete(w.hovered, p.ID)
+	}
+	pos := p.Position
+	for i := len(old) - 1; i >= n; i-- {
+		p.Position = old[i].view().mapFromWindow(pos)
+		old[i].PointerLeave(p)
+	}
+	for _, v := range chain[n:] {
+		p.Position = v.view().mapFromWindow(pos)
+		v.PointerEnter(p)
+	}
+}
+
+// pointerCancel tells the view capturing p that the system has taken it away.
+func (w *windowBase) pointerCancel(p Pointer) {
+	if _, down := w.pointers[p.ID]; down {
+		w.gestures.pointerCancel(p)
+		w.gestures.release(p.ID)
+	}
+	if v := w.pointerViews[p.ID]; v != nil {
 		p.Position = v.view().mapFromWindow(p.Position)
-		v.PointerUp(p)
-		delete(w.pointerViews, p.ID)
+		v.PointerCancel(p)
+	}
+	delete(w.pointers, p.ID)
+	delete(w.pointerViews, p.ID)
+	w.pointerLeave(p)
+}
+
+// pointerTarget returns the view that captures a pointer that is down or,
+// if its capture was released, the view under it.
+func (w *windowBase) pointerTarget(p Pointer) (View, bool) {
+	v, ok := w.pointerViews[p.ID]
+	if !ok {
+		return nil, false
+	}
+	if v == nil {
+		v = w.ViewAt(p.Position)
+	}
+	return v, v != nil
+}
+
+// SetPointerCapture directs the later events of a pointer that is down to v.
+// The view that had captured it receives PointerCancel.
+func (w *windowBase) SetPointerCapture(id PointerID, v View) {
+	old, ok := w.pointerViews[id]
+	if !ok || v == old || !w.contains(v) {
+		return
+	}
+	w.pointerViews[id] = v
+	if old != nil {
+		p := w.pointers[id]
+		p.Position = old.view().mapFromWindow(p.Position)
+		old.PointerCancel(p)
+	}
+}
+
+// ReleasePointerCapture directs the later events of a pointer to whichever
+// view is under it.
+func (w *windowBase) ReleasePointerCapture(id PointerID) {
+	if _, ok := w.pointerViews[id]; ok {
+		w.pointerViews[id] = nil
+	}
+}
+
+func (w *windowBase) PointerCapture(id PointerID) View {
+	return w.pointerViews[id]
+}
+
+type pointerEventType uint8
+
+const (
+	pointerDownEvent pointerEventType = iota
+	pointerMoveEvent
+	pointerUpEvent
+)
+
+// dispatchPointer delivers p, in window coordinates, to target.  First the
+// ancestors of target may intercept it, from the window down; then target
+// and its ancestors in turn may handle it.  A view that intercepts the
+// event, or handles PointerDown, receives the pointer's later events.
+func (w *windowBase) dispatchPointer(p Pointer, target View, typ pointerEventType) {
+	pos := p.Position
+	prop := &propagation{}
+	p.propagation = prop
+	deliver := func(v View, intercept bool) bool {
+		p.Position = v.view().mapFromWindow(pos)
+		return callPointer(v, p, typ, intercept)
+	}
+
+	var ancestors []View
+	for v := target.Parent(); v != nil && v.view() != w.view(); v = v.Parent() {
+		ancestors = append(ancestors, v)
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		a := ancestors[i]
+		if deliver(a, true) {
+			switch typ {
+			case pointerDownEvent:
+				// The target has not seen the pointer, so it has nothing to
+				// cancel.
+				w.pointerViews[p.ID] = a
+			case pointerMoveEvent:
+				w.SetPointerCapture(p.ID, a)
+			}
+			deliver(a, false)
+			return
+		}
+		if prop.stopped {
+			return
+		}
+	}
+
+	for v := target; v != nil && v.view() != w.view(); v = v.Parent() {
+		if deliver(v, false) {
+			// The handler captures the pointer, unless it set the capture itself.
+			if typ == pointerDownEvent && w.pointerViews[p.ID] == target {
+				w.pointerViews[p.ID] = v
+			}
+			return
+		}
+		if prop.stopped {
+			return
+		}
+	}
+}
+
+func callPointer(v View, p Pointer, typ pointerEventType, intercept bool) bool {
+	switch {
+	case typ == pointerDownEvent && intercept:
+		return v.InterceptPointerDown(p)
+	case typ == pointerMoveEvent && intercept:
+		return v.InterceptPointerMove(p)
+	case typ == pointerUpEvent && intercept:
+		return v.InterceptPointerUp(p)
+	case typ == pointerDownEvent:
+		return v.PointerDown(p)
+	case typ == pointerMoveEvent:
+		return v.PointerMove(p)
+	default:
+		return v.PointerUp(p)
+	}
+}
+
+// keyView returns the view that receives key events:  the focused view or,
+// if there is none, the window's content view.
+func (w *windowBase) keyView() View {
+	if f := w.Focused(); f != nil {
+		return f
+	}
+	return w.theView
+}
+
+// keyDown delivers a key press, except that Tab and Shift-Tab move the focus.
+func (w *windowBase) keyDown(k Key) {
+	if isFocusTraversal(k) {
+		w.moveFocus(!k.Modifiers.Shift())
+		return
+	}
+	w.keyView().KeyDown(k)
+}
+
+func (w *windowBase) keyUp(k Key) {
+	if isFocusTraversal(k) {
+		return
+	}
+	w.keyView().KeyUp(k)
+}
+
+func isFocusTraversal(k Key) bool {
+	return k.Code == KeyCodeTab && k.Modifiers&^KeyModifierShift == 0
+}
+
+func (w *windowBase) SetFocus(v View) {
+	if v != nil && !w.contains(v) {
+		return
+	}
+	if v == w.focused {
+		return
+	}
+	if w.focused != nil {
+		w.focused.FocusOut()
+	}
+	w.focused = v
+	if v != nil {
+		v.FocusIn()
+	}
+}
+
+func (w *windowBase) Focused() View {
+	// The focused view may have been removed from the window.
+	if w.focused != nil && !w.contains(w.focused) {
+		w.focused = nil
+	}
+	return w.focused
+}
+
+// focusFromPointer focuses the nearest focusable view at or above v.
+func (w *windowBase) focusFromPointer(v View) {
+	for p := v.view(); p != nil; p = p.parent {
+		if p.self.Focusable() {
+			w.SetFocus(p.self)
+			return
+		}
+	}
+}
+
+// moveFocus focuses the next or previous focusable view in focus order,
+// wrapping around at the ends.
+func (w *windowBase) moveFocus(forward bool) {
+	var chain []View
+	var collect func(v View)
+	collect = func(v View) {
+		if v.Focusable() {
+			chain = append(chain, v)
+		}
+		for _, c := range v.FocusOrder() {
+			collect(c)
+		}
+	}
+	collect(w.theView)
+	if len(chain) == 0 {
+		return
+	}
+
+	i := -1
+	focused := w.Focused()
+	for j, v := range chain {
+		if v == focused {
+			i = j
+			break
+		}
+	}
+	switch {
+	case forward:
+		i = (i + 1) % len(chain)
+	case i < 0:
+		i = len(chain) - 1
+	default:
+		i = (i - 1 + len(chain)) % len(chain)
 	}
+	w.SetFocus(chain[i])
 }
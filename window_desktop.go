@@ -3,6 +3,7 @@
 package ui
 
 import (
+	"math"
 	"runtime"
 	"sync"
 	"unsafe"
@@ -16,13 +17,37 @@ var (
 	windows   = map[uintptr]*window{}
 )
 
+// openWindows returns a copy of the open windows, so that events can be sent
+// to them without holding windowsMu, which their loops may need.
+func openWindows() []*window {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	ws := make([]*window, 0, len(windows))
+	for _, w := range windows {
+		ws = append(ws, w)
+	}
+	return ws
+}
+
 type window struct {
 	*windowBase
-	w             uintptr
-	sizeEvents    chan sizeEvent
-	drawEvents    chan drawEvent
-	pointerEvents chan pointerEvent
-	size          sizeEvent
+	w          uintptr
+	drawEvents chan drawEvent
+	// events carries the backend's sizeEvents, pointerEvents, Scrolls, Keys
+	// and textEvents, in the order they happen.
+	events chan interface{}
+	// closed is closed when the window's loop ends.
+	closed    chan struct{}
+	size      sizeEvent
+	textInput textInputState
+}
+
+// send sends an event to the window's loop, unless it has ended.
+func (w *window) send(e interface{}) {
+	select {
+	case w.events <- e:
+	case <-w.closed:
+	}
 }
 
 type sizeEvent struct {
@@ -32,17 +57,67 @@ type sizeEvent struct {
 
 type drawEvent struct{}
 
+// A closeEvent ends a window's loop.  release is called once the graphics
+// resources are released, to release the context.
+type closeEvent struct {
+	release func()
+}
+
+// A textEvent carries committed text or, if compose is set, a composition.
+type textEvent struct {
+	compose bool
+	c       Composition
+}
+
+// textInputState is what the backend was last told about text input: whether
+// a TextInput has the focus and its composition rectangle in pixels from the
+// top-left corner of the window.
+type textInputState struct {
+	enabled    bool
+	x, y, w, h int
+}
+
 type pointerEvent struct {
-	down, up bool
-	p        Pointer
+	down, up, cancel, leave bool
+	p                       Pointer
+}
+
+// Backends that support pen tablets report the tool of each mouse event.
+const (
+	toolMouse = iota
+	toolPen
+	toolEraser
+)
+
+// penPointer is the pointer of pen tablets, which are reported separately
+// from the mouse.
+var penPointer = activePointers.new(Pointer{
+	Type: PointerTypePen,
+})
+
+// penButton returns the button of a pen or eraser given whether it is the
+// tip, which makes contact, or one on the barrel.
+func penButton(tool int32, tip bool) PointerButtons {
+	switch {
+	case !tip:
+		return PointerButtonPenBarrel
+	case tool == toolEraser:
+		return PointerButtonPenEraser
+	}
+	return PointerButtonPenContact
 }
 
 func newWindow(size Size, v View) (Window, error) {
+	impl, err := newWindowImpl(size)
+	if err != nil {
+		return nil, err
+	}
+
 	w := &window{
-		w:             newWindowImpl(size),
-		sizeEvents:    make(chan sizeEvent, 1),
-		drawEvents:    make(chan drawEvent, 1),
-		pointerEvents: make(chan pointerEvent, 1),
+		w:          impl,
+		drawEvents: make(chan drawEvent, 1),
+		events:     make(chan interface{}, 1),
+		closed:     make(chan struct{}),
 	}
 	w.windowBase = newWindowBase(w, v)
 	v.SetParent(w)
@@ -51,13 +126,16 @@ func newWindow(size Size, v View) (Window, error) {
 	windows[w.w] = w
 	windowsMu.Unlock()
 
+	// Only now that the window is registered can its events be handled.
+	mapWindowImpl(w.w)
+
 	return w, nil
 }
 
 func (w *window) Resize(s Size) {
 	w.View.Resize(s)
 	w.gfx.Size(s)
-	w.theView.Resize(s)
+	w.Layout()
 	w.Redraw()
 }
 
@@ -84,30 +162,98 @@ func windowLoop(window uintptr, ctx uintptr) {
 	gl.BindVertexArray(va)
 
 	w.gfx = newGraphics(glContext{})
-	defer w.gfx.release()
 
 	for {
 		select {
 		case f := <-w.do:
 			f()
-		case s := <-w.sizeEvents:
-			w.size = s
-			w.Resize(s.size)
 		case <-w.drawEvents:
+			if !beginFrame(ctx) {
+				// The backend sends another drawEvent when it is ready for a new frame.
+				break
+			}
 			w.windowBase.draw()
 			flushContext(ctx)
-		case p := <-w.pointerEvents:
-			p.p.X *= w.size.px.Width
-			p.p.Y *= w.size.px.Height
-			p.p.Y = w.size.size.Height - p.p.Y
-			if p.down {
-				w.windowBase.pointerDown(p.p)
-			} else if p.up {
-				w.windowBase.pointerUp(p.p)
-			} else {
-				w.windowBase.pointerMove(p.p)
+		case e := <-w.events:
+			if c, ok := e.(closeEvent); ok {
+				w.gfx.release()
+				c.release()
+				close(w.closed)
+				return
 			}
+			w.handleEvent(ctx, e)
+		}
+		w.updateTextInput()
+	}
+}
+
+// handleEvent handles an event from the backend.
+func (w *window) handleEvent(ctx uintptr, e interface{}) {
+	switch e := e.(type) {
+	case sizeEvent:
+		w.size = e
+		resizeContext(ctx, e)
+		w.Resize(e.size)
+	case pointerEvent:
+		e.p.X *= w.size.px.Width
+		e.p.Y *= w.size.px.Height
+		e.p.Y = w.size.size.Height - e.p.Y
+		e.p.ContactSize.Width *= w.size.px.Width
+		e.p.ContactSize.Height *= w.size.px.Height
+		if e.down {
+			w.windowBase.pointerDown(e.p)
+		} else if e.cancel {
+			w.windowBase.pointerCancel(e.p)
+		} else if e.leave {
+			w.windowBase.pointerLeave(e.p)
+		} else if e.up {
+			w.windowBase.pointerUp(e.p)
+		} else {
+			w.windowBase.pointerMove(e.p)
 		}
+	case Scroll:
+		// Backends report positions like pointer events, and deltas in
+		// pixels if precise and in lines otherwise.
+		e.X *= w.size.px.Width
+		e.Y *= w.size.px.Height
+		e.Y = w.size.size.Height - e.Y
+		if e.Precise {
+			e.Delta.Width *= w.size.px.Width
+			e.Delta.Height *= w.size.px.Height
+		}
+		w.windowBase.scroll(e)
+	case Key:
+		if e.Direction == KeyPress {
+			w.windowBase.keyDown(e)
+		} else {
+			w.windowBase.keyUp(e)
+		}
+	case textEvent:
+		if e.compose {
+			w.windowBase.setComposition(e.c)
+		} else {
+			w.windowBase.insertText(e.c.Text)
+		}
+	}
+}
+
+// updateTextInput tells the backend when the focused TextInput or its
+// composition rectangle changes, so that it can enable the input method and
+// place its candidate window.
+func (w *window) updateTextInput() {
+	var t textInputState
+	if r, ok := w.windowBase.textInputRect(); ok && w.size.px.Width > 0 && w.size.px.Height > 0 {
+		t = textInputState{
+			enabled: true,
+			x:       int(math.Round(r.Min.X / w.size.px.Width)),
+			y:       int(math.Round(r.Min.Y / w.size.px.Height)),
+			w:       int(math.Round(r.Width() / w.size.px.Width)),
+			h:       int(math.Round(r.Height() / w.size.px.Height)),
+		}
+	}
+	if t != w.textInput {
+		w.textInput = t
+		setTextInput(w.w, t)
 	}
 }
 
@@ -121,6 +267,10 @@ func (glContext) BlendFunc(sfactor, dfactor glmobile.Enum) {
 	gl.BlendFunc(uint32(sfactor), uint32(dfactor))
 }
 
+func (glContext) BlendFuncSeparate(sfactorRGB, dfactorRGB, sfactorAlpha, dfactorAlpha glmobile.Enum) {
+	gl.BlendFuncSeparate(uint32(sfactorRGB), uint32(dfactorRGB), uint32(sfactorAlpha), uint32(dfactorAlpha))
+}
+
 func (glContext) CreateProgram() glmobile.Program {
 	return glmobile.Program{Init: true, Value: gl.CreateProgram()}
 }
@@ -224,7 +374,7 @@ func (glContext) DeleteBuffer(b glmobile.Buffer) {
 }
 
 func (glContext) VertexAttribPointer(dst glmobile.Attrib, size int, ty glmobile.Enum, normalized bool, stride, offset int) {
-	gl.VertexAttribPointer(uint32(dst.Value), int32(size), uint32(ty), normalized, int32(stride), unsafe.Pointer(uintptr(offset)))
+	gl.VertexAttribPointer(uint32(dst.Value), int32(size), uint32(ty), normalized, int32(stride), gl.PtrOffset(offset))
 }
 
 func (glContext) EnableVertexAttribArray(a glmobile.Attrib) {
@@ -234,3 +384,71 @@ func (glContext) EnableVertexAttribArray(a glmobile.Attrib) {
 func (glContext) DrawArrays(mode glmobile.Enum, first, count int) {
 	gl.DrawArrays(uint32(mode), int32(first), int32(count))
 }
+
+func (glContext) GetError() glmobile.Enum {
+	return glmobile.Enum(gl.GetError())
+}
+
+func (glContext) GetIntegerv(dst []int32, pname glmobile.Enum) {
+	gl.GetIntegerv(uint32(pname), &dst[0])
+}
+
+func (glContext) Viewport(x, y, width, height int) {
+	gl.Viewport(int32(x), int32(y), int32(width), int32(height))
+}
+
+func (glContext) ReadPixels(dst []byte, x, y, width, height int, format, ty glmobile.Enum) {
+	gl.ReadPixels(int32(x), int32(y), int32(width), int32(height), uint32(format), uint32(ty), gl.Ptr(dst))
+}
+
+func (glContext) CreateTexture() glmobile.Texture {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	return glmobile.Texture{Value: texture}
+}
+
+func (glContext) BindTexture(target glmobile.Enum, t glmobile.Texture) {
+	gl.BindTexture(uint32(target), t.Value)
+}
+
+func (glContext) DeleteTexture(t glmobile.Texture) {
+	gl.DeleteTextures(1, &t.Value)
+}
+
+func (glContext) TexImage2D(target glmobile.Enum, level int, internalFormat int, width, height int, format glmobile.Enum, ty glmobile.Enum, data []byte) {
+	var p unsafe.Pointer
+	if len(data) > 0 {
+		p = gl.Ptr(data)
+	}
+	gl.TexImage2D(uint32(target), int32(level), int32(internalFormat), int32(width), int32(height), 0, uint32(format), uint32(ty), p)
+}
+
+func (glContext) TexSubImage2D(target glmobile.Enum, level int, x, y, width, height int, format, ty glmobile.Enum, data []byte) {
+	gl.TexSubImage2D(uint32(target), int32(level), int32(x), int32(y), int32(width), int32(height), uint32(format), uint32(ty), gl.Ptr(data))
+}
+
+func (glContext) TexParameteri(target, pname glmobile.Enum, param int) {
+	gl.TexParameteri(uint32(target), uint32(pname), int32(param))
+}
+
+func (glContext) CreateFramebuffer() glmobile.Framebuffer {
+	var framebuffer uint32
+	gl.GenFramebuffers(1, &framebuffer)
+	return glmobile.Framebuffer{Value: framebuffer}
+}
+
+func (glContext) BindFramebuffer(target glmobile.Enum, fb glmobile.Framebuffer) {
+	gl.BindFramebuffer(uint32(target), fb.Value)
+}
+
+func (glContext) DeleteFramebuffer(fb glmobile.Framebuffer) {
+	gl.DeleteFramebuffers(1, &fb.Value)
+}
+
+func (glContext) FramebufferTexture2D(target, attachment, texTarget glmobile.Enum, t glmobile.Texture, level int) {
+	gl.FramebufferTexture2D(uint32(target), uint32(attachment), uint32(texTarget), t.Value, int32(level))
+}
+
+func (glContext) CheckFramebufferStatus(target glmobile.Enum) glmobile.Enum {
+	return glmobile.Enum(gl.CheckFramebufferStatus(uint32(target)))
+}
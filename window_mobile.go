@@ -7,6 +7,7 @@ import (
 	"log"
 
 	"golang.org/x/mobile/app"
+	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
@@ -47,7 +48,7 @@ func newWindow(size Size, v View) (Window, error) {
 
 func (w *window) Resize(s Size) {
 	w.View.Resize(s)
-	w.theView.Resize(s)
+	w.Layout()
 	if w.gfx != nil {
 		w.gfx.Size(s)
 		w.Redraw()
@@ -106,6 +107,8 @@ func (w *window) handleEvents() {
 				}
 			case touch.Event:
 				w.handleTouchEvent(e)
+			case key.Event:
+				w.handleKeyEvent(e)
 			}
 		}
 	}
@@ -146,6 +149,36 @@ func (w *window) handleTouchEvent(e touch.Event) {
 	}
 }
 
+func (w *window) handleKeyEvent(e key.Event) {
+	// key.Code values are HID usage IDs, like KeyCode.
+	k := Key{
+		Code: KeyCode(e.Code),
+		Rune: e.Rune,
+	}
+	if e.Modifiers&key.ModShift != 0 {
+		k.Modifiers |= KeyModifierShift
+	}
+	if e.Modifiers&key.ModControl != 0 {
+		k.Modifiers |= KeyModifierControl
+	}
+	if e.Modifiers&key.ModAlt != 0 {
+		k.Modifiers |= KeyModifierAlt
+	}
+	if e.Modifiers&key.ModMeta != 0 {
+		k.Modifiers |= KeyModifierMeta
+	}
+
+	// DirNone events stand for a press and release together.
+	if e.Direction != key.DirRelease {
+		k.Direction = KeyPress
+		w.windowBase.keyDown(k)
+	}
+	if e.Direction != key.DirPress {
+		k.Direction = KeyRelease
+		w.windowBase.keyUp(k)
+	}
+}
+
 func ptToMM(pt geom.Pt) float64 {
 	const mmPerPt = 10 * 2.54 / 72
 	return mmPerPt * float64(pt)
@@ -0,0 +1,935 @@
+// +build linux
+// +build !android,!wayland
+
+#include "_cgo_export.h"
+#include <ctype.h>
+#include <dlfcn.h>
+#include <locale.h>
+#include <pthread.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <wchar.h>
+
+#include <X11/Xlib.h>
+#include <X11/XKBlib.h>
+#include <X11/Xutil.h>
+#include <X11/extensions/XI2.h>
+#include <GL/glx.h>
+
+typedef GLXContext (*glXCreateContextAttribsARBProc)(Display*, GLXFBConfig, GLXContext, Bool, const int*);
+
+#define GLX_CONTEXT_MAJOR_VERSION_ARB    0x2091
+#define GLX_CONTEXT_MINOR_VERSION_ARB    0x2092
+#define GLX_CONTEXT_FLAGS_ARB            0x2094
+#define GLX_CONTEXT_PROFILE_MASK_ARB     0x9126
+#define GLX_CONTEXT_CORE_PROFILE_BIT_ARB 0x00000001
+#define GLX_CONTEXT_FORWARD_COMPATIBLE_BIT_ARB 0x00000002
+
+typedef struct window {
+	Window win;
+	GLXContext ctx;
+	int prepared;
+	int width, height;
+	long eventMask;
+
+	// ic is the window's input context, or NULL if there is no input method.
+	XIC ic;
+	// textInput is set while a TextInput has the focus and focused while
+	// the window has the keyboard focus; the input context is focused when
+	// both are.
+	int textInput, focused;
+	// preedit is the composition, kept up to date by the preedit callbacks
+	// and sent to Go once the event that changed it has been filtered.
+	wchar_t *preedit;
+	int preeditLen, preeditCap, preeditCaret, preeditChanged;
+
+	// closing is set when the window manager asks to close the window.
+	int closing;
+
+	struct window *next;
+} window;
+
+static Display *dpy;
+static Atom wmDeleteWindow;
+
+// windows is the list of windows, which windowsLock guards.  Windows are
+// created on Go's goroutines but only destroyed on the event thread, so the
+// event thread may use a window it found without holding the lock.
+static window *windows;
+static pthread_mutex_t windowsLock = PTHREAD_MUTEX_INITIALIZER;
+
+static XIM im;
+static XIMStyle imStyle;
+
+// mmPerPx is the physical size of a pixel on the default screen.
+static double mmPerPxX, mmPerPxY;
+
+// XInput2 reports the pressure, tilt and rotation of pens.  libXi is loaded
+// at run time, so the declarations its header would provide are here.
+typedef struct {
+	int deviceid;
+	int mask_len;
+	unsigned char *mask;
+} XIEventMask;
+
+typedef struct {
+	int type;
+	int sourceid;
+} XIAnyClassInfo;
+
+typedef struct {
+	int type;
+	int sourceid;
+	int number;
+	Atom label;
+	double min;
+	double max;
+	double value;
+	int resolution;
+	int mode;
+} XIValuatorClassInfo;
+
+typedef struct {
+	int deviceid;
+	char *name;
+	int use;
+	int attachment;
+	Bool enabled;
+	int num_classes;
+	XIAnyClassInfo **classes;
+} XIDeviceInfo;
+
+typedef struct {
+	int mask_len;
+	unsigned char *mask;
+} XIButtonState;
+
+typedef struct {
+	int mask_len;
+	unsigned char *mask;
+	double *values;
+} XIValuatorState;
+
+typedef struct {
+	int base;
+	int latched;
+	int locked;
+	int effective;
+} XIModifierState;
+
+typedef XIModifierState XIGroupState;
+
+typedef struct {
+	int type;
+	unsigned long serial;
+	Bool send_event;
+	Display *display;
+	int extension;
+	int evtype;
+	Time time;
+	int deviceid;
+	int sourceid;
+	int detail;
+	Window root;
+	Window event;
+	Window child;
+	double root_x;
+	double root_y;
+	double event_x;
+	double event_y;
+	int flags;
+	XIButtonState buttons;
+	XIValuatorState valuators;
+	XIModifierState mods;
+	XIGroupState group;
+} XIDeviceEvent;
+
+static Status (*pXIQueryVersion)(Display*, int*, int*);
+static Status (*pXISelectEvents)(Display*, Window, XIEventMask*, int);
+static XIDeviceInfo *(*pXIQueryDevice)(Display*, int, int*);
+static void (*pXIFreeDeviceInfo)(XIDeviceInfo*);
+
+// xiOpcode identifies XInput2 events, or is 0 if XInput2 is unavailable.
+static int xiOpcode;
+
+enum { PRESSURE, TILT_X, TILT_Y, WHEEL, PEN_AXES };
+
+static const char *penAxisLabels[PEN_AXES] = {"Abs Pressure", "Abs Tilt X", "Abs Tilt Y", "Abs Wheel"};
+
+// A pen is a tablet's device for a pen or eraser, with the numbers and
+// ranges of the valuators that carry its axes and their last values.
+typedef struct pen {
+	int deviceid;
+	int eraser;
+	int axis[PEN_AXES];
+	double min[PEN_AXES], max[PEN_AXES], value[PEN_AXES];
+} pen;
+
+#define MAX_PENS 16
+static pen pens[MAX_PENS];
+static int numPens;
+
+static int containsFold(const char *s, const char *sub) {
+	size_t i, j, n = strlen(sub);
+	for (i = 0; s[i]; i++) {
+		for (j = 0; j < n && s[i+j] && tolower((unsigned char)s[i+j]) == sub[j]; j++) {
+		}
+		if (j == n) {
+			return 1;
+		}
+	}
+	return 0;
+}
+
+// queryPens finds the devices that report pressure.
+static void queryPens() {
+	Atom labels[PEN_AXES];
+	int a, i, j, n;
+	for (a = 0; a < PEN_AXES; a++) {
+		labels[a] = XInternAtom(dpy, penAxisLabels[a], False);
+	}
+
+	numPens = 0;
+	XIDeviceInfo *devices = pXIQueryDevice(dpy, XIAllDevices, &n);
+	for (i = 0; i < n && numPens < MAX_PENS; i++) {
+		XIDeviceInfo *d = &devices[i];
+		if (d->use != XISlavePointer) {
+			continue;
+		}
+		pen *p = &pens[numPens];
+		memset(p, 0, sizeof *p);
+		p->deviceid = d->deviceid;
+		p->eraser = containsFold(d->name, "eraser");
+		for (a = 0; a < PEN_AXES; a++) {
+			p->axis[a] = -1;
+		}
+		for (j = 0; j < d->num_classes; j++) {
+			if (d->classes[j]->type != XIValuatorClass) {
+				continue;
+			}
+			XIValuatorClassInfo *v = (XIValuatorClassInfo*)d->classes[j];
+			for (a = 0; a < PEN_AXES; a++) {
+				if (v->label == labels[a]) {
+					p->axis[a] = v->number;
+					p->min[a] = v->min;
+					p->max[a] = v->max;
+					p->value[a] = v->value;
+				}
+			}
+		}
+		if (p->axis[PRESSURE] >= 0) {
+			numPens++;
+		}
+	}
+	pXIFreeDeviceInfo(devices);
+}
+
+static pen *findPen(int deviceid) {
+	int i;
+	for (i = 0; i < numPens; i++) {
+		if (pens[i].deviceid == deviceid) {
+			return &pens[i];
+		}
+	}
+	return NULL;
+}
+
+static void openXI() {
+	void *lib = dlopen("libXi.so.6", RTLD_LAZY);
+	if (!lib) {
+		return;
+	}
+	pXIQueryVersion = dlsym(lib, "XIQueryVersion");
+	pXISelectEvents = dlsym(lib, "XISelectEvents");
+	pXIQueryDevice = dlsym(lib, "XIQueryDevice");
+	pXIFreeDeviceInfo = dlsym(lib, "XIFreeDeviceInfo");
+	int opcode, event, error, major = 2, minor = 0;
+	if (!pXIQueryVersion || !pXISelectEvents || !pXIQueryDevice || !pXIFreeDeviceInfo ||
+		!XQueryExtension(dpy, "XInputExtension", &opcode, &event, &error) ||
+		pXIQueryVersion(dpy, &major, &minor) != Success) {
+		dlclose(lib);
+		return;
+	}
+	xiOpcode = opcode;
+	queryPens();
+
+	// Find pens that are plugged in later.
+	unsigned char bits[XIMaskLen(XI_HierarchyChanged)] = {0};
+	XISetMask(bits, XI_HierarchyChanged);
+	XIEventMask mask = {XIAllDevices, sizeof bits, bits};
+	pXISelectEvents(dpy, DefaultRootWindow(dpy), &mask, 1);
+}
+
+// selectXI has XInput2 report w's pointer events in place of the core
+// events.
+static void selectXI(window *w) {
+	if (!xiOpcode) {
+		return;
+	}
+	unsigned char bits[XIMaskLen(XI_Motion)] = {0};
+	XISetMask(bits, XI_ButtonPress);
+	XISetMask(bits, XI_ButtonRelease);
+	XISetMask(bits, XI_Motion);
+	XIEventMask mask = {XIAllMasterDevices, sizeof bits, bits};
+	pXISelectEvents(dpy, w->win, &mask, 1);
+}
+
+static void openIM() {
+	// Input methods can't produce UTF-8 in the C locale.
+	if (strcmp(setlocale(LC_CTYPE, NULL), "C") == 0) {
+		setlocale(LC_CTYPE, "");
+	}
+	XSetLocaleModifiers("");
+	im = XOpenIM(dpy, NULL, NULL, NULL);
+	if (!im) {
+		return;
+	}
+
+	// Prefer drawing the composition ourselves, but settle for letting the
+	// input method draw it.
+	XIMStyles *styles = NULL;
+	if (XGetIMValues(im, XNQueryInputStyle, &styles, NULL) == NULL && styles) {
+		int i;
+		for (i = 0; i < styles->count_styles; i++) {
+			XIMStyle style = styles->supported_styles[i];
+			if (style == (XIMPreeditCallbacks | XIMStatusNothing)) {
+				imStyle = style;
+				break;
+			}
+			if (style == (XIMPreeditNothing | XIMStatusNothing)) {
+				imStyle = style;
+			}
+		}
+		XFree(styles);
+	}
+	if (!imStyle) {
+		XCloseIM(im);
+		im = NULL;
+	}
+}
+
+int openDisplay() {
+	XInitThreads();
+	dpy = XOpenDisplay(NULL);
+	if (!dpy) {
+		return 0;
+	}
+	int screen = DefaultScreen(dpy);
+	mmPerPxX = (double)DisplayWidthMM(dpy, screen) / DisplayWidth(dpy, screen);
+	mmPerPxY = (double)DisplayHeightMM(dpy, screen) / DisplayHeight(dpy, screen);
+	wmDeleteWindow = XInternAtom(dpy, "WM_DELETE_WINDOW", False);
+	// Report auto-repeat as repeated KeyPresses without the KeyReleases in between.
+	XkbSetDetectableAutoRepeat(dpy, True, NULL);
+	openIM();
+	openXI();
+	return 1;
+}
+
+// findWindowLocked returns the window of win, or NULL.  windowsLock must
+// be held.
+static window *findWindowLocked(Window win) {
+	window *w;
+	for (w = windows; w; w = w->next) {
+		if (w->win == win) {
+			return w;
+		}
+	}
+	return NULL;
+}
+
+static window *findWindow(Window win) {
+	pthread_mutex_lock(&windowsLock);
+	window *w = findWindowLocked(win);
+	pthread_mutex_unlock(&windowsLock);
+	return w;
+}
+
+static int preeditStart(XIC ic, XPointer client, XPointer data) {
+	window *w = (window*)client;
+	w->preeditLen = 0;
+	w->preeditCaret = 0;
+	w->preeditChanged = 1;
+	return -1; // no length limit
+}
+
+static void preeditDone(XIC ic, XPointer client, XPointer data) {
+	window *w = (window*)client;
+	w->preeditLen = 0;
+	w->preeditCaret = 0;
+	w->preeditChanged = 1;
+}
+
+static void preeditDraw(XIC ic, XPointer client, XIMPreeditDrawCallbackStruct *data) {
+	window *w = (window*)client;
+
+	wchar_t *text = NULL;
+	int n = 0;
+	if (data->text) {
+		n = data->text->length;
+		text = malloc((n + 1) * sizeof *text);
+		if (data->text->encoding_is_wchar) {
+			memcpy(text, data->text->string.wide_char, n * sizeof *text);
+		} else if (data->text->string.multi_byte) {
+			int m = mbstowcs(text, data->text->string.multi_byte, n + 1);
+			n = m < 0 ? 0 : m;
+		} else {
+			n = 0;
+		}
+	}
+
+	int first = data->chg_first, len = data->chg_length;
+	if (first < 0 || first > w->preeditLen) {
+		first = w->preeditLen;
+	}
+	if (len < 0 || first + len > w->preeditLen) {
+		len = w->preeditLen - first;
+	}
+	int newLen = w->preeditLen - len + n;
+	if (newLen > w->preeditCap) {
+		w->preeditCap = newLen * 2;
+		w->preedit = realloc(w->preedit, w->preeditCap * sizeof *w->preedit);
+	}
+	memmove(w->preedit + first + n, w->preedit + first + len, (w->preeditLen - first - len) * sizeof *w->preedit);
+	memcpy(w->preedit + first, text, n * sizeof *text);
+	w->preeditLen = newLen;
+	w->preeditCaret = data->caret;
+	w->preeditChanged = 1;
+	free(text);
+}
+
+static void preeditCaret(XIC ic, XPointer client, XIMPreeditCaretCallbackStruct *data) {
+	window *w = (window*)client;
+	switch (data->direction) {
+	case XIMAbsolutePosition:
+		w->preeditCaret = data->position;
+		break;
+	case XIMForwardChar:
+		w->preeditCaret++;
+		break;
+	case XIMBackwardChar:
+		w->preeditCaret--;
+		break;
+	case XIMLineStart:
+		w->preeditCaret = 0;
+		break;
+	case XIMLineEnd:
+		w->preeditCaret = w->preeditLen;
+		break;
+	default:
+		break;
+	}
+	if (w->preeditCaret < 0) {
+		w->preeditCaret = 0;
+	}
+	if (w->preeditCaret > w->preeditLen) {
+		w->preeditCaret = w->preeditLen;
+	}
+	data->position = w->preeditCaret;
+	w->preeditChanged = 1;
+}
+
+static void createIC(window *w) {
+	if (!im) {
+		return;
+	}
+	if (imStyle & XIMPreeditCallbacks) {
+		XIMCallback start = {(XPointer)w, (XIMProc)preeditStart};
+		XIMCallback done = {(XPointer)w, (XIMProc)preeditDone};
+		XIMCallback draw = {(XPointer)w, (XIMProc)preeditDraw};
+		XIMCallback caret = {(XPointer)w, (XIMProc)preeditCaret};
+		XVaNestedList attr = XVaCreateNestedList(0,
+			XNPreeditStartCallback, &start,
+			XNPreeditDoneCallback, &done,
+			XNPreeditDrawCallback, &draw,
+			XNPreeditCaretCallback, &caret,
+			NULL);
+		w->ic = XCreateIC(im, XNInputStyle, imStyle, XNClientWindow, w->win, XNFocusWindow, w->win,
+			XNPreeditAttributes, attr, NULL);
+		XFree(attr);
+	} else {
+		w->ic = XCreateIC(im, XNInputStyle, imStyle, XNClientWindow, w->win, XNFocusWindow, w->win, NULL);
+	}
+	if (!w->ic) {
+		return;
+	}
+	XUnsetICFocus(w->ic);
+
+	// The input method may need to see more events than we do.
+	long filterMask = 0;
+	XGetICValues(w->ic, XNFilterEvents, &filterMask, NULL);
+	XSelectInput(dpy, w->win, w->eventMask | filterMask);
+}
+
+uintptr_t newWindow(double width, double height) {
+	static int fbAttr[] = {
+		GLX_X_RENDERABLE,  True,
+		GLX_DRAWABLE_TYPE, GLX_WINDOW_BIT,
+		GLX_RENDER_TYPE,   GLX_RGBA_BIT,
+		GLX_RED_SIZE,      8,
+		GLX_GREEN_SIZE,    8,
+		GLX_BLUE_SIZE,     8,
+		GLX_ALPHA_SIZE,    8,
+		GLX_DEPTH_SIZE,    16,
+		GLX_DOUBLEBUFFER,  True,
+		None
+	};
+	static int ctxAttr[] = {
+		GLX_CONTEXT_MAJOR_VERSION_ARB, 3,
+		GLX_CONTEXT_MINOR_VERSION_ARB, 2,
+		GLX_CONTEXT_PROFILE_MASK_ARB,  GLX_CONTEXT_CORE_PROFILE_BIT_ARB,
+		GLX_CONTEXT_FLAGS_ARB,         GLX_CONTEXT_FORWARD_COMPATIBLE_BIT_ARB,
+		None
+	};
+
+	int screen = DefaultScreen(dpy);
+	int n;
+	GLXFBConfig *configs = glXChooseFBConfig(dpy, screen, fbAttr, &n);
+	if (!configs || n == 0) {
+		fprintf(stderr, "no suitable GLX framebuffer config\n");
+		return 0;
+	}
+	GLXFBConfig config = configs[0];
+	XFree(configs);
+
+	glXCreateContextAttribsARBProc glXCreateContextAttribsARB =
+		(glXCreateContextAttribsARBProc)glXGetProcAddressARB((const GLubyte*)"glXCreateContextAttribsARB");
+	if (!glXCreateContextAttribsARB) {
+		fprintf(stderr, "GLX_ARB_create_context is not supported\n");
+		return 0;
+	}
+	GLXContext ctx = glXCreateContextAttribsARB(dpy, config, NULL, True, ctxAttr);
+	if (!ctx) {
+		fprintf(stderr, "failed to create an OpenGL 3.2 core context\n");
+		return 0;
+	}
+
+	XVisualInfo *vi = glXGetVisualFromFBConfig(dpy, config);
+	Window root = RootWindow(dpy, screen);
+	XSetWindowAttributes attr;
+	memset(&attr, 0, sizeof attr);
+	attr.colormap = XCreateColormap(dpy, root, vi->visual, AllocNone);
+	attr.event_mask = StructureNotifyMask | ExposureMask |
+		ButtonPressMask | ButtonReleaseMask | PointerMotionMask |
+		EnterWindowMask | LeaveWindowMask | KeyPressMask | KeyReleaseMask | FocusChangeMask;
+
+	int w = (int)(width / mmPerPxX + 0.5);
+	int h = (int)(height / mmPerPxY + 0.5);
+	Window win = XCreateWindow(dpy, root, 0, 0, w, h, 0, vi->depth, InputOutput, vi->visual,
+		CWColormap | CWEventMask, &attr);
+	XFree(vi);
+
+	XSetWMProtocols(dpy, win, &wmDeleteWindow, 1);
+
+	window *wnd = calloc(1, sizeof *wnd);
+	wnd->win = win;
+	wnd->ctx = ctx;
+	wnd->width = w;
+	wnd->height = h;
+	wnd->eventMask = attr.event_mask;
+	XLockDisplay(dpy);
+	createIC(wnd);
+	selectXI(wnd);
+	XUnlockDisplay(dpy);
+
+	pthread_mutex_lock(&windowsLock);
+	wnd->next = windows;
+	windows = wnd;
+	pthread_mutex_unlock(&windowsLock);
+
+	return (uintptr_t)win;
+}
+
+// mapWindow shows a window.  It is separate from newWindow so that Go can
+// register the window before any of its events arrive.
+void mapWindow(uintptr_t win) {
+	XMapWindow(dpy, (Window)win);
+	XFlush(dpy);
+}
+
+void makeCurrentContext(uintptr_t context) {
+	window *w = (window*)context;
+	glXMakeCurrent(dpy, w->win, w->ctx);
+}
+
+void flushContext(uintptr_t context) {
+	window *w = (window*)context;
+	glXSwapBuffers(dpy, w->win);
+}
+
+static void callResize(window *w) {
+	resize((GoUintptr)w->win, mmPerPxX * w->width, mmPerPxY * w->height, mmPerPxX, mmPerPxY);
+}
+
+// updateICFocus focuses the input context while both the window and a
+// TextInput in it have the focus.
+static void updateICFocus(window *w) {
+	if (!w->ic) {
+		return;
+	}
+	if (w->focused && w->textInput) {
+		XSetICFocus(w->ic);
+	} else {
+		XUnsetICFocus(w->ic);
+	}
+}
+
+void setTextInput(uintptr_t win, int enabled, int x, int y, int width, int height) {
+	window *w = findWindow((Window)win);
+	if (!w || !w->ic) {
+		return;
+	}
+	XLockDisplay(dpy);
+	if (enabled) {
+		// Candidate windows go below the spot.
+		XPoint spot = {x, y + height};
+		XVaNestedList attr = XVaCreateNestedList(0, XNSpotLocation, &spot, NULL);
+		XSetICValues(w->ic, XNPreeditAttributes, attr, NULL);
+		XFree(attr);
+	} else if (w->textInput) {
+		// Abandon any composition.
+		char *s = Xutf8ResetIC(w->ic);
+		if (s) {
+			XFree(s);
+		}
+	}
+	w->textInput = enabled;
+	updateICFocus(w);
+	XUnlockDisplay(dpy);
+	XFlush(dpy);
+}
+
+// sendPreedit sends the composition to Go if it changed.
+static void sendPreedit(window *w) {
+	if (!w->preeditChanged) {
+		return;
+	}
+	w->preeditChanged = 0;
+
+	char *buf = malloc(4 * w->preeditLen + 1);
+	int i, n = 0, caret = 0;
+	for (i = 0; i < w->preeditLen; i++) {
+		uint32_t r = w->preedit[i];
+		if (r > 0x10ffff || (r >= 0xd800 && r <= 0xdfff)) {
+			r = 0xfffd;
+		}
+		if (r < 0x80) {
+			buf[n++] = r;
+		} else if (r < 0x800) {
+			buf[n++] = 0xc0 | r>>6;
+			buf[n++] = 0x80 | (r & 0x3f);
+		} else if (r < 0x10000) {
+			buf[n++] = 0xe0 | r>>12;
+			buf[n++] = 0x80 | (r>>6 & 0x3f);
+			buf[n++] = 0x80 | (r & 0x3f);
+		} else {
+			buf[n++] = 0xf0 | r>>18;
+			buf[n++] = 0x80 | (r>>12 & 0x3f);
+			buf[n++] = 0x80 | (r>>6 & 0x3f);
+			buf[n++] = 0x80 | (r & 0x3f);
+		}
+	}
+	buf[n] = 0;
+	composeText((GoUintptr)w->win, buf, w->preeditCaret);
+	free(buf);
+}
+
+// lookupText sends the text typed with a key press to Go.
+static void lookupText(window *w, XKeyEvent *ev) {
+	char buf[64];
+	char *text = buf;
+	KeySym keysym;
+	Status status;
+	int n = Xutf8LookupString(w->ic, ev, buf, sizeof buf - 1, &keysym, &status);
+	if (status == XBufferOverflow) {
+		text = malloc(n + 1);
+		n = Xutf8LookupString(w->ic, ev, text, n, &keysym, &status);
+	}
+	// Control characters are left to key events.
+	if ((status == XLookupChars || status == XLookupBoth) && n > 0 &&
+			(unsigned char)text[0] >= 0x20 && text[0] != 0x7f) {
+		text[n] = 0;
+		commitText((GoUintptr)w->win, text);
+	}
+	if (text != buf) {
+		free(text);
+	}
+}
+
+// sendScroll sends a scroll event if button is one of the scroll wheel's,
+// 4 through 7: up, down, left and right.  A click scrolls three lines.
+static int sendScroll(window *w, double x, double y, int button, unsigned int state) {
+	if (button < 4 || button > 7) {
+		return 0;
+	}
+	static const double dx[] = {0, 0, 3, -3}, dy[] = {3, -3, 0, 0};
+	int i = button - 4;
+	scrollEvent((GoUintptr)w->win, x, w->height - y, dx[i], dy[i], state);
+	return 1;
+}
+
+// handleXIEvent handles the XInput2 events that replace the core pointer
+// events, adding the axes of pens.
+static void handleXIEvent(XGenericEventCookie *cookie) {
+	if (cookie->evtype == XI_HierarchyChanged) {
+		queryPens();
+		return;
+	}
+	XIDeviceEvent *ev = cookie->data;
+	window *w = findWindow(ev->event);
+	if (!w) {
+		return;
+	}
+
+	int type, button = 0;
+	switch (cookie->evtype) {
+	default:
+		return;
+	case XI_Motion:
+		type = MotionNotify;
+		break;
+	case XI_ButtonPress:
+		if (sendScroll(w, ev->event_x, ev->event_y, ev->detail, ev->mods.effective)) {
+			return;
+		}
+		type = ButtonPress;
+		button = ev->detail;
+		break;
+	case XI_ButtonRelease:
+		type = ButtonRelease;
+		button = ev->detail;
+		break;
+	}
+
+	int tool = 0;
+	double axes[PEN_AXES] = {0};
+	pen *p = findPen(ev->sourceid);
+	if (p) {
+		// Valuators are only sent when they change.
+		int i, a, n = 0;
+		for (i = 0; i < ev->valuators.mask_len * 8; i++) {
+			if (!XIMaskIsSet(ev->valuators.mask, i)) {
+				continue;
+			}
+			double v = ev->valuators.values[n++];
+			for (a = 0; a < PEN_AXES; a++) {
+				if (p->axis[a] == i) {
+					p->value[a] = v;
+				}
+			}
+		}
+		tool = p->eraser ? 2 : 1;
+		for (a = 0; a < PEN_AXES; a++) {
+			if (p->axis[a] >= 0 && p->max[a] > p->min[a]) {
+				axes[a] = p->value[a];
+				if (a == PRESSURE || a == WHEEL) {
+					axes[a] = (axes[a] - p->min[a]) / (p->max[a] - p->min[a]);
+				}
+			}
+		}
+	}
+	// Tilt is reported in degrees and the wheel, which is the rotation of
+	// pens that have one, in its own range.
+	mouseEvent((GoUintptr)w->win, ev->event_x, w->height - ev->event_y, type, button,
+		tool, axes[PRESSURE], axes[TILT_X], axes[TILT_Y], 360 * axes[WHEEL]);
+}
+
+static void handleEvent(XEvent *ev) {
+	window *w = findWindow(ev->xany.window);
+	if (!w) {
+		return;
+	}
+
+	switch (ev->type) {
+	case MapNotify:
+		if (!w->prepared) {
+			w->prepared = 1;
+			preparedOpenGL((GoUintptr)w->win, (GoUintptr)w);
+			callResize(w);
+		}
+		break;
+	case ConfigureNotify:
+		if (ev->xconfigure.width != w->width || ev->xconfigure.height != w->height) {
+			w->width = ev->xconfigure.width;
+			w->height = ev->xconfigure.height;
+			if (w->prepared) {
+				callResize(w);
+			}
+		}
+		break;
+	case Expose:
+		if (w->prepared && ev->xexpose.count == 0) {
+			expose((GoUintptr)w->win);
+		}
+		break;
+	// X11's origin is the top-left corner; flip y to match Cocoa's.
+	case ButtonPress:
+		if (sendScroll(w, ev->xbutton.x, ev->xbutton.y, ev->xbutton.button, ev->xbutton.state)) {
+			break;
+		}
+		// fallthrough
+	case ButtonRelease:
+		mouseEvent((GoUintptr)w->win, ev->xbutton.x, w->height - ev->xbutton.y, ev->type, ev->xbutton.button, 0, 0, 0, 0, 0);
+		break;
+	case MotionNotify:
+		mouseEvent((GoUintptr)w->win, ev->xmotion.x, w->height - ev->xmotion.y, ev->type, 0, 0, 0, 0, 0, 0);
+		break;
+	case EnterNotify:
+	case LeaveNotify:
+		mouseEvent((GoUintptr)w->win, ev->xcrossing.x, w->height - ev->xcrossing.y, ev->type, 0, 0, 0, 0, 0, 0);
+		break;
+	case KeyPress:
+	case KeyRelease: {
+		char buf[8];
+		KeySym keysym = NoSymbol;
+		int n = XLookupString(&ev->xkey, buf, sizeof buf, &keysym, NULL);
+		keyEvent((GoUintptr)w->win, ev->type, ev->xkey.keycode, keysym,
+			n == 1 ? (unsigned char)buf[0] : -1, ev->xkey.state);
+		if (ev->type == KeyPress && w->ic && w->textInput) {
+			lookupText(w, &ev->xkey);
+		}
+		break;
+	}
+	case FocusIn:
+	case FocusOut:
+		XLockDisplay(dpy);
+		w->focused = ev->type == FocusIn;
+		updateICFocus(w);
+		XUnlockDisplay(dpy);
+		break;
+	case ClientMessage:
+		// Until it is prepared, the window has no loop in Go to end.
+		if ((Atom)ev->xclient.data.l[0] == wmDeleteWindow && w->prepared) {
+			w->closing = 1;
+		}
+		break;
+	}
+}
+
+// destroyWindow destroys a window that is closing, once Go has stopped
+// using it.  It returns whether any windows remain.
+static int destroyWindow(window *w) {
+	closeWindow((GoUintptr)w->win);
+
+	pthread_mutex_lock(&windowsLock);
+	window **p;
+	for (p = &windows; *p; p = &(*p)->next) {
+		if (*p == w) {
+			*p = w->next;
+			break;
+		}
+	}
+	int remain = windows != NULL;
+	pthread_mutex_unlock(&windowsLock);
+
+	if (w->ic) {
+		XDestroyIC(w->ic);
+	}
+	glXDestroyContext(dpy, w->ctx);
+	XDestroyWindow(dpy, w->win);
+	XFlush(dpy);
+	free(w->preedit);
+	free(w);
+	return remain;
+}
+
+// runApp handles events until the last window is closed.
+void runApp() {
+	for (;;) {
+		XEvent ev;
+		XNextEvent(dpy, &ev);
+		if (xiOpcode && ev.type == GenericEvent && ev.xcookie.extension == xiOpcode) {
+			if (XGetEventData(dpy, &ev.xcookie)) {
+				handleXIEvent(&ev.xcookie);
+				XFreeEventData(dpy, &ev.xcookie);
+			}
+			continue;
+		}
+		window *w = findWindow(ev.xany.window);
+		// Key events only go to the input method while a TextInput has the focus.
+		int isKey = ev.type == KeyPress || ev.type == KeyRelease;
+		if ((!isKey || (w && w->textInput)) && XFilterEvent(&ev, None)) {
+			if (w) {
+				sendPreedit(w);
+			}
+			continue;
+		}
+		handleEvent(&ev);
+		if (w && w->closing) {
+			if (!destroyWindow(w)) {
+				return;
+			}
+			continue;
+		}
+		if (w) {
+			sendPreedit(w);
+		}
+	}
+}
+
+// releaseContext releases the calling thread's OpenGL context, so that it
+// can be destroyed.
+void releaseContext() {
+	glXMakeCurrent(dpy, None, NULL);
+}
+
+// mapFromScreen maps a position in pixels from the top left of the screen
+// to one from the bottom left of a window.  It leaves the position alone if
+// the window has been closed.
+void mapFromScreen(uintptr_t win, double *x, double *y) {
+	// The lock keeps the event thread from destroying the window meanwhile.
+	pthread_mutex_lock(&windowsLock);
+	window *w = findWindowLocked((Window)win);
+	if (!w) {
+		pthread_mutex_unlock(&windowsLock);
+		return;
+	}
+	int ox, oy;
+	Window child;
+	XTranslateCoordinates(dpy, DefaultRootWindow(dpy), w->win, 0, 0, &ox, &oy, &child);
+	*x += ox;
+	*y = w->height - (*y + oy);
+	pthread_mutex_unlock(&windowsLock);
+}
+
+void screenSize(double *width, double *height) {
+	*width = *height = 0;
+	if (dpy) {
+		int screen = DefaultScreen(dpy);
+		*width = DisplayWidth(dpy, screen);
+		*height = DisplayHeight(dpy, screen);
+	}
+}
+
+// sendButton sends a window a core button event at a position in pixels
+// from its top left, as a real click would.  It is for tests.
+void sendButton(uintptr_t win, int press, int x, int y) {
+	XEvent ev;
+	memset(&ev, 0, sizeof ev);
+	ev.xbutton.type = press ? ButtonPress : ButtonRelease;
+	ev.xbutton.display = dpy;
+	ev.xbutton.window = (Window)win;
+	ev.xbutton.root = DefaultRootWindow(dpy);
+	ev.xbutton.x = x;
+	ev.xbutton.y = y;
+	ev.xbutton.button = Button1;
+	ev.xbutton.same_screen = True;
+	XSendEvent(dpy, (Window)win, False, 0, &ev);
+	XFlush(dpy);
+}
+
+// sendClose asks a window to close, as a window manager would.  It is for
+// tests.
+void sendClose(uintptr_t win) {
+	XEvent ev;
+	memset(&ev, 0, sizeof ev);
+	ev.xclient.type = ClientMessage;
+	ev.xclient.display = dpy;
+	ev.xclient.window = (Window)win;
+	ev.xclient.message_type = XInternAtom(dpy, "WM_PROTOCOLS", False);
+	ev.xclient.format = 32;
+	ev.xclient.data.l[0] = wmDeleteWindow;
+	ev.xclient.data.l[1] = CurrentTime;
+	XSendEvent(dpy, (Window)win, False, 0, &ev);
+	XFlush(dpy);
+}
@@ -0,0 +1,358 @@
+// +build linux
+// +build !android,!wayland
+
+package ui
+
+/*
+#cgo pkg-config: x11 gl
+#cgo LDFLAGS: -ldl
+#include <X11/Xlib.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+int openDisplay();
+void runApp();
+uintptr_t newWindow(double width, double height);
+void mapWindow(uintptr_t window);
+void releaseContext();
+void makeCurrentContext(uintptr_t ctx);
+void flushContext(uintptr_t ctx);
+void mapFromScreen(uintptr_t window, double *x, double *y);
+void screenSize(double *width, double *height);
+void setTextInput(uintptr_t window, int enabled, int x, int y, int width, int height);
+void sendButton(uintptr_t window, int press, int x, int y);
+void sendClose(uintptr_t window);
+*/
+import "C"
+
+import (
+	"errors"
+	"log"
+	"math"
+
+	"github.com/go-gl/gl/v3.2-core/gl"
+)
+
+var displayOpen = false
+
+// run returns when the last window is closed.
+func run(cb func()) {
+	if C.openDisplay() == 0 {
+		log.Fatal("cannot open X display")
+	}
+	displayOpen = true
+
+	go cb()
+	C.runApp()
+}
+
+func newWindowImpl(size Size) (uintptr, error) {
+	if !displayOpen {
+		return 0, errors.New("the X display is not open; windows must be created from the ui.Run callback")
+	}
+	w := C.newWindow(C.double(size.Width), C.double(size.Height))
+	if w == 0 {
+		return 0, errors.New("failed to create X window")
+	}
+	return uintptr(w), nil
+}
+
+func mapWindowImpl(w uintptr) {
+	C.mapWindow(C.uintptr_t(w))
+}
+
+//export preparedOpenGL
+func preparedOpenGL(window uintptr, ctx uintptr) {
+	go windowLoop(window, ctx)
+}
+
+// closeWindow ends the loop of a window that is closing and returns once its
+// context is no longer current, so that the window can be destroyed.
+//
+//export closeWindow
+func closeWindow(window uintptr) {
+	windowsMu.Lock()
+	w := windows[window]
+	windowsMu.Unlock()
+
+	w.send(closeEvent{release: func() { C.releaseContext() }})
+	<-w.closed
+
+	windowsMu.Lock()
+	delete(windows, window)
+	windowsMu.Unlock()
+}
+
+func makeCurrentContext(ctx uintptr) {
+	C.makeCurrentContext(C.uintptr_t(ctx))
+}
+
+func flushContext(ctx uintptr) {
+	C.flushContext(C.uintptr_t(ctx))
+}
+
+func beginFrame(ctx uintptr) bool { return true }
+
+// resizeContext updates the GL viewport, which X11 leaves at the window's
+// initial size.
+func resizeContext(ctx uintptr, s sizeEvent) {
+	gl.Viewport(0, 0, int32(math.Round(s.size.Width/s.px.Width)), int32(math.Round(s.size.Height/s.px.Height)))
+}
+
+//export resize
+func resize(window uintptr, width, height, pxWidth, pxHeight float64) {
+	windowsMu.Lock()
+	w := windows[window]
+	windowsMu.Unlock()
+
+	w.send(sizeEvent{
+		size: Size{width, height},
+		px:   Size{pxWidth, pxHeight},
+	})
+}
+
+//export expose
+func expose(window uintptr) {
+	windowsMu.Lock()
+	w := windows[window]
+	windowsMu.Unlock()
+
+	w.Redraw()
+}
+
+var mousePointer = activePointers.new(Pointer{
+	Type: PointerTypeMouse,
+})
+
+// mouseEvent receives pointer positions in pixels relative to the bottom-left
+// corner of the window, matching the Cocoa backend, and the tool of the event
+// and, for pens, its normalized pressure, tilt in degrees and twist.
+//
+//export mouseEvent
+func mouseEvent(window uintptr, x, y float64, typ, button, tool int32, pressure, tiltX, tiltY, twist float64) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	var down, up, leave bool
+
+	p := mousePointer
+	if tool != toolMouse {
+		p = penPointer
+	}
+	p.X = x
+	p.Y = y
+
+	switch typ {
+	case C.MotionNotify, C.EnterNotify:
+		p.Button = PointerButtonNone
+	case C.LeaveNotify:
+		leave = true
+		p.Button = PointerButtonNone
+	case C.ButtonPress:
+		b := x11Button(tool, button)
+		if b == PointerButtonNone {
+			return
+		}
+		down = true
+		p.Button = b
+		p.Buttons |= p.Button
+	case C.ButtonRelease:
+		b := x11Button(tool, button)
+		if b == PointerButtonNone {
+			return
+		}
+		up = true
+		p.Button = b
+		p.Buttons &^= p.Button
+	}
+
+	if tool == toolMouse {
+		p.setDefaultPressure()
+	} else {
+		p.Pressure = pressure
+		p.TiltX = math.Max(-90, math.Min(90, tiltX))
+		p.TiltY = math.Max(-90, math.Min(90, tiltY))
+		p.Twist = twist
+	}
+
+	w.send(pointerEvent{
+		down:  down,
+		up:    up,
+		leave: leave,
+		p:     *p,
+	})
+	if leave {
+		// The pen may have hovered too.
+		w.send(pointerEvent{leave: true, p: *penPointer})
+	}
+}
+
+// scrollEvent receives a scroll wheel click as a position, like mouseEvent,
+// and a delta in lines.
+//
+//export scrollEvent
+func scrollEvent(window uintptr, x, y, dx, dy float64, state uint32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	w.send(Scroll{
+		Position:  Position{x, y},
+		Delta:     Size{dx, dy},
+		Modifiers: x11Mods(state),
+	})
+}
+
+func x11Button(tool, button int32) PointerButtons {
+	if tool != toolMouse {
+		switch button {
+		case C.Button1:
+			return penButton(tool, true)
+		case C.Button2, C.Button3:
+			return penButton(tool, false)
+		}
+		return PointerButtonNone
+	}
+	return x11MouseButton(button)
+}
+
+func x11MouseButton(button int32) PointerButtons {
+	switch button {
+	default:
+		// Buttons 4 through 7 are the scroll wheel.
+		return PointerButtonNone
+	case C.Button1:
+		return PointerButtonLeftMouse
+	case C.Button2:
+		return PointerButtonMiddleMouse
+	case C.Button3:
+		return PointerButtonRightMouse
+	case 8:
+		return PointerButtonX1BackMouse
+	case 9:
+		return PointerButtonX2ForwardMouse
+	}
+}
+
+// pressedKeys holds the X key codes that are down, to detect repeats.
+var pressedKeys = map[uint32]bool{}
+
+// keyEvent receives an X key code, its keysym and, if the key produced a
+// single Latin-1 character, that character.
+//
+//export keyEvent
+func keyEvent(window uintptr, typ int32, keycode uint32, keysym uint64, ch int32, state uint32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	k := Key{
+		// X key codes are evdev codes offset by 8.
+		Code:      evdevKeyCode(keycode - 8),
+		Rune:      x11Rune(keysym, ch),
+		Modifiers: x11Mods(state),
+	}
+	switch typ {
+	case C.KeyPress:
+		k.Direction = KeyPress
+		k.Repeat = pressedKeys[keycode]
+		pressedKeys[keycode] = true
+	case C.KeyRelease:
+		k.Direction = KeyRelease
+		delete(pressedKeys, keycode)
+	}
+
+	w.send(k)
+}
+
+//export commitText
+func commitText(window uintptr, text *C.char) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	w.send(textEvent{c: Composition{Text: C.GoString(text)}})
+}
+
+// composeText receives the composition and its cursor position in characters.
+//
+//export composeText
+func composeText(window uintptr, text *C.char, cursor int32) {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	w := windows[window]
+
+	s := C.GoString(text)
+	w.send(textEvent{
+		compose: true,
+		c:       Composition{Text: s, Cursor: runeOffsetToByte(s, int(cursor))},
+	})
+}
+
+func setTextInput(window uintptr, t textInputState) {
+	enabled := C.int(0)
+	if t.enabled {
+		enabled = 1
+	}
+	C.setTextInput(C.uintptr_t(window), enabled, C.int(t.x), C.int(t.y), C.int(t.w), C.int(t.h))
+}
+
+func x11Rune(keysym uint64, ch int32) rune {
+	switch {
+	case 0x20 <= keysym && keysym <= 0x7e, 0xa0 <= keysym && keysym <= 0xff:
+		// Latin-1 keysyms are their own code points.
+		return rune(keysym)
+	case keysym&0xff000000 == 0x01000000:
+		return rune(keysym & 0xffffff)
+	}
+	// Keys like Return and the keypad produce characters but have other keysyms.
+	return ch
+}
+
+func x11Mods(state uint32) (m KeyModifiers) {
+	if state&C.ShiftMask != 0 {
+		m |= KeyModifierShift
+	}
+	if state&C.ControlMask != 0 {
+		m |= KeyModifierControl
+	}
+	if state&C.Mod1Mask != 0 {
+		m |= KeyModifierAlt
+	}
+	if state&C.Mod4Mask != 0 {
+		m |= KeyModifierMeta
+	}
+	return m
+}
+
+// screenSize returns the size of the default screen in pixels.
+func screenSize() Size {
+	var width, height C.double
+	C.screenSize(&width, &height)
+	return Size{float64(width), float64(height)}
+}
+
+func (w *window) MapFromParent(p Position) Position {
+	x, y := C.double(p.X), C.double(p.Y)
+	C.mapFromScreen(C.uintptr_t(w.w), &x, &y)
+	return Position{
+		X: float64(x),
+		Y: float64(y),
+	}
+}
+
+// sendButton sends w a press or release of the left mouse button at a
+// position in pixels from its top left.  It is for tests.
+func (w *window) sendButton(press bool, x, y int) {
+	p := C.int(0)
+	if press {
+		p = 1
+	}
+	C.sendButton(C.uintptr_t(w.w), p, C.int(x), C.int(y))
+}
+
+// sendClose asks w to close, as a window manager would.  It is for tests.
+func (w *window) sendClose() {
+	C.sendClose(C.uintptr_t(w.w))
+}
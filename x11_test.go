@@ -0,0 +1,85 @@
+// +build linux
+// +build !android,!wayland
+
+package ui
+
+import (
+	"image/color"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestX11 opens a window on a real X server, such as one started by
+// xvfb-run, draws it, clicks it and closes it.
+func TestX11(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("no X display; run the tests under xvfb-run")
+	}
+
+	v := &backendView{drawn: make(chan struct{}, 1), down: make(chan Position, 1)}
+	v.View = NewView(v, nil)
+	created := make(chan *window, 1)
+	done := make(chan struct{})
+	go func() {
+		run(func() {
+			w, err := newWindow(Size{Width: 40, Height: 30}, v)
+			if err != nil {
+				t.Error(err)
+				created <- nil
+				return
+			}
+			created <- w.(*window)
+		})
+		close(done)
+	}()
+
+	timeout := time.After(10 * time.Second)
+	var w *window
+	select {
+	case w = <-created:
+		if w == nil {
+			return
+		}
+	case <-timeout:
+		t.Fatal("timed out creating the window")
+	}
+	select {
+	case <-v.drawn:
+	case <-timeout:
+		t.Fatal("timed out waiting for the window to draw")
+	}
+
+	img, err := w.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := img.Bounds()
+	if c := img.RGBAAt(b.Dx()/2, b.Dy()/2); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("got color %v in the middle of the window, want red", c)
+	}
+
+	// A click near the top left of the window lands near the view's origin.
+	w.sendButton(true, 2, 2)
+	w.sendButton(false, 2, 2)
+	select {
+	case p := <-v.down:
+		if s := v.Size(); p.X < 0 || p.X > s.Width/2 || p.Y < 0 || p.Y > s.Height/2 {
+			t.Errorf("got pointer down at %v, want it near the top left of a view of size %v", p, s)
+		}
+	case <-timeout:
+		t.Fatal("timed out waiting for the pointer down")
+	}
+
+	w.sendClose()
+	select {
+	case <-done:
+	case <-timeout:
+		t.Fatal("timed out waiting for run to return after the last window closed")
+	}
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	if windows[w.w] != nil {
+		t.Error("the closed window is still registered")
+	}
+}